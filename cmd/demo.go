@@ -0,0 +1,34 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/joshuar/go-hass-agent/cmd/text"
+	"github.com/joshuar/go-hass-agent/internal/agent"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+// demoCmd represents the demo command.
+var demoCmd = &cobra.Command{
+	Use:   "demo",
+	Short: "Run the agent UI with simulated data, without connecting to Home Assistant",
+	Long:  text.DemoCmdLongText,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		logging.SetLoggingLevel(traceFlag, debugFlag, profileFlag)
+		setupPaths()
+		logging.SetLogFile()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		agent := agent.New(&agent.Options{
+			Headless: headlessFlag,
+			ID:       AppID,
+			Demo:     true,
+		})
+		agent.Demo()
+	},
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/joshuar/go-hass-agent/cmd/text"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+var (
+	logsFollowFlag                bool
+	logsLevelFlag, logsModuleFlag string
+	logsSinceFlag, logsUntilFlag  string
+)
+
+// logsCmd represents the logs command.
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the agent log",
+	Long:  text.LogsCmdLongText,
+	// Don't log to the file that is being read.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		logging.SetLoggingLevel(traceFlag, debugFlag, profileFlag)
+		setupPaths()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := &logging.Filter{Module: logsModuleFlag}
+		level, err := zerolog.ParseLevel(logsLevelFlag)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid log level.")
+		}
+		filter.Level = &level
+		now := time.Now()
+		if logsSinceFlag != "" {
+			if filter.Since, err = logging.ParseTime(logsSinceFlag, now); err != nil {
+				log.Fatal().Err(err).Msg("Invalid start time.")
+			}
+		}
+		if logsUntilFlag != "" {
+			if filter.Until, err = logging.ParseTime(logsUntilFlag, now); err != nil {
+				log.Fatal().Err(err).Msg("Invalid end time.")
+			}
+		}
+		ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancelFunc()
+		if err := logging.PrintLogs(ctx, os.Stdout, filter, logsFollowFlag); err != nil {
+			log.Fatal().Err(err).Msg("Could not read log file.")
+		}
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollowFlag,
+		"follow", "f", false,
+		"Keep printing new log entries as they are written.")
+	logsCmd.Flags().StringVar(&logsLevelFlag,
+		"level", "trace",
+		"Only print entries at this level or above (trace, debug, info, warn, error).")
+	logsCmd.Flags().StringVar(&logsModuleFlag,
+		"module", "",
+		"Only print entries logged from source files matching this path (e.g. battery or linux/net).")
+	logsCmd.Flags().StringVar(&logsSinceFlag,
+		"since", "",
+		"Only print entries after this time, as a duration ago (e.g. 1h) or RFC3339 timestamp.")
+	logsCmd.Flags().StringVar(&logsUntilFlag,
+		"until", "",
+		"Only print entries before this time, as a duration ago (e.g. 10m) or RFC3339 timestamp.")
+}
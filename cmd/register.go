@@ -12,12 +12,14 @@ import (
 	"github.com/joshuar/go-hass-agent/cmd/text"
 	"github.com/joshuar/go-hass-agent/internal/agent"
 	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/paths"
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
 
 var (
-	serverFlag, tokenFlag string
-	forcedFlag            bool
+	serverFlag, tokenFlag            string
+	deviceIDFlag, deviceIDSourceFlag string
+	forcedFlag                       bool
 )
 
 // registerCmd represents the register command.
@@ -27,20 +29,26 @@ var registerCmd = &cobra.Command{
 	Long:  text.RegisterCmdLongText,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		logging.SetLoggingLevel(traceFlag, debugFlag, profileFlag)
+		setupPaths()
 		logging.SetLogFile()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		lock := lockInstance()
+		defer lock.Release()
+
 		agent := agent.New(&agent.Options{
-			Headless:      headlessFlag,
-			ForceRegister: forcedFlag,
-			Server:        serverFlag,
-			Token:         tokenFlag,
-			ID:            AppID,
+			Headless:       headlessFlag,
+			ForceRegister:  forcedFlag,
+			Server:         serverFlag,
+			Token:          tokenFlag,
+			DeviceID:       deviceIDFlag,
+			DeviceIDSource: deviceIDSourceFlag,
+			ID:             AppID,
 		})
 		var err error
 
 		var trk *tracker.SensorTracker
-		if trk, err = tracker.NewSensorTracker(agent.AppID()); err != nil {
+		if trk, err = tracker.NewSensorTracker(paths.RegistryDir()); err != nil {
 			log.Fatal().Err(err).Msg("Could not start sensor tracker.")
 		}
 
@@ -55,6 +63,12 @@ func init() {
 	registerCmd.PersistentFlags().StringVar(&tokenFlag,
 		"token", "",
 		"Long-lived token (e.g. 123456)")
+	registerCmd.PersistentFlags().StringVar(&deviceIDFlag,
+		"device-id", "",
+		"Use the given device ID rather than one generated from the device (e.g. for containers).")
+	registerCmd.PersistentFlags().StringVar(&deviceIDSourceFlag,
+		"device-id-source", "",
+		"Source for the generated device ID (dmi, machine-id or host; only host is available on platforms other than Linux). Default is the first available.")
 	registerCmd.PersistentFlags().BoolVar(&forcedFlag,
 		"force", false,
 		"Ignore any previous registration and re-register the agent.")
@@ -6,6 +6,7 @@
 package cmd
 
 import (
+	"errors"
 	_ "net/http/pprof"
 	"os"
 
@@ -14,16 +15,23 @@ import (
 
 	"github.com/joshuar/go-hass-agent/cmd/text"
 	"github.com/joshuar/go-hass-agent/internal/agent"
+	"github.com/joshuar/go-hass-agent/internal/filelock"
 	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/paths"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
 
 var (
-	traceFlag    bool
-	debugFlag    bool
-	AppID        string
-	profileFlag  bool
-	headlessFlag bool
+	traceFlag     bool
+	debugFlag     bool
+	AppID         string
+	profileFlag   bool
+	headlessFlag  bool
+	telemetryFlag bool
+	configDirFlag string
+	dataDirFlag   string
+	cacheDirFlag  string
 )
 
 // rootCmd represents the base command when called without any subcommands.
@@ -33,21 +41,32 @@ var rootCmd = &cobra.Command{
 	Long:  text.RootCmdLongText,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		logging.SetLoggingLevel(traceFlag, debugFlag, profileFlag)
+		setupPaths()
 		logging.SetLogFile()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		lock := lockInstance()
+
 		agent := agent.New(&agent.Options{
-			Headless: headlessFlag,
-			ID:       AppID,
+			Headless:  headlessFlag,
+			ID:        AppID,
+			Telemetry: telemetryFlag,
 		})
 		var err error
 
 		var trk *tracker.SensorTracker
-		if trk, err = tracker.NewSensorTracker(agent.AppID()); err != nil {
+		if trk, err = tracker.NewSensorTracker(paths.RegistryDir()); err != nil {
 			log.Fatal().Err(err).Msg("Could not start sensor tracker.")
 		}
 
 		agent.Run(trk)
+
+		// Release the lock before any restart, so that the new instance can
+		// acquire it.
+		lock.Release()
+		if err := agent.Restart(); err != nil {
+			log.Fatal().Err(err).Msg("Could not restart.")
+		}
 	},
 }
 
@@ -68,9 +87,49 @@ func init() {
 		"specify a custom app ID (for debugging)")
 	rootCmd.PersistentFlags().BoolVar(&headlessFlag, "terminal", defaultHeadless(),
 		"run in terminal (without a GUI)")
+	rootCmd.PersistentFlags().StringVar(&configDirFlag, "config-dir", "",
+		"directory for preferences and scripts (default is $XDG_CONFIG_HOME/<appid>, or $"+paths.ConfigDirEnv+" if set)")
+	rootCmd.PersistentFlags().StringVar(&dataDirFlag, "data-dir", "",
+		"directory for the sensor registry and logs (default is $XDG_STATE_HOME/<appid>, or $"+paths.DataDirEnv+" if set)")
+	rootCmd.PersistentFlags().StringVar(&cacheDirFlag, "cache-dir", "",
+		"directory for cached data (default is $XDG_CACHE_HOME/<appid>, or $"+paths.CacheDirEnv+" if set)")
+	rootCmd.Flags().BoolVar(&telemetryFlag, "telemetry", false,
+		"opt-in to sending anonymous usage data (installation ID, agent version, OS and enabled features)")
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(registerCmd)
+	rootCmd.AddCommand(simulateCmd)
+	rootCmd.AddCommand(demoCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(sensorsCmd)
+	rootCmd.AddCommand(selftestCmd)
+}
+
+// setupPaths sets the directories used by the agent from the command-line
+// options and environment.
+func setupPaths() {
+	paths.Setup(AppID, paths.Dirs{
+		Config: configDirFlag,
+		Data:   dataDirFlag,
+		Cache:  cacheDirFlag,
+	})
+	preferences.SetPath(paths.ConfigDir())
+}
+
+// lockInstance ensures only one instance of the agent runs with the same data
+// directory, as concurrent instances would fight over the registration, sensor
+// registry and preferences. If another instance is running, it exits with a
+// message.
+func lockInstance() *filelock.Lock {
+	lock, err := filelock.TryAcquire(paths.LockFile())
+	switch {
+	case errors.Is(err, filelock.ErrLocked):
+		log.Fatal().Int("pid", filelock.Owner(paths.LockFile())).Str("data_dir", paths.DataDir()).
+			Msg("Go Hass Agent is already running. Stop the other instance or use a different --data-dir.")
+	case err != nil:
+		log.Fatal().Err(err).Msg("Could not check for another running instance.")
+	}
+	return lock
 }
 
 func defaultHeadless() bool {
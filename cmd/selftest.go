@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/joshuar/go-hass-agent/cmd/text"
+	"github.com/joshuar/go-hass-agent/internal/agent"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+var (
+	selftestTimeoutFlag time.Duration
+	selftestKeepFlag    bool
+)
+
+// selftestCmd represents the selftest command.
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check the agent works end-to-end with Home Assistant",
+	Long:  text.SelftestCmdLongText,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		logging.SetLoggingLevel(traceFlag, debugFlag, profileFlag)
+		setupPaths()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := &agent.SelfTestOptions{
+			Timeout: selftestTimeoutFlag,
+			Keep:    selftestKeepFlag,
+		}
+		agent := agent.New(&agent.Options{
+			Headless: true,
+			Server:   serverFlag,
+			Token:    tokenFlag,
+			ID:       AppID,
+		})
+		if err := agent.SelfTest(os.Stdout, opts); err != nil {
+			log.Fatal().Err(err).Msg("Self-test did not pass.")
+		}
+	},
+}
+
+func init() {
+	selftestCmd.Flags().StringVar(&serverFlag,
+		"server", "http://localhost:8123",
+		"URL to Home Assistant instance (e.g. https://somehost:someport)")
+	selftestCmd.Flags().StringVar(&tokenFlag,
+		"token", "",
+		"Long-lived token of an administrator (e.g. 123456)")
+	selftestCmd.Flags().DurationVar(&selftestTimeoutFlag,
+		"timeout", 30*time.Second,
+		"How long to wait for each step.")
+	selftestCmd.Flags().BoolVar(&selftestKeepFlag,
+		"keep", false,
+		"Leave the temporary device registered after the self-test.")
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/joshuar/go-hass-agent/cmd/text"
+	"github.com/joshuar/go-hass-agent/internal/linux/manifest"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/paths"
+	registry "github.com/joshuar/go-hass-agent/internal/tracker/registry/jsonFiles"
+)
+
+// sensorStatus is a sensor from the manifest with whether it has been
+// registered with and disabled in Home Assistant.
+type sensorStatus struct {
+	manifest.Sensor
+	// IDs are the IDs of the matching sensors in the registry, for sensors
+	// reported per device.
+	IDs        []string `json:"registered_ids,omitempty"`
+	Registered bool     `json:"registered"`
+	Disabled   bool     `json:"disabled"`
+}
+
+// sensorsCmd represents the sensors command.
+var sensorsCmd = &cobra.Command{
+	Use:   "sensors",
+	Short: "Print the sensors the agent can produce",
+	Long:  text.SensorsCmdLongText,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		logging.SetLoggingLevel(traceFlag, debugFlag, profileFlag)
+		setupPaths()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		sensors, err := manifest.Sensors()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not read sensor manifest.")
+		}
+		states, err := registry.States(paths.RegistryDir())
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not read sensor registry.")
+		}
+		status := make([]sensorStatus, 0, len(sensors))
+		for _, s := range sensors {
+			st := sensorStatus{Sensor: s}
+			disabled := 0
+			for id, state := range states {
+				if !s.Matches(id) || !state.Registered {
+					continue
+				}
+				st.IDs = append(st.IDs, id)
+				if state.Disabled {
+					disabled++
+				}
+			}
+			slices.Sort(st.IDs)
+			st.Registered = len(st.IDs) > 0
+			st.Disabled = st.Registered && disabled == len(st.IDs)
+			status = append(status, st)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(status); err != nil {
+			log.Fatal().Err(err).Msg("Could not print sensors.")
+		}
+	},
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joshuar/go-hass-agent/cmd/text"
+	"github.com/joshuar/go-hass-agent/internal/agent"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+var (
+	simDevicesFlag, simSensorsFlag int
+	simIntervalFlag                time.Duration
+)
+
+// simulateCmd represents the simulate command.
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Simulate virtual devices for testing Home Assistant (developer mode)",
+	Long:  text.SimulateCmdLongText,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		logging.SetLoggingLevel(traceFlag, debugFlag, profileFlag)
+		setupPaths()
+		logging.SetLogFile()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := &agent.SimulateOptions{
+			Devices:  simDevicesFlag,
+			Sensors:  simSensorsFlag,
+			Interval: simIntervalFlag,
+		}
+		agent := agent.New(&agent.Options{
+			Headless: true,
+			Server:   serverFlag,
+			Token:    tokenFlag,
+			ID:       AppID,
+		})
+		agent.Simulate(opts)
+	},
+}
+
+func init() {
+	simulateCmd.Flags().StringVar(&serverFlag,
+		"server", "http://localhost:8123",
+		"URL to Home Assistant instance (e.g. https://somehost:someport)")
+	simulateCmd.Flags().StringVar(&tokenFlag,
+		"token", "",
+		"Long-lived token (e.g. 123456)")
+	simulateCmd.Flags().IntVar(&simDevicesFlag,
+		"devices", 1,
+		"Number of virtual devices to simulate.")
+	simulateCmd.Flags().IntVar(&simSensorsFlag,
+		"sensors", 4,
+		"Number of simulated sensors per device.")
+	simulateCmd.Flags().DurationVar(&simIntervalFlag,
+		"interval", 30*time.Second,
+		"How often to send sensor updates for each device.")
+}
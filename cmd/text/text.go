@@ -12,3 +12,18 @@ var RegisterCmdLongText string
 
 //go:embed rootLong.txt
 var RootCmdLongText string
+
+//go:embed simulateLong.txt
+var SimulateCmdLongText string
+
+//go:embed demoLong.txt
+var DemoCmdLongText string
+
+//go:embed logsLong.txt
+var LogsCmdLongText string
+
+//go:embed sensorsLong.txt
+var SensorsCmdLongText string
+
+//go:embed selftestLong.txt
+var SelftestCmdLongText string
@@ -9,31 +9,38 @@ import (
 	"context"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
-	"github.com/adrg/xdg"
 	"github.com/rs/zerolog/log"
 
-	fyneui "github.com/joshuar/go-hass-agent/internal/agent/ui/fyneUI"
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
+// networkWaitTimeout is the longest the agent will wait for the network to come
+// online before starting anyway.
+const networkWaitTimeout = 2 * time.Minute
+
 // Agent holds the data and structure representing an instance of the agent.
 // This includes the data structure for the UI elements and tray and some
 // strings such as app name and version.
 type Agent struct {
-	ui      UI
-	done    chan struct{}
-	Options *Options
+	ui         UI
+	done       chan struct{}
+	Options    *Options
+	subsystems *subsystemManager
+	mqtt       *mqttClient
+	mu         sync.Mutex
 }
 
 // Options holds options taken from the command-line that was used to
 // invoke go-hass-agent that are relevant for agent functionality.
 type Options struct {
-	ID, Server, Token       string
-	Headless, ForceRegister bool
+	ID, Server, Token                        string
+	DeviceID, DeviceIDSource                 string
+	Headless, ForceRegister, Telemetry, Demo bool
 }
 
 func New(o *Options) *Agent {
@@ -42,7 +49,11 @@ func New(o *Options) *Agent {
 		Options: o,
 	}
 	if !a.Options.Headless {
-		a.ui = fyneui.NewFyneUI(a.Options.ID)
+		a.ui = newUI(a.Options.ID)
+	}
+	// Builds without a graphical UI can only run headless.
+	if a.ui == nil {
+		a.Options.Headless = true
 	}
 	return a
 }
@@ -52,13 +63,15 @@ func New(o *Options) *Agent {
 // publish it to Home Assistant.
 func (agent *Agent) Run(trk SensorTracker) {
 	var wg sync.WaitGroup
-	preferences.SetPath(filepath.Join(xdg.ConfigHome, agent.AppID()))
+	// Keep any warnings and errors for diagnostics reports.
+	log.Logger = log.Logger.Hook(diagnostics.Hook{})
 
 	// Pre-flight: check if agent is registered. If not, run registration flow.
 	var regWait sync.WaitGroup
 	regWait.Add(1)
 	go func() {
 		defer regWait.Done()
+		agent.waitForStartup()
 		if err := agent.checkRegistration(trk); err != nil {
 			log.Fatal().Err(err).Msg("Error checking registration status.")
 		}
@@ -74,6 +87,7 @@ func (agent *Agent) Run(trk SensorTracker) {
 		if err != nil {
 			log.Fatal().Err(err).Msg("Could not load preferences.")
 		}
+		applyMemoryTuning(prefs)
 		ctx, cancelFunc := setupContext(prefs)
 		runnerCtx := setupDeviceContext(ctx)
 
@@ -83,33 +97,26 @@ func (agent *Agent) Run(trk SensorTracker) {
 			cancelFunc()
 		}()
 
-		// Start worker funcs for sensors.
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			runWorkers(runnerCtx, trk)
-		}()
-		// Start any scripts.
+		// Start the parts of the agent that can be restarted when the
+		// preferences change: log outputs, sensor workers, scripts, the mqtt
+		// client, telemetry and summaries.
+		subsystems := agent.newSubsystems(runnerCtx, prefs, trk)
+		agent.mu.Lock()
+		agent.subsystems = subsystems
+		agent.mu.Unlock()
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			scriptPath := filepath.Join(xdg.ConfigHome, agent.AppID(), "scripts")
-			runScripts(runnerCtx, scriptPath, trk)
+			subsystems.run()
 		}()
-		// Start the mqtt client
-		if prefs.MQTTEnabled {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				runMQTTWorker(runnerCtx)
-			}()
-		}
 		// Listen for notifications from Home Assistant.
 		if !agent.IsHeadless() {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				agent.runNotificationsWorker(runnerCtx)
+				diagnostics.TrackWorker("notifications", func() {
+					agent.runNotificationsWorker(runnerCtx)
+				})
 			}()
 		}
 	}()
@@ -123,6 +130,40 @@ func (agent *Agent) Run(trk SensorTracker) {
 	wg.Wait()
 }
 
+// waitForStartup delays the agent start as configured in the preferences. This
+// avoids failed requests when the agent is started at login before the network
+// is available.
+func (agent *Agent) waitForStartup() {
+	prefs, err := preferences.Load()
+	if err != nil {
+		return
+	}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	go func() {
+		select {
+		case <-agent.done:
+			cancelFunc()
+		case <-ctx.Done():
+		}
+	}()
+	if prefs.StartupDelay > 0 {
+		log.Info().Int("seconds", prefs.StartupDelay).Msg("Delaying agent start.")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(prefs.StartupDelay) * time.Second):
+		}
+	}
+	if prefs.WaitForNetwork {
+		waitCtx, cancelWait := context.WithTimeout(ctx, networkWaitTimeout)
+		defer cancelWait()
+		if err := waitForNetwork(waitCtx); err != nil {
+			log.Warn().Err(err).Msg("Could not wait for network, continuing start.")
+		}
+	}
+}
+
 func (agent *Agent) Register(trk SensorTracker) {
 	var wg sync.WaitGroup
 
@@ -152,12 +193,46 @@ func (agent *Agent) handleSignals() {
 	}()
 }
 
+// ApplyPreferences reloads the preferences and restarts the parts of the agent
+// that use any changed preferences, so that the changes take effect without
+// restarting the agent. It returns the names of the parts that were restarted.
+func (agent *Agent) ApplyPreferences() ([]string, error) {
+	agent.mu.Lock()
+	subsystems := agent.subsystems
+	agent.mu.Unlock()
+	// If the agent has not started yet, the preferences will be used when it
+	// does.
+	if subsystems == nil {
+		return nil, nil
+	}
+	prefs, err := preferences.Load()
+	if err != nil {
+		return nil, err
+	}
+	return subsystems.apply(prefs), nil
+}
+
 // IsHeadless returns a bool indicating whether the agent is running in
 // "headless" mode (i.e., without a GUI) or not.
 func (agent *Agent) IsHeadless() bool {
 	return agent.Options.Headless
 }
 
+// notify shows a notification on the device. Notifications are dropped when
+// running headless.
+func (agent *Agent) notify(title, message string) {
+	if agent.IsHeadless() {
+		return
+	}
+	agent.ui.DisplayNotification(title, message)
+}
+
+// IsDemo returns a bool indicating whether the agent is running in demo mode,
+// with simulated sensors and no connection to Home Assistant.
+func (agent *Agent) IsDemo() bool {
+	return agent.Options.Demo
+}
+
 // AppID returns the "application ID". Currently, this ID is just used to
 // indicate whether the agent is running in debug mode or not.
 func (agent *Agent) AppID() string {
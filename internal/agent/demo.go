@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/simulator"
+)
+
+// demoInterval is how often the values of the demo sensors are updated.
+const demoInterval = 5 * time.Second
+
+// Demo runs the agent UI with simulated sensors. No connections are made to
+// Home Assistant or MQTT and preferences are not saved, so it can be used to
+// take screenshots or explore the UI without registering.
+func (agent *Agent) Demo() {
+	if agent.IsHeadless() {
+		log.Fatal().Msg("Demo mode requires a graphical environment.")
+	}
+	// Use a preferences path that does not exist, so that the default
+	// preferences are shown rather than those of the user.
+	preferences.SetPath(filepath.Join(os.TempDir(), agent.AppID()+"-demo"))
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	trk := simulator.NewDemoTracker()
+	go trk.Run(ctx, demoInterval)
+	go func() {
+		<-agent.done
+		log.Debug().Msg("Demo done.")
+		cancelFunc()
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-time.After(demoInterval):
+			agent.ui.DisplayNotification("Go Hass Agent Demo", "Notifications from Home Assistant are shown like this.")
+		}
+	}()
+
+	agent.handleSignals()
+	log.Info().Msg("Running in demo mode.")
+	agent.ui.DisplayTrayIcon(agent, trk)
+	agent.ui.Run(agent.done)
+}
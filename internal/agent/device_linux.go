@@ -3,32 +3,43 @@
 // This software is released under the MIT License.
 // https://opensource.org/licenses/MIT
 
+//go:build !termux
+
 package agent
 
 import (
 	"context"
 
+	"github.com/joshuar/go-hass-agent/internal/device/identity"
 	"github.com/joshuar/go-hass-agent/internal/hass"
 	"github.com/joshuar/go-hass-agent/internal/linux"
 	"github.com/joshuar/go-hass-agent/internal/linux/apps"
+	"github.com/joshuar/go-hass-agent/internal/linux/backup"
 	"github.com/joshuar/go-hass-agent/internal/linux/battery"
 	"github.com/joshuar/go-hass-agent/internal/linux/cpu"
+	"github.com/joshuar/go-hass-agent/internal/linux/desktop"
 	"github.com/joshuar/go-hass-agent/internal/linux/disk"
+	"github.com/joshuar/go-hass-agent/internal/linux/display"
+	"github.com/joshuar/go-hass-agent/internal/linux/dock"
+	"github.com/joshuar/go-hass-agent/internal/linux/iio"
 	"github.com/joshuar/go-hass-agent/internal/linux/location"
+	"github.com/joshuar/go-hass-agent/internal/linux/media"
 	"github.com/joshuar/go-hass-agent/internal/linux/mem"
 	"github.com/joshuar/go-hass-agent/internal/linux/net"
 	"github.com/joshuar/go-hass-agent/internal/linux/power"
 	"github.com/joshuar/go-hass-agent/internal/linux/problems"
 	"github.com/joshuar/go-hass-agent/internal/linux/system"
+	"github.com/joshuar/go-hass-agent/internal/linux/temper"
 	"github.com/joshuar/go-hass-agent/internal/linux/time"
+	"github.com/joshuar/go-hass-agent/internal/linux/ups"
 	"github.com/joshuar/go-hass-agent/internal/linux/user"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
 )
 
-func newDevice(_ context.Context) *linux.Device {
-	return linux.NewDevice(preferences.AppName, preferences.AppVersion)
+func newDevice(_ context.Context, sources ...identity.Source) *linux.Device {
+	return linux.NewDevice(preferences.AppName, preferences.AppVersion, sources...)
 }
 
 // sensorWorkers returns a list of functions to start to enable sensor tracking.
@@ -37,19 +48,49 @@ func sensorWorkers() []func(context.Context) chan tracker.Sensor {
 	workers = append(workers,
 		battery.Updater,
 		apps.Updater,
+		apps.ActiveWindowUpdater,
 		net.ConnectionsUpdater,
 		net.RatesUpdater,
+		net.ConnectionCountsUpdater,
+		net.HotspotUpdater,
+		net.EthernetLinkUpdater,
+		net.AppTrafficUpdater,
 		problems.Updater,
 		mem.Updater,
 		cpu.LoadAvgUpdater,
 		cpu.UsageUpdater,
+		cpu.RAPLUpdater,
+		cpu.EnergyUpdater,
 		disk.UsageUpdater,
+		disk.MDStatUpdater,
+		disk.PoolHealthUpdater,
+		disk.SharesUpdater,
+		disk.EncryptionUpdater,
+		backup.Updater,
 		time.Updater,
+		time.SyncUpdater,
+		time.ZoneUpdater,
 		power.ScreenLockUpdater,
+		desktop.DNDUpdater,
+		desktop.AppearanceUpdater,
+		power.LidUpdater,
+		power.InhibitorsUpdater,
+		dock.Updater,
+		display.BrightnessUpdater,
+		display.DisplaysUpdater,
+		iio.Updater,
+		temper.Updater,
+		media.NowPlayingUpdater,
 		power.PowerStateUpdater,
+		power.WakeUpdater,
 		power.PowerProfileUpdater,
 		user.Updater,
+		ups.Updater,
 		system.Versions,
+		system.SecurityUpdater,
+		system.LastUpdateUpdater,
+		system.BootUpdater,
+		system.VirtualizationUpdater,
 		// system.TempUpdater,
 		system.HWSensorUpdater,
 	)
@@ -60,7 +101,34 @@ func locationWorker() func(context.Context) chan *hass.LocationData {
 	return location.Updater
 }
 
+// eventWorkers returns a list of functions to start to fire events in Home
+// Assistant.
+func eventWorkers() []func(context.Context) chan *hass.Event {
+	return []func(context.Context) chan *hass.Event{
+		power.SleepEventsUpdater,
+		user.SessionEventsUpdater,
+		disk.ShareEventsUpdater,
+		time.ZoneEventsUpdater,
+	}
+}
+
+// waitForNetwork blocks until the device network is online or the context is
+// canceled.
+func waitForNetwork(ctx context.Context) error {
+	return net.WaitForOnline(setupDeviceContext(ctx))
+}
+
 // Setup returns a new Context that contains the D-Bus API.
 func setupDeviceContext(ctx context.Context) context.Context {
 	return dbusx.Setup(ctx)
 }
+
+// openDeviceURL opens the URL in the default browser of the desktop.
+func openDeviceURL(ctx context.Context, url string) error {
+	return desktop.OpenURL(ctx, url)
+}
+
+// setDeviceClipboard copies the text to the clipboard of the desktop.
+func setDeviceClipboard(ctx context.Context, text string) error {
+	return desktop.SetClipboard(ctx, text)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build termux
+
+package agent
+
+import (
+	"context"
+
+	"github.com/joshuar/go-hass-agent/internal/device/identity"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/linux/mem"
+	"github.com/joshuar/go-hass-agent/internal/linux/time"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/termux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+func newDevice(_ context.Context, sources ...identity.Source) *linux.Device {
+	return linux.NewDevice(preferences.AppName, preferences.AppVersion, sources...)
+}
+
+// sensorWorkers returns a list of functions to start to enable sensor tracking.
+// Only sensors that do not need D-Bus and that Android allows apps to read
+// are included.
+func sensorWorkers() []func(context.Context) chan tracker.Sensor {
+	var workers []func(context.Context) chan tracker.Sensor
+	workers = append(workers,
+		termux.BatteryUpdater,
+		termux.NetworkUpdater,
+		termux.StorageUpdater,
+		mem.Updater,
+		time.Updater,
+	)
+	return workers
+}
+
+// locationWorker returns a worker that sends no location updates, as there is
+// no location source under Termux.
+func locationWorker() func(context.Context) chan *hass.LocationData {
+	return func(_ context.Context) chan *hass.LocationData {
+		locationCh := make(chan *hass.LocationData)
+		close(locationCh)
+		return locationCh
+	}
+}
+
+// eventWorkers returns a list of functions to start to fire events in Home
+// Assistant. There are none under Termux.
+func eventWorkers() []func(context.Context) chan *hass.Event {
+	return nil
+}
+
+// waitForNetwork returns immediately, as there is no way to check the network
+// state without D-Bus.
+func waitForNetwork(_ context.Context) error {
+	return nil
+}
+
+// setupDeviceContext returns the context unchanged, as there is no D-Bus API to
+// add.
+func setupDeviceContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+// openDeviceURL opens the URL in the default browser of the Android device.
+func openDeviceURL(ctx context.Context, url string) error {
+	return termux.OpenURL(ctx, url)
+}
+
+// setDeviceClipboard copies the text to the clipboard of the Android device.
+func setDeviceClipboard(ctx context.Context, text string) error {
+	return termux.SetClipboard(ctx, text)
+}
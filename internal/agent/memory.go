@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"os"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+const mib = 1 << 20
+
+// applyMemoryTuning applies the memory limit and garbage collection target
+// from the preferences to the Go runtime. The GOMEMLIMIT and GOGC environment
+// variables, if set, take precedence over the preferences.
+func applyMemoryTuning(prefs *preferences.Preferences) {
+	if prefs.MemoryLimit > 0 {
+		if _, ok := os.LookupEnv("GOMEMLIMIT"); ok {
+			log.Debug().Msg("GOMEMLIMIT is set. Ignoring memory limit in preferences.")
+		} else {
+			debug.SetMemoryLimit(int64(prefs.MemoryLimit) * mib)
+			log.Debug().Int("limit_mib", prefs.MemoryLimit).Msg("Set agent memory limit.")
+		}
+	}
+	if prefs.GCPercent != 0 {
+		if _, ok := os.LookupEnv("GOGC"); ok {
+			log.Debug().Msg("GOGC is set. Ignoring garbage collection target in preferences.")
+		} else {
+			debug.SetGCPercent(prefs.GCPercent)
+			log.Debug().Int("percent", prefs.GCPercent).Msg("Set agent garbage collection target.")
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"math"
+	"os"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func Test_applyMemoryTuning(t *testing.T) {
+	origLimit := debug.SetMemoryLimit(-1)
+	origGC := debug.SetGCPercent(100)
+	debug.SetGCPercent(origGC)
+	t.Cleanup(func() {
+		debug.SetMemoryLimit(origLimit)
+		debug.SetGCPercent(origGC)
+	})
+
+	tests := []struct {
+		prefs     *preferences.Preferences
+		env       map[string]string
+		name      string
+		wantLimit int64
+		wantGC    int
+	}{
+		{
+			name:      "defaults",
+			prefs:     &preferences.Preferences{},
+			wantLimit: math.MaxInt64,
+			wantGC:    100,
+		},
+		{
+			name:      "set in preferences",
+			prefs:     &preferences.Preferences{MemoryLimit: 128, GCPercent: 50},
+			wantLimit: 128 * mib,
+			wantGC:    50,
+		},
+		{
+			name:      "environment takes precedence",
+			prefs:     &preferences.Preferences{MemoryLimit: 128, GCPercent: 50},
+			env:       map[string]string{"GOMEMLIMIT": "64MiB", "GOGC": "200"},
+			wantLimit: math.MaxInt64,
+			wantGC:    100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// t.Setenv restores the variables after the test, so they can
+			// be safely unset.
+			t.Setenv("GOMEMLIMIT", "")
+			t.Setenv("GOGC", "")
+			os.Unsetenv("GOMEMLIMIT")
+			os.Unsetenv("GOGC")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			debug.SetMemoryLimit(math.MaxInt64)
+			debug.SetGCPercent(100)
+			applyMemoryTuning(tt.prefs)
+			assert.Equal(t, tt.wantLimit, debug.SetMemoryLimit(-1))
+			assert.Equal(t, tt.wantGC, debug.SetGCPercent(100))
+		})
+	}
+}
@@ -6,6 +6,11 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
 	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
 	mqttapi "github.com/joshuar/go-hass-anything/v5/pkg/mqtt"
 	"github.com/rs/zerolog/log"
@@ -15,8 +20,21 @@ import (
 
 type mqttObj struct {
 	entities map[string]*mqtthass.EntityConfig
+	cameras  map[string]*mqttCamera
+	// extraConfig holds any config that mqtthass.Entity cannot represent
+	// (e.g., the options of a select entity, or the range of a number
+	// entity), by entity ID. It is added to the config of the entity.
+	extraConfig map[string]map[string]any
+	// watchers are started once connected to MQTT, to publish state changes
+	// of entities as they happen rather than waiting for the next update.
+	watchers []stateWatcher
 }
 
+// stateWatcher watches for changes to the state of entities, calling publish
+// with any entity whose state has changed, until the context is canceled. It
+// should return once watching has started.
+type stateWatcher func(ctx context.Context, publish func(*mqtthass.EntityConfig)) error
+
 func (o *mqttObj) Name() string {
 	return preferences.AppName
 }
@@ -24,15 +42,47 @@ func (o *mqttObj) Name() string {
 func (o *mqttObj) Configuration() []*mqttapi.Msg {
 	var msgs []*mqttapi.Msg
 	for id, c := range o.entities {
+		if extra, ok := o.extraConfig[id]; ok {
+			if config, err := marshalExtraConfig(c.Entity, extra); err != nil {
+				log.Error().Err(err).Msgf("Failed to marshal payload for %s.", id)
+			} else {
+				msgs = append(msgs, mqttapi.NewMsg(c.ConfigTopic, config).Retain())
+			}
+			continue
+		}
 		if msg, err := mqtthass.MarshalConfig(c); err != nil {
 			log.Error().Err(err).Msgf("Failed to marshal payload for %s.", id)
 		} else {
 			msgs = append(msgs, msg)
 		}
 	}
+	for id, c := range o.cameras {
+		if config, err := json.Marshal(c); err != nil {
+			log.Error().Err(err).Msgf("Failed to marshal payload for %s.", id)
+		} else {
+			msgs = append(msgs, mqttapi.NewMsg(c.configTopic, config).Retain())
+		}
+	}
 	return msgs
 }
 
+// marshalExtraConfig marshals the config of the entity with the extra config
+// added.
+func marshalExtraConfig(e *mqtthass.Entity, extra map[string]any) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]any)
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		config[k] = v
+	}
+	return json.Marshal(config)
+}
+
 func (o *mqttObj) Subscriptions() []*mqttapi.Subscription {
 	var subs []*mqttapi.Subscription
 	for _, v := range o.entities {
@@ -49,5 +99,130 @@ func (o *mqttObj) Subscriptions() []*mqttapi.Subscription {
 }
 
 func (o *mqttObj) States() []*mqttapi.Msg {
-	return nil
+	var msgs []*mqttapi.Msg
+	for id, c := range o.entities {
+		if c.StateCallback == nil {
+			continue
+		}
+		if msg, err := mqtthass.MarshalState(c); err != nil {
+			log.Warn().Err(err).Msgf("Failed to marshal state for %s.", id)
+		} else {
+			msgs = append(msgs, msg)
+		}
+		if c.AttributesCallback == nil || c.Entity.AttributesTopic == "" {
+			continue
+		}
+		if attrs, err := c.AttributesCallback(); err != nil {
+			log.Warn().Err(err).Msgf("Failed to marshal attributes for %s.", id)
+		} else {
+			msgs = append(msgs, mqttapi.NewMsg(c.Entity.AttributesTopic, attrs))
+		}
+	}
+	return msgs
+}
+
+// asSwitch will configure appropriate MQTT topics to represent a Home
+// Assistant switch.
+func asSwitch(e *mqtthass.EntityConfig) *mqtthass.EntityConfig {
+	prefix := strings.Join([]string{mqttapi.DiscoveryPrefix, "switch", e.App, e.Entity.UniqueID}, "/")
+	e.ConfigTopic = prefix + "/config"
+	e.Entity.StateTopic = prefix + "/state"
+	e.Entity.CommandTopic = prefix + "/set"
+	return e.WithValueTemplate("{{ value }}")
+}
+
+// asText will configure appropriate MQTT topics to represent a Home Assistant
+// text entity.
+func asText(e *mqtthass.EntityConfig) *mqtthass.EntityConfig {
+	prefix := strings.Join([]string{mqttapi.DiscoveryPrefix, "text", e.App, e.Entity.UniqueID}, "/")
+	e.ConfigTopic = prefix + "/config"
+	e.Entity.StateTopic = prefix + "/state"
+	e.Entity.CommandTopic = prefix + "/set"
+	return e.WithValueTemplate("{{ value }}")
+}
+
+// asNumber will configure appropriate MQTT topics to represent a Home Assistant
+// number entity. Home Assistant defaults to a range of 0 to 100; a different
+// range can be set with min and max in the extra config of the entity.
+func asNumber(e *mqtthass.EntityConfig) *mqtthass.EntityConfig {
+	prefix := strings.Join([]string{mqttapi.DiscoveryPrefix, "number", e.App, e.Entity.UniqueID}, "/")
+	e.ConfigTopic = prefix + "/config"
+	e.Entity.StateTopic = prefix + "/state"
+	e.Entity.CommandTopic = prefix + "/set"
+	return e.WithValueTemplate("{{ value }}")
+}
+
+// asSelect will configure appropriate MQTT topics to represent a Home Assistant
+// select entity. The options must be added to the extra config of the entity.
+func asSelect(e *mqtthass.EntityConfig) *mqtthass.EntityConfig {
+	prefix := strings.Join([]string{mqttapi.DiscoveryPrefix, "select", e.App, e.Entity.UniqueID}, "/")
+	e.ConfigTopic = prefix + "/config"
+	e.Entity.StateTopic = prefix + "/state"
+	e.Entity.CommandTopic = prefix + "/set"
+	return e.WithValueTemplate("{{ value }}")
+}
+
+// asUpdate will configure appropriate MQTT topics to represent a Home Assistant
+// update entity. The state is a JSON object with the installed and latest
+// versions, and the command topic receives "install" to install the update.
+func asUpdate(e *mqtthass.EntityConfig) *mqtthass.EntityConfig {
+	prefix := strings.Join([]string{mqttapi.DiscoveryPrefix, "update", e.App, e.Entity.UniqueID}, "/")
+	e.ConfigTopic = prefix + "/config"
+	e.Entity.StateTopic = prefix + "/state"
+	e.Entity.CommandTopic = prefix + "/set"
+	return e.WithValueTemplate("{{ value }}")
+}
+
+// asNotify will configure appropriate MQTT topics to represent a Home Assistant
+// notify entity. Notify entities only have a command topic.
+func asNotify(e *mqtthass.EntityConfig) *mqtthass.EntityConfig {
+	prefix := strings.Join([]string{mqttapi.DiscoveryPrefix, "notify", e.App, e.Entity.UniqueID}, "/")
+	e.ConfigTopic = prefix + "/config"
+	e.Entity.CommandTopic = prefix + "/set"
+	return e
+}
+
+// mqttCamera is a Home Assistant MQTT camera entity. A camera publishes images
+// to a topic rather than having a state, which mqtthass.Entity cannot
+// represent, so cameras are configured separately from the other entities.
+type mqttCamera struct {
+	Origin   *mqtthass.Origin `json:"origin,omitempty"`
+	Device   *mqtthass.Device `json:"device,omitempty"`
+	Topic    string           `json:"topic"`
+	UniqueID string           `json:"unique_id"`
+	Name     string           `json:"name"`
+	Icon     string           `json:"icon,omitempty"`
+	// capture returns the image to publish.
+	capture     func(ctx context.Context) ([]byte, error)
+	configTopic string
+	// interval is how often an image is published. Images are only
+	// published on demand if it is zero.
+	interval time.Duration
+}
+
+// asCamera will configure appropriate MQTT topics to represent a Home
+// Assistant camera, using the details of the given entity.
+func asCamera(e *mqtthass.EntityConfig, capture func(ctx context.Context) ([]byte, error)) *mqttCamera {
+	prefix := strings.Join([]string{mqttapi.DiscoveryPrefix, "camera", e.App, e.Entity.UniqueID}, "/")
+	return &mqttCamera{
+		Origin:      e.Entity.Origin,
+		Device:      e.Entity.Device,
+		Topic:       prefix + "/image",
+		UniqueID:    e.Entity.UniqueID,
+		Name:        e.Entity.Name,
+		Icon:        e.Entity.Icon,
+		capture:     capture,
+		configTopic: prefix + "/config",
+	}
+}
+
+// image captures an image and returns the message to publish it. Images are
+// not retained, as they can be large and would be kept by the broker
+// indefinitely.
+func (c *mqttCamera) image(ctx context.Context) (*mqttapi.Msg, error) {
+	img, err := c.capture(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mqttapi.NewMsg(c.Topic, img), nil
 }
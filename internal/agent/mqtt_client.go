@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog/log"
+
+	mqttapi "github.com/joshuar/go-hass-anything/v5/pkg/mqtt"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// mqttDisconnectQuiesce is how long, in milliseconds, the client waits for
+// in-flight work to complete when disconnecting.
+const mqttDisconnectQuiesce = 250
+
+var errMQTTNotConnected = errors.New("not connected to MQTT")
+
+// mqttClient is a connection to the MQTT broker. It satisfies the client
+// interface of go-hass-anything, but unlike the client provided there, it can
+// be disconnected, which also removes all of its subscriptions.
+type mqttClient struct {
+	conn MQTT.Client
+}
+
+// Publish sends the given messages to the broker. Messages are skipped if the
+// client is not connected.
+func (c *mqttClient) Publish(msgs ...*mqttapi.Msg) error {
+	for _, msg := range msgs {
+		log.Trace().Str("topic", msg.Topic).Bool("retain", msg.Retained).Msg("Publishing message.")
+		if !c.conn.IsConnected() {
+			log.Debug().Msg("Not connected.")
+			continue
+		}
+		if token := c.conn.Publish(msg.Topic, msg.QOS, msg.Retained, []byte(msg.Message)); token.Wait() && token.Error() != nil {
+			return token.Error()
+		}
+	}
+	return nil
+}
+
+// Subscribe listens on the topics of the given subscriptions, passing any
+// received messages to their callbacks.
+func (c *mqttClient) Subscribe(subs ...*mqttapi.Subscription) error {
+	for _, sub := range subs {
+		log.Trace().Str("topic", sub.Topic).Bool("retain", sub.Retained).Msg("Adding subscription.")
+		if token := c.conn.Subscribe(sub.Topic, sub.QOS, sub.Callback); token.Wait() && token.Error() != nil {
+			return token.Error()
+		}
+	}
+	return nil
+}
+
+// Disconnect closes the connection to the broker. No subscription callbacks
+// are run after it returns.
+func (c *mqttClient) Disconnect() {
+	c.conn.Disconnect(mqttDisconnectQuiesce)
+	log.Debug().Msg("Disconnected from MQTT.")
+}
+
+// newMQTTClient connects to the MQTT broker in the preferences, retrying with
+// a backoff until the context is canceled.
+func newMQTTClient(ctx context.Context, prefs *preferences.MQTTPreferences) (*mqttClient, error) {
+	hostname, _ := os.Hostname()
+	clientid := hostname + strconv.Itoa(time.Now().Second())
+
+	connOpts := MQTT.NewClientOptions().
+		AddBroker(prefs.MQTTServer()).
+		SetClientID(clientid).
+		SetCleanSession(true)
+	if prefs.MQTTUser() != "" {
+		connOpts.SetUsername(prefs.MQTTUser())
+		if prefs.MQTTPassword() != "" {
+			connOpts.SetPassword(prefs.MQTTPassword())
+		}
+	}
+	conn := MQTT.NewClient(connOpts)
+
+	connect := func() error {
+		if token := conn.Connect(); token.Wait() && token.Error() != nil {
+			return token.Error()
+		}
+		return nil
+	}
+	if err := backoff.Retry(connect, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("Connected to MQTT server %s.", prefs.MQTTServer())
+	return &mqttClient{conn: conn}, nil
+}
+
+// setMQTTConnection records the connection of the MQTT worker, so that other
+// workers can publish with it, or clears it when the worker stops.
+func (agent *Agent) setMQTTConnection(c *mqttClient) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	agent.mqtt = c
+}
+
+// mqttConnection returns the connection of the MQTT worker, or nil if it is
+// not running.
+func (agent *Agent) mqttConnection() *mqttClient {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	return agent.mqtt
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !termux
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+const (
+	defaultCommandTimeout = time.Minute
+	// maxCommandOutput is the maximum length of command output published as
+	// an attribute. Longer output is truncated, keeping the end, which usually
+	// has any errors.
+	maxCommandOutput = 4096
+)
+
+var errCommandRunning = errors.New("command already running")
+
+// commandResult is the result of the last run of a user-defined command.
+type commandResult struct {
+	LastRun  time.Time `json:"Last Run"`
+	Output   string    `json:"Output"`
+	Error    string    `json:"Error,omitempty"`
+	Duration string    `json:"Duration"`
+	ExitCode int       `json:"-"`
+}
+
+// userCommand is a user-defined command from the preferences.
+type userCommand struct {
+	result *commandResult
+	preferences.Command
+	mu      sync.Mutex
+	running bool
+}
+
+// run runs the command and records the result. The exit code is -1 if the
+// command could not be started or did not finish before the timeout.
+func (c *userCommand) run(ctx context.Context) error {
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return errCommandRunning
+	}
+	c.running = true
+	c.mu.Unlock()
+
+	timeout := defaultCommandTimeout
+	if c.Timeout > 0 {
+		timeout = time.Duration(c.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command.Command)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Run the command in its own process group, so that any processes it
+	// starts are also killed on timeout.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	start := time.Now()
+	err := cmd.Run()
+
+	result := &commandResult{
+		LastRun:  start,
+		Output:   truncateOutput(output.String()),
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.result = result
+	c.running = false
+	return err
+}
+
+// state returns the exit code of the last run, or None if the command has not
+// been run.
+func (c *userCommand) state() (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.result == nil {
+		return json.RawMessage(`None`), nil
+	}
+	return json.RawMessage(strconv.Itoa(c.result.ExitCode)), nil
+}
+
+// attributes returns the output and details of the last run.
+func (c *userCommand) attributes() (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.result == nil {
+		return json.Marshal(struct {
+			Command string `json:"Command"`
+		}{Command: c.Command.Command})
+	}
+	return json.Marshal(struct {
+		*commandResult
+		Command string `json:"Command"`
+	}{
+		commandResult: c.result,
+		Command:       c.Command.Command,
+	})
+}
+
+// truncateOutput returns the last maxCommandOutput bytes of the output, with
+// surrounding whitespace removed.
+func truncateOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > maxCommandOutput {
+		output = "..." + output[len(output)-maxCommandOutput:]
+	}
+	return output
+}
+
+// addCommandEntities adds a button for each user-defined command in the
+// preferences, and a sensor with the exit code of its last run. The output of
+// the command is published as attributes of the sensor.
+func addCommandEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	prefs := preferences.FetchFromContext(ctx)
+	for _, command := range prefs.Commands {
+		id := "command_" + strcase.ToSnake(command.Name)
+		resultID := id + "_result"
+		c := &userCommand{Command: command}
+		entities[resultID] = mqtthass.NewEntityByID(resultID, appName).
+			AsSensor().
+			WithAttributesTopic().
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx)).
+			WithIcon("mdi:console").
+			WithStateCallback(c.state).
+			WithAttributesCallback(c.attributes)
+		entities[id] = mqtthass.NewEntityByID(id, appName).
+			AsButton().
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx)).
+			WithIcon("mdi:console-line").
+			WithCommandCallback(func(client MQTT.Client, _ MQTT.Message) {
+				// Run the command in the background, so that a long-running
+				// command does not block other MQTT messages.
+				go func() {
+					err := c.run(ctx)
+					if errors.Is(err, errCommandRunning) {
+						log.Warn().Str("command", command.Name).Msg("Command is already running.")
+						return
+					}
+					if err != nil {
+						log.Warn().Err(err).Str("command", command.Name).Msg("Command failed.")
+					}
+					result := entities[resultID].Entity
+					if state, err := c.state(); err == nil {
+						client.Publish(result.StateTopic, 0, false, []byte(state))
+					}
+					if attrs, err := c.attributes(); err == nil {
+						client.Publish(result.AttributesTopic, 0, false, []byte(attrs))
+					}
+				}()
+			})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !termux
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func Test_userCommand_run(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    preferences.Command
+		wantState  string
+		wantOutput string
+		wantErr    bool
+	}{
+		{
+			name:       "success",
+			command:    preferences.Command{Name: "Echo", Command: "echo hello"},
+			wantState:  "0",
+			wantOutput: "hello",
+		},
+		{
+			name:       "failure with output",
+			command:    preferences.Command{Name: "Fail", Command: "echo oops >&2; exit 3"},
+			wantState:  "3",
+			wantOutput: "oops",
+			wantErr:    true,
+		},
+		{
+			name:      "timeout",
+			command:   preferences.Command{Name: "Sleep", Command: "sleep 5", Timeout: 1},
+			wantState: "-1",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &userCommand{Command: tt.command}
+			state, err := c.state()
+			require.NoError(t, err)
+			assert.Equal(t, "None", string(state))
+
+			err = c.run(context.Background())
+			assert.Equal(t, tt.wantErr, err != nil)
+			state, err = c.state()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantState, string(state))
+
+			attrs, err := c.attributes()
+			require.NoError(t, err)
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(attrs, &got))
+			assert.Equal(t, tt.wantOutput, got["Output"])
+			assert.Equal(t, tt.command.Command, got["Command"])
+		})
+	}
+}
+
+func Test_truncateOutput(t *testing.T) {
+	assert.Equal(t, "short", truncateOutput(" short\n"))
+	long := strings.Repeat("a", maxCommandOutput) + "end"
+	got := truncateOutput(long)
+	assert.Len(t, got, maxCommandOutput+3)
+	assert.True(t, strings.HasPrefix(got, "..."))
+	assert.True(t, strings.HasSuffix(got, "end"))
+}
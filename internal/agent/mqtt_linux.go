@@ -3,42 +3,70 @@
 // This software is released under the MIT License.
 // https://opensource.org/licenses/MIT
 
+//go:build !termux
+
 package agent
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
+	"os/user"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	"github.com/godbus/dbus/v5"
+	"github.com/iancoleman/strcase"
 	"github.com/rs/zerolog/log"
 
 	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
 
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
 	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/linux/audio"
+	"github.com/joshuar/go-hass-agent/internal/linux/bluetooth"
+	"github.com/joshuar/go-hass-agent/internal/linux/containers"
+	"github.com/joshuar/go-hass-agent/internal/linux/desktop"
+	"github.com/joshuar/go-hass-agent/internal/linux/display"
+	"github.com/joshuar/go-hass-agent/internal/linux/media"
+	linuxnet "github.com/joshuar/go-hass-agent/internal/linux/net"
+	linuxpower "github.com/joshuar/go-hass-agent/internal/linux/power"
+	"github.com/joshuar/go-hass-agent/internal/linux/systemd"
+	"github.com/joshuar/go-hass-agent/internal/linux/webcam"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/update"
 	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
 )
 
 const (
-	dbusSessionDest           = "org.freedesktop.login1"
-	dbusSessionLockMethod     = dbusSessionDest + ".Session.Lock"
-	dbusSessionUnlockMethod   = dbusSessionDest + ".Session.UnLock"
-	dbusSessionRebootMethod   = dbusSessionDest + ".Manager.Reboot"
-	dbusSessionPowerOffMethod = dbusSessionDest + ".Manager.PowerOff"
+	dbusSessionDest         = "org.freedesktop.login1"
+	dbusSessionUnlockMethod = dbusSessionDest + ".Session.UnLock"
+	dbusLoginPath           = "/org/freedesktop/login1"
+	dbusLoginManager        = dbusSessionDest + ".Manager"
 
 	dbusEmptyScreensaverMessage = ""
+
+	dbusFreedesktopScreensaverDest = "org.freedesktop.ScreenSaver"
+	dbusFreedesktopScreensaverPath = "/org/freedesktop/ScreenSaver"
+	dbusGnomeScreensaverDest       = "org.gnome.ScreenSaver"
+	dbusGnomeScreensaverPath       = "/org/gnome/ScreenSaver"
 )
 
-func newMQTTObject(ctx context.Context) *mqttObj {
+// newMQTTObject returns an MQTT object with the device controls. If
+// reloadScripts is not nil, a button to reload the scripts is added.
+func newMQTTObject(ctx context.Context, reloadScripts func() error) *mqttObj {
 	appName := "go_hass_agent"
 
 	baseEntity := func(entityID string) *mqtthass.EntityConfig {
 		return mqtthass.NewEntityByID(entityID, appName).
 			AsButton().
 			WithDefaultOriginInfo().
-			WithDeviceInfo(mqttDevice())
+			WithDeviceInfo(mqttDevice(ctx))
 	}
 
 	systemDbusCall := func(ctx context.Context, path dbus.ObjectPath, dest, method string, args ...any) error {
@@ -79,11 +107,17 @@ func newMQTTObject(ctx context.Context) *mqttObj {
 	entities["lock_session"] = baseEntity("lock_session").
 		WithIcon("mdi:eye-lock").
 		WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
-			err := systemDbusCall(ctx, sessionPath, dbusSessionDest, dbusSessionLockMethod)
-			if err != nil {
+			if err := lockUserSessions(ctx); err != nil {
 				log.Warn().Err(err).Msg("Could not lock session.")
 			}
 		})
+	entities["wake_screen"] = baseEntity("wake_screen").
+		WithIcon("mdi:monitor-eye").
+		WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
+			if err := wakeScreen(ctx); err != nil {
+				log.Warn().Err(err).Msg("Could not wake screen.")
+			}
+		})
 	entities["unlock_session"] = baseEntity("unlock_session").
 		WithIcon("mdi:eye-lock-open").
 		WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
@@ -92,36 +126,526 @@ func newMQTTObject(ctx context.Context) *mqttObj {
 				log.Warn().Err(err).Msg("Could not unlock session.")
 			}
 		})
-	entities["reboot"] = baseEntity("reboot").
+	// Each power action can be disabled in the preferences. Actions are
+	// allowed by default, and only added if logind says they are possible.
+	prefs := preferences.FetchFromContext(ctx)
+	for _, action := range []struct {
+		id     string
+		icon   string
+		method string
+	}{
+		{id: "reboot", icon: "mdi:restart", method: "Reboot"},
+		{id: "poweroff", icon: "mdi:power", method: "PowerOff"},
+		{id: "suspend", icon: "mdi:power-sleep", method: "Suspend"},
+		{id: "hibernate", icon: "mdi:power-sleep", method: "Hibernate"},
+	} {
+		if allowed, ok := prefs.PowerActions[action.id]; ok && !allowed {
+			log.Debug().Str("action", action.id).Msg("Power action disabled in preferences.")
+			continue
+		}
+		if !canPowerAction(ctx, action.method) {
+			log.Debug().Str("action", action.id).Msg("Power action not supported.")
+			continue
+		}
+		entities[action.id] = baseEntity(action.id).
+			WithIcon(action.icon).
+			WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
+				err := systemDbusCall(ctx, dbusLoginPath, dbusSessionDest, dbusLoginManager+"."+action.method, true)
+				if err != nil {
+					log.Warn().Err(err).Str("action", action.id).Msg("Could not perform power action.")
+				}
+			})
+	}
+	// The diagnostics sensor state is the time the report was generated. The
+	// report itself is published as the sensor attributes, so that it is
+	// included when downloading the device diagnostics in Home Assistant.
+	entities["diagnostics"] = mqtthass.NewEntityByID("diagnostics", appName).
+		AsSensor().
+		WithAttributesTopic().
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx)).
+		WithIcon("mdi:stethoscope").
+		WithDeviceClass("timestamp").
+		WithStateCallback(func() (json.RawMessage, error) {
+			return json.RawMessage(time.Now().Format(time.RFC3339)), nil
+		}).
+		WithAttributesCallback(func() (json.RawMessage, error) {
+			prefs := preferences.FetchFromContext(ctx)
+			return json.Marshal(diagnostics.NewReport(&prefs))
+		})
+	entities["diagnostics"].Entity.EntityCategory = "diagnostic"
+	entities["restart_agent"] = baseEntity("restart_agent").
 		WithIcon("mdi:restart").
+		WithDeviceClass("restart").
 		WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
-			err := systemDbusCall(ctx, dbus.ObjectPath("/org/freedesktop/login1"), dbusSessionDest, dbusSessionRebootMethod, true)
+			exe, err := update.Executable()
+			if err == nil {
+				log.Info().Msg("Restart requested from Home Assistant.")
+				err = requestRestart(exe)
+			}
 			if err != nil {
-				log.Warn().Err(err).Msg("Could not reboot session.")
+				log.Warn().Err(err).Msg("Could not restart agent.")
 			}
 		})
-	entities["poweroff"] = baseEntity("poweroff").
-		WithIcon("mdi:power").
-		WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
-			err := systemDbusCall(ctx, dbus.ObjectPath("/org/freedesktop/login1"), dbusSessionDest, dbusSessionPowerOffMethod, true)
+	entities["restart_agent"].Entity.EntityCategory = "config"
+	if reloadScripts != nil {
+		entities["reload_scripts"] = baseEntity("reload_scripts").
+			WithIcon("mdi:script-text-play").
+			WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
+				// Reloading waits for any running scripts to stop, so don't
+				// block other MQTT messages.
+				go func() {
+					if err := reloadScripts(); err != nil {
+						log.Warn().Err(err).Msg("Could not reload scripts.")
+					}
+				}()
+			})
+		entities["reload_scripts"].Entity.EntityCategory = "config"
+	}
+	if hotspot, err := linuxnet.FindHotspot(ctx); err == nil {
+		hotspotState := func() (json.RawMessage, error) {
+			if hotspot.Active(ctx) {
+				return json.RawMessage(`ON`), nil
+			}
+			return json.RawMessage(`OFF`), nil
+		}
+		entities["hotspot"] = asSwitch(mqtthass.NewEntityByID("hotspot", appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:access-point").
+			WithStateCallback(hotspotState).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				var err error
+				switch string(m.Payload()) {
+				case "ON":
+					err = hotspot.Enable(ctx)
+				case "OFF":
+					err = hotspot.Disable(ctx)
+				default:
+					log.Warn().Str("payload", string(m.Payload())).Msg("Unknown hotspot command.")
+					return
+				}
+				if err != nil {
+					log.Warn().Err(err).Str("hotspot", hotspot.Name).Msg("Could not change hotspot state.")
+				}
+				if state, err := hotspotState(); err == nil {
+					c.Publish(entities["hotspot"].Entity.StateTopic, 0, false, []byte(state))
+				}
+			})
+	} else {
+		log.Debug().Err(err).Msg("Not adding hotspot control.")
+	}
+	addVPNEntities(ctx, appName, entities)
+	var watchers []stateWatcher
+	if _, err := linuxnet.WifiEnabled(ctx); err == nil {
+		wifiState := func() (json.RawMessage, error) {
+			enabled, err := linuxnet.WifiEnabled(ctx)
 			if err != nil {
-				log.Warn().Err(err).Msg("Could not power off session.")
+				return nil, err
 			}
+			if enabled {
+				return json.RawMessage(`ON`), nil
+			}
+			return json.RawMessage(`OFF`), nil
+		}
+		entities["wifi"] = asSwitch(mqtthass.NewEntityByID("wifi", appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:wifi").
+			WithStateCallback(wifiState).
+			WithCommandCallback(func(_ MQTT.Client, m MQTT.Message) {
+				var err error
+				switch string(m.Payload()) {
+				case "ON":
+					err = linuxnet.SetWifiEnabled(ctx, true)
+				case "OFF":
+					err = linuxnet.SetWifiEnabled(ctx, false)
+				default:
+					log.Warn().Str("payload", string(m.Payload())).Msg("Unknown Wi-Fi command.")
+					return
+				}
+				// The new state is published by the watcher below.
+				if err != nil {
+					log.Warn().Err(err).Msg("Could not change Wi-Fi state.")
+				}
+			})
+		watchers = append(watchers, func(ctx context.Context, publish func(*mqtthass.EntityConfig)) error {
+			return linuxnet.WatchWifiEnabled(ctx, func() {
+				publish(entities["wifi"])
+			})
+		})
+	} else {
+		log.Debug().Err(err).Msg("Not adding Wi-Fi control.")
+	}
+	if adapter, err := bluetooth.FindAdapter(ctx); err == nil {
+		bluetoothState := func() (json.RawMessage, error) {
+			powered, err := adapter.Powered(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if powered {
+				return json.RawMessage(`ON`), nil
+			}
+			return json.RawMessage(`OFF`), nil
+		}
+		entities["bluetooth"] = asSwitch(mqtthass.NewEntityByID("bluetooth", appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:bluetooth").
+			WithStateCallback(bluetoothState).
+			WithCommandCallback(func(_ MQTT.Client, m MQTT.Message) {
+				var err error
+				switch string(m.Payload()) {
+				case "ON":
+					err = adapter.SetPowered(ctx, true)
+				case "OFF":
+					err = adapter.SetPowered(ctx, false)
+				default:
+					log.Warn().Str("payload", string(m.Payload())).Msg("Unknown Bluetooth command.")
+					return
+				}
+				// The new state is published by the watcher below.
+				if err != nil {
+					log.Warn().Err(err).Msg("Could not change Bluetooth state.")
+				}
+			})
+		watchers = append(watchers, func(ctx context.Context, publish func(*mqtthass.EntityConfig)) error {
+			return adapter.WatchPowered(ctx, func() {
+				publish(entities["bluetooth"])
+			})
 		})
+	} else {
+		log.Debug().Err(err).Msg("Not adding Bluetooth control.")
+	}
+	if linuxpower.HasWakeAlarm() {
+		wakeAlarmState := func() (json.RawMessage, error) {
+			alarm, err := linuxpower.WakeAlarm()
+			if err != nil || alarm.IsZero() {
+				return json.RawMessage(``), err
+			}
+			return json.RawMessage(alarm.Format(time.RFC3339)), nil
+		}
+		entities["wake_alarm"] = asText(mqtthass.NewEntityByID("wake_alarm", appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:alarm").
+			WithStateCallback(wakeAlarmState).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				alarm, err := linuxpower.ParseWakeAlarm(string(m.Payload()), time.Now())
+				if err != nil {
+					log.Warn().Err(err).Str("payload", string(m.Payload())).Msg("Could not set wake alarm.")
+					return
+				}
+				if err := linuxpower.SetWakeAlarm(alarm); err != nil {
+					log.Warn().Err(err).Msg("Could not set wake alarm.")
+				}
+				if state, err := wakeAlarmState(); err == nil {
+					c.Publish(entities["wake_alarm"].Entity.StateTopic, 0, false, []byte(state))
+				}
+			})
+	} else {
+		log.Debug().Msg("No RTC wake alarm found. Not adding wake alarm control.")
+	}
+	entities["notification"] = asNotify(mqtthass.NewEntityByID("notification", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:message-badge").
+		WithCommandCallback(func(_ MQTT.Client, m MQTT.Message) {
+			n := parseNotification(m.Payload())
+			if err := desktop.Notify(ctx, n); err != nil {
+				log.Warn().Err(err).Msg("Could not show notification.")
+			}
+		})
+	// The open URL state is the last URL opened.
+	var (
+		lastURL   string
+		lastURLMu sync.Mutex
+	)
+	openURLState := func() (json.RawMessage, error) {
+		lastURLMu.Lock()
+		defer lastURLMu.Unlock()
+		return json.RawMessage(lastURL), nil
+	}
+	entities["open_url"] = asText(mqtthass.NewEntityByID("open_url", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:open-in-new").
+		WithStateCallback(openURLState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			if err := openURL(ctx, string(m.Payload())); err != nil {
+				log.Warn().Err(err).Str("url", string(m.Payload())).Msg("Could not open URL.")
+				return
+			}
+			lastURLMu.Lock()
+			lastURL = strings.TrimSpace(string(m.Payload()))
+			lastURLMu.Unlock()
+			if state, err := openURLState(); err == nil {
+				c.Publish(entities["open_url"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+	// The clipboard text is not published back as the state, as it may be
+	// sensitive (e.g., a one-time code).
+	entities["clipboard"] = asText(mqtthass.NewEntityByID("clipboard", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:clipboard-text").
+		WithStateCallback(func() (json.RawMessage, error) {
+			return json.RawMessage(``), nil
+		}).
+		WithCommandCallback(func(_ MQTT.Client, m MQTT.Message) {
+			if err := desktop.SetClipboard(ctx, string(m.Payload())); err != nil {
+				log.Warn().Err(err).Msg("Could not set clipboard.")
+			}
+		})
+	addMediaEntities(ctx, appName, entities)
+	if _, err := desktop.DoNotDisturb(ctx); err == nil {
+		dndState := func() (json.RawMessage, error) {
+			dnd, err := desktop.DoNotDisturb(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if dnd {
+				return json.RawMessage(`ON`), nil
+			}
+			return json.RawMessage(`OFF`), nil
+		}
+		entities["do_not_disturb"] = asSwitch(mqtthass.NewEntityByID("do_not_disturb", appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:bell-off").
+			WithStateCallback(dndState).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				var err error
+				switch string(m.Payload()) {
+				case "ON":
+					err = desktop.SetDoNotDisturb(ctx, true)
+				case "OFF":
+					err = desktop.SetDoNotDisturb(ctx, false)
+				default:
+					log.Warn().Str("payload", string(m.Payload())).Msg("Unknown do not disturb command.")
+					return
+				}
+				if err != nil {
+					log.Warn().Err(err).Msg("Could not change do not disturb state.")
+				}
+				if state, err := dndState(); err == nil {
+					c.Publish(entities["do_not_disturb"].Entity.StateTopic, 0, false, []byte(state))
+				}
+			})
+	} else {
+		log.Debug().Err(err).Msg("Not adding do not disturb control.")
+	}
+	extraConfig := make(map[string]map[string]any)
+	addAudioEntities(ctx, appName, entities, extraConfig)
+	addNightLightEntities(ctx, appName, entities, extraConfig)
+	addKeyboardLayoutEntity(ctx, appName, entities, extraConfig)
+	addSpeechEntity(ctx, appName, entities)
+	addBrightnessEntities(ctx, appName, entities)
+	watchers = append(watchers, addKbdBacklightEntity(ctx, appName, entities)...)
+	addCommandEntities(ctx, appName, entities)
+	addUpdateEntity(ctx, appName, entities)
+	addWakeOnLANEntity(ctx, appName, entities)
+	addMaintenanceEntities(ctx, appName, entities)
+	addUserServiceEntities(ctx, appName, entities)
+	addContainerEntities(ctx, appName, entities)
+	cameras := make(map[string]*mqttCamera)
+	addScreenshotEntities(ctx, appName, entities, cameras)
+	addWebcamEntities(ctx, appName, entities, cameras)
 	return &mqttObj{
-		entities: entities,
+		entities:    entities,
+		cameras:     cameras,
+		extraConfig: extraConfig,
+		watchers:    watchers,
 	}
 }
 
-func mqttDevice() *mqtthass.Device {
+// addMediaEntities adds controls for the active MPRIS media player: buttons
+// for play/pause, next and previous, and numbers for the volume and the
+// position in the current track (both as a percentage). The active player is
+// found on each command, so the controls follow whichever player is in use.
+func addMediaEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	for id, control := range map[string]struct {
+		method string
+		icon   string
+	}{
+		"media_play_pause": {method: "PlayPause", icon: "mdi:play-pause"},
+		"media_next":       {method: "Next", icon: "mdi:skip-next"},
+		"media_previous":   {method: "Previous", icon: "mdi:skip-previous"},
+	} {
+		entities[id] = mqtthass.NewEntityByID(id, appName).
+			AsButton().
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx)).
+			WithIcon(control.icon).
+			WithCommandCallback(func(_ MQTT.Client, _ MQTT.Message) {
+				player := media.ActivePlayer(ctx)
+				if player == nil {
+					log.Warn().Msg("No media player found.")
+					return
+				}
+				if err := player.Call(ctx, control.method); err != nil {
+					log.Warn().Err(err).Str("player", player.Name).Msg("Could not control media player.")
+				}
+			})
+	}
+
+	// Numbers report "None" (i.e., unknown) when there is no player or the
+	// value is not available.
+	mediaNumber := func(id, icon string, get func(*media.Player) (float64, error), set func(*media.Player, float64) error) {
+		state := func() (json.RawMessage, error) {
+			if player := media.ActivePlayer(ctx); player != nil {
+				if value, err := get(player); err == nil {
+					return json.RawMessage(strconv.Itoa(int(value*100 + 0.5))), nil
+				}
+			}
+			return json.RawMessage(`None`), nil
+		}
+		entities[id] = asNumber(mqtthass.NewEntityByID(id, appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon(icon).
+			WithStateCallback(state).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				value, err := strconv.ParseFloat(string(m.Payload()), 64)
+				if err != nil {
+					log.Warn().Err(err).Str("payload", string(m.Payload())).Msg("Invalid media player value.")
+					return
+				}
+				player := media.ActivePlayer(ctx)
+				if player == nil {
+					log.Warn().Msg("No media player found.")
+					return
+				}
+				if err := set(player, value/100); err != nil {
+					log.Warn().Err(err).Str("player", player.Name).Msg("Could not control media player.")
+				}
+				if s, err := state(); err == nil {
+					c.Publish(entities[id].Entity.StateTopic, 0, false, []byte(s))
+				}
+			})
+		entities[id].Entity.UnitOfMeasurement = "%"
+	}
+	mediaNumber("media_volume", "mdi:volume-high",
+		func(p *media.Player) (float64, error) { return p.Volume(ctx) },
+		func(p *media.Player, v float64) error { return p.SetVolume(ctx, v) })
+	mediaNumber("media_position", "mdi:fast-forward",
+		func(p *media.Player) (float64, error) { return p.Progress(ctx) },
+		func(p *media.Player, v float64) error { return p.SetProgress(ctx, v) })
+}
+
+// parseNotification parses a notification command payload, which is either a
+// JSON object with title, message, urgency and icon fields, or just the
+// message as plain text.
+func parseNotification(payload []byte) *desktop.Notification {
+	n := &desktop.Notification{}
+	if err := json.Unmarshal(payload, n); err != nil || n.Message == "" {
+		n = &desktop.Notification{Message: string(payload)}
+	}
+	if n.Title == "" {
+		n.Title = "Home Assistant"
+	}
+	return n
+}
+
+// wakeScreen turns the screen on and resets the idle timer, as if the user had
+// used the device. The freedesktop screensaver interface (e.g., KDE Plasma,
+// XFCE) is tried first, then GNOME's, which cannot simulate activity but can
+// turn off the screensaver.
+func wakeScreen(ctx context.Context) error {
+	err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(dbusFreedesktopScreensaverPath).
+		Destination(dbusFreedesktopScreensaverDest).
+		Call(dbusFreedesktopScreensaverDest + ".SimulateUserActivity")
+	if err == nil {
+		return nil
+	}
+	gnomeErr := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(dbusGnomeScreensaverPath).
+		Destination(dbusGnomeScreensaverDest).
+		Call(dbusGnomeScreensaverDest+".SetActive", false)
+	if gnomeErr == nil {
+		return nil
+	}
+	return errors.Join(err, gnomeErr)
+}
+
+// lockUserSessions locks every logind session of the user running the agent,
+// using LockSession. The sessions are found each time, so that a session
+// started after the agent (e.g., logging in again) is also locked, and the
+// graphical session is locked even if the user also has other (e.g., SSH)
+// sessions.
+func lockUserSessions(ctx context.Context) error {
+	u, err := user.Current()
+	if err != nil {
+		return err
+	}
+	sessions, ok := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dbusLoginPath).
+		Destination(dbusSessionDest).
+		GetData(dbusLoginManager + ".ListSessions").
+		AsRawInterface().([][]any)
+	if !ok {
+		return errors.New("could not list sessions")
+	}
+	var errs []error
+	locked := 0
+	for _, session := range sessions {
+		if len(session) < 3 {
+			continue
+		}
+		id, idOK := session[0].(string)
+		name, nameOK := session[2].(string)
+		if !idOK || !nameOK || name != u.Username {
+			continue
+		}
+		err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(dbusLoginPath).
+			Destination(dbusSessionDest).
+			Call(dbusLoginManager+".LockSession", id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		locked++
+	}
+	if locked == 0 && len(errs) == 0 {
+		return errors.New("no sessions found for user")
+	}
+	return errors.Join(errs...)
+}
+
+// canPowerAction returns whether logind reports the given power action (e.g.,
+// Suspend) is possible. Actions needing authentication are allowed, as polkit
+// may permit them for the user without asking.
+func canPowerAction(ctx context.Context, method string) bool {
+	result, ok := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dbusLoginPath).
+		Destination(dbusSessionDest).
+		GetData(dbusLoginManager + ".Can" + method).
+		AsRawInterface().(string)
+	return ok && (result == "yes" || result == "challenge")
+}
+
+// mqttDevice returns the device details for MQTT entities. The name and ID
+// saved at registration are used, so that the MQTT device matches the device
+// registered with Home Assistant.
+func mqttDevice(ctx context.Context) *mqtthass.Device {
+	prefs := preferences.FetchFromContext(ctx)
 	dev := linux.NewDevice(preferences.AppName, preferences.AppVersion)
+	name, id := prefs.DeviceName, prefs.DeviceID
+	if name == "" {
+		name = dev.DeviceName()
+	}
+	if id == "" {
+		id = dev.DeviceID()
+	}
 	return &mqtthass.Device{
-		Name:         dev.DeviceName(),
+		Name:         name,
 		URL:          preferences.AppURL,
 		SWVersion:    dev.OsVersion(),
 		Manufacturer: dev.Manufacturer(),
 		Model:        dev.Model(),
-		Identifiers:  []string{dev.DeviceID()},
+		Identifiers:  []string{id},
 	}
 }
 
@@ -139,3 +663,561 @@ func GetDesktopEnvScreensaverConfig() (string, string, *string) {
 		return "", "", nil
 	}
 }
+
+// addAudioEntities adds controls for the volume and mute state of the default
+// audio output, if it can be controlled, and to select the default output.
+func addAudioEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig, extraConfig map[string]map[string]any) {
+	control, err := audio.NewControl()
+	if err == nil {
+		_, err = control.Volume(ctx)
+	}
+	if err != nil {
+		log.Debug().Err(err).Msg("Not adding volume controls.")
+		return
+	}
+
+	volumeState := func() (json.RawMessage, error) {
+		volume, err := control.Volume(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(strconv.Itoa(volume)), nil
+	}
+	entities["volume"] = asNumber(mqtthass.NewEntityByID("volume", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:volume-high").
+		WithStateCallback(volumeState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			volume, err := strconv.ParseFloat(string(m.Payload()), 64)
+			if err != nil {
+				log.Warn().Err(err).Str("payload", string(m.Payload())).Msg("Invalid volume.")
+				return
+			}
+			if err := control.SetVolume(ctx, int(volume+0.5)); err != nil {
+				log.Warn().Err(err).Msg("Could not set volume.")
+			}
+			if state, err := volumeState(); err == nil {
+				c.Publish(entities["volume"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+	entities["volume"].Entity.UnitOfMeasurement = "%"
+
+	muteState := func() (json.RawMessage, error) {
+		muted, err := control.Muted(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if muted {
+			return json.RawMessage(`ON`), nil
+		}
+		return json.RawMessage(`OFF`), nil
+	}
+	entities["mute"] = asSwitch(mqtthass.NewEntityByID("mute", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:volume-off").
+		WithStateCallback(muteState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			var err error
+			switch string(m.Payload()) {
+			case "ON":
+				err = control.SetMuted(ctx, true)
+			case "OFF":
+				err = control.SetMuted(ctx, false)
+			default:
+				log.Warn().Str("payload", string(m.Payload())).Msg("Unknown mute command.")
+				return
+			}
+			if err != nil {
+				log.Warn().Err(err).Msg("Could not change mute state.")
+			}
+			if state, err := muteState(); err == nil {
+				c.Publish(entities["mute"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+
+	sinks, err := control.Sinks(ctx)
+	if err != nil || len(sinks) == 0 {
+		log.Debug().Err(err).Msg("Not adding audio output control.")
+		return
+	}
+	// The options are the sink descriptions, which are the names shown by
+	// the desktop.
+	outputState := func() (json.RawMessage, error) {
+		name, err := control.DefaultSink(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, sink := range sinks {
+			if sink.Name == name {
+				return json.RawMessage(sink.Description), nil
+			}
+		}
+		return json.RawMessage(`None`), nil
+	}
+	entities["audio_output"] = asSelect(mqtthass.NewEntityByID("audio_output", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:speaker").
+		WithStateCallback(outputState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			i := slices.IndexFunc(sinks, func(sink audio.Sink) bool {
+				return sink.Description == string(m.Payload())
+			})
+			if i == -1 {
+				log.Warn().Str("payload", string(m.Payload())).Msg("Unknown audio output.")
+				return
+			}
+			if err := control.SetDefaultSink(ctx, sinks[i].Name); err != nil {
+				log.Warn().Err(err).Msg("Could not change audio output.")
+			}
+			if state, err := outputState(); err == nil {
+				c.Publish(entities["audio_output"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+	options := make([]string, 0, len(sinks))
+	for _, sink := range sinks {
+		options = append(options, sink.Description)
+	}
+	extraConfig["audio_output"] = map[string]any{"options": options}
+}
+
+// addKeyboardLayoutEntity adds a select to change the active keyboard layout,
+// from those configured on the desktop.
+func addKeyboardLayoutEntity(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig, extraConfig map[string]map[string]any) {
+	layouts, _, err := desktop.KeyboardLayouts(ctx)
+	if err != nil || len(layouts) < 2 {
+		log.Debug().Err(err).Msg("Not adding keyboard layout control.")
+		return
+	}
+	layoutState := func() (json.RawMessage, error) {
+		current, active, err := desktop.KeyboardLayouts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if active < 0 || active >= len(current) {
+			return json.RawMessage(`None`), nil
+		}
+		return json.RawMessage(current[active].Description), nil
+	}
+	entities["keyboard_layout"] = asSelect(mqtthass.NewEntityByID("keyboard_layout", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:keyboard").
+		WithStateCallback(layoutState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			i := slices.IndexFunc(layouts, func(l desktop.KeyboardLayout) bool {
+				return l.Description == string(m.Payload())
+			})
+			if i == -1 {
+				log.Warn().Str("payload", string(m.Payload())).Msg("Unknown keyboard layout.")
+				return
+			}
+			if err := desktop.SetKeyboardLayout(ctx, layouts[i].Name); err != nil {
+				log.Warn().Err(err).Msg("Could not change keyboard layout.")
+			}
+			if state, err := layoutState(); err == nil {
+				c.Publish(entities["keyboard_layout"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+	options := make([]string, 0, len(layouts))
+	for _, l := range layouts {
+		options = append(options, l.Description)
+	}
+	extraConfig["keyboard_layout"] = map[string]any{"options": options}
+}
+
+// addSpeechEntity adds a notify entity that speaks the messages sent to it
+// through the speakers of the device.
+func addSpeechEntity(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	prefs := preferences.FetchFromContext(ctx)
+	speaker, err := audio.NewSpeaker(prefs.TTSModel)
+	if err != nil {
+		log.Debug().Err(err).Msg("Not adding speech control.")
+		return
+	}
+	entities["speak"] = asNotify(mqtthass.NewEntityByID("speak", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:account-voice").
+		WithCommandCallback(func(_ MQTT.Client, m MQTT.Message) {
+			// Speak in the background, as speaking takes as long as the
+			// message does.
+			go func() {
+				if err := speaker.Speak(ctx, string(m.Payload())); err != nil {
+					log.Warn().Err(err).Msg("Could not speak message.")
+				}
+			}()
+		})
+}
+
+// addUserServiceEntities adds a switch to start and stop each systemd user
+// service listed in the preferences.
+func addUserServiceEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	prefs := preferences.FetchFromContext(ctx)
+	for _, name := range prefs.UserServices {
+		service, err := systemd.NewUserService(ctx, name)
+		if err != nil {
+			log.Warn().Err(err).Str("service", name).Msg("Not adding user service control.")
+			continue
+		}
+		id := "service_" + strcase.ToSnake(strings.TrimSuffix(service.Name, ".service"))
+		serviceState := func() (json.RawMessage, error) {
+			active, err := service.Active(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if active {
+				return json.RawMessage(`ON`), nil
+			}
+			return json.RawMessage(`OFF`), nil
+		}
+		entities[id] = asSwitch(mqtthass.NewEntityByID(id, appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:cogs").
+			WithStateCallback(serviceState).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				var change func(context.Context) error
+				switch string(m.Payload()) {
+				case "ON":
+					change = service.Start
+				case "OFF":
+					change = service.Stop
+				default:
+					log.Warn().Str("payload", string(m.Payload())).Msg("Unknown user service command.")
+					return
+				}
+				// Services can take a while to start or stop, so wait for
+				// the new state in the background.
+				go func() {
+					if err := change(ctx); err != nil {
+						log.Warn().Err(err).Str("service", service.Name).Msg("Could not change user service state.")
+					}
+					if state, err := serviceState(); err == nil {
+						c.Publish(entities[id].Entity.StateTopic, 0, false, []byte(state))
+					}
+				}()
+			})
+	}
+}
+
+// addContainerEntities adds a switch to start and stop each Docker or Podman
+// container listed in the preferences.
+func addContainerEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	prefs := preferences.FetchFromContext(ctx)
+	if len(prefs.Containers) == 0 {
+		return
+	}
+	client, err := containers.NewClient()
+	if err != nil {
+		log.Warn().Err(err).Msg("Not adding container controls.")
+		return
+	}
+	for _, name := range prefs.Containers {
+		if _, err := client.Running(ctx, name); err != nil {
+			log.Warn().Err(err).Str("container", name).Str("socket", client.Socket()).Msg("Not adding container control.")
+			continue
+		}
+		id := "container_" + strcase.ToSnake(name)
+		containerState := func() (json.RawMessage, error) {
+			running, err := client.Running(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			if running {
+				return json.RawMessage(`ON`), nil
+			}
+			return json.RawMessage(`OFF`), nil
+		}
+		entities[id] = asSwitch(mqtthass.NewEntityByID(id, appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:docker").
+			WithStateCallback(containerState).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				var change func(context.Context, string) error
+				switch string(m.Payload()) {
+				case "ON":
+					change = client.Start
+				case "OFF":
+					change = client.Stop
+				default:
+					log.Warn().Str("payload", string(m.Payload())).Msg("Unknown container command.")
+					return
+				}
+				// Containers can take a while to start or stop, so wait for
+				// the new state in the background.
+				go func() {
+					if err := change(ctx, name); err != nil {
+						log.Warn().Err(err).Str("container", name).Msg("Could not change container state.")
+					}
+					if state, err := containerState(); err == nil {
+						c.Publish(entities[id].Entity.StateTopic, 0, false, []byte(state))
+					}
+				}()
+			})
+	}
+}
+
+// addScreenshotEntities adds a camera showing a screenshot of the desktop, and
+// a button to update it. Screenshots are only taken if enabled in the
+// preferences, as they may show sensitive information.
+func addScreenshotEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig, cameras map[string]*mqttCamera) {
+	prefs := preferences.FetchFromContext(ctx)
+	if !prefs.Screenshots {
+		return
+	}
+	camera := asCamera(mqtthass.NewEntityByID("screen", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx)).
+		WithIcon("mdi:monitor-screenshot"), desktop.Screenshot)
+	camera.interval = time.Duration(prefs.ScreenshotSecs) * time.Second
+	cameras["screen"] = camera
+	entities["take_screenshot"] = mqtthass.NewEntityByID("take_screenshot", appName).
+		AsButton().
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx)).
+		WithIcon("mdi:monitor-screenshot").
+		WithCommandCallback(func(c MQTT.Client, _ MQTT.Message) {
+			// Capturing can take a while, so don't block other MQTT
+			// messages.
+			go func() {
+				msg, err := camera.image(ctx)
+				if err != nil {
+					log.Warn().Err(err).Msg("Could not take screenshot.")
+					return
+				}
+				c.Publish(msg.Topic, msg.QOS, msg.Retained, []byte(msg.Message))
+			}()
+		})
+}
+
+// addWebcamEntities adds a camera showing a still image from the webcam
+// configured in the preferences, and a button to capture a new one. Nothing is
+// added unless a webcam has been configured.
+func addWebcamEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig, cameras map[string]*mqttCamera) {
+	prefs := preferences.FetchFromContext(ctx)
+	if prefs.Webcam == "" {
+		return
+	}
+	cam, err := webcam.New(prefs.Webcam)
+	if err != nil {
+		log.Warn().Err(err).Str("device", prefs.Webcam).Msg("Not adding webcam.")
+		return
+	}
+	camera := asCamera(mqtthass.NewEntityByID("webcam", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx)).
+		WithIcon("mdi:webcam"), cam.Capture)
+	camera.interval = time.Duration(prefs.WebcamSecs) * time.Second
+	cameras["webcam"] = camera
+	entities["capture_webcam"] = mqtthass.NewEntityByID("capture_webcam", appName).
+		AsButton().
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx)).
+		WithIcon("mdi:webcam").
+		WithCommandCallback(func(c MQTT.Client, _ MQTT.Message) {
+			// Capturing can take a while, so don't block other MQTT
+			// messages.
+			go func() {
+				msg, err := camera.image(ctx)
+				if err != nil {
+					log.Warn().Err(err).Msg("Could not capture webcam image.")
+					return
+				}
+				c.Publish(msg.Topic, msg.QOS, msg.Retained, []byte(msg.Message))
+			}()
+		})
+}
+
+// addVPNEntities adds a switch to connect or disconnect each VPN connection
+// configured in NetworkManager.
+func addVPNEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	for _, vpn := range linuxnet.FindVPNs(ctx) {
+		id := "vpn_" + strcase.ToSnake(vpn.Name)
+		vpnState := func() (json.RawMessage, error) {
+			if vpn.Active(ctx) {
+				return json.RawMessage(`ON`), nil
+			}
+			return json.RawMessage(`OFF`), nil
+		}
+		entities[id] = asSwitch(mqtthass.NewEntityByID(id, appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:vpn").
+			WithStateCallback(vpnState).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				var err error
+				switch string(m.Payload()) {
+				case "ON":
+					err = vpn.Connect(ctx)
+				case "OFF":
+					err = vpn.Disconnect(ctx)
+				default:
+					log.Warn().Str("payload", string(m.Payload())).Msg("Unknown VPN command.")
+					return
+				}
+				if err != nil {
+					log.Warn().Err(err).Str("vpn", vpn.Name).Msg("Could not change VPN state.")
+				}
+				if state, err := vpnState(); err == nil {
+					c.Publish(entities[id].Entity.StateTopic, 0, false, []byte(state))
+				}
+			})
+	}
+}
+
+// addNightLightEntities adds controls to turn night light on or off and set
+// its color temperature, if available.
+func addNightLightEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig, extraConfig map[string]map[string]any) {
+	if _, err := desktop.NightLight(ctx); err != nil {
+		log.Debug().Err(err).Msg("Not adding night light controls.")
+		return
+	}
+	nightLightState := func() (json.RawMessage, error) {
+		enabled, err := desktop.NightLight(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			return json.RawMessage(`ON`), nil
+		}
+		return json.RawMessage(`OFF`), nil
+	}
+	entities["night_light"] = asSwitch(mqtthass.NewEntityByID("night_light", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:weather-night").
+		WithStateCallback(nightLightState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			var err error
+			switch string(m.Payload()) {
+			case "ON":
+				err = desktop.SetNightLight(ctx, true)
+			case "OFF":
+				err = desktop.SetNightLight(ctx, false)
+			default:
+				log.Warn().Str("payload", string(m.Payload())).Msg("Unknown night light command.")
+				return
+			}
+			if err != nil {
+				log.Warn().Err(err).Msg("Could not change night light state.")
+			}
+			if state, err := nightLightState(); err == nil {
+				c.Publish(entities["night_light"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+
+	temperatureState := func() (json.RawMessage, error) {
+		kelvin, err := desktop.NightLightTemperature(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(strconv.Itoa(kelvin)), nil
+	}
+	entities["night_light_temperature"] = asNumber(mqtthass.NewEntityByID("night_light_temperature", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:thermometer").
+		WithStateCallback(temperatureState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			kelvin, err := strconv.ParseFloat(string(m.Payload()), 64)
+			if err != nil {
+				log.Warn().Err(err).Str("payload", string(m.Payload())).Msg("Invalid night light temperature.")
+				return
+			}
+			if err := desktop.SetNightLightTemperature(ctx, int(kelvin)); err != nil {
+				log.Warn().Err(err).Msg("Could not set night light temperature.")
+			}
+			if state, err := temperatureState(); err == nil {
+				c.Publish(entities["night_light_temperature"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+	entities["night_light_temperature"].Entity.UnitOfMeasurement = "K"
+	extraConfig["night_light_temperature"] = map[string]any{
+		"min":  desktop.NightLightMinTemperature,
+		"max":  desktop.NightLightMaxTemperature,
+		"step": 100,
+	}
+}
+
+// addKbdBacklightEntity adds a control for the brightness of the keyboard
+// backlight, if there is one. It returns a watcher to publish changes made
+// outside of Home Assistant.
+func addKbdBacklightEntity(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) []stateWatcher {
+	if _, err := display.KbdBrightness(ctx); err != nil {
+		log.Debug().Err(err).Msg("Not adding keyboard backlight control.")
+		return nil
+	}
+	kbdBrightnessState := func() (json.RawMessage, error) {
+		brightness, err := display.KbdBrightness(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(strconv.Itoa(brightness)), nil
+	}
+	entities["keyboard_brightness"] = asNumber(mqtthass.NewEntityByID("keyboard_brightness", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:keyboard-settings").
+		WithStateCallback(kbdBrightnessState).
+		WithCommandCallback(func(_ MQTT.Client, m MQTT.Message) {
+			brightness, err := strconv.ParseFloat(string(m.Payload()), 64)
+			if err != nil {
+				log.Warn().Err(err).Str("payload", string(m.Payload())).Msg("Invalid keyboard brightness.")
+				return
+			}
+			// The new state is published by the watcher below.
+			if err := display.SetKbdBrightness(ctx, int(brightness+0.5)); err != nil {
+				log.Warn().Err(err).Msg("Could not set keyboard brightness.")
+			}
+		})
+	entities["keyboard_brightness"].Entity.UnitOfMeasurement = "%"
+	return []stateWatcher{
+		func(ctx context.Context, publish func(*mqtthass.EntityConfig)) error {
+			return display.WatchKbdBrightness(ctx, func() {
+				publish(entities["keyboard_brightness"])
+			})
+		},
+	}
+}
+
+// addBrightnessEntities adds a control for the brightness of each backlight
+// device.
+func addBrightnessEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	for _, device := range display.Backlights() {
+		if _, err := display.Brightness(device); err != nil {
+			log.Debug().Err(err).Str("device", device).Msg("Not adding brightness control.")
+			continue
+		}
+		id := strcase.ToSnake(device + "_brightness")
+		brightnessState := func() (json.RawMessage, error) {
+			brightness, err := display.Brightness(device)
+			if err != nil {
+				return nil, err
+			}
+			return json.RawMessage(strconv.Itoa(brightness)), nil
+		}
+		entities[id] = asNumber(mqtthass.NewEntityByID(id, appName).
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx))).
+			WithIcon("mdi:brightness-6").
+			WithStateCallback(brightnessState).
+			WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+				brightness, err := strconv.ParseFloat(string(m.Payload()), 64)
+				if err != nil {
+					log.Warn().Err(err).Str("payload", string(m.Payload())).Msg("Invalid brightness.")
+					return
+				}
+				if err := display.SetBrightness(ctx, device, int(brightness+0.5)); err != nil {
+					log.Warn().Err(err).Str("device", device).Msg("Could not set brightness.")
+				}
+				if state, err := brightnessState(); err == nil {
+					c.Publish(entities[id].Entity.StateTopic, 0, false, []byte(state))
+				}
+			})
+		entities[id].Entity.UnitOfMeasurement = "%"
+	}
+}
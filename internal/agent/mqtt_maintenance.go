@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !termux
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog/log"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
+
+	"github.com/joshuar/go-hass-agent/internal/linux/maintenance"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// maintenanceTimeout is the maximum time a maintenance action can run for.
+// Trimming large or slow disks can take a while.
+const maintenanceTimeout = 30 * time.Minute
+
+// maintenanceEntities are the button IDs and icons of each maintenance action.
+var maintenanceEntities = map[string]struct {
+	id   string
+	icon string
+}{
+	maintenance.EmptyTrash:      {id: "empty_trash", icon: "mdi:trash-can"},
+	maintenance.ClearThumbnails: {id: "clear_thumbnails", icon: "mdi:image-remove"},
+	maintenance.Trim:            {id: "trim_filesystems", icon: "mdi:harddisk"},
+}
+
+// maintenanceResult is the result of the last run of a maintenance action.
+type maintenanceResult struct {
+	Completed  time.Time `json:"-"`
+	Error      string    `json:"Error,omitempty"`
+	Duration   string    `json:"Duration"`
+	FreedBytes int64     `json:"Freed Bytes"`
+}
+
+// maintenanceAction is a maintenance action enabled in the preferences.
+type maintenanceAction struct {
+	result  *maintenanceResult
+	name    string
+	mu      sync.Mutex
+	running bool
+}
+
+// run runs the action and records the result. It returns false if the action
+// is already running.
+func (a *maintenanceAction) run(ctx context.Context) bool {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return false
+	}
+	a.running = true
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, maintenanceTimeout)
+	defer cancel()
+	start := time.Now()
+	freed, err := maintenance.Run(ctx, a.name)
+	result := &maintenanceResult{
+		Completed:  time.Now(),
+		Duration:   time.Since(start).Round(time.Millisecond).String(),
+		FreedBytes: freed,
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("action", a.name).Msg("Maintenance action failed.")
+		result.Error = err.Error()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.result = result
+	a.running = false
+	return true
+}
+
+// state returns when the action last completed, or None if it has not been
+// run.
+func (a *maintenanceAction) state() (json.RawMessage, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.result == nil {
+		return json.RawMessage(`None`), nil
+	}
+	return json.RawMessage(a.result.Completed.Format(time.RFC3339)), nil
+}
+
+// attributes returns the details of the last run.
+func (a *maintenanceAction) attributes() (json.RawMessage, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.result == nil {
+		return json.RawMessage(`{}`), nil
+	}
+	return json.Marshal(a.result)
+}
+
+// addMaintenanceEntities adds a button for each maintenance action enabled in
+// the preferences, and a sensor with when it last completed. Unlike the power
+// controls, maintenance actions are disabled by default.
+func addMaintenanceEntities(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	prefs := preferences.FetchFromContext(ctx)
+	for _, name := range maintenance.Actions {
+		if !prefs.Maintenance[name] {
+			continue
+		}
+		id := maintenanceEntities[name].id
+		resultID := id + "_result"
+		a := &maintenanceAction{name: name}
+		entities[resultID] = mqtthass.NewEntityByID(resultID, appName).
+			AsSensor().
+			WithAttributesTopic().
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx)).
+			WithIcon("mdi:wrench-clock").
+			WithDeviceClass("timestamp").
+			WithStateCallback(a.state).
+			WithAttributesCallback(a.attributes)
+		entities[id] = mqtthass.NewEntityByID(id, appName).
+			AsButton().
+			WithDefaultOriginInfo().
+			WithDeviceInfo(mqttDevice(ctx)).
+			WithIcon(maintenanceEntities[name].icon).
+			WithCommandCallback(func(client MQTT.Client, _ MQTT.Message) {
+				// Maintenance actions can take a while, so don't block other
+				// MQTT messages.
+				go func() {
+					if !a.run(ctx) {
+						log.Warn().Str("action", name).Msg("Maintenance action is already running.")
+						return
+					}
+					result := entities[resultID].Entity
+					if state, err := a.state(); err == nil {
+						client.Publish(result.StateTopic, 0, false, []byte(state))
+					}
+					if attrs, err := a.attributes(); err == nil {
+						client.Publish(result.AttributesTopic, 0, false, []byte(attrs))
+					}
+				}()
+			})
+	}
+}
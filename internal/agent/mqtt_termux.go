@@ -0,0 +1,22 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build termux
+
+package agent
+
+import (
+	"context"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
+)
+
+// newMQTTObject returns an MQTT object with no entities, as the device
+// controls all use D-Bus.
+func newMQTTObject(_ context.Context, _ func() error) *mqttObj {
+	return &mqttObj{
+		entities: make(map[string]*mqtthass.EntityConfig),
+	}
+}
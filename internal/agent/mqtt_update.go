@@ -0,0 +1,174 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !termux
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog/log"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/update"
+)
+
+const (
+	// agentUpdateCheckInterval is how often the latest release is checked
+	// for. The state is published more often, so the latest release is
+	// cached between checks.
+	agentUpdateCheckInterval = 6 * time.Hour
+	agentUpdateCheckTimeout  = 30 * time.Second
+	agentUpdateTimeout       = 10 * time.Minute
+)
+
+var errNoUpdate = errors.New("no newer version available")
+
+// agentUpdater tracks the latest release of the agent and installs it when
+// requested from Home Assistant.
+type agentUpdater struct {
+	checked    time.Time
+	latest     *update.Release
+	channel    update.Channel
+	mu         sync.Mutex
+	inProgress bool
+	checking   bool
+}
+
+// refresh checks for the latest release, if it has not been checked recently.
+// The lock is not held while checking, so that the state can still be read.
+func (u *agentUpdater) refresh(ctx context.Context) {
+	u.mu.Lock()
+	if u.checking || time.Since(u.checked) < agentUpdateCheckInterval {
+		u.mu.Unlock()
+		return
+	}
+	u.checking = true
+	u.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, agentUpdateCheckTimeout)
+	defer cancel()
+	latest, err := update.Latest(ctx, u.channel)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.checking = false
+	if err != nil {
+		log.Debug().Err(err).Str("channel", string(u.channel)).Msg("Could not check for updates.")
+		return
+	}
+	u.latest = latest
+	u.checked = time.Now()
+}
+
+// state returns the installed and latest versions. Until the latest release
+// is known, it is reported as the installed version.
+func (u *agentUpdater) state(ctx context.Context) (json.RawMessage, error) {
+	u.refresh(ctx)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	state := struct {
+		InstalledVersion string `json:"installed_version"`
+		LatestVersion    string `json:"latest_version"`
+		Title            string `json:"title"`
+		ReleaseURL       string `json:"release_url,omitempty"`
+		InProgress       bool   `json:"in_progress"`
+	}{
+		InstalledVersion: preferences.AppVersion,
+		LatestVersion:    preferences.AppVersion,
+		Title:            "Go Hass Agent",
+		InProgress:       u.inProgress,
+	}
+	if u.latest != nil {
+		state.LatestVersion = u.latest.Version
+		state.ReleaseURL = u.latest.URL
+	}
+	return json.Marshal(state)
+}
+
+// install installs the latest release, if it is newer than the installed
+// version, and restarts the agent with it.
+func (u *agentUpdater) install(ctx context.Context) error {
+	u.mu.Lock()
+	latest := u.latest
+	u.mu.Unlock()
+	if latest == nil || !update.IsNewer(preferences.AppVersion, latest.Version) {
+		return errNoUpdate
+	}
+	exe, err := update.Executable()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, agentUpdateTimeout)
+	defer cancel()
+	if err := latest.Install(ctx, exe); err != nil {
+		return err
+	}
+	log.Info().Str("version", latest.Version).Msg("Agent updated. Restarting.")
+	return requestRestart(exe)
+}
+
+// setInProgress sets whether an update is being installed, returning false if
+// one already is.
+func (u *agentUpdater) setInProgress(inProgress bool) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if inProgress && u.inProgress {
+		return false
+	}
+	u.inProgress = inProgress
+	return true
+}
+
+// addUpdateEntity adds an update entity reporting the installed and latest
+// versions of the agent, on the release channel chosen in the preferences.
+// Installing the update from Home Assistant downloads the new version, verifies
+// its checksum and signature, replaces the agent executable with it and
+// restarts the agent.
+func addUpdateEntity(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	u := &agentUpdater{
+		channel: update.ChannelFromString(preferences.FetchFromContext(ctx).UpdateChannel),
+	}
+	publish := func(c MQTT.Client) {
+		if state, err := u.state(ctx); err == nil {
+			c.Publish(entities["agent_update"].Entity.StateTopic, 0, false, []byte(state))
+		}
+	}
+	entities["agent_update"] = asUpdate(mqtthass.NewEntityByID("agent_update", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:package-up").
+		WithStateCallback(func() (json.RawMessage, error) {
+			return u.state(ctx)
+		}).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			if string(m.Payload()) != "install" {
+				log.Warn().Str("payload", string(m.Payload())).Msg("Unknown update command.")
+				return
+			}
+			if !u.setInProgress(true) {
+				log.Warn().Msg("Agent update already in progress.")
+				return
+			}
+			publish(c)
+			// Downloading the update can take a while, so don't block other
+			// MQTT messages.
+			go func() {
+				if err := u.install(ctx); err != nil {
+					log.Warn().Err(err).Msg("Could not update agent.")
+					u.setInProgress(false)
+					publish(c)
+				}
+			}()
+		})
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !termux
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog/log"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/wol"
+)
+
+// wakeTarget returns the MAC and broadcast addresses to wake the given target,
+// which is either the name of a wake host in the preferences (ignoring case) or
+// a MAC address.
+func wakeTarget(hosts []preferences.WakeHost, target string) (mac, broadcast string) {
+	target = strings.TrimSpace(target)
+	for _, host := range hosts {
+		if strings.EqualFold(host.Name, target) {
+			return host.MAC, host.Broadcast
+		}
+	}
+	return target, ""
+}
+
+// addWakeOnLANEntity adds a text entity that sends a Wake-on-LAN magic packet
+// on the local network when set to a MAC address or the name of a wake host in
+// the preferences. This lets the agent wake devices on a different network to
+// Home Assistant.
+func addWakeOnLANEntity(ctx context.Context, appName string, entities map[string]*mqtthass.EntityConfig) {
+	hosts := preferences.FetchFromContext(ctx).WakeHosts
+	// The state is the last device woken.
+	var (
+		lastWoken   string
+		lastWokenMu sync.Mutex
+	)
+	wakeState := func() (json.RawMessage, error) {
+		lastWokenMu.Lock()
+		defer lastWokenMu.Unlock()
+		return json.RawMessage(lastWoken), nil
+	}
+	entities["wake_on_lan"] = asText(mqtthass.NewEntityByID("wake_on_lan", appName).
+		WithDefaultOriginInfo().
+		WithDeviceInfo(mqttDevice(ctx))).
+		WithIcon("mdi:lan-pending").
+		WithStateCallback(wakeState).
+		WithCommandCallback(func(c MQTT.Client, m MQTT.Message) {
+			target := strings.TrimSpace(string(m.Payload()))
+			mac, broadcast := wakeTarget(hosts, target)
+			if err := wol.Send(ctx, mac, broadcast); err != nil {
+				log.Warn().Err(err).Str("target", target).Msg("Could not send Wake-on-LAN packet.")
+				return
+			}
+			log.Debug().Str("target", target).Str("mac", mac).Msg("Sent Wake-on-LAN packet.")
+			lastWokenMu.Lock()
+			lastWoken = target
+			lastWokenMu.Unlock()
+			if state, err := wakeState(); err == nil {
+				c.Publish(entities["wake_on_lan"].Entity.StateTopic, 0, false, []byte(state))
+			}
+		})
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !termux
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func Test_wakeTarget(t *testing.T) {
+	hosts := []preferences.WakeHost{
+		{Name: "NAS", MAC: "00:11:22:33:44:55"},
+		{Name: "Gaming PC", MAC: "66:77:88:99:aa:bb", Broadcast: "192.168.2.255"},
+	}
+	tests := []struct {
+		target        string
+		wantMAC       string
+		wantBroadcast string
+	}{
+		{target: "NAS", wantMAC: "00:11:22:33:44:55"},
+		{target: " gaming pc\n", wantMAC: "66:77:88:99:aa:bb", wantBroadcast: "192.168.2.255"},
+		{target: "cc:dd:ee:ff:00:11", wantMAC: "cc:dd:ee:ff:00:11"},
+		{target: "unknown", wantMAC: "unknown"},
+	}
+	for _, tt := range tests {
+		mac, broadcast := wakeTarget(hosts, tt.target)
+		assert.Equal(t, tt.wantMAC, mac, tt.target)
+		assert.Equal(t, tt.wantBroadcast, broadcast, tt.target)
+	}
+}
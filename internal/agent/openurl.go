@@ -0,0 +1,36 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var errInvalidOpenURL = errors.New("only http and https URLs can be opened")
+
+// parseOpenURL validates a URL sent from Home Assistant to be opened. Only
+// http and https URLs are allowed, so that Home Assistant cannot open local
+// files or launch other applications through their URL schemes.
+func parseOpenURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errInvalidOpenURL
+	}
+	return u.String(), nil
+}
+
+// openURL opens the URL sent from Home Assistant in the default browser of the
+// device.
+func openURL(ctx context.Context, raw string) error {
+	u, err := parseOpenURL(raw)
+	if err != nil {
+		return err
+	}
+	return openDeviceURL(ctx, u)
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseOpenURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "https://homeassistant.local:8123/lovelace/cameras", want: "https://homeassistant.local:8123/lovelace/cameras"},
+		{raw: " http://example.com/docs?page=1\n", want: "http://example.com/docs?page=1"},
+		{raw: "file:///etc/passwd", wantErr: true},
+		{raw: "javascript:alert(1)", wantErr: true},
+		{raw: "steam://run/440", wantErr: true},
+		{raw: "https://", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseOpenURL(tt.raw)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errInvalidOpenURL)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
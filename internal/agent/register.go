@@ -8,11 +8,13 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
 	"os"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/joshuar/go-hass-agent/internal/device/identity"
 	"github.com/joshuar/go-hass-agent/internal/hass/api"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 
@@ -37,6 +39,7 @@ func saveRegistration(server, token string, resp *api.RegistrationResponse, dev
 		preferences.DeviceID(dev.DeviceID()),
 		preferences.Version(preferences.AppVersion),
 		preferences.Registered(true),
+		preferences.EnsureInstallID(),
 	)
 }
 
@@ -60,7 +63,11 @@ func (agent *Agent) performRegistration(ctx context.Context, server, token strin
 	}
 
 	// Register with Home Assistant.
-	device := newDevice(ctx)
+	sources, err := agent.idSources()
+	if err != nil {
+		return err
+	}
+	device := newDevice(ctx, sources...)
 	resp, err := api.RegisterWithHass(ctx, server, token, device)
 	if err != nil {
 		return errors.New("could not register with Home Assistant")
@@ -75,6 +82,24 @@ func (agent *Agent) performRegistration(ctx context.Context, server, token strin
 	return nil
 }
 
+// idSources returns the sources to use for the device ID, based on the
+// command-line options. If no options were given, no sources are returned and
+// the default sources for the platform will be used.
+func (agent *Agent) idSources() ([]identity.Source, error) {
+	var sources []identity.Source
+	if agent.Options.DeviceID != "" {
+		sources = append(sources, identity.Static(agent.Options.DeviceID))
+	}
+	if agent.Options.DeviceIDSource != "" {
+		s, err := identity.SourceByName(agent.Options.DeviceIDSource)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, agent.Options.DeviceIDSource)
+		}
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
 func (agent *Agent) checkRegistration(trk SensorTracker) error {
 	prefs, err := preferences.Load()
 	if err != nil && !os.IsNotExist(err) {
@@ -84,7 +109,10 @@ func (agent *Agent) checkRegistration(trk SensorTracker) error {
 	// If the agent is not registered (or force registration requested) run a
 	// registration flow
 	if !prefs.Registered || agent.Options.ForceRegister {
-		if err := agent.performRegistration(context.Background(), agent.Options.Server, agent.Options.Token); err != nil {
+		// Any custom HTTP headers already in the preferences are needed to
+		// reach Home Assistant for registration.
+		ctx := preferences.EmbedInContext(context.Background(), prefs)
+		if err := agent.performRegistration(ctx, agent.Options.Server, agent.Options.Token); err != nil {
 			return err
 		}
 		if agent.Options.ForceRegister {
@@ -0,0 +1,43 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+// restartExe is the executable to run when the agent has stopped, if a restart
+// was requested.
+var (
+	restartExe   string
+	restartExeMu sync.Mutex
+)
+
+// requestRestart stops the agent, in the same way as when it receives SIGTERM,
+// and has it replaced by the executable at the given path once it has stopped
+// (see Restart).
+func requestRestart(exe string) error {
+	restartExeMu.Lock()
+	restartExe = exe
+	restartExeMu.Unlock()
+	return syscall.Kill(os.Getpid(), syscall.SIGTERM)
+}
+
+// Restart replaces the stopped agent with a new instance, if a restart was
+// requested, keeping the same arguments and environment. It should be called
+// after Run has returned and any locks are released. It returns nil straight
+// away if no restart was requested, and otherwise only returns on error.
+func (agent *Agent) Restart() error {
+	restartExeMu.Lock()
+	exe := restartExe
+	restartExeMu.Unlock()
+	if exe == "" {
+		return nil
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
@@ -7,7 +7,13 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"runtime"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog/log"
@@ -15,26 +21,47 @@ import (
 	mqtthass "github.com/joshuar/go-hass-anything/v5/pkg/hass"
 	mqttapi "github.com/joshuar/go-hass-anything/v5/pkg/mqtt"
 
+	"github.com/joshuar/go-hass-agent/internal/anomaly"
 	"github.com/joshuar/go-hass-agent/internal/device"
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
 	"github.com/joshuar/go-hass-agent/internal/hass"
 	"github.com/joshuar/go-hass-agent/internal/hass/api"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 	"github.com/joshuar/go-hass-agent/internal/scripts"
+	"github.com/joshuar/go-hass-agent/internal/summary"
+	"github.com/joshuar/go-hass-agent/internal/telemetry"
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
 
+const (
+	// mqttStateInterval is how often the state of MQTT entities is published.
+	mqttStateInterval = time.Minute
+	// mqttConnectTimeout is how long to keep trying to connect to MQTT before
+	// giving up.
+	mqttConnectTimeout = time.Minute
+)
+
 // runWorkers will call all the sensor worker functions that have been defined
-// for this device.
-func runWorkers(ctx context.Context, trk SensorTracker) {
+// for this device. If anomaly detection is enabled, sensor values that deviate
+// wildly from their baseline are reported with an event and the notify func.
+func runWorkers(ctx context.Context, trk SensorTracker, notify func(title, message string)) {
 	workerFuncs := sensorWorkers()
-	workerFuncs = append(workerFuncs, device.ExternalIPUpdater)
+	workerFuncs = append(workerFuncs, device.ExternalIPUpdater, device.WatchdogUpdater, device.MemoryUpdater, device.HealthUpdater, device.ConnectivityUpdater, device.UpdateAvailableUpdater)
 
 	var wg sync.WaitGroup
 	var outCh []<-chan tracker.Sensor
 
 	log.Debug().Msg("Starting worker funcs.")
 	for i := 0; i < len(workerFuncs); i++ {
-		outCh = append(outCh, workerFuncs[i](ctx))
+		name := workerName(workerFuncs[i])
+		diagnostics.SetWorkerState(name, diagnostics.WorkerRunning)
+		outCh = append(outCh, trackWorkerCh(ctx, name, workerFuncs[i](ctx)))
+	}
+
+	var detector *anomaly.Detector
+	if anomaly.Feature.EnabledInContext(ctx) {
+		log.Debug().Msg("Anomaly detection enabled.")
+		detector = anomaly.NewDetector()
 	}
 
 	wg.Add(1)
@@ -45,6 +72,11 @@ func runWorkers(ctx context.Context, trk SensorTracker) {
 			go func(s tracker.Sensor) {
 				trk.UpdateSensors(ctx, s)
 			}(s)
+			if detector != nil {
+				if a := detector.Observe(s); a != nil {
+					go reportAnomaly(ctx, trk, notify, a)
+				}
+			}
 		}
 	}()
 	wg.Add(1)
@@ -57,10 +89,62 @@ func runWorkers(ctx context.Context, trk SensorTracker) {
 			}(l)
 		}
 	}()
+	for _, worker := range eventWorkers() {
+		wg.Add(1)
+		go func(eventCh chan *hass.Event) {
+			log.Debug().Msg("Listening for events.")
+			defer wg.Done()
+			for e := range eventCh {
+				go func(e *hass.Event) {
+					trk.UpdateSensors(ctx, e)
+				}(e)
+			}
+		}(worker(ctx))
+	}
 
 	wg.Wait()
 }
 
+// reportAnomaly logs an anomalous sensor value, fires an event in Home
+// Assistant and shows a notification on the device.
+func reportAnomaly(ctx context.Context, trk SensorTracker, notify func(title, message string), a *anomaly.Anomaly) {
+	log.Warn().Str("sensor", a.ID).Float64("value", a.Value).Float64("mean", a.Mean).
+		Msg("Sensor value is anomalous.")
+	trk.UpdateSensors(ctx, a.Event(preferences.FetchFromContext(ctx).DeviceName))
+	notify("Unusual sensor value", a.Message())
+}
+
+// workerName returns a short name for a worker function, suitable for
+// identifying it in diagnostics reports.
+func workerName(f any) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		return path.Base(fn.Name())
+	}
+	return "unknown"
+}
+
+// trackWorkerCh passes through the sensors sent by a worker, recording the
+// worker as stopped once its channel is closed.
+func trackWorkerCh(ctx context.Context, name string, in <-chan tracker.Sensor) <-chan tracker.Sensor {
+	if in == nil {
+		diagnostics.SetWorkerState(name, diagnostics.WorkerStopped)
+		return nil
+	}
+	out := make(chan tracker.Sensor)
+	go func() {
+		defer close(out)
+		defer diagnostics.SetWorkerState(name, diagnostics.WorkerStopped)
+		for s := range in {
+			select {
+			case out <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
 // runScripts will retrieve all scripts that the agent can run and queue them up
 // to be run on their defined schedule using the cron scheduler. It also sets up
 // a channel to receive script output and send appropriate sensor objects to the
@@ -100,6 +184,13 @@ func runScripts(ctx context.Context, path string, trk SensorTracker) {
 			}(s)
 		}
 	}()
+	// Report when each script will first run. Scripts without a valid
+	// schedule have no schedule sensors.
+	for _, s := range allScripts {
+		for _, sensor := range s.ScheduleSensors() {
+			trk.UpdateSensors(ctx, sensor)
+		}
+	}
 	<-ctx.Done()
 	log.Debug().Msg("Stopping cron scheduler for script sensors.")
 	cronCtx := c.Stop()
@@ -108,11 +199,12 @@ func runScripts(ctx context.Context, path string, trk SensorTracker) {
 
 // runNotificationsWorker will run a goroutine that is listening for
 // notification messages from Home Assistant on a websocket connection. Any
-// received notifications will be dipslayed on the device running the agent.
+// received notifications will be dipslayed on the device running the agent,
+// except for commands (e.g., to open a URL), which are run instead.
 func (agent *Agent) runNotificationsWorker(ctx context.Context) {
 	log.Debug().Msg("Listening for notifications.")
 
-	notifyCh := make(chan [2]string)
+	notifyCh := make(chan api.Notification)
 	var wg sync.WaitGroup
 
 	wg.Add(1)
@@ -124,7 +216,21 @@ func (agent *Agent) runNotificationsWorker(ctx context.Context) {
 				log.Debug().Msg("Stopping notification handler.")
 				return
 			case n := <-notifyCh:
-				agent.ui.DisplayNotification(n[0], n[1])
+				switch n.Message {
+				case api.CommandOpenURL:
+					url, _ := n.Data["url"].(string)
+					if err := openURL(ctx, url); err != nil {
+						log.Warn().Err(err).Str("url", url).Msg("Could not open URL.")
+					}
+					continue
+				case api.CommandSetClipboard:
+					text, _ := n.Data["text"].(string)
+					if err := setDeviceClipboard(ctx, text); err != nil {
+						log.Warn().Err(err).Msg("Could not set clipboard.")
+					}
+					continue
+				}
+				agent.ui.DisplayNotification(n.Title, n.Message)
 			}
 		}
 	}()
@@ -132,49 +238,209 @@ func (agent *Agent) runNotificationsWorker(ctx context.Context) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for {
-			select {
-			case <-ctx.Done():
-				log.Debug().Msg("Stopping websocket.")
-				return
-			default:
-				api.StartWebsocket(ctx, notifyCh)
-			}
-		}
+		superviseWorker(ctx, "websocket", func(ctx context.Context) error {
+			return api.StartWebsocket(ctx, notifyCh)
+		})
+		log.Debug().Msg("Stopped websocket.")
 	}()
 
 	wg.Wait()
 }
 
+// runTelemetryWorker will periodically send an anonymous telemetry report. It
+// should only be run if the user has opted in to telemetry.
+func (agent *Agent) runTelemetryWorker(ctx context.Context, prefs *preferences.Preferences) {
+	if prefs.InstallID == "" {
+		if err := preferences.Save(preferences.EnsureInstallID()); err != nil {
+			log.Warn().Err(err).Msg("Could not generate an installation ID. Not sending telemetry.")
+			return
+		}
+		var err error
+		if prefs, err = preferences.Load(); err != nil {
+			log.Warn().Err(err).Msg("Could not load preferences. Not sending telemetry.")
+			return
+		}
+	}
+	dev := newDevice(ctx)
+	var features []string
+	if prefs.MQTTEnabled {
+		features = append(features, "mqtt")
+	}
+	if agent.IsHeadless() {
+		features = append(features, "headless")
+	}
+	report := telemetry.NewReport(prefs, dev.OsName(), dev.OsVersion(), features...)
+
+	send := func() {
+		if err := telemetry.Send(ctx, prefs.TelemetryURL, report); err != nil {
+			log.Warn().Err(err).Msg("Could not send telemetry report.")
+		}
+	}
+	send()
+	ticker := time.NewTicker(telemetry.ReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+// runSummaryWorker will compile and send a summary of the device on the
+// schedule configured in the preferences, either as a persistent notification
+// in Home Assistant or as a message on MQTT. Messages on MQTT are sent with the
+// connection of the MQTT worker.
+func (agent *Agent) runSummaryWorker(ctx context.Context, trk SensorTracker) {
+	prefs := preferences.FetchFromContext(ctx)
+	compiler := &summary.Compiler{}
+
+	var send func(*summary.Summary) error
+	switch prefs.SummaryTarget {
+	case summary.TargetMQTT:
+		if !prefs.MQTTEnabled {
+			log.Warn().Msg("Summary should be sent via MQTT but MQTT is not enabled. Not sending summaries.")
+			return
+		}
+		topic := preferences.AppName + "/" + prefs.DeviceName + "/summary"
+		send = func(s *summary.Summary) error {
+			c := agent.mqttConnection()
+			if c == nil {
+				return errMQTTNotConnected
+			}
+			msg, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			return c.Publish(mqttapi.NewMsg(topic, msg))
+		}
+	default:
+		send = func(s *summary.Summary) error {
+			n := hass.NewPersistentNotification(summary.NotificationID, s.Title, s.Message())
+			if err, ok := (<-api.ExecuteRequest(ctx, n)).(error); ok {
+				return err
+			}
+			return nil
+		}
+	}
+
+	c := cron.New()
+	_, err := c.AddFunc(prefs.SummaryCron, func() {
+		if err := send(compiler.Compile(prefs.DeviceName, trk)); err != nil {
+			log.Warn().Err(err).Msg("Could not send summary.")
+			return
+		}
+		log.Debug().Msg("Sent summary.")
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("schedule", prefs.SummaryCron).
+			Msg("Unable to schedule summary.")
+		return
+	}
+	log.Debug().Str("schedule", prefs.SummaryCron).Msg("Starting cron scheduler for summary.")
+	c.Start()
+	<-ctx.Done()
+	log.Debug().Msg("Stopping cron scheduler for summary.")
+	cronCtx := c.Stop()
+	<-cronCtx.Done()
+}
+
 // runMQTTWorker will set up a connection to MQTT and listen on topics for
-// controlling this device from Home Assistant.
-func runMQTTWorker(ctx context.Context) {
+// controlling this device from Home Assistant. It returns an error if the
+// connection could not be set up, so that it can be retried.
+func (agent *Agent) runMQTTWorker(ctx context.Context) error {
 	prefs := preferences.FetchFromContext(ctx)
 	mqttprefs := &preferences.MQTTPreferences{
 		Prefs: &prefs,
 	}
 
-	c, err := mqttapi.NewMQTTClient(ctx, mqttprefs)
+	// Only try to connect for a limited time, so that a failure to connect is
+	// reported and retried by the supervisor.
+	connectCtx, cancelConnect := context.WithTimeout(ctx, mqttConnectTimeout)
+	defer cancelConnect()
+	c, err := newMQTTClient(connectCtx, mqttprefs)
 	if err != nil {
-		log.Error().Err(err).Msg("Could not start MQTT client.")
-		return
+		return fmt.Errorf("could not start MQTT client: %w", err)
+	}
+	// Disconnecting also drops the subscriptions, so that commands are no
+	// longer acted on once this worker has stopped.
+	defer c.Disconnect()
+	agent.setMQTTConnection(c)
+	defer agent.setMQTTConnection(nil)
+	o := newMQTTObject(ctx, agent.reloadScripts)
+	// Always publish the entity configs, so that any entities added since the
+	// agent was first registered with MQTT are also registered.
+	log.Debug().Msg("Registering agent with MQTT.")
+	if err := mqtthass.Register(o, c); err != nil {
+		return fmt.Errorf("could not register with MQTT: %w", err)
 	}
-	o := newMQTTObject(ctx)
 	if !prefs.MQTTRegistered {
-		log.Debug().Msg("Registering agent with MQTT.")
-		if err := mqtthass.Register(o, c); err != nil {
-			log.Error().Err(err).Msg("Failed to register app!")
-			return
-		} else {
-			preferences.Save(preferences.MQTTRegistered(true))
-		}
+		preferences.Save(preferences.MQTTRegistered(true))
 	}
 	if err := mqtthass.Subscribe(o, c); err != nil {
-		log.Error().Err(err).Msg("Could not activate subscriptions.")
+		return fmt.Errorf("could not activate MQTT subscriptions: %w", err)
 	}
 	log.Debug().Msg("Listening for events on MQTT.")
 
-	<-ctx.Done()
+	publishStates := func() {
+		if err := mqtthass.PublishState(o, c); err != nil {
+			log.Warn().Err(err).Msg("Could not publish entity states.")
+		}
+	}
+	publishStates()
+	for _, watch := range o.watchers {
+		err := watch(ctx, func(e *mqtthass.EntityConfig) {
+			msg, err := mqtthass.MarshalState(e)
+			if err == nil {
+				err = c.Publish(msg)
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("entity", e.Entity.UniqueID).Msg("Could not publish entity state.")
+			}
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Could not watch for entity state changes.")
+		}
+	}
+	for _, camera := range o.cameras {
+		if camera.interval > 0 {
+			go publishCameraImages(ctx, camera, c)
+		}
+	}
+	ticker := time.NewTicker(mqttStateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			publishStates()
+		}
+	}
+}
+
+// publishCameraImages publishes an image from the camera at its interval,
+// until the context is canceled.
+func publishCameraImages(ctx context.Context, camera *mqttCamera, c *mqttClient) {
+	ticker := time.NewTicker(camera.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			msg, err := camera.image(ctx)
+			if err != nil {
+				log.Warn().Err(err).Str("camera", camera.UniqueID).Msg("Could not capture camera image.")
+				continue
+			}
+			if err := c.Publish(msg); err != nil {
+				log.Warn().Err(err).Str("camera", camera.UniqueID).Msg("Could not publish camera image.")
+			}
+		}
+	}
 }
 
 func resetMQTTWorker(ctx context.Context) {
@@ -183,14 +449,15 @@ func resetMQTTWorker(ctx context.Context) {
 		Prefs: &prefs,
 	}
 
-	c, err := mqttapi.NewMQTTClient(ctx, mqttprefs)
+	c, err := newMQTTClient(ctx, mqttprefs)
 	if err != nil {
 		log.Error().Err(err).Msg("Could not start MQTT client.")
 		return
 	}
+	defer c.Disconnect()
 
 	log.Info().Msgf("Clearing agent data from Home Assistant.")
-	d := newMQTTObject(ctx)
+	d := newMQTTObject(ctx, nil)
 
 	if prefs.MQTTRegistered {
 		if err := mqtthass.UnRegister(d, c); err != nil {
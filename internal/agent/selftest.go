@@ -0,0 +1,321 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/simulator"
+)
+
+const (
+	selfTestDevicePrefix = "go-hass-agent-selftest"
+	selfTestSensorID     = "selftest"
+	selfTestNotifyTitle  = "Go Hass Agent Self-Test"
+
+	// selfTestRetryInterval is how often a step waiting on Home Assistant
+	// (e.g., for the test sensor to appear) retries.
+	selfTestRetryInterval = time.Second
+)
+
+// ErrSelfTestFailed is returned when a self-test step failed.
+var ErrSelfTestFailed = errors.New("self-test failed")
+
+// SelfTestOptions holds the options for running a self-test.
+type SelfTestOptions struct {
+	// Timeout is the maximum time to wait for each step.
+	Timeout time.Duration
+	// Keep will leave the temporary device registered after the self-test.
+	Keep bool
+}
+
+// selfTest holds the state shared between the steps of a self-test.
+type selfTest struct {
+	device   *simulator.Device
+	prefs    *preferences.Preferences
+	notifyCh chan api.Notification
+	// stopWebsocket closes the websocket connection of the temporary
+	// device.
+	stopWebsocket context.CancelFunc
+	server        string
+	token         string
+	value         string
+}
+
+type selfTestStep struct {
+	run  func(ctx context.Context) error
+	name string
+}
+
+// SelfTest runs an end-to-end check of the agent against Home Assistant. It
+// registers a temporary device, sends a test sensor and verifies its state via
+// the REST API, sends a test notification and waits to receive it on the
+// websocket, then removes the temporary device. The result of each step is
+// written to w. It is intended for validating a setup or a test Home Assistant
+// instance, and needs a token for an administrator to remove the device.
+func (agent *Agent) SelfTest(w io.Writer, opts *SelfTestOptions) error {
+	if !validRegistrationSetting("server", agent.Options.Server) || !validRegistrationSetting("token", agent.Options.Token) {
+		return errors.New("cannot run self-test, invalid host and/or token")
+	}
+	if opts.Timeout <= 0 {
+		return errors.New("cannot run self-test, timeout must be greater than zero")
+	}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	agent.handleSignals()
+	go func() {
+		select {
+		case <-agent.done:
+			cancelFunc()
+		case <-ctx.Done():
+		}
+	}()
+
+	t := &selfTest{
+		server:   agent.Options.Server,
+		token:    agent.Options.Token,
+		notifyCh: make(chan api.Notification, 10),
+	}
+	defer func() {
+		if t.stopWebsocket != nil {
+			t.stopWebsocket()
+		}
+	}()
+	steps := []selfTestStep{
+		{name: "Connect to Home Assistant", run: t.checkServer},
+		{name: "Register temporary device", run: t.register},
+		{name: "Send test sensor", run: t.sendSensor},
+		{name: "Verify test sensor state", run: t.verifySensor},
+		{name: "Receive test notification", run: t.receiveNotification},
+	}
+
+	failed := false
+	for _, step := range steps {
+		if failed {
+			fmt.Fprintf(w, "SKIP %s\n", step.name)
+			continue
+		}
+		failed = runSelfTestStep(ctx, w, step, opts.Timeout) != nil
+	}
+	// Always clean up a registered device, even if a later step failed.
+	if t.prefs != nil && !opts.Keep {
+		// The device is still removed if the self-test was interrupted.
+		cleanupCtx := context.WithoutCancel(ctx)
+		if runSelfTestStep(cleanupCtx, w, selfTestStep{name: "Remove temporary device", run: t.removeDevice}, opts.Timeout) != nil {
+			fmt.Fprintf(w, "     Remove the %s device from the Mobile App integration in Home Assistant.\n",
+				t.device.DeviceName())
+			failed = true
+		}
+	}
+	if failed {
+		return ErrSelfTestFailed
+	}
+	return nil
+}
+
+// runSelfTestStep runs a single self-test step with the given timeout and
+// writes the result.
+func runSelfTestStep(ctx context.Context, w io.Writer, step selfTestStep, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := step.run(ctx)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		log.Debug().Err(err).Str("step", step.name).Msg("Self-test step failed.")
+		fmt.Fprintf(w, "FAIL %s (%s): %s\n", step.name, elapsed, err)
+		return err
+	}
+	fmt.Fprintf(w, "PASS %s (%s)\n", step.name, elapsed)
+	return nil
+}
+
+// restURL returns the URL of the given Home Assistant REST API path.
+func (t *selfTest) restURL(path ...string) string {
+	u, err := url.Parse(t.server)
+	if err != nil {
+		return ""
+	}
+	return u.JoinPath(path...).String()
+}
+
+// retry runs fn every selfTestRetryInterval until it succeeds or the context
+// is done, returning the last error from fn.
+func retry(ctx context.Context, fn func() error) error {
+	ticker := time.NewTicker(selfTestRetryInterval)
+	defer ticker.Stop()
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *selfTest) checkServer(ctx context.Context) error {
+	return api.CheckServer(ctx, t.server, t.token)
+}
+
+// register registers a temporary device and connects to the websocket as that
+// device, to receive the test notification.
+func (t *selfTest) register(ctx context.Context) error {
+	device, err := simulator.NewTemporaryDevice(selfTestDevicePrefix, preferences.AppName, preferences.AppVersion)
+	if err != nil {
+		return err
+	}
+	resp, err := api.RegisterWithHass(ctx, t.server, t.token, device)
+	if err != nil {
+		return err
+	}
+	t.device = device
+	t.prefs = &preferences.Preferences{
+		Host:         t.server,
+		Token:        t.token,
+		DeviceID:     device.DeviceID(),
+		DeviceName:   device.DeviceName(),
+		RestAPIURL:   generateAPIURL(t.server, resp),
+		WebsocketURL: generateWebsocketURL(t.server),
+		WebhookID:    resp.WebhookID,
+		Secret:       resp.Secret,
+		CloudhookURL: resp.CloudhookURL,
+		RemoteUIURL:  resp.RemoteUIURL,
+		Registered:   true,
+	}
+	// The websocket runs until the self-test is done, not just this step.
+	wsCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	t.stopWebsocket = cancel
+	go func() {
+		if err := api.RunWebsocket(wsCtx, t.prefs, t.notifyCh); err != nil {
+			log.Debug().Err(err).Msg("Self-test websocket closed.")
+		}
+	}()
+	return nil
+}
+
+// sendSensor registers a test sensor with a random state, so that it can be
+// found via the REST API.
+func (t *selfTest) sendSensor(ctx context.Context) error {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	t.value = hex.EncodeToString(b)
+	req := &sensor.SensorState{
+		SensorUpdateInfo: sensor.SensorUpdateInfo{
+			State:    t.value,
+			Icon:     "mdi:check-network",
+			Type:     sensor.TypeSensor.String(),
+			UniqueID: selfTestSensorID,
+		},
+		SensorRegistrationInfo: sensor.SensorRegistrationInfo{
+			Name: "Self-Test",
+		},
+	}
+	ctx = preferences.EmbedInContext(ctx, t.prefs)
+	if err, ok := (<-api.ExecuteRequest(ctx, req)).(error); ok {
+		return err
+	}
+	return nil
+}
+
+// verifySensor waits for the test sensor to have the state that was sent.
+func (t *selfTest) verifySensor(ctx context.Context) error {
+	return retry(ctx, func() error {
+		var states []struct {
+			State    any    `json:"state"`
+			EntityID string `json:"entity_id"`
+		}
+		err := requests.URL(t.restURL("/api/states")).
+			Bearer(t.token).
+			ToJSON(&states).
+			Fetch(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			if s.State == t.value {
+				log.Debug().Str("entity_id", s.EntityID).Msg("Found self-test sensor.")
+				return nil
+			}
+		}
+		return errors.New("test sensor not found")
+	})
+}
+
+// receiveNotification calls the notify service of the temporary device until
+// the notification is received on the websocket. The service is retried, as it
+// is only available once Home Assistant has set up the device and the
+// websocket has subscribed to notifications.
+func (t *selfTest) receiveNotification(ctx context.Context) error {
+	service := "mobile_app_" + strings.ReplaceAll(t.device.DeviceName(), "-", "_")
+	return retry(ctx, func() error {
+		err := requests.URL(t.restURL("/api/services/notify", service)).
+			Bearer(t.token).
+			BodyJSON(map[string]string{
+				"title":   selfTestNotifyTitle,
+				"message": "Notifications from Home Assistant are working.",
+			}).
+			Fetch(ctx)
+		if err != nil {
+			return err
+		}
+		select {
+		case n := <-t.notifyCh:
+			if n.Title == selfTestNotifyTitle {
+				return nil
+			}
+			return errors.New("unexpected notification received")
+		case <-time.After(selfTestRetryInterval):
+			return errors.New("notification not received")
+		}
+	})
+}
+
+// removeDevice deletes the Mobile App config entry of the temporary device,
+// which removes the device and its sensors from Home Assistant.
+func (t *selfTest) removeDevice(ctx context.Context) error {
+	var entries []struct {
+		EntryID string `json:"entry_id"`
+		Title   string `json:"title"`
+	}
+	err := requests.URL(t.restURL("/api/config/config_entries/entry")).
+		Param("domain", "mobile_app").
+		Bearer(t.token).
+		ToJSON(&entries).
+		Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Title != t.device.DeviceName() {
+			continue
+		}
+		return requests.URL(t.restURL("/api/config/config_entries/entry", e.EntryID)).
+			Bearer(t.token).
+			Delete().
+			Fetch(ctx)
+	}
+	return errors.New("temporary device not found")
+}
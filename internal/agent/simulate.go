@@ -0,0 +1,162 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
+	"github.com/joshuar/go-hass-agent/internal/paths"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/simulator"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const simRegistrationFile = "registration.json"
+
+// SimulateOptions holds the options for simulating virtual devices.
+type SimulateOptions struct {
+	Devices  int
+	Sensors  int
+	Interval time.Duration
+}
+
+// simRegistration is the registration details of a virtual device, stored so
+// that the device is only registered once with each server.
+type simRegistration struct {
+	Server   string                    `json:"server"`
+	Response *api.RegistrationResponse `json:"response"`
+}
+
+// Simulate registers the given number of virtual devices with Home Assistant
+// and sends updates for their simulated sensors until the agent is stopped.
+// It is intended for load-testing and developing against a test Home Assistant
+// instance, not for use with a production instance.
+func (agent *Agent) Simulate(opts *SimulateOptions) {
+	if !validRegistrationSetting("server", agent.Options.Server) || !validRegistrationSetting("token", agent.Options.Token) {
+		log.Fatal().Msg("Cannot simulate devices, invalid host and/or token.")
+	}
+	if opts.Devices < 1 || opts.Sensors < 1 || opts.Interval <= 0 {
+		log.Fatal().Msg("Cannot simulate devices, devices, sensors and interval must be greater than zero.")
+	}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	agent.handleSignals()
+	go func() {
+		<-agent.done
+		log.Debug().Msg("Simulator done.")
+		cancelFunc()
+	}()
+
+	var wg sync.WaitGroup
+	for n := 1; n <= opts.Devices; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if err := agent.simulateDevice(ctx, n, opts); err != nil {
+				log.Error().Err(err).Int("device", n).Msg("Could not simulate device.")
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// simulateDevice registers (if needed) the nth virtual device and sends its
+// sensor updates every interval.
+func (agent *Agent) simulateDevice(ctx context.Context, n int, opts *SimulateOptions) error {
+	device := simulator.NewDevice(n, preferences.AppName, preferences.AppVersion)
+	id := filepath.Join("simulator", device.DeviceName())
+
+	prefs, err := agent.simulatedRegistration(ctx, filepath.Join(paths.ConfigDir(), id), device)
+	if err != nil {
+		return err
+	}
+	trk, err := tracker.NewSensorTracker(paths.SimulatorRegistryDir(device.DeviceName()))
+	if err != nil {
+		return err
+	}
+	ctx = preferences.EmbedInContext(ctx, prefs)
+	sensors := simulator.NewSensors(opts.Sensors, int64(n))
+	log.Info().Str("device", device.DeviceName()).Int("sensors", len(sensors)).
+		Msg("Simulating device.")
+
+	// Spread updates of the devices over the interval.
+	delay := time.Duration(rand.Int63n(int64(opts.Interval)))
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(delay):
+	}
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		for _, s := range sensors {
+			s.Update(now)
+			trk.UpdateSensors(ctx, s)
+		}
+		select {
+		case <-ctx.Done():
+			log.Debug().Str("device", device.DeviceName()).Msg("Stopped simulating device.")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// simulatedRegistration returns the preferences needed to send requests for the
+// virtual device. If the device has not yet been registered with the server, it
+// is registered and the registration details are saved in the given path.
+func (agent *Agent) simulatedRegistration(ctx context.Context, path string, device *simulator.Device) (*preferences.Preferences, error) {
+	server := agent.Options.Server
+	file := filepath.Join(path, simRegistrationFile)
+
+	var reg simRegistration
+	if b, err := os.ReadFile(file); err == nil {
+		if err := json.Unmarshal(b, &reg); err != nil {
+			log.Warn().Err(err).Str("device", device.DeviceName()).Msg("Could not read registration, re-registering.")
+		}
+	}
+	if reg.Server != server || reg.Response == nil {
+		resp, err := api.RegisterWithHass(ctx, server, agent.Options.Token, device)
+		if err != nil {
+			return nil, errors.New("could not register with Home Assistant")
+		}
+		reg = simRegistration{Server: server, Response: resp}
+		b, err := json.Marshal(&reg)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(file, b, 0o600); err != nil {
+			return nil, err
+		}
+		log.Info().Str("device", device.DeviceName()).Msg("Registered simulated device.")
+	}
+	return &preferences.Preferences{
+		Host:         server,
+		Token:        agent.Options.Token,
+		DeviceID:     device.DeviceID(),
+		DeviceName:   device.DeviceName(),
+		RestAPIURL:   generateAPIURL(server, reg.Response),
+		WebsocketURL: generateWebsocketURL(server),
+		WebhookID:    reg.Response.WebhookID,
+		Secret:       reg.Response.Secret,
+		CloudhookURL: reg.Response.CloudhookURL,
+		RemoteUIURL:  reg.Response.RemoteUIURL,
+		Registered:   true,
+	}, nil
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/paths"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+var (
+	errUnknownSubsystem    = errors.New("unknown subsystem")
+	errSubsystemNotRunning = errors.New("subsystem not running")
+	errAgentNotStarted     = errors.New("agent not started")
+)
+
+// subsystem is a part of the agent that depends on some of the preferences.
+// When those preferences change, only the subsystem is restarted to apply
+// them, rather than the whole agent.
+type subsystem struct {
+	// uses returns the preferences used by the subsystem. The subsystem is
+	// restarted when any of these change.
+	uses func(prefs *preferences.Preferences) any
+	// enabled returns whether the subsystem should run with the given
+	// preferences. If nil, the subsystem always runs.
+	enabled func(prefs *preferences.Preferences) bool
+	// run runs the subsystem until the context is canceled. The preferences
+	// are embedded in the context.
+	run    func(ctx context.Context)
+	cancel context.CancelFunc
+	done   chan struct{}
+	name   string
+}
+
+func (s *subsystem) isEnabled(prefs *preferences.Preferences) bool {
+	return s.enabled == nil || s.enabled(prefs)
+}
+
+// subsystemManager starts and stops the subsystems of the agent as the
+// preferences change.
+type subsystemManager struct {
+	ctx        context.Context
+	prefs      *preferences.Preferences
+	subsystems []*subsystem
+	mu         sync.Mutex
+}
+
+func newSubsystemManager(ctx context.Context, prefs *preferences.Preferences, subsystems ...*subsystem) *subsystemManager {
+	return &subsystemManager{
+		ctx:        ctx,
+		prefs:      prefs,
+		subsystems: subsystems,
+	}
+}
+
+func (m *subsystemManager) start(s *subsystem) {
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(preferences.EmbedInContext(m.ctx, m.prefs))
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+}
+
+func (m *subsystemManager) stop(s *subsystem) {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+}
+
+// run starts the enabled subsystems and stops them all once the context is
+// canceled.
+func (m *subsystemManager) run() {
+	m.mu.Lock()
+	for _, s := range m.subsystems {
+		if s.isEnabled(m.prefs) {
+			m.start(s)
+		}
+	}
+	m.mu.Unlock()
+
+	<-m.ctx.Done()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.subsystems {
+		m.stop(s)
+	}
+}
+
+// apply restarts the subsystems whose preferences differ in the given
+// preferences, and starts or stops any subsystems that have been enabled or
+// disabled. It returns the names of the subsystems that were changed.
+func (m *subsystemManager) apply(prefs *preferences.Preferences) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx.Err() != nil {
+		return nil
+	}
+	previous := m.prefs
+	m.prefs = prefs
+
+	var changed []string
+	for _, s := range m.subsystems {
+		wasEnabled, isEnabled := s.isEnabled(previous), s.isEnabled(prefs)
+		if wasEnabled == isEnabled && (!isEnabled || reflect.DeepEqual(s.uses(previous), s.uses(prefs))) {
+			continue
+		}
+		log.Debug().Str("subsystem", s.name).Bool("enabled", isEnabled).Msg("Preferences changed, restarting subsystem.")
+		m.stop(s)
+		if isEnabled {
+			m.start(s)
+		}
+		changed = append(changed, s.name)
+	}
+	return changed
+}
+
+// restart restarts the running subsystem with the given name, so that it picks
+// up changes other than to the preferences (e.g., new scripts).
+func (m *subsystemManager) restart(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	for _, s := range m.subsystems {
+		if s.name != name {
+			continue
+		}
+		if s.cancel == nil {
+			return fmt.Errorf("%w: %s", errSubsystemNotRunning, name)
+		}
+		log.Debug().Str("subsystem", s.name).Msg("Restarting subsystem.")
+		m.stop(s)
+		m.start(s)
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errUnknownSubsystem, name)
+}
+
+// reloadScripts restarts the scripts subsystem, which finds the scripts again
+// and reschedules them, without restarting the agent.
+func (agent *Agent) reloadScripts() error {
+	agent.mu.Lock()
+	subsystems := agent.subsystems
+	agent.mu.Unlock()
+	if subsystems == nil {
+		return errAgentNotStarted
+	}
+	return subsystems.restart("scripts")
+}
+
+// newSubsystems creates the subsystems of the agent that can be restarted
+// when the preferences change.
+func (agent *Agent) newSubsystems(ctx context.Context, prefs *preferences.Preferences, trk SensorTracker) *subsystemManager {
+	return newSubsystemManager(ctx, prefs,
+		// Send logs to the journal and/or syslog, as configured.
+		&subsystem{
+			name: "logging",
+			uses: func(p *preferences.Preferences) any {
+				return [2]any{p.LogJournal, p.LogSyslog}
+			},
+			run: func(ctx context.Context) {
+				p := preferences.FetchFromContext(ctx)
+				if err := logging.SetSinks(preferences.AppName, p.LogJournal, p.LogSyslog); err != nil {
+					log.Warn().Err(err).Msg("Could not set up all log outputs.")
+				}
+				<-ctx.Done()
+			},
+		},
+		// Run the worker funcs for sensors.
+		&subsystem{
+			name: "sensors",
+			uses: func(p *preferences.Preferences) any {
+				return [7]any{p.ActiveWindow, p.AppTraffic, p.UPSServer, p.CPULimit, p.Features, p.UpdateChannel, p.BackupUnits}
+			},
+			run: func(ctx context.Context) {
+				runWorkers(ctx, trk, agent.notify)
+			},
+		},
+		// Run any scripts. Scripts do not use any preferences, but can be
+		// reloaded from Home Assistant (see reloadScripts).
+		&subsystem{
+			name: "scripts",
+			uses: func(_ *preferences.Preferences) any {
+				return nil
+			},
+			run: func(ctx context.Context) {
+				scriptPath := filepath.Join(paths.ConfigDir(), "scripts")
+				diagnostics.TrackWorker("scripts", func() {
+					runScripts(ctx, scriptPath, trk)
+				})
+			},
+		},
+		// Run the mqtt client.
+		&subsystem{
+			name: "mqtt",
+			uses: func(p *preferences.Preferences) any {
+				return [16]any{
+					p.MQTTServer, p.MQTTUser, p.MQTTPassword, p.Features, p.PowerActions, p.Commands, p.TTSModel,
+					p.Screenshots, p.ScreenshotSecs, p.Webcam, p.WebcamSecs, p.UserServices, p.Containers,
+					p.UpdateChannel, p.WakeHosts, p.Maintenance,
+				}
+			},
+			enabled: func(p *preferences.Preferences) bool {
+				return p.MQTTEnabled
+			},
+			run: func(ctx context.Context) {
+				superviseWorker(ctx, "mqtt", agent.runMQTTWorker)
+			},
+		},
+		// Send telemetry reports, only if the user has opted in and there is
+		// somewhere to send them.
+		&subsystem{
+			name: "telemetry",
+			uses: func(p *preferences.Preferences) any {
+				return p.TelemetryURL
+			},
+			enabled: func(p *preferences.Preferences) bool {
+				return (p.Telemetry || agent.Options.Telemetry) && p.TelemetryURL != ""
+			},
+			run: func(ctx context.Context) {
+				p := preferences.FetchFromContext(ctx)
+				diagnostics.TrackWorker("telemetry", func() {
+					agent.runTelemetryWorker(ctx, &p)
+				})
+			},
+		},
+		// Send a summary on the configured schedule.
+		&subsystem{
+			name: "summary",
+			uses: func(p *preferences.Preferences) any {
+				return [4]any{p.SummaryCron, p.SummaryTarget, p.MQTTEnabled, p.DeviceName}
+			},
+			enabled: func(p *preferences.Preferences) bool {
+				return p.SummaryCron != ""
+			},
+			run: func(ctx context.Context) {
+				diagnostics.TrackWorker("summary", func() {
+					agent.runSummaryWorker(ctx, trk)
+				})
+			},
+		},
+	)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// testSubsystem returns a subsystem that records the preferences it was
+// started with and whether it is running.
+func testSubsystem(name string, uses func(*preferences.Preferences) any, enabled func(*preferences.Preferences) bool) (*subsystem, func() (int, bool, string)) {
+	var mu sync.Mutex
+	var starts int
+	var running bool
+	var server string
+	s := &subsystem{
+		name:    name,
+		uses:    uses,
+		enabled: enabled,
+		run: func(ctx context.Context) {
+			mu.Lock()
+			starts++
+			running = true
+			server = preferences.FetchFromContext(ctx).MQTTServer
+			mu.Unlock()
+			<-ctx.Done()
+			mu.Lock()
+			running = false
+			mu.Unlock()
+		},
+	}
+	return s, func() (int, bool, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		return starts, running, server
+	}
+}
+
+func Test_subsystemManager_apply(t *testing.T) {
+	mqtt, mqttState := testSubsystem("mqtt",
+		func(p *preferences.Preferences) any { return p.MQTTServer },
+		func(p *preferences.Preferences) bool { return p.MQTTEnabled })
+	sensors, sensorsState := testSubsystem("sensors",
+		func(p *preferences.Preferences) any { return p.ActiveWindow },
+		nil)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	m := newSubsystemManager(ctx, &preferences.Preferences{}, mqtt, sensors)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.run()
+	}()
+	waitFor := func(state func() (int, bool, string), starts int, running bool) {
+		t.Helper()
+		assert.Eventually(t, func() bool {
+			s, r, _ := state()
+			return s == starts && r == running
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	// Disabled subsystems are not started.
+	waitFor(sensorsState, 1, true)
+	waitFor(mqttState, 0, false)
+
+	// Enabling a subsystem starts only that subsystem.
+	changed := m.apply(&preferences.Preferences{MQTTEnabled: true, MQTTServer: "tcp://one:1883"})
+	assert.Equal(t, []string{"mqtt"}, changed)
+	waitFor(mqttState, 1, true)
+	waitFor(sensorsState, 1, true)
+
+	// Changing the preferences of a subsystem restarts it with the new
+	// preferences.
+	changed = m.apply(&preferences.Preferences{MQTTEnabled: true, MQTTServer: "tcp://two:1883"})
+	assert.Equal(t, []string{"mqtt"}, changed)
+	waitFor(mqttState, 2, true)
+	_, _, server := mqttState()
+	assert.Equal(t, "tcp://two:1883", server)
+
+	// Unchanged preferences do not restart anything.
+	changed = m.apply(&preferences.Preferences{MQTTEnabled: true, MQTTServer: "tcp://two:1883"})
+	assert.Empty(t, changed)
+
+	// Disabling a subsystem stops it.
+	changed = m.apply(&preferences.Preferences{ActiveWindow: true})
+	assert.ElementsMatch(t, []string{"mqtt", "sensors"}, changed)
+	waitFor(mqttState, 2, false)
+	waitFor(sensorsState, 2, true)
+
+	// All subsystems are stopped with the context.
+	cancelFunc()
+	<-done
+	waitFor(sensorsState, 2, false)
+	assert.Empty(t, m.apply(&preferences.Preferences{}))
+}
+
+func Test_subsystemManager_restart(t *testing.T) {
+	mqtt, mqttState := testSubsystem("mqtt",
+		func(p *preferences.Preferences) any { return p.MQTTServer },
+		func(p *preferences.Preferences) bool { return p.MQTTEnabled })
+	scripts, scriptsState := testSubsystem("scripts",
+		func(_ *preferences.Preferences) any { return nil },
+		nil)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	m := newSubsystemManager(ctx, &preferences.Preferences{}, mqtt, scripts)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.run()
+	}()
+	waitFor := func(state func() (int, bool, string), starts int, running bool) {
+		t.Helper()
+		assert.Eventually(t, func() bool {
+			s, r, _ := state()
+			return s == starts && r == running
+		}, time.Second, 10*time.Millisecond)
+	}
+	waitFor(scriptsState, 1, true)
+
+	// Restarting a running subsystem only restarts that subsystem.
+	assert.NoError(t, m.restart("scripts"))
+	waitFor(scriptsState, 2, true)
+	waitFor(mqttState, 0, false)
+
+	// Disabled and unknown subsystems cannot be restarted.
+	assert.ErrorIs(t, m.restart("mqtt"), errSubsystemNotRunning)
+	assert.ErrorIs(t, m.restart("unknown"), errUnknownSubsystem)
+
+	// Nothing is restarted once the agent is stopping.
+	cancelFunc()
+	<-done
+	assert.Error(t, m.restart("scripts"))
+	waitFor(scriptsState, 2, false)
+}
+
+func TestTelemetrySubsystem(t *testing.T) {
+	agent := &Agent{Options: &Options{}}
+	m := agent.newSubsystems(context.TODO(), &preferences.Preferences{}, nil)
+	var telemetry *subsystem
+	for _, s := range m.subsystems {
+		if s.name == "telemetry" {
+			telemetry = s
+		}
+	}
+	if !assert.NotNil(t, telemetry) {
+		return
+	}
+	assert.False(t, telemetry.isEnabled(&preferences.Preferences{}))
+	// Enabling telemetry without a URL to send reports to does nothing.
+	assert.False(t, telemetry.isEnabled(&preferences.Preferences{Telemetry: true}))
+	assert.True(t, telemetry.isEnabled(&preferences.Preferences{Telemetry: true, TelemetryURL: "https://example.com/report"}))
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+const (
+	// workerFailureWindow is the period over which worker failures are
+	// counted. A worker that runs for longer than this is considered healthy.
+	workerFailureWindow = 10 * time.Minute
+	// workerFailureLimit is the number of failures within workerFailureWindow
+	// after which a worker is considered to be crash-looping.
+	workerFailureLimit = 3
+
+	workerInitialBackoff = 5 * time.Second
+	workerMaxBackoff     = 5 * time.Minute
+)
+
+// workerSupervisor restarts a worker whenever it fails, waiting an escalating
+// amount of time between each restart. If the worker fails repeatedly, it is
+// marked as failing and the problem callback is run, so that the problem can
+// be surfaced to the user rather than the worker silently not running.
+type workerSupervisor struct {
+	name     string
+	worker   func(context.Context) error
+	problem  func(ctx context.Context, name string, err error)
+	resolved func(ctx context.Context, name string)
+	failures []time.Time
+	failing  bool
+}
+
+// recordFailure records a worker failure at the given time and returns whether
+// the worker has now failed too many times within the failure window.
+func (s *workerSupervisor) recordFailure(t time.Time) bool {
+	recent := s.failures[:0]
+	for _, f := range s.failures {
+		if t.Sub(f) < workerFailureWindow {
+			recent = append(recent, f)
+		}
+	}
+	s.failures = append(recent, t)
+	return len(s.failures) >= workerFailureLimit
+}
+
+func (s *workerSupervisor) newBackoff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = workerInitialBackoff
+	b.MaxInterval = workerMaxBackoff
+	b.MaxElapsedTime = 0
+	return b
+}
+
+// recovered marks the worker as healthy, once it has run for longer than the
+// failure window. The backoff starts over and, if the worker had been marked
+// as failing, the resolved callback is run.
+func (s *workerSupervisor) recovered(ctx context.Context, b backoff.BackOff) {
+	b.Reset()
+	s.failures = nil
+	if !s.failing {
+		return
+	}
+	s.failing = false
+	diagnostics.SetWorkerState(s.name, diagnostics.WorkerRunning)
+	log.Info().Str("worker", s.name).Msg("Worker has recovered.")
+	if s.resolved != nil {
+		s.resolved(ctx, s.name)
+	}
+}
+
+// run runs the worker until the context is canceled.
+func (s *workerSupervisor) run(ctx context.Context) {
+	b := s.newBackoff()
+	for {
+		diagnostics.SetWorkerState(s.name, diagnostics.WorkerRunning)
+		if s.failing {
+			diagnostics.SetWorkerState(s.name, diagnostics.WorkerFailing)
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- s.worker(ctx)
+		}()
+		// Most workers only return on failure, so a worker is marked as
+		// healthy once it has been running for long enough.
+		healthy := time.NewTimer(workerFailureWindow)
+		var err error
+	running:
+		for {
+			select {
+			case err = <-done:
+				break running
+			case <-healthy.C:
+				s.recovered(ctx, b)
+			}
+		}
+		healthy.Stop()
+		if ctx.Err() != nil {
+			diagnostics.SetWorkerState(s.name, diagnostics.WorkerStopped)
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("%s worker stopped unexpectedly", s.name)
+		}
+		if s.recordFailure(time.Now()) && !s.failing {
+			s.failing = true
+			diagnostics.SetWorkerState(s.name, diagnostics.WorkerFailing)
+			log.Error().Err(err).Str("worker", s.name).
+				Msgf("Worker has failed %d times in %s. Will keep retrying.", len(s.failures), workerFailureWindow)
+			if s.problem != nil {
+				s.problem(ctx, s.name, err)
+			}
+		}
+		wait := b.NextBackOff()
+		log.Warn().Err(err).Str("worker", s.name).Dur("retry_in", wait).Msg("Worker failed, restarting.")
+		select {
+		case <-ctx.Done():
+			diagnostics.SetWorkerState(s.name, diagnostics.WorkerStopped)
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// superviseWorker runs the given worker under a workerSupervisor until the
+// context is canceled. If the worker is crash-looping, a persistent
+// notification is shown in Home Assistant, which is dismissed once the worker
+// recovers.
+func superviseWorker(ctx context.Context, name string, worker func(context.Context) error) {
+	s := &workerSupervisor{
+		name:     name,
+		worker:   worker,
+		problem:  notifyWorkerProblem,
+		resolved: dismissWorkerProblem,
+	}
+	s.run(ctx)
+}
+
+func workerProblemID(name string) string {
+	return "go_hass_agent_" + name + "_problem"
+}
+
+func notifyWorkerProblem(ctx context.Context, name string, err error) {
+	prefs := preferences.FetchFromContext(ctx)
+	n := hass.NewPersistentNotification(workerProblemID(name),
+		"Go Hass Agent problem",
+		fmt.Sprintf("The %s worker on %s keeps failing (%s). The agent will keep retrying. Check the agent logs for details.",
+			name, prefs.DeviceName, err.Error()))
+	if err, ok := (<-api.ExecuteRequest(ctx, n)).(error); ok {
+		log.Debug().Err(err).Str("worker", name).Msg("Could not send worker problem notification.")
+	}
+}
+
+func dismissWorkerProblem(ctx context.Context, name string) {
+	n := hass.NewDismissPersistentNotification(workerProblemID(name))
+	if err, ok := (<-api.ExecuteRequest(ctx, n)).(error); ok {
+		log.Debug().Err(err).Str("worker", name).Msg("Could not dismiss worker problem notification.")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_workerSupervisor_recordFailure(t *testing.T) {
+	s := &workerSupervisor{name: "test"}
+	start := time.Now()
+	assert.False(t, s.recordFailure(start))
+	assert.False(t, s.recordFailure(start.Add(time.Minute)))
+	assert.True(t, s.recordFailure(start.Add(2*time.Minute)))
+
+	s = &workerSupervisor{name: "test"}
+	assert.False(t, s.recordFailure(start))
+	assert.False(t, s.recordFailure(start.Add(workerFailureWindow)))
+	assert.False(t, s.recordFailure(start.Add(2*workerFailureWindow)))
+	assert.Len(t, s.failures, 1)
+}
+
+func Test_workerSupervisor_recovered(t *testing.T) {
+	var resolved []string
+	s := &workerSupervisor{
+		name: "test",
+		resolved: func(_ context.Context, name string) {
+			resolved = append(resolved, name)
+		},
+	}
+	b := s.newBackoff()
+	s.recordFailure(time.Now())
+	s.recovered(context.TODO(), b)
+	assert.Empty(t, s.failures)
+	assert.Empty(t, resolved)
+
+	s.failing = true
+	s.recovered(context.TODO(), b)
+	assert.False(t, s.failing)
+	assert.Equal(t, []string{"test"}, resolved)
+}
@@ -10,7 +10,10 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -27,23 +30,39 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/joshuar/go-hass-agent/internal/agent/ui"
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
 	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/simulator"
 	"github.com/joshuar/go-hass-agent/internal/translations"
+	"github.com/joshuar/go-hass-agent/internal/update"
 )
 
+// startupDelays are the available startup delays, in seconds.
+var startupDelays = []string{"0", "10", "30", "60", "120"}
+
 const (
 	explainRegistration = `To register the agent, please enter the relevant details for your Home Assistant
 server (if not auto-detected) and long-lived access token.`
-	restartNote           = `Please restart the agent to use changed preferences.`
 	errMsgInvalidURL      = `You need to specify a valid http(s)://host:port.`
 	errMsgInvalidURI      = `You need to specify a valid scheme://host:port.`
 	errMsgInvalidHostPort = `You need to specify a valid host:port combination.`
+	errMsgInvalidSyslog   = `You need to specify a valid udp://, tcp:// or unix:// address.`
 )
 
 type fyneUI struct {
-	app  fyne.App
-	text *translations.Translator
+	app         fyne.App
+	text        *translations.Translator
+	agent       ui.Agent
+	trk         ui.SensorTracker
+	windows     map[fyne.Window]func() fyne.Window
+	windowIDs   map[fyne.Window]string
+	windowScale float64
+	// connectivity is the last known connectivity to Home Assistant, shown
+	// in the tray.
+	connectivity diagnostics.Connectivity
+	mu           sync.Mutex
 }
 
 func (i *fyneUI) Run(doneCh chan struct{}) {
@@ -77,8 +96,19 @@ func (i *fyneUI) Translate(text string) string {
 
 func NewFyneUI(id string) *fyneUI {
 	i := &fyneUI{
-		app:  app.NewWithID(id),
-		text: translations.NewTranslator(),
+		app:       app.NewWithID(id),
+		text:      translations.NewTranslator(),
+		windows:   make(map[fyne.Window]func() fyne.Window),
+		windowIDs: make(map[fyne.Window]string),
+	}
+	if prefs, err := preferences.Load(); err == nil {
+		if prefs.Language != "" {
+			i.text.SetLanguage(prefs.Language)
+		}
+		if prefs.FontScale != 0 {
+			i.app.Settings().SetTheme(newScaledTheme(i.app.Settings().Theme(), prefs.FontScale))
+		}
+		i.windowScale = prefs.WindowScale
 	}
 	i.app.SetIcon(&ui.TrayIcon{})
 	return i
@@ -87,39 +117,225 @@ func NewFyneUI(id string) *fyneUI {
 // DisplayTrayIcon displays an icon in the desktop tray with a menu for
 // controlling the agent and showing other informational windows.
 func (i *fyneUI) DisplayTrayIcon(agent ui.Agent, trk ui.SensorTracker) {
+	i.agent = agent
+	i.trk = trk
 	if desk, ok := i.app.(desktop.App); ok {
-		// About menu item.
-		menuItemAbout := fyne.NewMenuItem(i.Translate("About"),
-			func() {
-				i.aboutWindow().Show()
-			})
-		// Sensors menu item.
-		menuItemSensors := fyne.NewMenuItem(i.Translate("Sensors"),
-			func() {
-				i.sensorsWindow(trk).Show()
-			})
-
-		// Settings menu and submenu items.
-		settingsMenu := fyne.NewMenuItem(i.Translate("Preferences"), nil)
-		settingsMenu.ChildMenu = fyne.NewMenu("",
-			fyne.NewMenuItem(i.Translate("App"),
-				func() { i.agentSettingsWindow().Show() }),
-			fyne.NewMenuItem(i.text.Translate("Fyne"),
-				func() { i.fyneSettingsWindow().Show() }),
-		)
-		// Quit menu item.
-		menuItemQuit := fyne.NewMenuItem(i.Translate("Quit"), func() {
-			log.Debug().Msg("User requested stop agent.")
-			agent.Stop()
+		desk.SetSystemTrayMenu(i.trayMenu())
+		go i.watchConnectivity(desk)
+	}
+}
+
+// watchConnectivity updates the tray icon and menu whenever the connectivity
+// to Home Assistant changes, so the user can tell at a glance whether data is
+// being sent. The icon is greyed out while disconnected.
+func (i *fyneUI) watchConnectivity(desk desktop.App) {
+	for c := range diagnostics.WatchConnectivity(context.Background()) {
+		if !c.Known() {
+			continue
+		}
+		i.mu.Lock()
+		changed := !i.connectivity.Known() || c.Connected() != i.connectivity.Connected()
+		i.connectivity = c
+		i.mu.Unlock()
+		if !changed {
+			continue
+		}
+		if c.Connected() {
+			desk.SetSystemTrayIcon(&ui.TrayIcon{})
+		} else {
+			desk.SetSystemTrayIcon(&ui.DisconnectedTrayIcon{})
+		}
+		desk.SetSystemTrayMenu(i.trayMenu())
+	}
+}
+
+// connectivityMenuItem returns a disabled menu item showing whether the agent
+// is connected to Home Assistant, or nil if this is not yet known.
+func (i *fyneUI) connectivityMenuItem() *fyne.MenuItem {
+	i.mu.Lock()
+	c := i.connectivity
+	i.mu.Unlock()
+	if !c.Known() {
+		return nil
+	}
+	label := i.Translate("Connected to Home Assistant")
+	if !c.Connected() {
+		label = i.Translate("Not connected to Home Assistant")
+	}
+	item := fyne.NewMenuItem(label, nil)
+	item.Disabled = true
+	return item
+}
+
+// trayMenu creates the menu shown for the tray icon, in the current language.
+func (i *fyneUI) trayMenu() *fyne.Menu {
+	// About menu item.
+	menuItemAbout := fyne.NewMenuItem(i.Translate("About"),
+		func() { i.showWindow(i.aboutWindow) })
+	// Sensors menu item.
+	menuItemSensors := fyne.NewMenuItem(i.Translate("Sensors"),
+		func() {
+			i.showWindow(func() fyne.Window { return i.sensorsWindow(i.trk) })
 		})
-		menuItemQuit.IsQuit = true
 
-		menu := fyne.NewMenu("",
-			menuItemAbout,
-			menuItemSensors,
-			settingsMenu,
-			menuItemQuit)
-		desk.SetSystemTrayMenu(menu)
+	// Settings menu and submenu items.
+	settingsMenu := fyne.NewMenuItem(i.Translate("Preferences"), nil)
+	settingsMenu.ChildMenu = fyne.NewMenu("",
+		fyne.NewMenuItem(i.Translate("App"),
+			func() { i.showWindow(i.agentSettingsWindow) }),
+		fyne.NewMenuItem(i.text.Translate("Fyne"),
+			func() { i.showWindow(i.fyneSettingsWindow) }),
+	)
+	// Quit menu item.
+	menuItemQuit := fyne.NewMenuItem(i.Translate("Quit"), func() {
+		log.Debug().Msg("User requested stop agent.")
+		i.agent.Stop()
+	})
+	menuItemQuit.IsQuit = true
+
+	items := []*fyne.MenuItem{menuItemAbout, menuItemSensors, settingsMenu, menuItemQuit}
+	if dashboards := i.dashboardsMenuItem(); dashboards != nil {
+		items = append([]*fyne.MenuItem{dashboards}, items...)
+	}
+	if status := i.connectivityMenuItem(); status != nil {
+		items = append([]*fyne.MenuItem{status, fyne.NewMenuItemSeparator()}, items...)
+	}
+	return fyne.NewMenu("", items...)
+}
+
+// dashboardsMenuItem returns a menu item with a submenu of the Home Assistant
+// dashboards configured in the preferences, each opening in the default
+// browser, or nil if none are configured.
+func (i *fyneUI) dashboardsMenuItem() *fyne.MenuItem {
+	prefs, err := preferences.Load()
+	if err != nil || len(prefs.Dashboards) == 0 {
+		return nil
+	}
+	var items []*fyne.MenuItem
+	for _, d := range prefs.Dashboards {
+		dest := parseURL(dashboardURL(prefs.Host, d.URL))
+		if dest == nil {
+			continue
+		}
+		items = append(items, fyne.NewMenuItem(d.Name, func() {
+			if err := i.app.OpenURL(dest); err != nil {
+				log.Warn().Err(err).Str("url", dest.String()).Msg("Could not open dashboard.")
+			}
+		}))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	menu := fyne.NewMenuItem(i.Translate("Dashboards"), nil)
+	menu.ChildMenu = fyne.NewMenu("", items...)
+	return menu
+}
+
+// dashboardURL returns the full URL of a dashboard. Paths are relative to the
+// Home Assistant server.
+func dashboardURL(server, dashboard string) string {
+	if strings.HasPrefix(dashboard, "/") {
+		return strings.TrimSuffix(server, "/") + dashboard
+	}
+	return dashboard
+}
+
+// isDemo returns whether the agent is running in demo mode.
+func (i *fyneUI) isDemo() bool {
+	return i.agent != nil && i.agent.IsDemo()
+}
+
+// newWindow creates a new window with the given title. The window can be closed
+// with the Escape key. The id is used to remember the size of the window
+// between runs.
+func (i *fyneUI) newWindow(id, title string) fyne.Window {
+	w := i.app.NewWindow(title)
+	w.Canvas().SetOnTypedKey(func(k *fyne.KeyEvent) {
+		if k.Name == fyne.KeyEscape {
+			w.Close()
+		}
+	})
+	i.mu.Lock()
+	i.windowIDs[w] = id
+	i.mu.Unlock()
+	w.SetOnClosed(func() {
+		i.saveWindowSize(w)
+		i.mu.Lock()
+		delete(i.windows, w)
+		i.mu.Unlock()
+	})
+	return w
+}
+
+// focusFirst will give keyboard focus to the first focusable widget of the
+// given form items.
+func focusFirst(w fyne.Window, items []*widget.FormItem) {
+	for _, item := range items {
+		if f, ok := item.Widget.(fyne.Focusable); ok {
+			if d, ok := item.Widget.(fyne.Disableable); ok && d.Disabled() {
+				continue
+			}
+			w.Canvas().Focus(f)
+			return
+		}
+	}
+}
+
+// showWindow creates and shows a window using the given function. The function
+// is kept so that the window can be recreated if the language changes.
+func (i *fyneUI) showWindow(newWindow func() fyne.Window) {
+	w := newWindow()
+	if w == nil {
+		return
+	}
+	i.mu.Lock()
+	i.windows[w] = newWindow
+	i.mu.Unlock()
+	i.sizeWindow(w)
+	w.Show()
+}
+
+// setLanguage changes the language of the UI. The tray menu and any open
+// windows are recreated to show the new language.
+func (i *fyneUI) setLanguage(lang string) {
+	i.text.SetLanguage(lang)
+	if desk, ok := i.app.(desktop.App); ok && i.agent != nil {
+		desk.SetSystemTrayMenu(i.trayMenu())
+	}
+	open := make(map[fyne.Window]bool)
+	for _, w := range i.app.Driver().AllWindows() {
+		open[w] = true
+	}
+	i.mu.Lock()
+	windows := i.windows
+	i.windows = make(map[fyne.Window]func() fyne.Window)
+	i.mu.Unlock()
+	for w, newWindow := range windows {
+		if !open[w] {
+			continue
+		}
+		w.Close()
+		i.showWindow(newWindow)
+	}
+}
+
+// applyPreferences restarts the parts of the agent that use any changed
+// preferences, so that they take effect straight away. The result is shown in
+// the given window if showResult is true.
+func (i *fyneUI) applyPreferences(w fyne.Window, showResult bool) {
+	restarted, err := i.agent.ApplyPreferences()
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not apply preferences.")
+		if showResult {
+			dialog.ShowError(err, w)
+		}
+		return
+	}
+	if len(restarted) > 0 {
+		log.Info().Strs("restarted", restarted).Msg("Applied changed preferences.")
+	}
+	if showResult {
+		dialog.ShowInformation(i.Translate("Applied"), i.Translate("Preferences have been saved and applied."), w)
 	}
 }
 
@@ -127,7 +343,7 @@ func (i *fyneUI) DisplayTrayIcon(agent ui.Agent, trk ui.SensorTracker) {
 // complete registration. It will populate with any values that were already
 // provided via the command-line.
 func (i *fyneUI) DisplayRegistrationWindow(ctx context.Context, server, token *string, done chan struct{}) {
-	w := i.app.NewWindow(i.Translate("App Registration"))
+	w := i.newWindow("registration", i.Translate("App Registration"))
 
 	var allFormItems []*widget.FormItem
 
@@ -143,19 +359,29 @@ func (i *fyneUI) DisplayRegistrationWindow(ctx context.Context, server, token *s
 		w.Close()
 		ctx.Done()
 	}
+	registrationForm.SubmitText = i.Translate("Register")
+	registrationForm.CancelText = i.Translate("Cancel")
+	// Escape should cancel registration rather than just close the window.
+	w.Canvas().SetOnTypedKey(func(k *fyne.KeyEvent) {
+		if k.Name == fyne.KeyEscape {
+			registrationForm.OnCancel()
+		}
+	})
 
 	w.SetContent(container.New(layout.NewVBoxLayout(),
 		widget.NewLabel(i.Translate(explainRegistration)),
 		registrationForm,
 	))
 	log.Debug().Msg("Asking user for registration details.")
+	i.sizeWindow(w)
 	w.Show()
+	focusFirst(w, allFormItems)
 }
 
 // aboutWindow creates a window that will show some interesting information
 // about the agent, such as version numbers.
 func (i *fyneUI) aboutWindow() fyne.Window {
-	haCfg := getHAConfig()
+	haCfg := i.getHAConfig()
 	c := container.NewCenter(container.NewVBox(
 		widget.NewLabelWithStyle("Go Hass Agent "+preferences.AppVersion, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Home Assistant "+haCfg.Version, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
@@ -170,7 +396,7 @@ func (i *fyneUI) aboutWindow() fyne.Window {
 		),
 	))
 
-	w := i.app.NewWindow(i.Translate("About"))
+	w := i.newWindow("about", i.Translate("About"))
 	w.SetContent(c)
 	return w
 }
@@ -178,7 +404,7 @@ func (i *fyneUI) aboutWindow() fyne.Window {
 // fyneSettingsWindow creates a window that will show the Fyne settings for
 // controlling the look and feel of other windows.
 func (i *fyneUI) fyneSettingsWindow() fyne.Window {
-	w := i.app.NewWindow(i.Translate("Fyne Preferences"))
+	w := i.newWindow("fyne_settings", i.Translate("Fyne Preferences"))
 	w.SetContent(settings.NewSettings().LoadAppearanceScreen(w))
 	return w
 }
@@ -189,7 +415,7 @@ func (i *fyneUI) agentSettingsWindow() fyne.Window {
 	var allFormItems []*widget.FormItem
 
 	prefs, err := preferences.Load()
-	if err != nil {
+	if err != nil && !i.isDemo() {
 		log.Error().Err(err).Msg("Could not load preferences.")
 		return nil
 	}
@@ -203,32 +429,96 @@ func (i *fyneUI) agentSettingsWindow() fyne.Window {
 	}
 	allFormItems = append(allFormItems, i.mqttConfigItems(mqttPrefs)...)
 
-	w := i.app.NewWindow(i.Translate("App Preferences"))
+	// Telemetry settings
+	telemetryEnabled := prefs.Telemetry
+	allFormItems = append(allFormItems, i.telemetryConfigItems(&telemetryEnabled)...)
+
+	// Update settings
+	updateChannel := prefs.UpdateChannel
+	allFormItems = append(allFormItems, i.updateConfigItems(&updateChannel)...)
+
+	// Language settings
+	language := prefs.Language
+	allFormItems = append(allFormItems, i.languageConfigItems(&language)...)
+
+	// Accessibility settings
+	fontScale := prefs.FontScale
+	windowScale := prefs.WindowScale
+	allFormItems = append(allFormItems, i.accessibilityConfigItems(&fontScale, &windowScale)...)
+
+	// Startup settings
+	startupDelay := prefs.StartupDelay
+	waitForNetwork := prefs.WaitForNetwork
+	allFormItems = append(allFormItems, i.startupConfigItems(&startupDelay, &waitForNetwork)...)
+
+	// Logging settings
+	logJournal := prefs.LogJournal
+	logSyslog := prefs.LogSyslog
+	allFormItems = append(allFormItems, i.loggingConfigItems(&logJournal, &logSyslog)...)
+
+	// Opt-in sensor settings
+	activeWindowEnabled := prefs.ActiveWindow
+	allFormItems = append(allFormItems, i.activeWindowConfigItems(&activeWindowEnabled)...)
+	appTrafficEnabled := prefs.AppTraffic
+	allFormItems = append(allFormItems, i.appTrafficConfigItems(&appTrafficEnabled)...)
+
+	w := i.newWindow("settings", i.Translate("App Preferences"))
 	settingsForm := widget.NewForm(allFormItems...)
 	settingsForm.OnSubmit = func() {
-		err := preferences.Save(
-			preferences.MQTTEnabled(mqttPrefs.Enabled),
-			preferences.MQTTServer(mqttPrefs.Server),
-			preferences.MQTTUser(mqttPrefs.User),
-			preferences.MQTTPassword(mqttPrefs.Password),
-		)
-		if err != nil {
-			dialog.ShowError(err, w)
-			log.Warn().Err(err).Msg("Could not save MQTT preferences.")
+		// In demo mode, changes to the UI are applied but not saved.
+		if !i.isDemo() {
+			err := preferences.Save(
+				preferences.MQTTEnabled(mqttPrefs.Enabled),
+				preferences.MQTTServer(mqttPrefs.Server),
+				preferences.MQTTUser(mqttPrefs.User),
+				preferences.MQTTPassword(mqttPrefs.Password),
+				preferences.Telemetry(telemetryEnabled),
+				preferences.UpdateChannel(updateChannel),
+				preferences.ActiveWindow(activeWindowEnabled),
+				preferences.AppTraffic(appTrafficEnabled),
+				preferences.Language(language),
+				preferences.FontScale(fontScale),
+				preferences.WindowScale(windowScale),
+				preferences.StartupDelay(startupDelay),
+				preferences.WaitForNetwork(waitForNetwork),
+				preferences.LogJournal(logJournal),
+				preferences.LogSyslog(logSyslog),
+			)
+			if err != nil {
+				dialog.ShowError(err, w)
+				log.Warn().Err(err).Msg("Could not save preferences.")
+				return
+			}
+			// Restarting parts of the agent can take a while (e.g.,
+			// reconnecting to MQTT), so apply the preferences in the
+			// background. If the language changed, the settings window is
+			// about to be recreated, so don't show the result in it.
+			go i.applyPreferences(w, language == prefs.Language)
+		}
+		i.mu.Lock()
+		i.windowScale = windowScale
+		i.mu.Unlock()
+		if fontScale != prefs.FontScale {
+			i.app.Settings().SetTheme(newScaledTheme(i.app.Settings().Theme(), fontScale))
+		}
+		if language != prefs.Language {
+			log.Info().Str("language", language).Msg("Changed language.")
+			i.setLanguage(language)
+			return
+		}
+		if i.isDemo() {
+			dialog.ShowInformation(i.Translate("Demo Mode"), i.Translate("Preferences are not saved in demo mode."), w)
 			return
 		}
-		dialog.ShowInformation("Saved", "MQTT Preferences have been saved.", w)
-		log.Info().Msg("Saved MQTT preferences.")
+		log.Info().Msg("Saved preferences.")
 	}
 	settingsForm.OnCancel = func() {
 		w.Close()
-		log.Info().Msg("No MQTT preferences saved.")
+		log.Info().Msg("No preferences saved.")
 	}
-	settingsForm.SubmitText = i.Translate("Save")
-	w.SetContent(container.New(layout.NewVBoxLayout(),
-		widget.NewLabelWithStyle(i.Translate(restartNote), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
-		settingsForm,
-	))
+	settingsForm.SubmitText = i.Translate("Apply")
+	w.SetContent(settingsForm)
+	focusFirst(w, allFormItems)
 	return w
 }
 
@@ -282,10 +572,10 @@ func (i *fyneUI) sensorsWindow(t ui.SensorTracker) fyne.Window {
 			return
 		}
 		if id.Row == -1 && id.Col == 0 {
-			label.SetText("Sensor")
+			label.SetText(i.Translate("Sensor"))
 		}
 		if id.Row == -1 && id.Col == 1 {
-			label.SetText("Value")
+			label.SetText(i.Translate("Value"))
 		}
 	}
 	// TODO: this is clunky. better way would be use Fyne bindings to sensor values
@@ -307,20 +597,23 @@ func (i *fyneUI) sensorsWindow(t ui.SensorTracker) fyne.Window {
 			}
 		}
 	}()
-	w := i.app.NewWindow(i.Translate("Sensors"))
+	w := i.newWindow("sensors", i.Translate("Sensors"))
 	w.SetContent(sensorsTable)
 	w.Resize(fyne.NewSize(480, 640))
 	w.SetOnClosed(func() {
+		i.saveWindowSize(w)
 		close(doneCh)
 	})
+	// Focus the table so the sensors can be navigated with the arrow keys.
+	w.Canvas().Focus(sensorsTable)
 	return w
 }
 
 // registrationFields generates a list of form item widgets for selecting a
-// server to register the agent against.
+// server to register the agent against. Servers are added to the list as they
+// are discovered and the chosen server and token are checked in the
+// background, so that the form can be used straight away.
 func (i *fyneUI) registrationFields(ctx context.Context, server, token *string) []*widget.FormItem {
-	allServers := hass.FindServers(ctx)
-
 	if *token == "" {
 		*token = "ASecretLongLivedToken"
 	}
@@ -328,15 +621,30 @@ func (i *fyneUI) registrationFields(ctx context.Context, server, token *string)
 	tokenEntry.Validator = validation.NewRegexp("[A-Za-z0-9_\\.]+", "Invalid token format")
 
 	if *server == "" {
-		*server = allServers[0]
+		*server = hass.DefaultServer
 	}
 	serverEntry := configEntry(server, false)
 	serverEntry.Validator = httpValidator()
 	serverEntry.Disable()
 
-	autoServerSelect := widget.NewSelect(allServers, func(s string) {
+	autoServerSelect := widget.NewSelect([]string{hass.DefaultServer}, func(s string) {
 		serverEntry.SetText(s)
 	})
+	go func() {
+		for s := range hass.DiscoverServers(ctx) {
+			if !slices.Contains(autoServerSelect.Options, s) {
+				autoServerSelect.Options = append(autoServerSelect.Options, s)
+				autoServerSelect.Refresh()
+			}
+		}
+	}()
+
+	serverStatus := widget.NewLabel("")
+	serverStatus.Wrapping = fyne.TextWrapWord
+	checkServer := i.serverChecker(ctx, serverStatus)
+	serverEntry.OnChanged = func(s string) { checkServer(s, tokenEntry.Text) }
+	tokenEntry.OnChanged = func(t string) { checkServer(serverEntry.Text, t) }
+	checkServer(*server, *token)
 
 	manualServerEntry := serverEntry
 	manualServerSelect := widget.NewCheck("", func(b bool) {
@@ -352,14 +660,69 @@ func (i *fyneUI) registrationFields(ctx context.Context, server, token *string)
 
 	var items []*widget.FormItem
 
-	items = append(items, widget.NewFormItem(i.Translate("Token"), tokenEntry),
-		widget.NewFormItem(i.Translate("Auto-discovered Servers"), autoServerSelect),
-		widget.NewFormItem(i.Translate("Use Custom Server?"), manualServerSelect),
-		widget.NewFormItem(i.Translate("Manual Server Entry"), manualServerEntry))
+	tokenFormItem := widget.NewFormItem(i.Translate("Token"), tokenEntry)
+	tokenFormItem.HintText = i.Translate("A long-lived access token created in your Home Assistant profile.")
+	autoServerFormItem := widget.NewFormItem(i.Translate("Auto-discovered Servers"), autoServerSelect)
+	autoServerFormItem.HintText = i.Translate("Home Assistant servers found on your network.")
+	manualServerSelectFormItem := widget.NewFormItem(i.Translate("Use Custom Server?"), manualServerSelect)
+	manualServerSelectFormItem.HintText = i.Translate("Enter the server address manually.")
+	manualServerFormItem := widget.NewFormItem(i.Translate("Manual Server Entry"), manualServerEntry)
+	manualServerFormItem.HintText = i.Translate("For example, http://homeassistant.local:8123.")
+	serverStatusFormItem := widget.NewFormItem(i.Translate("Server Status"), serverStatus)
+
+	items = append(items, tokenFormItem,
+		autoServerFormItem,
+		manualServerSelectFormItem,
+		manualServerFormItem,
+		serverStatusFormItem)
 
 	return items
 }
 
+// serverCheckDelay is how long to wait after the server or token has changed
+// before checking them, so that a check is not made on every key press.
+const serverCheckDelay = 500 * time.Millisecond
+
+// serverChecker returns a function that checks whether a server can be reached
+// and accepts a token, displaying the result in the given label. Each call
+// cancels any check still in progress.
+func (i *fyneUI) serverChecker(ctx context.Context, status *widget.Label) func(server, token string) {
+	var mu sync.Mutex
+	cancel := context.CancelFunc(func() {})
+	return func(server, token string) {
+		mu.Lock()
+		defer mu.Unlock()
+		cancel()
+		if httpValidator()(server) != nil {
+			status.SetText("")
+			return
+		}
+		var checkCtx context.Context
+		checkCtx, cancel = context.WithCancel(ctx)
+		status.SetText(i.Translate("Checking server..."))
+		go func() {
+			select {
+			case <-checkCtx.Done():
+				return
+			case <-time.After(serverCheckDelay):
+			}
+			err := api.CheckServer(checkCtx, server, token)
+			if checkCtx.Err() != nil {
+				return
+			}
+			switch {
+			case err == nil:
+				status.SetText(i.Translate("Server found and token accepted."))
+			case errors.Is(err, api.ErrInvalidToken):
+				status.SetText(i.Translate("Server found but the token was not accepted."))
+			default:
+				log.Debug().Err(err).Str("server", server).Msg("Could not check server.")
+				status.SetText(i.Translate("Could not reach server."))
+			}
+		}()
+	}
+}
+
 // mqttConfigItems generates a list of for item widgets for configuring the
 // agent to use an MQTT for pub/sub functionality.
 func (i *fyneUI) mqttConfigItems(prefs *ui.MQTTPreferences) []*widget.FormItem {
@@ -405,6 +768,132 @@ func (i *fyneUI) mqttConfigItems(prefs *ui.MQTTPreferences) []*widget.FormItem {
 	return items
 }
 
+// telemetryConfigItems generates a form item widget for opting in to sending
+// anonymous telemetry.
+func (i *fyneUI) telemetryConfigItems(enabled *bool) []*widget.FormItem {
+	telemetryCheck := configCheck(enabled, func(b bool) {
+		*enabled = b
+	})
+	telemetryFormItem := widget.NewFormItem(i.Translate("Send Anonymous Usage Data?"), telemetryCheck)
+	telemetryFormItem.HintText = ui.TelemetryHelp
+	return []*widget.FormItem{telemetryFormItem}
+}
+
+// updateConfigItems generates a form item widget for choosing the release
+// channel to check for updates on.
+func (i *fyneUI) updateConfigItems(channel *string) []*widget.FormItem {
+	channelSelect := widget.NewSelect([]string{string(update.Stable), string(update.Beta)}, func(s string) {
+		*channel = s
+	})
+	channelSelect.SetSelected(string(update.ChannelFromString(*channel)))
+	channelFormItem := widget.NewFormItem(i.Translate("Update Channel"), channelSelect)
+	channelFormItem.HintText = i.Translate("Choose beta to also be told about prereleases.")
+	return []*widget.FormItem{channelFormItem}
+}
+
+// languageConfigItems generates a form item widget for selecting the language
+// of the UI.
+func (i *fyneUI) languageConfigItems(lang *string) []*widget.FormItem {
+	systemDefault := i.Translate("System Default")
+	options := []string{systemDefault, "en"}
+	options = append(options, translations.Languages...)
+	languageSelect := widget.NewSelect(options, func(s string) {
+		if s == systemDefault {
+			*lang = ""
+		} else {
+			*lang = s
+		}
+	})
+	if *lang == "" {
+		languageSelect.SetSelected(systemDefault)
+	} else {
+		languageSelect.SetSelected(*lang)
+	}
+	return []*widget.FormItem{widget.NewFormItem(i.Translate("Language"), languageSelect)}
+}
+
+// accessibilityConfigItems generates form item widgets for changing the
+// accessibility options of the UI.
+func (i *fyneUI) accessibilityConfigItems(fontScale, windowScale *float64) []*widget.FormItem {
+	fontScaleSelect := widget.NewSelect(scales, func(s string) {
+		*fontScale = scaleValue(s)
+	})
+	fontScaleSelect.SetSelected(scaleLabel(*fontScale))
+	fontScaleFormItem := widget.NewFormItem(i.Translate("Text and Control Size"), fontScaleSelect)
+	fontScaleFormItem.HintText = i.Translate("Increase the size of text and controls in the agent windows.")
+
+	windowScaleSelect := widget.NewSelect(scales, func(s string) {
+		*windowScale = scaleValue(s)
+	})
+	windowScaleSelect.SetSelected(scaleLabel(*windowScale))
+	windowScaleFormItem := widget.NewFormItem(i.Translate("Default Window Size"), windowScaleSelect)
+	windowScaleFormItem.HintText = i.Translate("Increase the size windows open at. Resized windows remember their size.")
+
+	return []*widget.FormItem{
+		widget.NewFormItem("", widget.NewLabelWithStyle(i.Translate("Accessibility"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})),
+		fontScaleFormItem,
+		windowScaleFormItem,
+	}
+}
+
+// startupConfigItems generates form item widgets for delaying the agent start
+// until the network is available.
+func (i *fyneUI) startupConfigItems(delay *int, waitForNetwork *bool) []*widget.FormItem {
+	delaySelect := widget.NewSelect(startupDelays, func(s string) {
+		*delay, _ = strconv.Atoi(s)
+	})
+	delaySelect.SetSelected(strconv.Itoa(*delay))
+	delayFormItem := widget.NewFormItem(i.Translate("Startup Delay (seconds)"), delaySelect)
+	delayFormItem.HintText = i.Translate("Wait before connecting to Home Assistant when the agent starts.")
+
+	networkCheck := configCheck(waitForNetwork, func(b bool) {
+		*waitForNetwork = b
+	})
+	networkFormItem := widget.NewFormItem(i.Translate("Wait for Network?"), networkCheck)
+	networkFormItem.HintText = ui.WaitForNetworkHelp
+
+	return []*widget.FormItem{delayFormItem, networkFormItem}
+}
+
+// loggingConfigItems generates form item widgets for sending logs to the
+// systemd journal or a syslog server.
+func (i *fyneUI) loggingConfigItems(journal *bool, syslogServer *string) []*widget.FormItem {
+	journalCheck := configCheck(journal, func(b bool) {
+		*journal = b
+	})
+	journalFormItem := widget.NewFormItem(i.Translate("Log to Journal?"), journalCheck)
+	journalFormItem.HintText = i.Translate("Send logs to the systemd journal as well as the log file.")
+
+	syslogEntry := configEntry(syslogServer, false)
+	syslogEntry.Validator = syslogValidator()
+	syslogFormItem := widget.NewFormItem(i.Translate("Syslog Server"), syslogEntry)
+	syslogFormItem.HintText = ui.LogSyslogHelp
+
+	return []*widget.FormItem{journalFormItem, syslogFormItem}
+}
+
+// activeWindowConfigItems generates a form item widget for opting in to the
+// active window sensor.
+func (i *fyneUI) activeWindowConfigItems(enabled *bool) []*widget.FormItem {
+	activeWindowCheck := configCheck(enabled, func(b bool) {
+		*enabled = b
+	})
+	activeWindowFormItem := widget.NewFormItem(i.Translate("Report Active Window?"), activeWindowCheck)
+	activeWindowFormItem.HintText = ui.ActiveWindowHelp
+	return []*widget.FormItem{activeWindowFormItem}
+}
+
+// appTrafficConfigItems generates a form item widget for opting in to the
+// app network traffic sensor.
+func (i *fyneUI) appTrafficConfigItems(enabled *bool) []*widget.FormItem {
+	appTrafficCheck := configCheck(enabled, func(b bool) {
+		*enabled = b
+	})
+	appTrafficFormItem := widget.NewFormItem(i.Translate("Report App Network Usage?"), appTrafficCheck)
+	appTrafficFormItem.HintText = ui.AppTrafficHelp
+	return []*widget.FormItem{appTrafficFormItem}
+}
+
 // configEntry creates a form entry widget that is tied to the given config
 // value of the given agent. When the value of the entry widget changes, the
 // corresponding config value will be updated.
@@ -474,8 +963,24 @@ func uriValidator() fyne.StringValidator {
 	}
 }
 
+// syslogValidator is a custom fyne validator that will validate a string is
+// either empty or a syslog server address with a supported protocol.
+func syslogValidator() fyne.StringValidator {
+	return func(text string) error {
+		if text == "" {
+			return nil
+		}
+		u, err := url.Parse(text)
+		if err != nil || !slices.Contains([]string{"udp", "tcp", "unix", "unixgram"}, u.Scheme) {
+			return errors.New(errMsgInvalidSyslog)
+		}
+		return nil
+	}
+}
+
 // hostPortValidator is a custom fyne validator that will validate a string is a
-// valid hostname:port combination.
+// valid host:port combination, where host can be a hostname or IPv4/IPv6
+// address.
 func hostPortValidator(msg string) fyne.StringValidator {
 	var errMsg error
 	if msg != "" {
@@ -484,14 +989,10 @@ func hostPortValidator(msg string) fyne.StringValidator {
 		errMsg = errors.New(errMsgInvalidHostPort)
 	}
 
-	v := validator.New()
 	return func(text string) error {
-		if v.Var(text, "hostname_port") != nil {
+		if !preferences.ValidHostPort(text) {
 			return errMsg
 		}
-		// if _, err := url.Parse(text); err != nil {
-		// 	return errors.New("string is invalid")
-		// }
 		return nil
 	}
 }
@@ -506,7 +1007,10 @@ func parseURL(u string) *url.URL {
 	return dest
 }
 
-func getHAConfig() *hass.Config {
+func (i *fyneUI) getHAConfig() *hass.Config {
+	if i.isDemo() {
+		return simulator.DemoHAConfig()
+	}
 	prefs, err := preferences.Load()
 	if err != nil {
 		log.Warn().Err(err).Msg("Could not load preferences.")
@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package fyneui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// scales are the available scales for text and windows in the UI, as a
+// percentage.
+var scales = []string{"100%", "125%", "150%", "200%"}
+
+// scaledTheme is a fyne.Theme that scales the text, icons and padding of
+// another theme. As padding is also scaled, this gives larger hit targets for
+// buttons and other widgets.
+type scaledTheme struct {
+	fyne.Theme
+	scale float32
+}
+
+func (t *scaledTheme) Color(n fyne.ThemeColorName, v fyne.ThemeVariant) color.Color {
+	return t.Theme.Color(n, v)
+}
+
+func (t *scaledTheme) Font(s fyne.TextStyle) fyne.Resource {
+	return t.Theme.Font(s)
+}
+
+func (t *scaledTheme) Icon(n fyne.ThemeIconName) fyne.Resource {
+	return t.Theme.Icon(n)
+}
+
+func (t *scaledTheme) Size(n fyne.ThemeSizeName) float32 {
+	return t.Theme.Size(n) * t.scale
+}
+
+// newScaledTheme returns the given theme scaled by the given factor. A scale
+// of 0 is treated as no scaling. If the given theme is already scaled, the
+// theme it scales is rescaled instead.
+func newScaledTheme(base fyne.Theme, scale float64) fyne.Theme {
+	if scale <= 0 {
+		scale = 1
+	}
+	if scaled, ok := base.(*scaledTheme); ok {
+		base = scaled.Theme
+	}
+	if base == nil {
+		base = theme.DefaultTheme()
+	}
+	return &scaledTheme{Theme: base, scale: float32(scale)}
+}
+
+// scaleLabel returns the label for the given scale factor.
+func scaleLabel(scale float64) string {
+	switch {
+	case scale >= 2:
+		return "200%"
+	case scale >= 1.5:
+		return "150%"
+	case scale >= 1.25:
+		return "125%"
+	default:
+		return "100%"
+	}
+}
+
+// scaleValue returns the scale factor for the given label.
+func scaleValue(label string) float64 {
+	switch label {
+	case "125%":
+		return 1.25
+	case "150%":
+		return 1.5
+	case "200%":
+		return 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package fyneui
+
+import (
+	"fyne.io/fyne/v2"
+)
+
+// windowSizeKey returns the key in the Fyne app preferences under which the
+// given dimension of the window with the given id is stored.
+func windowSizeKey(id, dimension string) string {
+	return "window." + id + "." + dimension
+}
+
+// saveWindowSize stores the current size of the window, so that it will open
+// at the same size next time. Sizes are in Fyne's scale-independent units, so
+// the window will keep its apparent size if the display scale changes.
+func (i *fyneUI) saveWindowSize(w fyne.Window) {
+	i.mu.Lock()
+	id, ok := i.windowIDs[w]
+	delete(i.windowIDs, w)
+	i.mu.Unlock()
+	size := w.Canvas().Size()
+	if !ok || size.IsZero() || i.isDemo() {
+		return
+	}
+	i.app.Preferences().SetFloat(windowSizeKey(id, "width"), float64(size.Width))
+	i.app.Preferences().SetFloat(windowSizeKey(id, "height"), float64(size.Height))
+}
+
+// sizeWindow resizes the window to the size it was when it was last closed.
+// If it has not been opened before, its default size (or the size of its
+// content if it has no default) is scaled by the preferred window scale.
+func (i *fyneUI) sizeWindow(w fyne.Window) {
+	i.mu.Lock()
+	id, ok := i.windowIDs[w]
+	scale := i.windowScale
+	i.mu.Unlock()
+	if !ok {
+		return
+	}
+	width := i.app.Preferences().Float(windowSizeKey(id, "width"))
+	height := i.app.Preferences().Float(windowSizeKey(id, "height"))
+	if width > 0 && height > 0 {
+		w.Resize(fyne.NewSize(float32(width), float32(height)))
+		return
+	}
+	if scale <= 0 {
+		scale = 1
+	}
+	size := w.Canvas().Size()
+	if w.Content() != nil {
+		size = size.Max(w.Content().MinSize())
+	}
+	w.Resize(fyne.NewSize(size.Width*float32(scale), size.Height*float32(scale)))
+}
@@ -17,6 +17,12 @@ var _ Agent = &AgentMock{}
 //
 //		// make and configure a mocked Agent
 //		mockedAgent := &AgentMock{
+//			ApplyPreferencesFunc: func() ([]string, error) {
+//				panic("mock out the ApplyPreferences method")
+//			},
+//			IsDemoFunc: func() bool {
+//				panic("mock out the IsDemo method")
+//			},
 //			StopFunc: func()  {
 //				panic("mock out the Stop method")
 //			},
@@ -27,16 +33,84 @@ var _ Agent = &AgentMock{}
 //
 //	}
 type AgentMock struct {
+	// ApplyPreferencesFunc mocks the ApplyPreferences method.
+	ApplyPreferencesFunc func() ([]string, error)
+
+	// IsDemoFunc mocks the IsDemo method.
+	IsDemoFunc func() bool
+
 	// StopFunc mocks the Stop method.
 	StopFunc func()
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// ApplyPreferences holds details about calls to the ApplyPreferences method.
+		ApplyPreferences []struct {
+		}
+		// IsDemo holds details about calls to the IsDemo method.
+		IsDemo []struct {
+		}
 		// Stop holds details about calls to the Stop method.
 		Stop []struct {
 		}
 	}
-	lockStop sync.RWMutex
+	lockApplyPreferences sync.RWMutex
+	lockIsDemo           sync.RWMutex
+	lockStop             sync.RWMutex
+}
+
+// ApplyPreferences calls ApplyPreferencesFunc.
+func (mock *AgentMock) ApplyPreferences() ([]string, error) {
+	if mock.ApplyPreferencesFunc == nil {
+		panic("AgentMock.ApplyPreferencesFunc: method is nil but Agent.ApplyPreferences was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockApplyPreferences.Lock()
+	mock.calls.ApplyPreferences = append(mock.calls.ApplyPreferences, callInfo)
+	mock.lockApplyPreferences.Unlock()
+	return mock.ApplyPreferencesFunc()
+}
+
+// ApplyPreferencesCalls gets all the calls that were made to ApplyPreferences.
+// Check the length with:
+//
+//	len(mockedAgent.ApplyPreferencesCalls())
+func (mock *AgentMock) ApplyPreferencesCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockApplyPreferences.RLock()
+	calls = mock.calls.ApplyPreferences
+	mock.lockApplyPreferences.RUnlock()
+	return calls
+}
+
+// IsDemo calls IsDemoFunc.
+func (mock *AgentMock) IsDemo() bool {
+	if mock.IsDemoFunc == nil {
+		panic("AgentMock.IsDemoFunc: method is nil but Agent.IsDemo was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockIsDemo.Lock()
+	mock.calls.IsDemo = append(mock.calls.IsDemo, callInfo)
+	mock.lockIsDemo.Unlock()
+	return mock.IsDemoFunc()
+}
+
+// IsDemoCalls gets all the calls that were made to IsDemo.
+// Check the length with:
+//
+//	len(mockedAgent.IsDemoCalls())
+func (mock *AgentMock) IsDemoCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockIsDemo.RLock()
+	calls = mock.calls.IsDemo
+	mock.lockIsDemo.RUnlock()
+	return calls
 }
 
 // Stop calls StopFunc.
@@ -6,7 +6,14 @@
 package ui
 
 import (
+	"bytes"
 	_ "embed"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+
+	"github.com/rs/zerolog/log"
 
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
@@ -14,6 +21,8 @@ import (
 //go:generate moq -out mock_Agent_test.go . Agent
 type Agent interface {
 	Stop()
+	IsDemo() bool
+	ApplyPreferences() ([]string, error)
 }
 
 //go:generate moq -out mock_SensorTracker_test.go . SensorTracker
@@ -47,6 +56,21 @@ var MQTTUserHelp string
 //go:embed assets/mqttPasswordHelp.txt
 var MQTTPasswordHelp string
 
+//go:embed assets/telemetryHelp.txt
+var TelemetryHelp string
+
+//go:embed assets/activeWindowHelp.txt
+var ActiveWindowHelp string
+
+//go:embed assets/appTrafficHelp.txt
+var AppTrafficHelp string
+
+//go:embed assets/waitForNetworkHelp.txt
+var WaitForNetworkHelp string
+
+//go:embed assets/logSyslogHelp.txt
+var LogSyslogHelp string
+
 //go:embed assets/logo-pretty.png
 var hassIcon []byte
 
@@ -60,3 +84,41 @@ func (i *TrayIcon) Name() string {
 func (i *TrayIcon) Content() []byte {
 	return hassIcon
 }
+
+// DisconnectedTrayIcon satisfies the fyne.Resource interface to represent the
+// tray icon when the agent cannot reach Home Assistant. It is a greyed-out
+// version of the tray icon.
+type DisconnectedTrayIcon struct{}
+
+func (i *DisconnectedTrayIcon) Name() string {
+	return "DisconnectedTrayIcon"
+}
+
+func (i *DisconnectedTrayIcon) Content() []byte {
+	return greyIcon()
+}
+
+// greyIcon converts the tray icon to greyscale, keeping its transparency. If
+// the icon cannot be converted, the original icon is returned.
+var greyIcon = sync.OnceValue(func() []byte {
+	src, err := png.Decode(bytes.NewReader(hassIcon))
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not decode tray icon.")
+		return hassIcon
+	}
+	bounds := src.Bounds()
+	grey := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			g := color.GrayModel.Convert(color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}).(color.Gray)
+			grey.SetNRGBA(x, y, color.NRGBA{R: g.Y, G: g.Y, B: g.Y, A: c.A / 2})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, grey); err != nil {
+		log.Debug().Err(err).Msg("Could not encode tray icon.")
+		return hassIcon
+	}
+	return buf.Bytes()
+})
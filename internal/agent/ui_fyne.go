@@ -0,0 +1,16 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !termux
+
+package agent
+
+import (
+	fyneui "github.com/joshuar/go-hass-agent/internal/agent/ui/fyneUI"
+)
+
+func newUI(id string) UI {
+	return fyneui.NewFyneUI(id)
+}
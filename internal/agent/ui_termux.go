@@ -0,0 +1,14 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build termux
+
+package agent
+
+// newUI returns nil as there is no graphical UI under Termux. The agent will
+// always run headless.
+func newUI(_ string) UI {
+	return nil
+}
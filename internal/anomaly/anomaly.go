@@ -0,0 +1,205 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package anomaly learns a rough baseline for each numeric sensor and detects
+// when a sensor value deviates wildly from it (e.g., a sudden disk fill or a
+// temperature spike), so that the agent can raise an alert independently of
+// any automations in Home Assistant.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/features"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	// Event is fired on the Home Assistant event bus when an anomaly is
+	// detected.
+	Event = "go_hass_agent_sensor_anomaly"
+
+	// DefaultThreshold is how many standard deviations from the baseline
+	// mean a value must be to be an anomaly.
+	DefaultThreshold = 4.0
+	// DefaultMinSamples is how many values of a sensor must be seen before
+	// its baseline is considered learned.
+	DefaultMinSamples = 30
+	// DefaultCooldown is the minimum time between anomalies for the same
+	// sensor.
+	DefaultCooldown = time.Hour
+
+	// smoothing is the weight given to each new value when updating the
+	// baseline, so that the baseline roughly follows the last 40 values.
+	smoothing = 0.05
+	// minRelativeDeviation stops sensors with a (nearly) constant value from
+	// raising anomalies on small changes, by treating the standard deviation
+	// as at least this fraction of the mean.
+	minRelativeDeviation = 0.05
+)
+
+// Feature enables anomaly detection.
+var Feature = features.New("anomaly_detection",
+	"Raise an event and notification when a numeric sensor deviates wildly from its learned baseline.",
+	features.Dev)
+
+// baseline is the exponentially weighted rolling mean and variance of the
+// values of a sensor.
+type baseline struct {
+	lastAnomaly time.Time
+	mean        float64
+	variance    float64
+	samples     int
+}
+
+func (b *baseline) add(value float64) {
+	b.samples++
+	if b.samples == 1 {
+		b.mean = value
+		return
+	}
+	diff := value - b.mean
+	incr := smoothing * diff
+	b.mean += incr
+	b.variance = (1 - smoothing) * (b.variance + diff*incr)
+}
+
+func (b *baseline) stddev() float64 {
+	return math.Max(math.Sqrt(b.variance), math.Abs(b.mean)*minRelativeDeviation)
+}
+
+// Anomaly is a sensor value that deviated wildly from the baseline.
+type Anomaly struct {
+	Time   time.Time
+	ID     string
+	Name   string
+	Units  string
+	Value  float64
+	Mean   float64
+	StdDev float64
+}
+
+// Message returns a short description of the anomaly, suitable for a
+// notification.
+func (a *Anomaly) Message() string {
+	units := ""
+	if a.Units != "" {
+		units = " " + a.Units
+	}
+	return fmt.Sprintf("%s is %.4g%s, usually around %.4g%s.", a.Name, a.Value, units, a.Mean, units)
+}
+
+// Event returns a Home Assistant event for the anomaly.
+func (a *Anomaly) Event(deviceName string) *hass.Event {
+	return &hass.Event{
+		EventType: Event,
+		EventData: map[string]any{
+			"device_name": deviceName,
+			"sensor_id":   a.ID,
+			"sensor_name": a.Name,
+			"value":       a.Value,
+			"mean":        a.Mean,
+			"stddev":      a.StdDev,
+			"units":       a.Units,
+		},
+	}
+}
+
+// Detector detects anomalies in sensor values. Detectors are safe for
+// concurrent use.
+type Detector struct {
+	baselines  map[string]*baseline
+	now        func() time.Time
+	Threshold  float64
+	MinSamples int
+	Cooldown   time.Duration
+	mu         sync.Mutex
+}
+
+// NewDetector creates a detector with the default threshold, minimum samples
+// and cooldown.
+func NewDetector() *Detector {
+	return &Detector{
+		baselines:  make(map[string]*baseline),
+		now:        time.Now,
+		Threshold:  DefaultThreshold,
+		MinSamples: DefaultMinSamples,
+		Cooldown:   DefaultCooldown,
+	}
+}
+
+// Observe adds the value of the sensor to its baseline and returns an anomaly
+// if the value deviates wildly from it. Only numeric sensors that measure a
+// value are considered; binary sensors and ever-increasing totals are ignored.
+func (d *Detector) Observe(s tracker.Sensor) *Anomaly {
+	if s.SensorType() == sensor.TypeBinary || s.StateClass() == sensor.StateTotalIncreasing {
+		return nil
+	}
+	value, ok := numericValue(s.State())
+	if !ok {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b, ok := d.baselines[s.ID()]
+	if !ok {
+		b = &baseline{}
+		d.baselines[s.ID()] = b
+	}
+	var anomaly *Anomaly
+	if b.samples >= d.MinSamples {
+		stddev := b.stddev()
+		now := d.now()
+		if stddev > 0 && math.Abs(value-b.mean) > d.Threshold*stddev && now.Sub(b.lastAnomaly) >= d.Cooldown {
+			b.lastAnomaly = now
+			anomaly = &Anomaly{
+				Time:   now,
+				ID:     s.ID(),
+				Name:   s.Name(),
+				Units:  s.Units(),
+				Value:  value,
+				Mean:   b.mean,
+				StdDev: stddev,
+			}
+		}
+	}
+	b.add(value)
+	return anomaly
+}
+
+// numericValue returns the sensor state as a float, if it is a number.
+func numericValue(state any) (float64, bool) {
+	var value float64
+	switch v := state.(type) {
+	case float64:
+		value = v
+	case float32:
+		value = float64(v)
+	case int:
+		value = float64(v)
+	case int32:
+		value = float64(v)
+	case int64:
+		value = float64(v)
+	case uint:
+		value = float64(v)
+	case uint32:
+		value = float64(v)
+	case uint64:
+		value = float64(v)
+	default:
+		return 0, false
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+type testSensor struct {
+	state      any
+	sensorType sensor.SensorType
+	stateClass sensor.SensorStateClass
+}
+
+func (s *testSensor) Name() string                          { return "Test Sensor" }
+func (s *testSensor) ID() string                            { return "test_sensor" }
+func (s *testSensor) Icon() string                          { return "" }
+func (s *testSensor) SensorType() sensor.SensorType         { return s.sensorType }
+func (s *testSensor) DeviceClass() sensor.SensorDeviceClass { return 0 }
+func (s *testSensor) StateClass() sensor.SensorStateClass   { return s.stateClass }
+func (s *testSensor) State() any                            { return s.state }
+func (s *testSensor) Units() string                         { return "°C" }
+func (s *testSensor) Category() string                      { return "" }
+func (s *testSensor) Attributes() any                       { return nil }
+
+// learn feeds the detector values alternating around 50, so that the baseline
+// has a mean of about 50 and a small standard deviation.
+func learn(d *Detector, n int) {
+	for i := 0; i < n; i++ {
+		value := 49.0
+		if i%2 == 0 {
+			value = 51.0
+		}
+		d.Observe(&testSensor{state: value})
+	}
+}
+
+func TestDetector_Observe(t *testing.T) {
+	t.Run("no anomaly while learning", func(t *testing.T) {
+		d := NewDetector()
+		learn(d, d.MinSamples-1)
+		assert.Nil(t, d.Observe(&testSensor{state: 500.0}))
+	})
+	t.Run("no anomaly within baseline", func(t *testing.T) {
+		d := NewDetector()
+		learn(d, 100)
+		assert.Nil(t, d.Observe(&testSensor{state: 52.0}))
+	})
+	t.Run("anomaly on spike", func(t *testing.T) {
+		d := NewDetector()
+		learn(d, 100)
+		a := d.Observe(&testSensor{state: 90})
+		require.NotNil(t, a)
+		assert.Equal(t, "test_sensor", a.ID)
+		assert.InDelta(t, 90, a.Value, 0.001)
+		assert.InDelta(t, 50, a.Mean, 1)
+		assert.Contains(t, a.Message(), "Test Sensor is 90 °C, usually around")
+	})
+	t.Run("cooldown", func(t *testing.T) {
+		d := NewDetector()
+		now := time.Now()
+		d.now = func() time.Time { return now }
+		learn(d, 100)
+		require.NotNil(t, d.Observe(&testSensor{state: 10.0}))
+		learn(d, 300)
+		assert.Nil(t, d.Observe(&testSensor{state: 10.0}))
+		now = now.Add(DefaultCooldown)
+		learn(d, 300)
+		assert.NotNil(t, d.Observe(&testSensor{state: 10.0}))
+	})
+	t.Run("constant sensor", func(t *testing.T) {
+		d := NewDetector()
+		for i := 0; i < 100; i++ {
+			d.Observe(&testSensor{state: 40})
+		}
+		assert.Nil(t, d.Observe(&testSensor{state: 41}))
+		assert.NotNil(t, d.Observe(&testSensor{state: 80}))
+	})
+	t.Run("ignored sensors", func(t *testing.T) {
+		d := NewDetector()
+		d.MinSamples = 0
+		assert.Nil(t, d.Observe(&testSensor{state: true, sensorType: sensor.TypeBinary}))
+		assert.Nil(t, d.Observe(&testSensor{state: "on"}))
+		for i := 0; i < 100; i++ {
+			d.Observe(&testSensor{state: 1.0, stateClass: sensor.StateTotalIncreasing})
+		}
+		assert.Nil(t, d.Observe(&testSensor{state: 1000.0, stateClass: sensor.StateTotalIncreasing}))
+	})
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package device
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+// connectivitySensor reports whether the agent can talk to Home Assistant.
+type connectivitySensor struct {
+	diagnostics.Connectivity
+}
+
+func (s *connectivitySensor) Name() string { return "Home Assistant Connected" }
+
+func (s *connectivitySensor) ID() string { return "home_assistant_connected" }
+
+func (s *connectivitySensor) Icon() string {
+	if s.Connected() {
+		return "mdi:lan-connect"
+	}
+	return "mdi:lan-disconnect"
+}
+
+func (s *connectivitySensor) SensorType() sensor.SensorType {
+	return sensor.TypeBinary
+}
+
+func (s *connectivitySensor) DeviceClass() sensor.SensorDeviceClass { return 0 }
+
+func (s *connectivitySensor) StateClass() sensor.SensorStateClass { return 0 }
+
+func (s *connectivitySensor) State() interface{} { return s.Connected() }
+
+func (s *connectivitySensor) Units() string { return "" }
+
+func (s *connectivitySensor) Category() string {
+	return "diagnostic"
+}
+
+func (s *connectivitySensor) Attributes() interface{} {
+	var lastSuccess string
+	if !s.LastSuccess.IsZero() {
+		lastSuccess = s.LastSuccess.Format(time.RFC3339)
+	}
+	return &struct {
+		LastSuccess string `json:"Last Success,omitempty"`
+		LastError   string `json:"Last Error,omitempty"`
+		Webhook     bool   `json:"Webhook"`
+		Websocket   bool   `json:"Websocket"`
+	}{
+		LastSuccess: lastSuccess,
+		LastError:   s.LastError,
+		Webhook:     s.Webhook,
+		Websocket:   s.Websocket,
+	}
+}
+
+// ConnectivityUpdater reports whether the last webhook request to Home
+// Assistant succeeded and the websocket connection is established, updating
+// whenever either changes. While disconnected, the update is queued and sent
+// once Home Assistant can be reached again, so the sensor is mostly useful as
+// a record of when the agent lost contact.
+func ConnectivityUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	go func() {
+		defer close(sensorCh)
+		for c := range diagnostics.WatchConnectivity(ctx) {
+			if !c.Known() {
+				continue
+			}
+			select {
+			case sensorCh <- &connectivitySensor{Connectivity: c}:
+			case <-ctx.Done():
+			}
+		}
+		log.Debug().Msg("Stopped connectivity sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package device
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const healthPollInterval = time.Minute
+
+// agentHealthSensor is a diagnostic sensor reporting on the health of the
+// agent itself.
+type agentHealthSensor struct {
+	value       any
+	attributes  any
+	name        string
+	icon        string
+	units       string
+	deviceClass sensor.SensorDeviceClass
+	stateClass  sensor.SensorStateClass
+}
+
+func (s *agentHealthSensor) Name() string { return s.name }
+
+func (s *agentHealthSensor) ID() string { return strcase.ToSnake(s.name) }
+
+func (s *agentHealthSensor) Icon() string { return s.icon }
+
+func (s *agentHealthSensor) SensorType() sensor.SensorType {
+	return sensor.TypeSensor
+}
+
+func (s *agentHealthSensor) DeviceClass() sensor.SensorDeviceClass {
+	return s.deviceClass
+}
+
+func (s *agentHealthSensor) StateClass() sensor.SensorStateClass {
+	return s.stateClass
+}
+
+func (s *agentHealthSensor) State() interface{} { return s.value }
+
+func (s *agentHealthSensor) Units() string { return s.units }
+
+func (s *agentHealthSensor) Category() string {
+	return "diagnostic"
+}
+
+func (s *agentHealthSensor) Attributes() interface{} {
+	return s.attributes
+}
+
+// milliseconds converts a duration to milliseconds, rounded to one decimal
+// place.
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
+
+func newHealthSensors(h diagnostics.Health) []tracker.Sensor {
+	return []tracker.Sensor{
+		&agentHealthSensor{
+			name:       "Agent Active Workers",
+			icon:       "mdi:account-hard-hat",
+			stateClass: sensor.StateMeasurement,
+			value:      h.ActiveWorkers,
+			attributes: &struct {
+				Failing int `json:"Failing Workers"`
+			}{Failing: h.FailingWorkers},
+		},
+		&agentHealthSensor{
+			name:       "Agent Failed Updates",
+			icon:       "mdi:alert-circle-outline",
+			stateClass: sensor.StateMeasurement,
+			value:      h.FailedUpdates,
+			attributes: &struct {
+				Period string `json:"Period"`
+			}{Period: "1h"},
+		},
+		&agentHealthSensor{
+			name:        "Agent Request Latency",
+			icon:        "mdi:timer-outline",
+			units:       "ms",
+			deviceClass: sensor.Duration,
+			stateClass:  sensor.StateMeasurement,
+			value:       milliseconds(h.RequestLatency),
+			attributes: &struct {
+				Average float64 `json:"Average"`
+			}{Average: milliseconds(h.AverageRequestLatency)},
+		},
+		&agentHealthSensor{
+			name:       "Agent Websocket Reconnects",
+			icon:       "mdi:connection",
+			stateClass: sensor.StateTotalIncreasing,
+			value:      h.WebsocketReconnects,
+		},
+	}
+}
+
+// HealthUpdater reports on the health of the agent itself as diagnostic
+// sensors: the number of active workers, sensor updates that failed in the
+// last hour, the round-trip time of requests to Home Assistant and the number
+// of times the websocket connection has been re-established. Along with the
+// memory and CPU sensors, this allows the agent to be monitored from Home
+// Assistant.
+func HealthUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var mu sync.Mutex
+	sendHealthSensors := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, s := range newHealthSensors(diagnostics.CurrentHealth()) {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case sensorCh <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendHealthSensors, healthPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped agent health sensors.")
+	}()
+	return sensorCh
+}
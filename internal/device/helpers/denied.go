@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package helpers
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
+)
+
+// dbusDeniedErrors are the D-Bus errors returned when a security policy (such
+// as an AppArmor profile or the D-Bus daemon policy) denies a method call.
+var dbusDeniedErrors = []string{
+	"org.freedesktop.DBus.Error.AccessDenied",
+	"org.freedesktop.DBus.Error.AuthFailed",
+}
+
+var (
+	deniedMu sync.Mutex
+	denied   = make(map[string]bool)
+)
+
+// isDeniedErr returns whether err indicates that access was denied, either for
+// a file (e.g., in /proc or /sys) or a D-Bus method.
+func isDeniedErr(err error) bool {
+	if errors.Is(err, fs.ErrPermission) {
+		return true
+	}
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return slices.Contains(dbusDeniedErrors, dbusErr.Name)
+	}
+	var dbusErrPtr *dbus.Error
+	if errors.As(err, &dbusErrPtr) {
+		return slices.Contains(dbusDeniedErrors, dbusErrPtr.Name)
+	}
+	return false
+}
+
+// CheckDenied returns whether err indicates that access to the given resource
+// (e.g., a file path or D-Bus method) was denied, which usually means the agent
+// is confined by SELinux or AppArmor. The first time a resource is denied, a
+// warning is logged and the resource is recorded in diagnostics reports.
+// Sensors should then stop trying to access the resource (see IsDenied),
+// rather than retrying and logging the same error every interval.
+func CheckDenied(resource string, err error) bool {
+	if err == nil || !isDeniedErr(err) {
+		return false
+	}
+	deniedMu.Lock()
+	defer deniedMu.Unlock()
+	if denied[resource] {
+		return true
+	}
+	denied[resource] = true
+	log.Warn().Err(err).Str("resource", resource).
+		Msg("Access denied, possibly by SELinux or AppArmor confinement. Sensors that use it are disabled.")
+	diagnostics.RecordDenied(resource, err.Error())
+	return true
+}
+
+// IsDenied returns whether access to the given resource has been denied
+// previously, as recorded by CheckDenied.
+func IsDenied(resource string) bool {
+	deniedMu.Lock()
+	defer deniedMu.Unlock()
+	return denied[resource]
+}
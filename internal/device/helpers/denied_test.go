@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
+)
+
+func TestCheckDenied(t *testing.T) {
+	tests := []struct {
+		err      error
+		name     string
+		resource string
+		want     bool
+	}{
+		{
+			name:     "no error",
+			resource: "none",
+		},
+		{
+			name:     "other error",
+			resource: "other",
+			err:      errors.New("not found"),
+		},
+		{
+			name:     "file permission",
+			resource: "/proc/stat",
+			err:      &fs.PathError{Op: "open", Path: "/proc/stat", Err: syscall.EACCES},
+			want:     true,
+		},
+		{
+			name:     "wrapped errno",
+			resource: "/",
+			err:      fmt.Errorf("statfs: %w", syscall.EPERM),
+			want:     true,
+		},
+		{
+			name:     "dbus access denied",
+			resource: "org.freedesktop.login1.Manager.ListInhibitors",
+			err:      dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"},
+			want:     true,
+		},
+		{
+			name:     "dbus other error",
+			resource: "org.freedesktop.UPower",
+			err:      &dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CheckDenied(tt.resource, tt.err))
+			assert.Equal(t, tt.want, IsDenied(tt.resource))
+			_, recorded := diagnostics.NewReport(nil).Denied[tt.resource]
+			assert.Equal(t, tt.want, recorded)
+		})
+	}
+}
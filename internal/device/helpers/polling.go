@@ -8,41 +8,94 @@ package helpers
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lthibault/jitterbug/v2"
 )
 
+// MaxThrottle is the largest factor that polling can be throttled by.
+const MaxThrottle = 8
+
+// throttle is the factor by which all polling intervals are currently
+// multiplied.
+var throttle atomic.Int32
+
+// SetThrottle sets the factor by which the intervals of all polled sensors are
+// multiplied. A factor of 1 (or less) means no throttling. This is used to
+// reduce the agent CPU usage if it is too high.
+func SetThrottle(factor int) {
+	throttle.Store(int32(max(1, min(factor, MaxThrottle))))
+}
+
+// Throttle returns the factor by which polling intervals are currently
+// multiplied.
+func Throttle() int {
+	return int(max(1, throttle.Load()))
+}
+
+var (
+	refreshMu sync.Mutex
+	refreshCh = make(chan struct{})
+)
+
+// RefreshAll makes all polled sensors update straight away, rather than waiting
+// for their next interval. This is useful when sensor values are known to be
+// stale, such as after the device resumes from suspend.
+func RefreshAll() {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	close(refreshCh)
+	refreshCh = make(chan struct{})
+}
+
+// refreshed returns a channel that is closed when RefreshAll is next called.
+func refreshed() <-chan struct{} {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	return refreshCh
+}
+
 // PollSensors is a helper function that will call the passed `updater()`
 // function around each `interval` duration within the `stdev` duration window.
 // Effectively, `updater()` will get called sometime near `interval`, but not
 // exactly on it. This can help avoid a "thundering herd" problem of sensors all
-// trying to update at the same time.
+// trying to update at the same time. If polling is throttled, ticks are skipped
+// so that `updater()` is called every `interval` multiplied by the throttle
+// factor. `updater()` is also called whenever RefreshAll is called.
 func PollSensors(ctx context.Context, updater func(time.Duration), interval, stdev time.Duration) {
 	var wg sync.WaitGroup
 	lastTick := time.Now()
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		updater(time.Since(lastTick))
-	}()
-	wg.Wait()
+	update := func(t time.Time) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updater(time.Since(lastTick))
+		}()
+		wg.Wait()
+		lastTick = t
+	}
+	refresh := refreshed()
+	update(lastTick)
 	ticker := jitterbug.New(
 		interval,
 		&jitterbug.Norm{Stdev: stdev},
 	)
+	var skipped int
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-refresh:
+			refresh = refreshed()
+			skipped = 0
+			update(time.Now())
 		case t := <-ticker.C:
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				updater(time.Since(lastTick))
-			}()
-			wg.Wait()
-			lastTick = t
+			if skipped++; skipped < Throttle() {
+				continue
+			}
+			skipped = 0
+			update(t)
 		}
 	}
 }
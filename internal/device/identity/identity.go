@@ -0,0 +1,165 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package identity generates the name and ID used to identify the device
+// running the agent in Home Assistant. IDs are taken from one or more sources,
+// which are tried in order until one provides an ID.
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+const (
+	// UnknownID is the ID used when no source can provide one.
+	UnknownID = "unknown"
+	// defaultName is the device name used when the hostname is not available.
+	defaultName = "localhost"
+)
+
+var (
+	ErrNoID     = errors.New("no device ID available")
+	ErrBadID    = errors.New("invalid device ID")
+	ErrNoSource = errors.New("unknown device ID source")
+)
+
+// Source is a source of a device ID.
+type Source interface {
+	// Name is a short name for the source, such as "machine-id".
+	Name() string
+	// ID returns the device ID from the source.
+	ID() (string, error)
+}
+
+// staticSource is a user-specified device ID.
+type staticSource string
+
+func (s staticSource) Name() string {
+	return "static"
+}
+
+func (s staticSource) ID() (string, error) {
+	id := strings.TrimSpace(string(s))
+	if id == "" {
+		return "", ErrBadID
+	}
+	return id, nil
+}
+
+// Static returns a source that always provides the given ID. This can be used
+// to keep the same ID where the other sources are not stable, such as in
+// containers.
+func Static(id string) Source {
+	return staticSource(id)
+}
+
+// fileSource reads the device ID from a file, optionally reformatting it.
+type fileSource struct {
+	format func(string) (string, error)
+	name   string
+	paths  []string
+}
+
+func (s *fileSource) Name() string {
+	return s.name
+}
+
+func (s *fileSource) ID() (string, error) {
+	for _, path := range s.paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		id := strings.TrimSpace(string(b))
+		if id == "" {
+			continue
+		}
+		if s.format != nil {
+			return s.format(id)
+		}
+		return id, nil
+	}
+	return "", ErrNoID
+}
+
+// hostSource uses the host ID reported by the operating system.
+type hostSource struct{}
+
+func (s hostSource) Name() string {
+	return "host"
+}
+
+func (s hostSource) ID() (string, error) {
+	id, err := host.HostID()
+	if err != nil {
+		return "", err
+	}
+	if id = strings.TrimSpace(id); id == "" {
+		return "", ErrNoID
+	}
+	return id, nil
+}
+
+// HostID returns a source that uses the host ID reported by the operating
+// system (e.g., the registry machine GUID on Windows or the platform UUID on
+// macOS). On Linux, this falls back to the boot ID, which changes on every
+// boot, if no other ID is available.
+func HostID() Source {
+	return hostSource{}
+}
+
+// formatMachineID formats the 32 character hexadecimal machine ID as a UUID.
+func formatMachineID(id string) (string, error) {
+	if len(id) != 32 {
+		return "", ErrBadID
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32]), nil
+}
+
+// DeviceID returns the ID from the first of the given sources that can provide
+// one. If no sources are given, the default sources for the platform are used.
+func DeviceID(sources ...Source) (string, error) {
+	if len(sources) == 0 {
+		sources = DefaultSources()
+	}
+	for _, s := range sources {
+		id, err := s.ID()
+		if err != nil {
+			log.Debug().Err(err).Str("source", s.Name()).Msg("Could not retrieve device ID from source.")
+			continue
+		}
+		log.Debug().Str("source", s.Name()).Msg("Using device ID from source.")
+		return id, nil
+	}
+	return "", ErrNoID
+}
+
+// SourceByName returns the default source for the platform with the given
+// name.
+func SourceByName(name string) (Source, error) {
+	for _, s := range DefaultSources() {
+		if s.Name() == name {
+			return s, nil
+		}
+	}
+	return nil, ErrNoSource
+}
+
+// DeviceName returns the name of the device, which is the short hostname.
+func DeviceName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not retrieve hostname. Using '" + defaultName + "'.")
+		return defaultName
+	}
+	shortHostname, _, _ := strings.Cut(hostname, ".")
+	return shortHostname
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package identity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceID(t *testing.T) {
+	dir := t.TempDir()
+	machineID := filepath.Join(dir, "machine-id")
+	err := os.WriteFile(machineID, []byte("0123456789abcdef0123456789abcdef\n"), 0o600)
+	assert.NoError(t, err)
+	badID := filepath.Join(dir, "bad-id")
+	err = os.WriteFile(badID, []byte("short\n"), 0o600)
+	assert.NoError(t, err)
+
+	fromFile := &fileSource{name: "test", paths: []string{filepath.Join(dir, "missing"), machineID}, format: formatMachineID}
+	missing := &fileSource{name: "missing", paths: []string{filepath.Join(dir, "missing")}}
+	bad := &fileSource{name: "bad", paths: []string{badID}, format: formatMachineID}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+		sources []Source
+	}{
+		{
+			name:    "static",
+			sources: []Source{Static(" my-device "), fromFile},
+			want:    "my-device",
+		},
+		{
+			name:    "empty static falls through",
+			sources: []Source{Static(""), fromFile},
+			want:    "01234567-89ab-cdef-0123-456789abcdef",
+		},
+		{
+			name:    "missing and bad sources fall through",
+			sources: []Source{missing, bad, fromFile},
+			want:    "01234567-89ab-cdef-0123-456789abcdef",
+		},
+		{
+			name:    "no usable sources",
+			sources: []Source{missing, bad},
+			wantErr: ErrNoID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DeviceID(tt.sources...)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSourceByName(t *testing.T) {
+	s, err := SourceByName("host")
+	assert.NoError(t, err)
+	assert.Equal(t, "host", s.Name())
+	_, err = SourceByName("nonexistent")
+	assert.ErrorIs(t, err, ErrNoSource)
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package identity
+
+import (
+	"strings"
+)
+
+// DMIUUID returns a source that reads the system UUID from the DMI tables. This
+// is stable for the lifetime of the hardware, but normally only readable by
+// root.
+func DMIUUID() Source {
+	return &fileSource{
+		name:  "dmi",
+		paths: []string{"/sys/class/dmi/id/product_uuid"},
+		format: func(id string) (string, error) {
+			return strings.ToLower(id), nil
+		},
+	}
+}
+
+// MachineID returns a source that reads the systemd/D-Bus machine ID. The ID is
+// formatted as a UUID.
+func MachineID() Source {
+	return &fileSource{
+		name:   "machine-id",
+		paths:  []string{"/etc/machine-id", "/var/lib/dbus/machine-id"},
+		format: formatMachineID,
+	}
+}
+
+// DefaultSources returns the sources tried, in order, when no sources are
+// specified.
+func DefaultSources() []Source {
+	return []Source{DMIUUID(), MachineID(), HostID()}
+}
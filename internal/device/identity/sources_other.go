@@ -0,0 +1,14 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build !linux
+
+package identity
+
+// DefaultSources returns the sources tried, in order, when no sources are
+// specified.
+func DefaultSources() []Source {
+	return []Source{HostID()}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package device
+
+import (
+	"context"
+	"math"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const memoryPollInterval = time.Minute
+
+type agentMemorySensor struct {
+	rss   uint64
+	limit int64
+}
+
+func (s *agentMemorySensor) Name() string { return "Agent Memory Usage" }
+
+func (s *agentMemorySensor) ID() string { return "agent_memory_usage" }
+
+func (s *agentMemorySensor) Icon() string {
+	return "mdi:memory"
+}
+
+func (s *agentMemorySensor) SensorType() sensor.SensorType {
+	return sensor.TypeSensor
+}
+
+func (s *agentMemorySensor) DeviceClass() sensor.SensorDeviceClass {
+	return sensor.Data_size
+}
+
+func (s *agentMemorySensor) StateClass() sensor.SensorStateClass {
+	return sensor.StateMeasurement
+}
+
+func (s *agentMemorySensor) State() interface{} {
+	return math.Round(float64(s.rss)/1024/1024*10) / 10
+}
+
+func (s *agentMemorySensor) Units() string {
+	return "MiB"
+}
+
+func (s *agentMemorySensor) Category() string {
+	return "diagnostic"
+}
+
+func (s *agentMemorySensor) Attributes() interface{} {
+	attrs := &struct {
+		Limit float64 `json:"Memory Limit,omitempty"`
+	}{}
+	// The runtime reports math.MaxInt64 when no limit is set.
+	if s.limit != math.MaxInt64 {
+		attrs.Limit = math.Round(float64(s.limit)/1024/1024*10) / 10
+	}
+	return attrs
+}
+
+// MemoryUpdater reports the resident memory (RSS) used by the agent, along with
+// any memory limit set, as a diagnostic sensor.
+func MemoryUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	p, err := process.NewProcessWithContext(ctx, int32(os.Getpid()))
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not measure agent memory usage. Memory sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	var mu sync.Mutex
+	sendMemorySensor := func(_ time.Duration) {
+		info, err := p.MemoryInfoWithContext(ctx)
+		if err != nil {
+			log.Debug().Err(err).Msg("Could not measure agent memory usage.")
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case sensorCh <- &agentMemorySensor{rss: info.RSS, limit: debug.SetMemoryLimit(-1)}:
+		case <-ctx.Done():
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendMemorySensor, memoryPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped agent memory sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package device
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/internal/update"
+)
+
+const (
+	updateCheckInterval = 6 * time.Hour
+	updateCheckTimeout  = 30 * time.Second
+)
+
+// updateSensor reports whether a newer version of the agent is available on
+// the chosen release channel.
+type updateSensor struct {
+	latest    *update.Release
+	installed string
+	channel   update.Channel
+}
+
+func (s *updateSensor) Name() string { return "Agent Update Available" }
+
+func (s *updateSensor) ID() string { return "agent_update_available" }
+
+func (s *updateSensor) Icon() string {
+	if s.State().(bool) {
+		return "mdi:package-up"
+	}
+	return "mdi:package-check"
+}
+
+func (s *updateSensor) SensorType() sensor.SensorType {
+	return sensor.TypeBinary
+}
+
+func (s *updateSensor) DeviceClass() sensor.SensorDeviceClass { return 0 }
+
+func (s *updateSensor) StateClass() sensor.SensorStateClass { return 0 }
+
+func (s *updateSensor) State() interface{} {
+	return update.IsNewer(s.installed, s.latest.Version)
+}
+
+func (s *updateSensor) Units() string { return "" }
+
+func (s *updateSensor) Category() string {
+	return "diagnostic"
+}
+
+func (s *updateSensor) Attributes() interface{} {
+	return &struct {
+		InstalledVersion string `json:"Installed Version"`
+		LatestVersion    string `json:"Latest Version"`
+		Channel          string `json:"Channel"`
+		ReleaseURL       string `json:"Release URL"`
+	}{
+		InstalledVersion: s.installed,
+		LatestVersion:    s.latest.Version,
+		Channel:          string(s.channel),
+		ReleaseURL:       s.latest.URL,
+	}
+}
+
+// UpdateAvailableUpdater periodically checks for a newer version of the agent
+// on the release channel chosen in the preferences. On the beta channel,
+// prereleases are also offered.
+func UpdateAvailableUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	channel := update.ChannelFromString(preferences.FetchFromContext(ctx).UpdateChannel)
+
+	var mu sync.Mutex
+	checkForUpdate := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		requestCtx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+		defer cancel()
+		latest, err := update.Latest(requestCtx, channel)
+		if err != nil {
+			log.Debug().Err(err).Str("channel", string(channel)).Msg("Could not check for updates.")
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case sensorCh <- &updateSensor{latest: latest, installed: preferences.AppVersion, channel: channel}:
+		case <-ctx.Done():
+		}
+	}
+
+	go helpers.PollSensors(ctx, checkForUpdate, updateCheckInterval, time.Minute)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped update check.")
+	}()
+	return sensorCh
+}
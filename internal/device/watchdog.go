@@ -0,0 +1,192 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package device
+
+import (
+	"context"
+	"math"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	// DefaultCPULimit is the agent CPU usage (as a percentage of one CPU core)
+	// above which polling is throttled, if no limit is set in the preferences.
+	DefaultCPULimit = 50
+
+	watchdogInterval = 10 * time.Second
+	// watchdogThrottleSamples is how many consecutive samples must be above
+	// the limit before throttling is increased (i.e., a sustained minute).
+	watchdogThrottleSamples = 6
+	// watchdogRecoverSamples is how many consecutive samples must be below
+	// half the limit before throttling is decreased (i.e., five minutes).
+	watchdogRecoverSamples = 30
+)
+
+// cpuWatchdog tracks the agent CPU usage against a limit and decides how much
+// to throttle polling by.
+type cpuWatchdog struct {
+	limit    float64
+	above    int
+	below    int
+	throttle int
+}
+
+// update records a CPU usage sample and returns whether the throttle factor
+// changed. Throttling is doubled whenever the usage has been above the limit
+// for watchdogThrottleSamples samples and halved whenever it has been below
+// half the limit for watchdogRecoverSamples samples.
+func (w *cpuWatchdog) update(usage float64) bool {
+	switch {
+	case usage > w.limit:
+		w.above++
+		w.below = 0
+	case usage < w.limit/2:
+		w.below++
+		w.above = 0
+	default:
+		w.above, w.below = 0, 0
+	}
+	switch {
+	case w.above >= watchdogThrottleSamples && w.throttle < helpers.MaxThrottle:
+		w.throttle *= 2
+		w.above = 0
+		return true
+	case w.below >= watchdogRecoverSamples && w.throttle > 1:
+		w.throttle /= 2
+		w.below = 0
+		return true
+	}
+	return false
+}
+
+// cpuTime returns the total user and system CPU time used by the agent.
+func cpuTime() (time.Duration, error) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, err
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), nil
+}
+
+type agentCPUSensor struct {
+	usage    float64
+	limit    float64
+	throttle int
+}
+
+func (s *agentCPUSensor) Name() string { return "Agent CPU Usage" }
+
+func (s *agentCPUSensor) ID() string { return "agent_cpu_usage" }
+
+func (s *agentCPUSensor) Icon() string {
+	if s.throttle > 1 {
+		return "mdi:speedometer-slow"
+	}
+	return "mdi:speedometer"
+}
+
+func (s *agentCPUSensor) SensorType() sensor.SensorType {
+	return sensor.TypeSensor
+}
+
+func (s *agentCPUSensor) DeviceClass() sensor.SensorDeviceClass {
+	return 0
+}
+
+func (s *agentCPUSensor) StateClass() sensor.SensorStateClass {
+	return sensor.StateMeasurement
+}
+
+func (s *agentCPUSensor) State() interface{} {
+	return math.Round(s.usage*10) / 10
+}
+
+func (s *agentCPUSensor) Units() string {
+	return "%"
+}
+
+func (s *agentCPUSensor) Category() string {
+	return "diagnostic"
+}
+
+func (s *agentCPUSensor) Attributes() interface{} {
+	return &struct {
+		Limit    float64 `json:"Limit"`
+		Throttle int     `json:"Polling Throttle Factor"`
+	}{
+		Limit:    s.limit,
+		Throttle: s.throttle,
+	}
+}
+
+// WatchdogUpdater measures the CPU usage of the agent itself. If the usage is
+// above the limit set in the preferences for a sustained period, polling of
+// sensors is throttled to protect low-power devices. The usage and current
+// throttling are reported as a diagnostic sensor.
+func WatchdogUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	limit := preferences.FetchFromContext(ctx).CPULimit
+	if limit == 0 {
+		limit = DefaultCPULimit
+	}
+	w := &cpuWatchdog{limit: float64(limit), throttle: 1}
+
+	go func() {
+		defer close(sensorCh)
+		ticker := time.NewTicker(watchdogInterval)
+		defer ticker.Stop()
+		lastCPU, err := cpuTime()
+		if err != nil {
+			log.Warn().Err(err).Msg("Could not measure agent CPU usage. CPU watchdog will not run.")
+			return
+		}
+		lastTick := time.Now()
+		var samples int
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug().Msg("Stopped CPU watchdog.")
+				return
+			case t := <-ticker.C:
+				cpu, err := cpuTime()
+				if err != nil {
+					continue
+				}
+				usage := float64(cpu-lastCPU) / float64(t.Sub(lastTick)) * 100
+				lastCPU, lastTick = cpu, t
+				previous := w.throttle
+				changed := w.update(usage)
+				switch {
+				case w.throttle > previous:
+					log.Warn().Float64("usage", usage).Float64("limit", w.limit).Int("throttle", w.throttle).
+						Msg("Agent CPU usage is above the limit. Slowing sensor polling.")
+				case w.throttle < previous:
+					log.Info().Float64("usage", usage).Int("throttle", w.throttle).
+						Msg("Agent CPU usage has recovered. Speeding up sensor polling.")
+				}
+				helpers.SetThrottle(w.throttle)
+				// Report about once a minute, or straight away if
+				// throttling changed.
+				if samples++; changed || samples >= watchdogThrottleSamples {
+					samples = 0
+					select {
+					case sensorCh <- &agentCPUSensor{usage: usage, limit: w.limit, throttle: w.throttle}:
+					case <-ctx.Done():
+					}
+				}
+			}
+		}
+	}()
+	return sensorCh
+}
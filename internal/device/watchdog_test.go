@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+)
+
+func Test_cpuWatchdog_update(t *testing.T) {
+	w := &cpuWatchdog{limit: 50, throttle: 1}
+
+	// A brief spike does not throttle.
+	for i := 0; i < watchdogThrottleSamples-1; i++ {
+		assert.False(t, w.update(90))
+	}
+	assert.False(t, w.update(40))
+	assert.Equal(t, 1, w.throttle)
+
+	// Sustained high usage throttles, up to the maximum.
+	for w.throttle < helpers.MaxThrottle {
+		for i := 0; i < watchdogThrottleSamples-1; i++ {
+			w.update(90)
+		}
+		assert.True(t, w.update(90))
+	}
+	for i := 0; i < watchdogThrottleSamples; i++ {
+		assert.False(t, w.update(90))
+	}
+	assert.Equal(t, helpers.MaxThrottle, w.throttle)
+
+	// Sustained low usage recovers.
+	for i := 0; i < watchdogRecoverSamples-1; i++ {
+		assert.False(t, w.update(10))
+	}
+	assert.True(t, w.update(10))
+	assert.Equal(t, helpers.MaxThrottle/2, w.throttle)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package diagnostics
+
+import (
+	"context"
+	"time"
+)
+
+// Connectivity is whether the agent can currently talk to Home Assistant,
+// based on the result of its last webhook request and the state of its
+// websocket connection.
+type Connectivity struct {
+	// LastSuccess is when a webhook request last succeeded.
+	LastSuccess time.Time `json:"last_success"`
+	// LastError is the error from the last webhook request, if it failed.
+	LastError string `json:"last_error,omitempty"`
+	// Webhook is whether the last webhook request succeeded.
+	Webhook bool `json:"webhook"`
+	// Websocket is whether the websocket connection is established.
+	Websocket bool `json:"websocket"`
+}
+
+// Connected returns whether both the webhook and websocket are working, that
+// is, data is flowing in both directions.
+func (c Connectivity) Connected() bool {
+	return c.Webhook && c.Websocket
+}
+
+// Known returns whether any webhook requests have been made yet, and so
+// whether the connectivity is known.
+func (c Connectivity) Known() bool {
+	return !c.LastSuccess.IsZero() || c.LastError != ""
+}
+
+var (
+	connectivity         Connectivity
+	connectivityWatchers = make(map[chan Connectivity]struct{})
+)
+
+// RecordWebhookResult records the result of a webhook request to Home
+// Assistant. A nil error indicates the request succeeded.
+func RecordWebhookResult(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	c := connectivity
+	if err != nil {
+		c.Webhook = false
+		c.LastError = err.Error()
+	} else {
+		c.Webhook = true
+		c.LastError = ""
+		c.LastSuccess = time.Now()
+	}
+	setConnectivity(c)
+}
+
+// RecordWebsocketDisconnect records that the websocket connection to Home
+// Assistant was closed.
+func RecordWebsocketDisconnect() {
+	mu.Lock()
+	defer mu.Unlock()
+	c := connectivity
+	c.Websocket = false
+	setConnectivity(c)
+}
+
+// setConnectivity updates the connectivity and notifies any watchers if it
+// has changed. Watchers are only sent the latest value, so slow watchers do
+// not block recording. It must be called with the lock held.
+func setConnectivity(c Connectivity) {
+	changed := c.Webhook != connectivity.Webhook ||
+		c.Websocket != connectivity.Websocket ||
+		c.LastError != connectivity.LastError
+	connectivity = c
+	if !changed {
+		return
+	}
+	for ch := range connectivityWatchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- c
+	}
+}
+
+// CurrentConnectivity returns the current connectivity to Home Assistant.
+func CurrentConnectivity() Connectivity {
+	mu.Lock()
+	defer mu.Unlock()
+	return connectivity
+}
+
+// WatchConnectivity returns a channel that receives the connectivity to Home
+// Assistant whenever it changes, starting with the current connectivity. The
+// channel is closed when the context is canceled.
+func WatchConnectivity(ctx context.Context) <-chan Connectivity {
+	ch := make(chan Connectivity, 1)
+	mu.Lock()
+	connectivityWatchers[ch] = struct{}{}
+	ch <- connectivity
+	mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		delete(connectivityWatchers, ch)
+		close(ch)
+		mu.Unlock()
+	}()
+	return ch
+}
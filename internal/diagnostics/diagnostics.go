@@ -0,0 +1,180 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package diagnostics collects details about the running agent that are useful
+// when troubleshooting: its configuration (with secrets redacted), the state of
+// its workers and any recent warnings or errors it has logged.
+package diagnostics
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/joshuar/go-hass-agent/internal/features"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+const (
+	// Redacted replaces the value of any sensitive configuration option.
+	Redacted = "**REDACTED**"
+	// maxErrors is the number of recent errors kept for a report.
+	maxErrors = 20
+)
+
+// WorkerState is the state of an agent worker.
+type WorkerState string
+
+const (
+	WorkerRunning WorkerState = "running"
+	WorkerStopped WorkerState = "stopped"
+	// WorkerFailing indicates a worker that is repeatedly failing and being
+	// restarted.
+	WorkerFailing WorkerState = "failing"
+)
+
+// LogEntry is a warning or error message logged by the agent.
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// Report is a snapshot of the agent diagnostics.
+type Report struct {
+	Generated    time.Time              `json:"generated"`
+	Version      string                 `json:"version"`
+	Config       map[string]any         `json:"config"`
+	Workers      map[string]WorkerState `json:"workers"`
+	Denied       map[string]string      `json:"denied,omitempty"`
+	Errors       []LogEntry             `json:"recent_errors"`
+	Health       Health                 `json:"health"`
+	Connectivity Connectivity           `json:"connectivity"`
+	Features     map[string]bool        `json:"features,omitempty"`
+}
+
+var (
+	mu           sync.Mutex
+	workers      = make(map[string]WorkerState)
+	denied       = make(map[string]string)
+	recentErrors []LogEntry
+)
+
+// SetWorkerState records the current state of the named worker.
+func SetWorkerState(name string, state WorkerState) {
+	mu.Lock()
+	defer mu.Unlock()
+	workers[name] = state
+}
+
+// RecordDenied records a resource (e.g., a file path or D-Bus method) that the
+// agent has been denied access to, with the reason. Sensors that need the
+// resource are disabled, which is shown in diagnostics reports.
+func RecordDenied(resource, reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+	denied[resource] = reason
+}
+
+// TrackWorker runs the given worker function, recording it as running until
+// the function returns.
+func TrackWorker(name string, f func()) {
+	SetWorkerState(name, WorkerRunning)
+	defer SetWorkerState(name, WorkerStopped)
+	f()
+}
+
+// recordLog adds a log entry, discarding the oldest entry if the maximum number
+// of entries is reached.
+func recordLog(e LogEntry) {
+	mu.Lock()
+	defer mu.Unlock()
+	recentErrors = append(recentErrors, e)
+	if len(recentErrors) > maxErrors {
+		recentErrors = recentErrors[len(recentErrors)-maxErrors:]
+	}
+}
+
+// Hook is a zerolog hook that keeps any warnings or errors logged for
+// diagnostics reports.
+type Hook struct{}
+
+// Run records the message if it is logged at warning level or above.
+func (Hook) Run(_ *zerolog.Event, level zerolog.Level, msg string) {
+	if level < zerolog.WarnLevel || level == zerolog.NoLevel || level == zerolog.Disabled {
+		return
+	}
+	recordLog(LogEntry{
+		Time:    time.Now(),
+		Level:   level.String(),
+		Message: msg,
+	})
+}
+
+// NewReport creates a diagnostics report using the given preferences and the
+// worker states and errors recorded so far.
+func NewReport(prefs *preferences.Preferences) *Report {
+	health := CurrentHealth()
+	conn := CurrentConnectivity()
+	mu.Lock()
+	defer mu.Unlock()
+	r := &Report{
+		Generated:    time.Now(),
+		Version:      preferences.AppVersion,
+		Config:       redactedConfig(prefs),
+		Workers:      make(map[string]WorkerState, len(workers)),
+		Errors:       make([]LogEntry, len(recentErrors)),
+		Health:       health,
+		Connectivity: conn,
+	}
+	for k, v := range workers {
+		r.Workers[k] = v
+	}
+	if flags := features.All(); prefs != nil && len(flags) > 0 {
+		r.Features = make(map[string]bool, len(flags))
+		for _, f := range flags {
+			r.Features[f.Name] = f.Enabled(prefs)
+		}
+	}
+	if len(denied) > 0 {
+		r.Denied = make(map[string]string, len(denied))
+		for k, v := range denied {
+			r.Denied[k] = v
+		}
+	}
+	copy(r.Errors, recentErrors)
+	return r
+}
+
+// redactedConfig returns the preferences as a map keyed by their config file
+// option names. Options tagged for redaction have any set value replaced.
+func redactedConfig(prefs *preferences.Preferences) map[string]any {
+	config := make(map[string]any)
+	if prefs == nil {
+		return config
+	}
+	v := reflect.ValueOf(prefs).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		value := v.Field(i)
+		if field.Tag.Get("diag") == "redact" && !value.IsZero() {
+			config[name] = Redacted
+			continue
+		}
+		config[name] = value.Interface()
+	}
+	return config
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package diagnostics
+
+import (
+	"time"
+)
+
+const (
+	// failureWindow is the period over which failed sensor updates are
+	// counted.
+	failureWindow = time.Hour
+	// maxLatencySamples is the number of request latencies kept to calculate
+	// the average latency.
+	maxLatencySamples = 20
+)
+
+// Health is a summary of the health of the agent.
+type Health struct {
+	// ActiveWorkers is the number of workers currently running.
+	ActiveWorkers int `json:"active_workers"`
+	// FailingWorkers is the number of workers that are repeatedly failing.
+	FailingWorkers int `json:"failing_workers"`
+	// FailedUpdates is the number of sensor updates that could not be sent
+	// to Home Assistant in the last hour.
+	FailedUpdates int `json:"failed_updates"`
+	// RequestLatency is the round-trip time of the last request to Home
+	// Assistant.
+	RequestLatency time.Duration `json:"request_latency"`
+	// AverageRequestLatency is the average round-trip time of recent requests
+	// to Home Assistant.
+	AverageRequestLatency time.Duration `json:"average_request_latency"`
+	// WebsocketReconnects is the number of times the websocket connection to
+	// Home Assistant has been re-established since the agent started.
+	WebsocketReconnects int `json:"websocket_reconnects"`
+}
+
+var (
+	updateFailures    []time.Time
+	requestLatencies  []time.Duration
+	websocketConnects int
+)
+
+// RecordUpdateFailure records that a sensor update could not be sent to Home
+// Assistant.
+func RecordUpdateFailure() {
+	mu.Lock()
+	defer mu.Unlock()
+	updateFailures = append(recentFailures(time.Now()), time.Now())
+}
+
+// recentFailures returns the update failures within the failure window. It
+// must be called with the lock held.
+func recentFailures(now time.Time) []time.Time {
+	recent := updateFailures[:0]
+	for _, f := range updateFailures {
+		if now.Sub(f) < failureWindow {
+			recent = append(recent, f)
+		}
+	}
+	return recent
+}
+
+// RecordRequestLatency records the round-trip time of a request to Home
+// Assistant.
+func RecordRequestLatency(d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	requestLatencies = append(requestLatencies, d)
+	if len(requestLatencies) > maxLatencySamples {
+		requestLatencies = requestLatencies[len(requestLatencies)-maxLatencySamples:]
+	}
+}
+
+// RecordWebsocketConnect records that a websocket connection to Home Assistant
+// was established.
+func RecordWebsocketConnect() {
+	mu.Lock()
+	defer mu.Unlock()
+	websocketConnects++
+	c := connectivity
+	c.Websocket = true
+	setConnectivity(c)
+}
+
+// CurrentHealth returns a summary of the current health of the agent.
+func CurrentHealth() Health {
+	mu.Lock()
+	defer mu.Unlock()
+	var h Health
+	for _, state := range workers {
+		switch state {
+		case WorkerRunning:
+			h.ActiveWorkers++
+		case WorkerFailing:
+			h.ActiveWorkers++
+			h.FailingWorkers++
+		}
+	}
+	updateFailures = recentFailures(time.Now())
+	h.FailedUpdates = len(updateFailures)
+	if n := len(requestLatencies); n > 0 {
+		h.RequestLatency = requestLatencies[n-1]
+		var total time.Duration
+		for _, l := range requestLatencies {
+			total += l
+		}
+		h.AverageRequestLatency = total / time.Duration(n)
+	}
+	if websocketConnects > 1 {
+		h.WebsocketReconnects = websocketConnects - 1
+	}
+	return h
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package features provides flags for experimental features. Each flag is
+// enabled by default on some release channels (e.g., only on development
+// builds) and can be enabled or disabled per machine in the preferences, so
+// that experimental workers can ship disabled without needing separate builds.
+package features
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// Channel is a release channel of the agent.
+type Channel string
+
+const (
+	// Stable is a release version (e.g., v7.0.0).
+	Stable Channel = "stable"
+	// Beta is a prerelease version (e.g., v7.1.0-beta.1 or v7.1.0-rc.1).
+	Beta Channel = "beta"
+	// Dev is any other build, such as one built from a git checkout.
+	Dev Channel = "dev"
+)
+
+var (
+	versionRegex    = regexp.MustCompile(`^v?\d+\.\d+\.\d+(?:-([0-9A-Za-z.-]+))?$`)
+	prereleaseRegex = regexp.MustCompile(`^(?:alpha|beta|rc)(?:[.-]?\d+)*$`)
+)
+
+// ChannelOf returns the release channel of the given agent version.
+func ChannelOf(version string) Channel {
+	m := versionRegex.FindStringSubmatch(strings.TrimSpace(version))
+	switch {
+	case m == nil:
+		return Dev
+	case m[1] == "":
+		return Stable
+	case prereleaseRegex.MatchString(m[1]):
+		return Beta
+	default:
+		return Dev
+	}
+}
+
+// Flag is an experimental feature that can be enabled or disabled.
+type Flag struct {
+	// Name is the name of the flag, as used in the preferences.
+	Name string
+	// Description briefly describes the feature.
+	Description string
+	// Channels are the release channels on which the feature is enabled by
+	// default.
+	Channels []Channel
+}
+
+var (
+	flags   []*Flag
+	flagsMu sync.Mutex
+)
+
+// New creates and registers a new flag with the given name and description,
+// enabled by default on the given release channels.
+func New(name, description string, channels ...Channel) *Flag {
+	f := &Flag{
+		Name:        name,
+		Description: description,
+		Channels:    channels,
+	}
+	flagsMu.Lock()
+	defer flagsMu.Unlock()
+	flags = append(flags, f)
+	return f
+}
+
+// All returns all registered flags, sorted by name.
+func All() []*Flag {
+	flagsMu.Lock()
+	defer flagsMu.Unlock()
+	all := slices.Clone(flags)
+	slices.SortFunc(all, func(a, b *Flag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return all
+}
+
+// Default returns whether the feature is enabled by default for the given
+// agent version.
+func (f *Flag) Default(version string) bool {
+	return slices.Contains(f.Channels, ChannelOf(version))
+}
+
+// Enabled returns whether the feature is enabled with the given preferences.
+// The preferences override the default for the running agent version.
+func (f *Flag) Enabled(prefs *preferences.Preferences) bool {
+	if enabled, ok := prefs.Features[f.Name]; ok {
+		return enabled
+	}
+	return f.Default(preferences.AppVersion)
+}
+
+// EnabledInContext returns whether the feature is enabled with the
+// preferences stored in the context.
+func (f *Flag) EnabledInContext(ctx context.Context) bool {
+	prefs := preferences.FetchFromContext(ctx)
+	return f.Enabled(&prefs)
+}
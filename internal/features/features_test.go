@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package features
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func TestChannelOf(t *testing.T) {
+	tests := map[string]Channel{
+		"v7.0.0":          Stable,
+		"7.0.0":           Stable,
+		"v7.1.0-beta.1":   Beta,
+		"v7.1.0-rc1":      Beta,
+		"v7.1.0-alpha":    Beta,
+		"v7.0.0-3-gabcd1": Dev,
+		"Unknown":         Dev,
+		"":                Dev,
+	}
+	for version, want := range tests {
+		assert.Equal(t, want, ChannelOf(version), version)
+	}
+}
+
+func TestFlag_Enabled(t *testing.T) {
+	origVersion := preferences.AppVersion
+	defer func() { preferences.AppVersion = origVersion }()
+
+	f := New("testFeature", "A test feature.", Beta, Dev)
+	assert.Contains(t, All(), f)
+
+	preferences.AppVersion = "v7.0.0"
+	assert.False(t, f.Enabled(&preferences.Preferences{}))
+	preferences.AppVersion = "v7.1.0-beta.1"
+	assert.True(t, f.Enabled(&preferences.Preferences{}))
+
+	// Preferences override the default.
+	prefs := &preferences.Preferences{}
+	assert.Nil(t, preferences.Feature("testFeature", false)(prefs))
+	assert.False(t, f.Enabled(prefs))
+	preferences.AppVersion = "v7.0.0"
+	assert.Nil(t, preferences.Feature("testFeature", true)(prefs))
+	assert.True(t, f.Enabled(prefs))
+	assert.True(t, f.EnabledInContext(preferences.EmbedInContext(context.TODO(), prefs)))
+	assert.False(t, f.EnabledInContext(context.TODO()))
+}
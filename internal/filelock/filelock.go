@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package filelock provides advisory file locks, which can be used to
+// coordinate access to files between processes.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrLocked is returned by TryAcquire when the lock is held by another process.
+var ErrLocked = errors.New("locked by another process")
+
+// Lock is an exclusive advisory lock on a file. The lock is released when
+// Release is called or the process exits.
+type Lock struct {
+	file *os.File
+}
+
+func open(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+}
+
+// Acquire acquires the lock on the given file, creating it if needed. It
+// blocks until the lock is available.
+func Acquire(path string) (*Lock, error) {
+	f, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not lock %s: %w", path, err)
+	}
+	return &Lock{file: f}, nil
+}
+
+// TryAcquire acquires the lock on the given file, creating it if needed. If
+// the lock is held by another process, it returns ErrLocked straight away. The
+// PID of the current process is written to the file, so that other processes
+// can report which process holds the lock (see Owner).
+func TryAcquire(path string) (*Lock, error) {
+	f, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("could not lock %s: %w", path, err)
+	}
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{file: f}, nil
+}
+
+// Owner returns the PID of the process that last acquired the lock on the
+// given file with TryAcquire, or 0 if it is not known.
+func Owner(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	defer l.file.Close()
+	return syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+}
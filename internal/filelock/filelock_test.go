@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "test.lock")
+
+	lock, err := TryAcquire(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), Owner(path))
+
+	// A second lock on the file (e.g., by another instance) fails.
+	_, err = TryAcquire(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// Until the first is released.
+	require.NoError(t, lock.Release())
+	lock, err = TryAcquire(path)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	lock, err := Acquire(path)
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		second, err := Acquire(path)
+		assert.NoError(t, err)
+		close(acquired)
+		assert.NoError(t, second.Release())
+	}()
+
+	// The second lock blocks until the first is released.
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, lock.Release())
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("lock not acquired after release")
+	}
+}
@@ -13,11 +13,13 @@ func _() {
 	_ = x[RequestTypeUpdateLocation-3]
 	_ = x[RequestTypeRegisterSensor-4]
 	_ = x[RequestTypeUpdateSensorStates-5]
+	_ = x[RequestTypeCallService-6]
+	_ = x[RequestTypeFireEvent-7]
 }
 
-const _RequestType_name = "encryptedget_configupdate_locationregister_sensorupdate_sensor_states"
+const _RequestType_name = "encryptedget_configupdate_locationregister_sensorupdate_sensor_statescall_servicefire_event"
 
-var _RequestType_index = [...]uint8{0, 9, 19, 34, 49, 69}
+var _RequestType_index = [...]uint8{0, 9, 19, 34, 49, 69, 81, 91}
 
 func (i RequestType) String() string {
 	i -= 1
@@ -30,8 +32,8 @@ func _() {
 	// An "invalid array index" compiler error signifies that the constant values have changed.
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
-	_ = x[ResponseTypeRegistration-6]
-	_ = x[ResponseTypeUpdate-7]
+	_ = x[ResponseTypeRegistration-1]
+	_ = x[ResponseTypeUpdate-2]
 }
 
 const _ResponseType_name = "registrationupdate"
@@ -39,9 +41,9 @@ const _ResponseType_name = "registrationupdate"
 var _ResponseType_index = [...]uint8{0, 12, 18}
 
 func (i ResponseType) String() string {
-	i -= 6
+	i -= 1
 	if i < 0 || i >= ResponseType(len(_ResponseType_index)-1) {
-		return "ResponseType(" + strconv.FormatInt(int64(i+6), 10) + ")"
+		return "ResponseType(" + strconv.FormatInt(int64(i+1), 10) + ")"
 	}
 	return _ResponseType_name[_ResponseType_index[i]:_ResponseType_index[i+1]]
 }
@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dialTimeout = 30 * time.Second
+	// preferredDialTimeout is how long to try connecting over the preferred IP
+	// version before falling back to any IP version.
+	preferredDialTimeout = 5 * time.Second
+)
+
+// preferredNetwork returns the network to use when the given IP version
+// ("ipv4" or "ipv6") is preferred for a TCP connection. An empty string is
+// returned if there is no preference.
+func preferredNetwork(preferIP string) string {
+	switch preferIP {
+	case "ipv4":
+		return "tcp4"
+	case "ipv6":
+		return "tcp6"
+	default:
+		return ""
+	}
+}
+
+// preferredDialer dials TCP connections preferring the given IP version. If
+// a connection cannot be made with the preferred IP version, such as when the
+// host has no address of that version, any IP version is used instead.
+type preferredDialer struct {
+	net.Dialer
+	preferred string
+}
+
+func newPreferredDialer(preferIP string) *preferredDialer {
+	return &preferredDialer{
+		Dialer:    net.Dialer{Timeout: dialTimeout, KeepAlive: dialTimeout},
+		preferred: preferredNetwork(preferIP),
+	}
+}
+
+func (d *preferredDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.preferred != "" && network == "tcp" {
+		preferCtx, cancel := context.WithTimeout(ctx, preferredDialTimeout)
+		conn, err := d.Dialer.DialContext(preferCtx, d.preferred, addr)
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return d.Dialer.DialContext(ctx, network, addr)
+}
+
+// Dial is used for the websocket connection. As the websocket library joins the
+// host and port without brackets, IPv6 addresses are fixed up first.
+func (d *preferredDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, joinHostPort(addr))
+}
+
+// joinHostPort ensures an address in the form host:port has brackets around
+// the host if it is an IPv6 address.
+func joinHostPort(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	if i := strings.LastIndex(addr, ":"); i > 0 {
+		return net.JoinHostPort(addr[:i], addr[i+1:])
+	}
+	return addr
+}
+
+var (
+	httpClientsMu sync.Mutex
+	httpClients   = make(map[string]*http.Client)
+)
+
+// httpClient returns a HTTP client that prefers the given IP version for
+// connections. Clients are reused, so that connections can be kept alive.
+func httpClient(preferIP string) *http.Client {
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	if c, ok := httpClients[preferIP]; ok {
+		return c
+	}
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultClient
+	}
+	transport = transport.Clone()
+	transport.DialContext = newPreferredDialer(preferIP).DialContext
+	c := &http.Client{Transport: transport}
+	httpClients[preferIP] = c
+	return c
+}
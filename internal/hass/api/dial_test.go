@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package api
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_joinHostPort(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{addr: "localhost:8123", want: "localhost:8123"},
+		{addr: "192.168.1.1:8123", want: "192.168.1.1:8123"},
+		{addr: "[::1]:8123", want: "[::1]:8123"},
+		{addr: "::1:8123", want: "[::1]:8123"},
+		{addr: "2001:db8::1:443", want: "[2001:db8::1]:443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, joinHostPort(tt.addr))
+		})
+	}
+}
+
+func Test_preferredDialer(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on IPv4")
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// Preferring IPv6 falls back to IPv4 when the host only has an IPv4
+	// address.
+	conn, err := newPreferredDialer("ipv6").Dial("tcp", l.Addr().String())
+	assert.NoError(t, err)
+	if conn != nil {
+		conn.Close()
+	}
+
+	conn, err = newPreferredDialer("ipv4").Dial("tcp", l.Addr().String())
+	assert.NoError(t, err)
+	if conn != nil {
+		conn.Close()
+	}
+}
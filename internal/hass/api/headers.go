@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+
+	"github.com/carlmjohnson/requests"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+const userAgentHeader = "User-Agent"
+
+// userAgent returns the User-Agent to identify the agent with in requests. If
+// one is not set in the preferences, it includes the agent version and device
+// name, so that requests can be attributed to the device in proxy and Home
+// Assistant logs.
+func userAgent(prefs *preferences.Preferences) string {
+	if prefs.UserAgent != "" {
+		return prefs.UserAgent
+	}
+	ua := preferences.AppName + "/" + preferences.AppVersion
+	if prefs.DeviceName != "" {
+		return ua + " (" + prefs.DeviceName + "; " + runtime.GOOS + ")"
+	}
+	return ua + " (" + runtime.GOOS + ")"
+}
+
+// newRequest creates a request to the given URL with the User-Agent and any
+// custom HTTP headers from the preferences set, such as those needed by an
+// authenticating proxy in front of Home Assistant. Headers set on the request
+// afterwards, such as the Home Assistant Authorization header, take
+// precedence.
+func newRequest(ctx context.Context, url string) *requests.Builder {
+	prefs := preferences.FetchFromContext(ctx)
+	r := requests.URL(url).
+		Client(httpClient(prefs.PreferIP)).
+		UserAgent(userAgent(&prefs))
+	for k, v := range prefs.HTTPHeaders {
+		r = r.Header(k, v)
+	}
+	return r
+}
+
+// requestHeaders returns the User-Agent and any custom HTTP headers from the
+// preferences.
+func requestHeaders(prefs *preferences.Preferences) http.Header {
+	h := http.Header{}
+	h.Set(userAgentHeader, userAgent(prefs))
+	for k, v := range prefs.HTTPHeaders {
+		h.Set(k, v)
+	}
+	return h
+}
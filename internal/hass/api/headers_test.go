@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func Test_newRequest(t *testing.T) {
+	var got http.Header
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer mockServer.Close()
+
+	prefs := &preferences.Preferences{
+		HTTPHeaders: map[string]string{
+			"CF-Access-Client-Id": "anID",
+			"Authorization":       "Basic proxyAuth",
+		},
+	}
+	ctx := preferences.EmbedInContext(context.Background(), prefs)
+
+	// Custom headers are sent.
+	err := newRequest(ctx, mockServer.URL).Fetch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, userAgent(prefs), got.Get(userAgentHeader))
+	assert.Equal(t, "anID", got.Get("CF-Access-Client-Id"))
+	assert.Equal(t, "Basic proxyAuth", got.Get("Authorization"))
+
+	// Headers set afterwards take precedence.
+	err = newRequest(ctx, mockServer.URL).Header(authHeader, "Bearer aToken").Fetch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "anID", got.Get("CF-Access-Client-Id"))
+	assert.Equal(t, "Bearer aToken", got.Get("Authorization"))
+}
+
+func Test_userAgent(t *testing.T) {
+	tests := []struct {
+		prefs *preferences.Preferences
+		name  string
+		want  string
+	}{
+		{
+			name:  "default",
+			prefs: &preferences.Preferences{},
+			want:  preferences.AppName + "/" + preferences.AppVersion + " (" + runtime.GOOS + ")",
+		},
+		{
+			name:  "with device name",
+			prefs: &preferences.Preferences{DeviceName: "myPC"},
+			want:  preferences.AppName + "/" + preferences.AppVersion + " (myPC; " + runtime.GOOS + ")",
+		},
+		{
+			name:  "custom",
+			prefs: &preferences.Preferences{DeviceName: "myPC", UserAgent: "myAgent/1.0"},
+			want:  "myAgent/1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, userAgent(tt.prefs))
+		})
+	}
+}
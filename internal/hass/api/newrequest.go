@@ -12,8 +12,6 @@ import (
 	"errors"
 	"sync"
 	"time"
-
-	"github.com/carlmjohnson/requests"
 )
 
 var (
@@ -37,8 +35,7 @@ func ExecuteRequest2(ctx context.Context, req Request2) <-chan Response {
 	responseCh := make(chan Response, 1)
 	defer close(responseCh)
 
-	r := requests.
-		URL(req.URL()).
+	r := newRequest(ctx, req.URL()).
 		Header("Authorization", "Bearer "+req.Auth())
 
 	if req.Body() != nil {
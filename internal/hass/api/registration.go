@@ -7,6 +7,8 @@ package api
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -17,9 +19,16 @@ const (
 	WebsocketPath    = "/api/websocket"
 	WebHookPath      = "/api/webhook/"
 	registrationPath = "/api/mobile_app/registrations"
+	apiPath          = "/api/"
 	authHeader       = "Authorization"
+
+	checkServerTimeout = 5 * time.Second
 )
 
+// ErrInvalidToken is returned by CheckServer when the server could be reached
+// but did not accept the token.
+var ErrInvalidToken = errors.New("token not accepted by server")
+
 type RegistrationResponse struct {
 	CloudhookURL string `json:"cloudhook_url"`
 	RemoteUIURL  string `json:"remote_ui_url"`
@@ -56,8 +65,7 @@ func RegisterWithHass(ctx context.Context, server, token string, device DeviceIn
 	var response *RegistrationResponse
 	ctx, cancel := context.WithTimeout(ctx, time.Minute)
 	defer cancel()
-	err = requests.
-		URL(serverURL.String()).
+	err = newRequest(ctx, serverURL.String()).
 		Header(authHeader, "Bearer "+token).
 		BodyBytes(request).
 		ToJSON(&response).
@@ -67,3 +75,24 @@ func RegisterWithHass(ctx context.Context, server, token string, device DeviceIn
 	}
 	return response, nil
 }
+
+// CheckServer checks whether the given server is a reachable Home Assistant
+// instance that accepts the given token. If the server is reachable but the
+// token is not valid, ErrInvalidToken is returned.
+func CheckServer(ctx context.Context, server, token string) error {
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+	serverURL = serverURL.JoinPath(apiPath)
+
+	ctx, cancel := context.WithTimeout(ctx, checkServerTimeout)
+	defer cancel()
+	err = newRequest(ctx, serverURL.String()).
+		Header(authHeader, "Bearer "+token).
+		Fetch(ctx)
+	if requests.HasStatusErr(err, http.StatusUnauthorized, http.StatusForbidden) {
+		return ErrInvalidToken
+	}
+	return err
+}
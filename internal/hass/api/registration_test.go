@@ -126,3 +126,43 @@ func TestRegisterWithHass(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckServer(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != apiPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get(authHeader) != "Bearer aToken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"message":"API running."}`))
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		wantErr error
+		name    string
+		server  string
+		token   string
+		anyErr  bool
+	}{
+		{name: "valid server and token", server: mockServer.URL, token: "aToken"},
+		{name: "invalid token", server: mockServer.URL, token: "badToken", wantErr: ErrInvalidToken},
+		{name: "unreachable server", server: "http://localhost:0", token: "aToken", anyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckServer(context.Background(), tt.server, tt.token)
+			switch {
+			case tt.anyErr:
+				assert.Error(t, err)
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -10,11 +10,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/carlmjohnson/requests"
 
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
@@ -25,7 +27,11 @@ const (
 	RequestTypeUpdateLocation                            // update_location
 	RequestTypeRegisterSensor                            // register_sensor
 	RequestTypeUpdateSensorStates                        // update_sensor_states
+	RequestTypeCallService                               // call_service
+	RequestTypeFireEvent                                 // fire_event
+)
 
+const (
 	ResponseTypeRegistration ResponseType = iota + 1 // registration
 	ResponseTypeUpdate                               // update
 )
@@ -92,12 +98,21 @@ func ExecuteRequest(ctx context.Context, request Request) <-chan any {
 	go func() {
 		defer wg.Done()
 		var rBuf bytes.Buffer
-		err = requests.
-			URL(prefs.RestAPIURL).
+		start := time.Now()
+		err = newRequest(ctx, prefs.RestAPIURL).
 			BodyBytes(reqJSON).
 			ToBytesBuffer(&rBuf).
 			Fetch(requestCtx)
-		if err != nil {
+		if err == nil {
+			diagnostics.RecordRequestLatency(time.Since(start))
+		}
+		if ctx.Err() == nil {
+			diagnostics.RecordWebhookResult(err)
+		}
+		if se := new(requests.ResponseError); errors.As(err, &se) && se.StatusCode == http.StatusTooManyRequests {
+			cancel()
+			responseCh <- &RateLimitError{RetryAfter: parseRetryAfter(se.Header.Get("Retry-After"))}
+		} else if err != nil {
 			cancel()
 			responseCh <- err
 		} else {
@@ -10,8 +10,49 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"time"
 )
 
+var (
+	// ErrSensorRejected indicates Home Assistant rejected a sensor
+	// registration or update.
+	ErrSensorRejected = errors.New("sensor rejected by Home Assistant")
+	// ErrNotRegistered indicates Home Assistant does not know about the sensor
+	// being updated.
+	ErrNotRegistered = errors.New("sensor not registered")
+)
+
+// RateLimitError is returned when Home Assistant is rate limiting requests.
+// RetryAfter will contain the period requested by Home Assistant before
+// retrying, if it was provided.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited by Home Assistant, retry after %s", e.RetryAfter)
+	}
+	return "rate limited by Home Assistant"
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which can be either
+// a number of seconds or a HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
 type SensorResponseBody struct {
 	Error    ResponseError `json:"error,omitempty"`
 	Success  bool          `json:"success"`
@@ -48,7 +89,7 @@ func parseRegistrationResponse(buf *bytes.Buffer) (*SensorResponse, error) {
 	}
 	if _, ok := r["success"]; ok {
 		if success, err := assertAs[bool](r["success"]); err != nil || !success {
-			return nil, errors.New("unsuccessful registration")
+			return nil, fmt.Errorf("%w: unsuccessful registration", ErrSensorRejected)
 		}
 		return &SensorResponse{registered: true, responseType: ResponseTypeRegistration}, nil
 	}
@@ -63,7 +104,11 @@ func parseUpdateResponse(buf *bytes.Buffer) (*SensorResponse, error) {
 	}
 	for sensorID, v := range r {
 		if !v.Success {
-			return nil, fmt.Errorf("sensor %s, code %s: %s", sensorID, v.Error.ErrorCode, v.Error.ErrorMsg)
+			err := fmt.Errorf("%w: sensor %s, code %s: %s", ErrSensorRejected, sensorID, v.Error.ErrorCode, v.Error.ErrorMsg)
+			if v.Error.ErrorCode == "not_registered" {
+				err = errors.Join(err, ErrNotRegistered)
+			}
+			return nil, err
 		}
 		return &SensorResponse{disabled: v.Disabled, responseType: ResponseTypeUpdate}, nil
 	}
@@ -76,6 +121,10 @@ func parseResponse(t RequestType, buf *bytes.Buffer) (any, error) {
 		return buf.Bytes(), nil
 	case RequestTypeGetConfig:
 		return buf.Bytes(), nil
+	case RequestTypeCallService:
+		return buf.Bytes(), nil
+	case RequestTypeFireEvent:
+		return buf.Bytes(), nil
 	case RequestTypeRegisterSensor:
 		return parseRegistrationResponse(buf)
 	case RequestTypeUpdateSensorStates:
@@ -7,15 +7,17 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
-	"net/http"
+	"errors"
+	"net/url"
 	"sync/atomic"
 	"time"
 
-	"github.com/cenkalti/backoff/v4"
 	"github.com/lxzan/gws"
 	"github.com/rs/zerolog/log"
 
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
@@ -59,53 +61,92 @@ type websocketNotification struct {
 	Target    []string `json:"target,omitempty"`
 }
 
-func StartWebsocket(ctx context.Context, notifyCh chan [2]string) {
-	var prefs *preferences.Preferences
-	var err error
-	var socket *gws.Conn
+// CommandOpenURL is the message of a notification that opens the URL in its
+// data on the device, rather than being shown. For example, with the notify
+// service: {"message": "command_open_url", "data": {"url": "https://..."}}.
+const CommandOpenURL = "command_open_url"
 
-	prefs, err = preferences.Load()
+// CommandSetClipboard is the message of a notification that copies the text
+// in its data to the clipboard of the device, rather than being shown. For
+// example: {"message": "command_set_clipboard", "data": {"text": "..."}}.
+const CommandSetClipboard = "command_set_clipboard"
+
+// Notification is a notification received from Home Assistant. Data holds any
+// data sent with the notification.
+type Notification struct {
+	Data    map[string]any
+	Title   string
+	Message string
+}
+
+// ErrWebsocketClosed is returned when the websocket connection is closed
+// before the context is canceled.
+var ErrWebsocketClosed = errors.New("websocket connection closed")
+
+// StartWebsocket connects to the Home Assistant websocket and listens for
+// notifications until the context is canceled. It returns an error if it could
+// not connect or the connection was closed. Retrying is left to the caller.
+func StartWebsocket(ctx context.Context, notifyCh chan Notification) error {
+	prefs, err := preferences.Load()
 	if err != nil {
-		log.Error().Err(err).Msg("Could not load preferences.")
+		return err
 	}
+	return RunWebsocket(ctx, prefs, notifyCh)
+}
 
-	retryFunc := func() error {
-		var resp *http.Response
-		socket, resp, err = gws.NewClient(
-			newWebsocket(prefs, notifyCh),
-			&gws.ClientOption{Addr: prefs.WebsocketURL})
-		if err != nil {
-			log.Error().Err(err).
-				Msg("Could not connect to websocket.")
-			return err
-		}
-		defer resp.Body.Close()
-		return nil
+// RunWebsocket is like StartWebsocket but uses the given preferences rather
+// than loading them, for connecting as a device other than the one running the
+// agent.
+func RunWebsocket(ctx context.Context, prefs *preferences.Preferences, notifyCh chan Notification) error {
+	wsURL, err := url.Parse(prefs.WebsocketURL)
+	if err != nil {
+		return err
 	}
-	err = backoff.Retry(retryFunc, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
+	socket, resp, err := gws.NewClient(
+		newWebsocket(prefs, notifyCh),
+		&gws.ClientOption{
+			Addr:          prefs.WebsocketURL,
+			RequestHeader: requestHeaders(prefs),
+			NewDialer: func() (gws.Dialer, error) {
+				return newPreferredDialer(prefs.PreferIP), nil
+			},
+			// The server name must not include the port (or brackets around
+			// an IPv6 address) for the certificate to be verified.
+			TlsConfig: &tls.Config{ServerName: wsURL.Hostname()},
+		})
 	if err != nil {
-		log.Error().Err(err).
-			Msg("Could not connect to websocket.")
-		return
+		return err
 	}
+	resp.Body.Close()
 	log.Trace().Caller().Msg("Websocket connection established.")
+	diagnostics.RecordWebsocketConnect()
+	defer diagnostics.RecordWebsocketDisconnect()
 
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		<-ctx.Done()
-		socket.WriteClose(1000, nil)
+		select {
+		case <-ctx.Done():
+			socket.WriteClose(1000, nil)
+		case <-done:
+		}
 	}()
 	socket.ReadLoop()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return ErrWebsocketClosed
 }
 
 type WebSocket struct {
-	notifyCh  chan [2]string
+	notifyCh  chan Notification
 	doneCh    chan struct{}
 	token     string
 	webhookID string
 	nextID    uint64
 }
 
-func newWebsocket(prefs *preferences.Preferences, notifyCh chan [2]string) *WebSocket {
+func newWebsocket(prefs *preferences.Preferences, notifyCh chan Notification) *WebSocket {
 	ws := &WebSocket{
 		notifyCh:  notifyCh,
 		doneCh:    make(chan struct{}),
@@ -177,7 +218,12 @@ func (c *WebSocket) OnMessage(socket *gws.Conn, message *gws.Message) {
 	var r *websocketMsg
 	switch response.Type {
 	case "event":
-		c.notifyCh <- [2]string{response.Notification.Title, response.Notification.Message}
+		data, _ := response.Notification.Data.(map[string]any)
+		c.notifyCh <- Notification{
+			Data:    data,
+			Title:   response.Notification.Title,
+			Message: response.Notification.Message,
+		}
 	case "result":
 		if !response.Success {
 			log.Error().
@@ -7,52 +7,112 @@ package hass
 
 import (
 	"context"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/grandcat/zeroconf"
 	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
-// FindServers is a helper function to generate a list of Home Assistant servers
-// via local network auto-discovery.
-func FindServers(ctx context.Context) []string {
-	var serverList []string
+const (
+	// DefaultServer is always offered as a fall-back/default server.
+	DefaultServer = "http://localhost:8123"
+
+	discoveryTimeout = 5 * time.Second
+)
 
-	// add http://localhost:8123 to the list of servers as a fall-back/default
-	serverList = append(serverList, "http://localhost:8123")
+// DiscoverServers looks for Home Assistant servers via local network
+// auto-discovery. The address of each server is sent on the returned channel as
+// soon as it is found, starting with DefaultServer. The channel is closed when
+// the search is finished.
+func DiscoverServers(ctx context.Context) <-chan string {
+	serverCh := make(chan string, 1)
+	serverCh <- DefaultServer
 
 	resolver, err := zeroconf.NewResolver(nil)
 	if err != nil {
 		log.Debug().Err(err).Msg("Failed to initialise resolver.")
-		return serverList
+		close(serverCh)
+		return serverCh
 	}
+
+	preferIP := preferences.FetchFromContext(ctx).PreferIP
+	searchCtx, searchCancel := context.WithTimeout(ctx, discoveryTimeout)
 	entries := make(chan *zeroconf.ServiceEntry)
-	go func(results <-chan *zeroconf.ServiceEntry) {
-		for entry := range results {
-			var server string
-			for _, t := range entry.Text {
-				if value, found := strings.CutPrefix(t, "base_url="); found {
-					server = value
+	go func() {
+		defer close(serverCh)
+		defer searchCancel()
+		found := map[string]bool{DefaultServer: true}
+		for {
+			select {
+			case <-searchCtx.Done():
+				return
+			case entry, ok := <-entries:
+				if !ok {
+					return
+				}
+				server := serverFromEntry(entry, preferIP)
+				switch {
+				case server == "":
+					log.Debug().Msgf("Entry %s did not have a base_url value or any address. Not using it.", entry.HostName)
+				case !found[server]:
+					found[server] = true
+					select {
+					case serverCh <- server:
+					case <-searchCtx.Done():
+						return
+					}
 				}
-			}
-			if server != "" {
-				serverList = append(serverList, server)
-			} else {
-				log.Debug().Msgf("Entry %s did not have a base_url value. Not using it.", entry.HostName)
 			}
 		}
-	}(entries)
+	}()
 
 	log.Info().Msg("Looking for Home Assistant instances on the network...")
-	searchCtx, searchCancel := context.WithTimeout(ctx, time.Second*5)
-	defer searchCancel()
-	err = resolver.Browse(searchCtx, "_home-assistant._tcp", "local.", entries)
-	if err != nil {
+	if err := resolver.Browse(searchCtx, "_home-assistant._tcp", "local.", entries); err != nil {
 		log.Debug().Err(err).Msg("Failed to browse")
+		searchCancel()
 	}
+	return serverCh
+}
 
-	<-searchCtx.Done()
+// serverFromEntry returns the address of the Home Assistant server advertised
+// in the given entry. The base_url value is used if present. Otherwise, the
+// address is built from the IP addresses of the entry, preferring the given IP
+// version ("ipv4" or "ipv6", defaulting to IPv4). IPv6 link-local addresses
+// are not used, as they cannot be used without a zone.
+func serverFromEntry(entry *zeroconf.ServiceEntry, preferIP string) string {
+	for _, t := range entry.Text {
+		if value, found := strings.CutPrefix(t, "base_url="); found && value != "" {
+			return value
+		}
+	}
+	if entry.Port == 0 {
+		return ""
+	}
+	addrs := append(slices.Clone(entry.AddrIPv4), entry.AddrIPv6...)
+	if preferIP == "ipv6" {
+		addrs = append(slices.Clone(entry.AddrIPv6), entry.AddrIPv4...)
+	}
+	for _, addr := range addrs {
+		if addr.IsLinkLocalUnicast() {
+			continue
+		}
+		return "http://" + net.JoinHostPort(addr.String(), strconv.Itoa(entry.Port))
+	}
+	return ""
+}
 
+// FindServers is a helper function to generate a list of Home Assistant servers
+// via local network auto-discovery. It blocks until the search is finished.
+func FindServers(ctx context.Context) []string {
+	var serverList []string
+	for server := range DiscoverServers(ctx) {
+		serverList = append(serverList, server)
+	}
 	return serverList
 }
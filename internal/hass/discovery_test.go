@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hass
+
+import (
+	"net"
+	"testing"
+
+	"github.com/grandcat/zeroconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_serverFromEntry(t *testing.T) {
+	newEntry := func(text []string, v4, v6 []net.IP) *zeroconf.ServiceEntry {
+		e := zeroconf.NewServiceEntry("Home", "_home-assistant._tcp", "local.")
+		e.Port = 8123
+		e.Text = text
+		e.AddrIPv4 = v4
+		e.AddrIPv6 = v6
+		return e
+	}
+	v4 := []net.IP{net.ParseIP("192.168.1.10")}
+	v6 := []net.IP{net.ParseIP("fe80::1"), net.ParseIP("2001:db8::10")}
+
+	tests := []struct {
+		entry    *zeroconf.ServiceEntry
+		name     string
+		preferIP string
+		want     string
+	}{
+		{
+			name:  "base_url",
+			entry: newEntry([]string{"base_url=http://homeassistant.local:8123"}, v4, v6),
+			want:  "http://homeassistant.local:8123",
+		},
+		{
+			name:  "dual-stack",
+			entry: newEntry(nil, v4, v6),
+			want:  "http://192.168.1.10:8123",
+		},
+		{
+			name:     "dual-stack, prefer IPv6",
+			entry:    newEntry(nil, v4, v6),
+			preferIP: "ipv6",
+			want:     "http://[2001:db8::10]:8123",
+		},
+		{
+			name:  "IPv6 only",
+			entry: newEntry(nil, nil, v6),
+			want:  "http://[2001:db8::10]:8123",
+		},
+		{
+			name:  "link-local only",
+			entry: newEntry(nil, nil, v6[:1]),
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, serverFromEntry(tt.entry, tt.preferIP))
+		})
+	}
+}
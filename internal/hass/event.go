@@ -0,0 +1,31 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hass
+
+import (
+	"encoding/json"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
+)
+
+// Event represents an event that can be fired on the Home Assistant event bus,
+// for use in automations.
+type Event struct {
+	EventData any    `json:"event_data,omitempty"`
+	EventType string `json:"event_type"`
+}
+
+func (e *Event) RequestType() api.RequestType {
+	return api.RequestTypeFireEvent
+}
+
+func (e *Event) RequestData() json.RawMessage {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(data)
+}
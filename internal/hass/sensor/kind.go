@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package sensor
+
+import "strings"
+
+// Kind is the kind of value a sensor reports, such as a number of bytes or a
+// temperature. The device class, state class and units that Home Assistant
+// expects for the kind can be inferred with Infer, so that they do not need to
+// be looked up in the Home Assistant documentation.
+type Kind string
+
+const (
+	KindBytes          Kind = "bytes"            // Data size, in bytes (B).
+	KindBytesPerSecond Kind = "bytes_per_second" // Data rate, in bytes per second (B/s).
+	KindPercent        Kind = "percent"          // Percentage (%).
+	KindTemperature    Kind = "temperature"      // Temperature, in degrees Celsius (°C).
+	KindSeconds        Kind = "seconds"          // Duration, in seconds (s).
+	KindWatts          Kind = "watts"            // Power, in watts (W).
+	KindKilowattHours  Kind = "kilowatt_hours"   // Energy used, in kilowatt-hours (kWh), which only increases.
+	KindVolts          Kind = "volts"            // Voltage, in volts (V).
+	KindHertz          Kind = "hertz"            // Frequency, in hertz (Hz).
+	KindCount          Kind = "count"            // A count of something that can go up or down.
+	KindCounter        Kind = "counter"          // A count of something that only increases.
+)
+
+// Inferred holds the device class, state class and units inferred for a
+// sensor.
+type Inferred struct {
+	Units       string
+	DeviceClass SensorDeviceClass
+	StateClass  SensorStateClass
+}
+
+var kinds = map[Kind]Inferred{
+	KindBytes:          {DeviceClass: Data_size, StateClass: StateMeasurement, Units: "B"},
+	KindBytesPerSecond: {DeviceClass: Data_rate, StateClass: StateMeasurement, Units: "B/s"},
+	KindPercent:        {StateClass: StateMeasurement, Units: "%"},
+	KindTemperature:    {DeviceClass: SensorTemperature, StateClass: StateMeasurement, Units: "°C"},
+	KindSeconds:        {DeviceClass: Duration, StateClass: StateMeasurement, Units: "s"},
+	KindWatts:          {DeviceClass: SensorPower, StateClass: StateMeasurement, Units: "W"},
+	KindKilowattHours:  {DeviceClass: Energy, StateClass: StateTotalIncreasing, Units: "kWh"},
+	KindVolts:          {DeviceClass: Voltage, StateClass: StateMeasurement, Units: "V"},
+	KindHertz:          {DeviceClass: Frequency, StateClass: StateMeasurement, Units: "Hz"},
+	KindCount:          {StateClass: StateMeasurement},
+	KindCounter:        {StateClass: StateTotalIncreasing},
+}
+
+// unitKinds maps commonly used units to the kind of value they measure, for
+// inferring the device class from the units alone.
+var unitKinds = map[string]Kind{
+	"B":   KindBytes,
+	"B/s": KindBytesPerSecond,
+	"%":   KindPercent,
+	"°C":  KindTemperature,
+	"s":   KindSeconds,
+	"W":   KindWatts,
+	"kWh": KindKilowattHours,
+	"V":   KindVolts,
+	"Hz":  KindHertz,
+}
+
+// Infer returns the device class, state class and units for the given kind of
+// value. It returns false if the kind is not known.
+func Infer(kind Kind) (Inferred, bool) {
+	i, ok := kinds[Kind(strings.ToLower(string(kind)))]
+	return i, ok
+}
+
+// InferFromUnits returns the device class and state class for a value in the
+// given units (e.g., °C or kWh). It returns false if the units are not known.
+func InferFromUnits(units string) (Inferred, bool) {
+	kind, ok := unitKinds[units]
+	if !ok {
+		return Inferred{}, false
+	}
+	return Infer(kind)
+}
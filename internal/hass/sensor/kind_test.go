@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package sensor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   Kind
+		want   Inferred
+		wantOK bool
+	}{
+		{
+			name:   "bytes",
+			kind:   KindBytes,
+			want:   Inferred{DeviceClass: Data_size, StateClass: StateMeasurement, Units: "B"},
+			wantOK: true,
+		},
+		{
+			name:   "percent",
+			kind:   KindPercent,
+			want:   Inferred{StateClass: StateMeasurement, Units: "%"},
+			wantOK: true,
+		},
+		{
+			name:   "upper case",
+			kind:   "Temperature",
+			want:   Inferred{DeviceClass: SensorTemperature, StateClass: StateMeasurement, Units: "°C"},
+			wantOK: true,
+		},
+		{
+			name:   "energy only increases",
+			kind:   KindKilowattHours,
+			want:   Inferred{DeviceClass: Energy, StateClass: StateTotalIncreasing, Units: "kWh"},
+			wantOK: true,
+		},
+		{
+			name: "unknown",
+			kind: "furlongs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Infer(tt.kind)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInferFromUnits(t *testing.T) {
+	got, ok := InferFromUnits("s")
+	assert.True(t, ok)
+	assert.Equal(t, Inferred{DeviceClass: Duration, StateClass: StateMeasurement, Units: "s"}, got)
+
+	_, ok = InferFromUnits("widgets")
+	assert.False(t, ok)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hass
+
+import (
+	"encoding/json"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
+)
+
+// ServiceCall represents a call to a Home Assistant service, made through the
+// mobile app webhook.
+type ServiceCall struct {
+	ServiceData any    `json:"service_data,omitempty"`
+	Domain      string `json:"domain"`
+	Service     string `json:"service"`
+}
+
+func (c *ServiceCall) RequestType() api.RequestType {
+	return api.RequestTypeCallService
+}
+
+func (c *ServiceCall) RequestData() json.RawMessage {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+// NewPersistentNotification creates a service call that will create (or
+// replace, if the ID matches an existing notification) a persistent
+// notification in Home Assistant.
+func NewPersistentNotification(id, title, message string) *ServiceCall {
+	return &ServiceCall{
+		Domain:  "persistent_notification",
+		Service: "create",
+		ServiceData: struct {
+			ID      string `json:"notification_id,omitempty"`
+			Title   string `json:"title,omitempty"`
+			Message string `json:"message"`
+		}{
+			ID:      id,
+			Title:   title,
+			Message: message,
+		},
+	}
+}
+
+// NewDismissPersistentNotification creates a service call that will dismiss
+// the persistent notification with the given ID in Home Assistant.
+func NewDismissPersistentNotification(id string) *ServiceCall {
+	return &ServiceCall{
+		Domain:  "persistent_notification",
+		Service: "dismiss",
+		ServiceData: struct {
+			ID string `json:"notification_id"`
+		}{
+			ID: id,
+		},
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package apps
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	gnomeIntrospectPath   = "/org/gnome/Shell/Introspect"
+	gnomeIntrospectDest   = "org.gnome.Shell"
+	gnomeIntrospectMethod = "org.gnome.Shell.Introspect.GetWindows"
+
+	dataSrcX11 = "X11"
+)
+
+var (
+	ErrNoActiveWindow = errors.New("could not determine active window")
+
+	xpropWindowID = regexp.MustCompile(`window id # (0x[0-9a-f]+)`)
+	xpropName     = regexp.MustCompile(`_NET_WM_NAME = "(.*)"`)
+	xpropClass    = regexp.MustCompile(`WM_CLASS = "[^"]*", "(.*)"`)
+)
+
+type activeWindow struct {
+	title  string
+	app    string
+	source string
+}
+
+type activeWindowSensor struct {
+	window *activeWindow
+	linux.Sensor
+}
+
+func (s *activeWindowSensor) Attributes() any {
+	return struct {
+		Application string `json:"Application,omitempty"`
+		DataSource  string `json:"Data Source"`
+	}{
+		Application: s.window.app,
+		DataSource:  s.window.source,
+	}
+}
+
+func newActiveWindowSensor(w *activeWindow) *activeWindowSensor {
+	s := &activeWindowSensor{window: w}
+	s.SensorTypeValue = linux.SensorActiveWindow
+	s.IconString = "mdi:application-outline"
+	s.Value = w.title
+	return s
+}
+
+// getGnomeActiveWindow uses the GNOME Shell introspection interface to find
+// the focused window. This interface is only available to some applications
+// unless GNOME Shell is running in unsafe mode.
+func getGnomeActiveWindow(ctx context.Context) (*activeWindow, error) {
+	data := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(gnomeIntrospectPath).
+		Destination(gnomeIntrospectDest).
+		GetData(gnomeIntrospectMethod).
+		AsRawInterface()
+	windows, ok := data.(map[uint64]map[string]dbus.Variant)
+	if !ok {
+		return nil, ErrNoActiveWindow
+	}
+	for _, w := range windows {
+		if focus, ok := w["has-focus"]; !ok || !dbusx.VariantToValue[bool](focus) {
+			continue
+		}
+		app := dbusx.VariantToValue[string](w["app-id"])
+		if app == "" {
+			app = dbusx.VariantToValue[string](w["wm-class"])
+		}
+		return &activeWindow{
+			title:  dbusx.VariantToValue[string](w["title"]),
+			app:    app,
+			source: linux.DataSrcDbus,
+		}, nil
+	}
+	return nil, ErrNoActiveWindow
+}
+
+// getX11ActiveWindow uses xprop to find the focused window on X11 (or
+// XWayland) desktops that follow the EWMH specification.
+func getX11ActiveWindow(ctx context.Context) (*activeWindow, error) {
+	if os.Getenv("DISPLAY") == "" {
+		return nil, ErrNoActiveWindow
+	}
+	xprop, err := exec.LookPath("xprop")
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.CommandContext(ctx, xprop, "-root", "_NET_ACTIVE_WINDOW").Output()
+	if err != nil {
+		return nil, err
+	}
+	id := xpropWindowID.FindSubmatch(out)
+	if id == nil || string(id[1]) == "0x0" {
+		return nil, ErrNoActiveWindow
+	}
+	out, err = exec.CommandContext(ctx, xprop, "-id", string(id[1]), "_NET_WM_NAME", "WM_CLASS").Output()
+	if err != nil {
+		return nil, err
+	}
+	w := &activeWindow{source: dataSrcX11}
+	if m := xpropName.FindSubmatch(out); m != nil {
+		w.title = string(m[1])
+	}
+	if m := xpropClass.FindSubmatch(out); m != nil {
+		w.app = string(m[1])
+	}
+	return w, nil
+}
+
+func getActiveWindow(ctx context.Context) (*activeWindow, error) {
+	if w, err := getGnomeActiveWindow(ctx); err == nil {
+		return w, nil
+	}
+	return getX11ActiveWindow(ctx)
+}
+
+// ActiveWindowUpdater reports the title and application of the focused window.
+// As window titles can contain personal information, it only runs if the user
+// has opted in via the preferences.
+func ActiveWindowUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	if !preferences.FetchFromContext(ctx).ActiveWindow {
+		log.Debug().Msg("Active window sensor not enabled in preferences.")
+		close(sensorCh)
+		return sensorCh
+	}
+	getWindow := getActiveWindow
+	// On KDE Plasma, KWin reports the focused window when it changes.
+	kwin, err := watchKWinActiveWindow(ctx)
+	if err == nil {
+		getWindow = func(_ context.Context) (*activeWindow, error) {
+			return kwin.get()
+		}
+	} else if !errors.Is(err, ErrNotKDE) {
+		log.Debug().Err(err).Msg("Could not watch KWin for the active window. Falling back to X11.")
+	}
+
+	var mu sync.Mutex
+	var last activeWindow
+	sendActiveWindow := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		w, err := getWindow(ctx)
+		if err != nil {
+			log.Trace().Err(err).Msg("Could not retrieve active window.")
+			return
+		}
+		if *w == last {
+			return
+		}
+		last = *w
+		select {
+		case sensorCh <- newActiveWindowSensor(w):
+		case <-ctx.Done():
+		}
+	}
+
+	if kwin != nil {
+		go func() {
+			for range kwin.changed {
+				sendActiveWindow(0)
+			}
+		}()
+	} else {
+		go helpers.PollSensors(ctx, sendActiveWindow, 5*time.Second, time.Second)
+	}
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped active window sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	kwinDest              = "org.kde.KWin"
+	kwinScriptingPath     = "/Scripting"
+	kwinScriptingIntr     = "org.kde.kwin.Scripting"
+	kwinLoadScriptMethod  = kwinScriptingIntr + ".loadScript"
+	kwinUnloadScriptMthd  = kwinScriptingIntr + ".unloadScript"
+	kwinStartScriptMethod = kwinScriptingIntr + ".start"
+	kwinScriptName        = "go-hass-agent-active-window"
+
+	activeWindowPath = "/com/github/joshuar/GoHassAgent/ActiveWindow"
+	activeWindowIntr = "com.github.joshuar.GoHassAgent.ActiveWindow"
+
+	dataSrcKWin = "D-Bus (KWin)"
+)
+
+var ErrNotKDE = errors.New("not running under KDE Plasma")
+
+// kwinScript is run by KWin to report the focused window, and any change of
+// its title, to the agent. KDE Plasma 5 and 6 use different names for the
+// active window. The format verbs are the destination, path and interface of
+// the agent's D-Bus object.
+const kwinScript = `
+var current = null;
+function update() {
+	var title = current ? current.caption : "";
+	var app = current ? String(current.resourceClass) : "";
+	callDBus(%[1]q, %[2]q, %[3]q, "Update", title, app);
+}
+function activated(w) {
+	if (current) {
+		current.captionChanged.disconnect(update);
+	}
+	current = w;
+	if (current) {
+		current.captionChanged.connect(update);
+	}
+	update();
+}
+if (workspace.windowActivated) {
+	workspace.windowActivated.connect(activated);
+	activated(workspace.activeWindow);
+} else {
+	workspace.clientActivated.connect(activated);
+	activated(workspace.activeClient);
+}
+`
+
+// kwinActiveWindow tracks the focused window on KDE Plasma, as reported by a
+// script loaded into KWin. Its Update method is called by the script over
+// D-Bus.
+type kwinActiveWindow struct {
+	window  *activeWindow
+	changed chan struct{}
+	mu      sync.Mutex
+	stopped bool
+}
+
+// Update records the focused window. An empty title and app means no window
+// has focus.
+func (k *kwinActiveWindow) Update(title, app string) *dbus.Error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if title == "" && app == "" {
+		k.window = nil
+	} else {
+		k.window = &activeWindow{title: title, app: app, source: dataSrcKWin}
+	}
+	if k.stopped {
+		return nil
+	}
+	select {
+	case k.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (k *kwinActiveWindow) get() (*activeWindow, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.window == nil {
+		return nil, ErrNoActiveWindow
+	}
+	w := *k.window
+	return &w, nil
+}
+
+// watchKWinActiveWindow loads a script into KWin that reports the focused
+// window whenever it changes. The script is unloaded when the context is
+// canceled, after which the changed channel is closed.
+func watchKWinActiveWindow(ctx context.Context) (*kwinActiveWindow, error) {
+	if !strings.Contains(os.Getenv("XDG_CURRENT_DESKTOP"), "KDE") {
+		return nil, ErrNotKDE
+	}
+	k := &kwinActiveWindow{changed: make(chan struct{}, 1)}
+	exportCtx, cancelExport := context.WithCancel(ctx)
+	dest, err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(activeWindowPath).
+		Export(exportCtx, k, activeWindowIntr)
+	if err != nil {
+		cancelExport()
+		return nil, fmt.Errorf("could not export active window object: %w", err)
+	}
+
+	script, err := os.CreateTemp("", kwinScriptName+"-*.js")
+	if err != nil {
+		cancelExport()
+		return nil, err
+	}
+	stop := func() {
+		cancelExport()
+		os.Remove(script.Name())
+	}
+	_, err = fmt.Fprintf(script, kwinScript, dest, activeWindowPath, activeWindowIntr)
+	if closeErr := script.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	scripting := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(kwinScriptingPath).
+		Destination(kwinDest)
+	// A script left loaded by an agent that did not stop cleanly would
+	// prevent loading it again.
+	if err := scripting.Call(kwinUnloadScriptMthd, kwinScriptName); err != nil {
+		stop()
+		return nil, fmt.Errorf("could not access KWin scripting: %w", err)
+	}
+	loaded := scripting.GetData(kwinLoadScriptMethod, script.Name(), kwinScriptName)
+	if loaded == nil || loaded.Err() != nil {
+		stop()
+		return nil, errors.New("could not load KWin script")
+	}
+	if err := scripting.Call(kwinStartScriptMethod); err != nil {
+		stop()
+		return nil, fmt.Errorf("could not start KWin script: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		// The bus in the context stays connected until the agent exits, so
+		// it can still be used to unload the script.
+		if err := scripting.Call(kwinUnloadScriptMthd, kwinScriptName); err != nil {
+			log.Debug().Err(err).Msg("Could not unload KWin script.")
+		}
+		stop()
+		k.mu.Lock()
+		k.stopped = true
+		close(k.changed)
+		k.mu.Unlock()
+	}()
+	return k, nil
+}
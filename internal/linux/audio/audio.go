@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package audio controls the volume and mute state of the default audio output
+// (sink), using the PulseAudio (pactl) or PipeWire (wpctl) command-line tools.
+// pactl also works with PipeWire, through pipewire-pulse. It also speaks text
+// through the speakers, with speech-dispatcher or piper.
+package audio
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	pactlSink = "@DEFAULT_SINK@"
+	wpctlSink = "@DEFAULT_AUDIO_SINK@"
+)
+
+var (
+	// ErrNoAudio is returned when neither pactl nor wpctl is available.
+	ErrNoAudio = errors.New("no pactl or wpctl command found")
+
+	// ErrNotSupported is returned for controls that need pactl when only
+	// wpctl is available.
+	ErrNotSupported = errors.New("not supported without pactl")
+
+	errInvalidOutput = errors.New("could not parse audio control output")
+
+	pactlVolumeRe = regexp.MustCompile(`(\d+)%`)
+	wpctlVolumeRe = regexp.MustCompile(`Volume:\s+([\d.]+)`)
+)
+
+// Control controls the default audio sink.
+type Control struct {
+	pactl string
+	wpctl string
+}
+
+// NewControl returns a control for the default audio sink, preferring pactl
+// over wpctl. It returns ErrNoAudio if neither is available.
+func NewControl() (*Control, error) {
+	c := &Control{}
+	if path, err := exec.LookPath("pactl"); err == nil {
+		c.pactl = path
+	} else if path, err := exec.LookPath("wpctl"); err == nil {
+		c.wpctl = path
+	} else {
+		return nil, ErrNoAudio
+	}
+	return c, nil
+}
+
+func (c *Control) run(ctx context.Context, args ...string) (string, error) {
+	cmd := c.pactl
+	if cmd == "" {
+		cmd = c.wpctl
+	}
+	command := exec.CommandContext(ctx, cmd, args...)
+	// The output is parsed, so it must not be translated.
+	command.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := command.Output()
+	return string(out), err
+}
+
+// Volume returns the volume of the default sink as a percentage. For sinks
+// with more than one channel, the volume of the first channel is returned.
+func (c *Control) Volume(ctx context.Context) (int, error) {
+	if c.pactl != "" {
+		out, err := c.run(ctx, "get-sink-volume", pactlSink)
+		if err != nil {
+			return 0, err
+		}
+		m := pactlVolumeRe.FindStringSubmatch(out)
+		if m == nil {
+			return 0, errInvalidOutput
+		}
+		return strconv.Atoi(m[1])
+	}
+	out, err := c.run(ctx, "get-volume", wpctlSink)
+	if err != nil {
+		return 0, err
+	}
+	m := wpctlVolumeRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, errInvalidOutput
+	}
+	volume, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Round(volume * 100)), nil
+}
+
+// SetVolume sets the volume of all channels of the default sink to the given
+// percentage.
+func (c *Control) SetVolume(ctx context.Context, percent int) error {
+	percent = min(max(percent, 0), 100)
+	if c.pactl != "" {
+		_, err := c.run(ctx, "set-sink-volume", pactlSink, strconv.Itoa(percent)+"%")
+		return err
+	}
+	_, err := c.run(ctx, "set-volume", wpctlSink, strconv.Itoa(percent)+"%")
+	return err
+}
+
+// Muted returns whether the default sink is muted.
+func (c *Control) Muted(ctx context.Context) (bool, error) {
+	if c.pactl != "" {
+		out, err := c.run(ctx, "get-sink-mute", pactlSink)
+		if err != nil {
+			return false, err
+		}
+		_, value, ok := strings.Cut(out, ":")
+		if !ok {
+			return false, errInvalidOutput
+		}
+		return strings.TrimSpace(value) == "yes", nil
+	}
+	out, err := c.run(ctx, "get-volume", wpctlSink)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(out, "[MUTED]"), nil
+}
+
+// SetMuted mutes or unmutes the default sink.
+func (c *Control) SetMuted(ctx context.Context, muted bool) error {
+	value := "0"
+	if muted {
+		value = "1"
+	}
+	if c.pactl != "" {
+		_, err := c.run(ctx, "set-sink-mute", pactlSink, value)
+		return err
+	}
+	_, err := c.run(ctx, "set-mute", wpctlSink, value)
+	return err
+}
+
+// Sink is an audio output.
+type Sink struct {
+	// Name is the name used to select the sink.
+	Name string
+	// Description is the name of the sink shown to users, e.g., Built-in
+	// Audio Analog Stereo.
+	Description string
+}
+
+// Sinks returns the available audio sinks. It needs pactl, returning
+// ErrNotSupported otherwise.
+func (c *Control) Sinks(ctx context.Context) ([]Sink, error) {
+	if c.pactl == "" {
+		return nil, ErrNotSupported
+	}
+	out, err := c.run(ctx, "list", "sinks")
+	if err != nil {
+		return nil, err
+	}
+	return parseSinks(out), nil
+}
+
+// parseSinks parses the output of pactl list sinks.
+func parseSinks(out string) []Sink {
+	var sinks []Sink
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			sinks = append(sinks, Sink{Name: value, Description: value})
+		case "Description":
+			if len(sinks) > 0 {
+				sinks[len(sinks)-1].Description = value
+			}
+		}
+	}
+	return sinks
+}
+
+// DefaultSink returns the name of the default sink. It needs pactl, returning
+// ErrNotSupported otherwise.
+func (c *Control) DefaultSink(ctx context.Context) (string, error) {
+	if c.pactl == "" {
+		return "", ErrNotSupported
+	}
+	out, err := c.run(ctx, "get-default-sink")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// SetDefaultSink sets the default sink to the sink with the given name. It
+// needs pactl, returning ErrNotSupported otherwise.
+func (c *Control) SetDefaultSink(ctx context.Context, name string) error {
+	if c.pactl == "" {
+		return ErrNotSupported
+	}
+	_, err := c.run(ctx, "set-default-sink", name)
+	return err
+}
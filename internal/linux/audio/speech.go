@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package audio
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// ErrNoSpeech is returned when no text-to-speech engine is available.
+var ErrNoSpeech = errors.New("no speech-dispatcher (spd-say) or piper found")
+
+// Speaker speaks text through the local speakers, using piper if a voice model
+// is given, or speech-dispatcher otherwise.
+type Speaker struct {
+	spdSay string
+	piper  string
+	player string
+	model  string
+	mu     sync.Mutex
+}
+
+// NewSpeaker returns a speaker for the available text-to-speech engine. Piper
+// is used if model is the path to a piper voice model and both piper and an
+// audio player (paplay, pw-play or aplay) are installed. It returns
+// ErrNoSpeech if no engine is available.
+func NewSpeaker(model string) (*Speaker, error) {
+	s := &Speaker{}
+	if model != "" {
+		if path, err := exec.LookPath("piper"); err == nil {
+			for _, player := range []string{"paplay", "pw-play", "aplay"} {
+				if playerPath, err := exec.LookPath(player); err == nil {
+					s.piper, s.player, s.model = path, playerPath, model
+					return s, nil
+				}
+			}
+		}
+	}
+	if path, err := exec.LookPath("spd-say"); err == nil {
+		s.spdSay = path
+		return s, nil
+	}
+	return nil, ErrNoSpeech
+}
+
+// Speak speaks the text, returning when it has finished. Text is spoken one
+// message at a time, so messages sent while another is being spoken are
+// queued.
+func (s *Speaker) Speak(ctx context.Context, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.piper == "" {
+		return exec.CommandContext(ctx, s.spdSay, "--wait", "--", text).Run()
+	}
+	f, err := os.CreateTemp("", "go-hass-agent-speech-*.wav")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	piper := exec.CommandContext(ctx, s.piper, "--model", s.model, "--output_file", f.Name())
+	piper.Stdin = strings.NewReader(text)
+	if err := piper.Run(); err != nil {
+		return err
+	}
+	return exec.CommandContext(ctx, s.player, f.Name()).Run()
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package backup
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	systemdDest    = "org.freedesktop.systemd1"
+	systemdPath    = "/org/freedesktop/systemd1"
+	managerIntr    = "org.freedesktop.systemd1.Manager"
+	unitIntr       = "org.freedesktop.systemd1.Unit"
+	serviceIntr    = "org.freedesktop.systemd1.Service"
+	managerSystem  = "system"
+	managerUser    = "user"
+	resultSuccess  = "success"
+	resultRunning  = "running"
+	backupInterval = time.Minute
+)
+
+// defaultUnits are the patterns of the systemd services commonly used to run
+// borg, borgmatic and restic backups, including those created by NixOS.
+var defaultUnits = []string{
+	"borgmatic*.service",
+	"borgbackup-job-*.service",
+	"borg-*.service",
+	"restic*.service",
+	"backup*.service",
+}
+
+// backupJob is the state of the systemd service running a backup.
+type backupJob struct {
+	lastSuccess time.Time
+	lastRun     time.Time
+	Unit        string `json:"Unit"`
+	Manager     string `json:"Manager"`
+	Result      string `json:"Result"`
+	ExitStatus  int32  `json:"Exit Status"`
+	Running     bool   `json:"Running"`
+}
+
+// name returns the backup job name, which is the unit name without the
+// .service suffix.
+func (j *backupJob) name() string {
+	return strings.TrimSuffix(j.Unit, ".service")
+}
+
+type backupSensor struct {
+	job *backupJob
+	linux.Sensor
+}
+
+func (s *backupSensor) Name() string {
+	return "Backup " + s.job.name() + " " + s.SensorTypeValue.String()
+}
+
+func (s *backupSensor) ID() string {
+	return strcase.ToSnake("backup_" + s.job.name() + "_" + s.SensorTypeValue.String())
+}
+
+func (s *backupSensor) Icon() string {
+	switch {
+	case s.job.Running:
+		return "mdi:backup-restore"
+	case s.job.Result == resultSuccess:
+		return "mdi:cloud-check-outline"
+	default:
+		return "mdi:cloud-alert-outline"
+	}
+}
+
+func (s *backupSensor) Attributes() any {
+	return struct {
+		*backupJob
+		DataSource string `json:"Data Source"`
+	}{
+		backupJob:  s.job,
+		DataSource: linux.DataSrcDbus,
+	}
+}
+
+func newBackupSensors(job *backupJob) []tracker.Sensor {
+	var sensors []tracker.Sensor
+	// Only report the last success once known. When the last run failed and
+	// the agent has not seen a successful run since it started, Home
+	// Assistant keeps the last reported time.
+	if !job.lastSuccess.IsZero() {
+		success := &backupSensor{job: job}
+		success.SensorTypeValue = linux.SensorBackupLastSuccess
+		success.DeviceClassValue = sensor.Timestamp
+		success.Value = job.lastSuccess.Format(time.RFC3339)
+		sensors = append(sensors, success)
+	}
+	result := &backupSensor{job: job}
+	result.SensorTypeValue = linux.SensorBackupResult
+	result.IsDiagnostic = true
+	result.Value = job.Result
+	if job.Running {
+		result.Value = resultRunning
+	}
+	return append(sensors, result)
+}
+
+// listUnits returns the names and object paths of the loaded units of the
+// given systemd manager (system or user) matching the given patterns.
+func listUnits(ctx context.Context, manager string, patterns []string) map[string]dbus.ObjectPath {
+	units := make(map[string]dbus.ObjectPath)
+	req := dbusx.NewBusRequest(ctx, dbusx.SystemBus)
+	if manager == managerUser {
+		req = dbusx.NewBusRequest(ctx, dbusx.SessionBus)
+	}
+	data := req.Path(systemdPath).
+		Destination(systemdDest).
+		GetData(managerIntr+".ListUnitsByPatterns", []string{}, patterns).
+		AsRawInterface()
+	list, ok := data.([][]any)
+	if !ok {
+		return units
+	}
+	for _, u := range list {
+		if len(u) < 7 {
+			continue
+		}
+		name, ok := u[0].(string)
+		path, pathOK := u[6].(dbus.ObjectPath)
+		if ok && pathOK {
+			units[name] = path
+		}
+	}
+	return units
+}
+
+// update refreshes the job from the properties of its systemd service. The
+// exit timestamp is the last time the service finished, so is the time of the
+// last successful backup if the service succeeded.
+func (j *backupJob) update(ctx context.Context, path dbus.ObjectPath) error {
+	req := dbusx.NewBusRequest(ctx, dbusx.SystemBus)
+	if j.Manager == managerUser {
+		req = dbusx.NewBusRequest(ctx, dbusx.SessionBus)
+	}
+	req = req.Path(path).Destination(systemdDest)
+	result, err := req.GetProp(serviceIntr + ".Result")
+	if err != nil {
+		return err
+	}
+	j.Result = dbusx.VariantToValue[string](result)
+	if state, err := req.GetProp(unitIntr + ".ActiveState"); err == nil {
+		j.Running = dbusx.VariantToValue[string](state) == "activating"
+	}
+	if status, err := req.GetProp(serviceIntr + ".ExecMainStatus"); err == nil {
+		j.ExitStatus = dbusx.VariantToValue[int32](status)
+	}
+	if exited, err := req.GetProp(serviceIntr + ".ExecMainExitTimestamp"); err == nil {
+		if usec := dbusx.VariantToValue[uint64](exited); usec > 0 {
+			j.lastRun = time.UnixMicro(int64(usec))
+		}
+	}
+	if j.Result == resultSuccess && !j.Running && !j.lastRun.IsZero() {
+		j.lastSuccess = j.lastRun
+	}
+	return nil
+}
+
+// Updater reports the time of the last successful backup and the result of the
+// last backup for each backup service managed by systemd, either system-wide
+// or for the user. The services to watch can be set with the
+// sensors.backupunits preference, as a list of unit names or patterns.
+func Updater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	patterns := preferences.FetchFromContext(ctx).BackupUnits
+	if len(patterns) == 0 {
+		patterns = defaultUnits
+	}
+
+	jobs := make(map[string]*backupJob)
+	var mu sync.Mutex
+	sendBackupSensors := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, manager := range []string{managerSystem, managerUser} {
+			for unit, path := range listUnits(ctx, manager, patterns) {
+				key := manager + "/" + unit
+				job, ok := jobs[key]
+				if !ok {
+					job = &backupJob{Unit: unit, Manager: manager}
+					jobs[key] = job
+				}
+				if err := job.update(ctx, path); err != nil {
+					log.Debug().Err(err).Str("unit", unit).Msg("Could not get backup service state.")
+					continue
+				}
+				// Send a copy, as the job is updated on the next poll.
+				state := *job
+				for _, s := range newBackupSensors(&state) {
+					if ctx.Err() != nil {
+						return
+					}
+					select {
+					case sensorCh <- s:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendBackupSensors, backupInterval, time.Second*5)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped backup sensors.")
+	}()
+	return sensorCh
+}
@@ -32,16 +32,24 @@ const (
 
 // dBusSensorToProps is a map of battery sensors to their D-Bus properties.
 var dBusSensorToProps = map[linux.SensorTypeValue]string{
-	linux.SensorBattType:       upowerDBusDeviceDest + ".Type",
-	linux.SensorBattPercentage: upowerDBusDeviceDest + ".Percentage",
-	linux.SensorBattTemp:       upowerDBusDeviceDest + ".Temperature",
-	linux.SensorBattVoltage:    upowerDBusDeviceDest + ".Voltage",
-	linux.SensorBattEnergy:     upowerDBusDeviceDest + ".Energy",
-	linux.SensorBattEnergyRate: upowerDBusDeviceDest + ".EnergyRate",
-	linux.SensorBattState:      upowerDBusDeviceDest + ".State",
-	linux.SensorBattNativePath: upowerDBusDeviceDest + ".NativePath",
-	linux.SensorBattLevel:      upowerDBusDeviceDest + ".BatteryLevel",
-	linux.SensorBattModel:      upowerDBusDeviceDest + ".Model",
+	linux.SensorBattType:        upowerDBusDeviceDest + ".Type",
+	linux.SensorBattPercentage:  upowerDBusDeviceDest + ".Percentage",
+	linux.SensorBattTemp:        upowerDBusDeviceDest + ".Temperature",
+	linux.SensorBattVoltage:     upowerDBusDeviceDest + ".Voltage",
+	linux.SensorBattEnergy:      upowerDBusDeviceDest + ".Energy",
+	linux.SensorBattEnergyRate:  upowerDBusDeviceDest + ".EnergyRate",
+	linux.SensorBattState:       upowerDBusDeviceDest + ".State",
+	linux.SensorBattNativePath:  upowerDBusDeviceDest + ".NativePath",
+	linux.SensorBattLevel:       upowerDBusDeviceDest + ".BatteryLevel",
+	linux.SensorBattModel:       upowerDBusDeviceDest + ".Model",
+	linux.SensorBattHealth:      upowerDBusDeviceDest + ".Capacity",
+	linux.SensorBattCycles:      upowerDBusDeviceDest + ".ChargeCycles",
+	linux.SensorBattTimeToEmpty: upowerDBusDeviceDest + ".TimeToEmpty",
+	linux.SensorBattTimeToFull:  upowerDBusDeviceDest + ".TimeToFull",
+	// Charging power is derived from the energy rate and charging state.
+	linux.SensorBattChargingPower: upowerDBusDeviceDest + ".EnergyRate",
+	// Whether a UPS is on battery is derived from the charging state.
+	linux.SensorUPSOnBattery: upowerDBusDeviceDest + ".State",
 }
 
 // dBusPropToSensor provides a map for to convert D-Bus properties to sensors.
@@ -53,6 +61,10 @@ var dBusPropToSensor = map[string]linux.SensorTypeValue{
 	"Temperatute":  linux.SensorBattTemp,
 	"State":        linux.SensorBattState,
 	"BatteryLevel": linux.SensorBattLevel,
+	"Capacity":     linux.SensorBattHealth,
+	"ChargeCycles": linux.SensorBattCycles,
+	"TimeToEmpty":  linux.SensorBattTimeToEmpty,
+	"TimeToFull":   linux.SensorBattTimeToFull,
 }
 
 type upowerBattery struct {
@@ -68,10 +80,39 @@ func (b *upowerBattery) getProp(ctx context.Context, t linux.SensorTypeValue) (d
 	if !b.dBusPath.IsValid() {
 		return dbus.MakeVariant(""), errors.New("invalid battery path")
 	}
+	return b.getNamedProp(ctx, dBusSensorToProps[t])
+}
+
+// getNamedProp retrieves the named property of the battery from D-Bus.
+func (b *upowerBattery) getNamedProp(ctx context.Context, prop string) (dbus.Variant, error) {
 	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
 		Path(b.dBusPath).
 		Destination(upowerDBusDest).
-		GetProp(dBusSensorToProps[t])
+		GetProp(prop)
+}
+
+// hasSensor returns whether the given sensor is monitored for the battery.
+func (b *upowerBattery) hasSensor(t linux.SensorTypeValue) bool {
+	for _, s := range b.sensors {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// chargingPower returns the power being used to charge the battery. This is
+// the energy rate while charging and 0 otherwise.
+func (b *upowerBattery) chargingPower(ctx context.Context) float64 {
+	state, err := b.getProp(ctx, linux.SensorBattState)
+	if err != nil || battChargeState(dbusx.VariantToValue[uint32](state)) != stateCharging {
+		return 0
+	}
+	rate, err := b.getProp(ctx, linux.SensorBattEnergyRate)
+	if err != nil {
+		return 0
+	}
+	return dbusx.VariantToValue[float64](rate)
 }
 
 // getSensors retrieves the sensors passed in for a given battery.
@@ -123,7 +164,20 @@ func newBattery(ctx context.Context, path dbus.ObjectPath) *upowerBattery {
 
 	if dbusx.VariantToValue[uint32](battType) == 2 {
 		// Battery has charge percentage, temp and charging rate sensors
-		b.sensors = append(b.sensors, linux.SensorBattPercentage, linux.SensorBattTemp, linux.SensorBattEnergyRate)
+		b.sensors = append(b.sensors, linux.SensorBattPercentage, linux.SensorBattTemp, linux.SensorBattEnergyRate,
+			linux.SensorBattChargingPower, linux.SensorBattTimeToEmpty, linux.SensorBattTimeToFull)
+		// Health and charge cycles are only available if the hardware reports
+		// them.
+		if design, err := b.getNamedProp(ctx, upowerDBusDeviceDest+".EnergyFullDesign"); err == nil && dbusx.VariantToValue[float64](design) > 0 {
+			b.sensors = append(b.sensors, linux.SensorBattHealth)
+		}
+		if cycles, err := b.getProp(ctx, linux.SensorBattCycles); err == nil && dbusx.VariantToValue[int32](cycles) >= 0 {
+			b.sensors = append(b.sensors, linux.SensorBattCycles)
+		}
+	} else if b.battType == batteryTypeUps {
+		// A UPS has a charge percentage and runtime remaining. It is on
+		// battery when its state is discharging.
+		b.sensors = append(b.sensors, linux.SensorBattPercentage, linux.SensorBattTimeToEmpty, linux.SensorUPSOnBattery)
 	} else {
 		// Battery has a textual level sensor
 		b.sensors = append(b.sensors, linux.SensorBattLevel)
@@ -157,6 +211,19 @@ func (s *upowerBatterySensor) Icon() string {
 		return battPcToIcon(s.Value)
 	case linux.SensorBattEnergyRate:
 		return battErToIcon(s.Value)
+	case linux.SensorBattHealth:
+		return "mdi:battery-heart-variant"
+	case linux.SensorBattCycles:
+		return "mdi:battery-sync"
+	case linux.SensorBattChargingPower:
+		return "mdi:battery-charging"
+	case linux.SensorBattTimeToEmpty, linux.SensorBattTimeToFull:
+		return "mdi:battery-clock"
+	case linux.SensorUPSOnBattery:
+		if v, ok := s.Value.(bool); ok && v {
+			return "mdi:power-plug-off"
+		}
+		return "mdi:power-plug"
 	default:
 		return "mdi:battery"
 	}
@@ -168,8 +235,10 @@ func (s *upowerBatterySensor) DeviceClass() sensor.SensorDeviceClass {
 		return sensor.SensorBattery
 	case linux.SensorBattTemp:
 		return sensor.SensorTemperature
-	case linux.SensorBattEnergyRate:
+	case linux.SensorBattEnergyRate, linux.SensorBattChargingPower:
 		return sensor.SensorPower
+	case linux.SensorBattTimeToEmpty, linux.SensorBattTimeToFull:
+		return sensor.Duration
 	default:
 		return 0
 	}
@@ -177,8 +246,10 @@ func (s *upowerBatterySensor) DeviceClass() sensor.SensorDeviceClass {
 
 func (s *upowerBatterySensor) StateClass() sensor.SensorStateClass {
 	switch s.SensorTypeValue {
-	case linux.SensorBattPercentage, linux.SensorBattTemp, linux.SensorBattEnergyRate:
+	case linux.SensorBattPercentage, linux.SensorBattTemp, linux.SensorBattEnergyRate, linux.SensorBattHealth, linux.SensorBattChargingPower:
 		return sensor.StateMeasurement
+	case linux.SensorBattCycles:
+		return sensor.StateTotalIncreasing
 	default:
 		return 0
 	}
@@ -189,12 +260,38 @@ func (s *upowerBatterySensor) State() any {
 		return sensor.StateUnknown
 	}
 	switch s.SensorTypeValue {
-	case linux.SensorBattVoltage, linux.SensorBattTemp, linux.SensorBattEnergy, linux.SensorBattEnergyRate, linux.SensorBattPercentage:
+	case linux.SensorBattVoltage, linux.SensorBattTemp, linux.SensorBattEnergy, linux.SensorBattEnergyRate, linux.SensorBattPercentage, linux.SensorBattChargingPower:
 		if value, ok := s.Value.(float64); !ok {
 			return sensor.StateUnknown
 		} else {
 			return value
 		}
+	case linux.SensorBattHealth:
+		if value, ok := s.Value.(float64); !ok {
+			return sensor.StateUnknown
+		} else {
+			return math.Round(value*10) / 10
+		}
+	case linux.SensorBattCycles:
+		if value, ok := s.Value.(int32); !ok || value < 0 {
+			return sensor.StateUnknown
+		} else {
+			return value
+		}
+	case linux.SensorBattTimeToEmpty, linux.SensorBattTimeToFull:
+		// UPower reports 0 when the time cannot be estimated, such as when
+		// the battery is not discharging/charging.
+		if value, ok := s.Value.(int64); !ok || value <= 0 {
+			return sensor.StateUnknown
+		} else {
+			return value
+		}
+	case linux.SensorUPSOnBattery:
+		if value, ok := s.Value.(bool); !ok {
+			return sensor.StateUnknown
+		} else {
+			return value
+		}
 	case linux.SensorBattState:
 		if value, ok := s.Value.(uint32); !ok {
 			return sensor.StateUnknown
@@ -222,8 +319,12 @@ func (s *upowerBatterySensor) Units() string {
 		return "%"
 	case linux.SensorBattTemp:
 		return "°C"
-	case linux.SensorBattEnergyRate:
+	case linux.SensorBattEnergyRate, linux.SensorBattChargingPower:
 		return "W"
+	case linux.SensorBattHealth:
+		return "%"
+	case linux.SensorBattTimeToEmpty, linux.SensorBattTimeToFull:
+		return "s"
 	default:
 		return ""
 	}
@@ -253,6 +354,24 @@ func (s *upowerBatterySensor) generateAttributes(ctx context.Context, b *upowerB
 			Energy:     dbusx.VariantToValue[float64](energy),
 			DataSource: linux.DataSrcDbus,
 		}
+	case linux.SensorBattHealth:
+		full, err := b.getNamedProp(ctx, upowerDBusDeviceDest+".EnergyFull")
+		if err != nil {
+			log.Warn().Err(err).Str("battery", string(b.dBusPath)).Msg("Could not retrieve battery full energy.")
+		}
+		design, err := b.getNamedProp(ctx, upowerDBusDeviceDest+".EnergyFullDesign")
+		if err != nil {
+			log.Warn().Err(err).Str("battery", string(b.dBusPath)).Msg("Could not retrieve battery design energy.")
+		}
+		s.attributes = &struct {
+			DataSource   string  `json:"Data Source"`
+			EnergyFull   float64 `json:"Full Capacity (Wh)"`
+			EnergyDesign float64 `json:"Design Capacity (Wh)"`
+		}{
+			EnergyFull:   dbusx.VariantToValue[float64](full),
+			EnergyDesign: dbusx.VariantToValue[float64](design),
+			DataSource:   linux.DataSrcDbus,
+		}
 	case linux.SensorBattPercentage, linux.SensorBattLevel:
 		s.attributes = &struct {
 			Type       string `json:"Battery Type"`
@@ -274,6 +393,16 @@ func newBatterySensor(ctx context.Context, b *upowerBattery, t linux.SensorTypeV
 	s.SensorTypeValue = t
 	s.Value = v.Value()
 	s.IsDiagnostic = true
+	switch t {
+	case linux.SensorBattChargingPower:
+		s.Value = b.chargingPower(ctx)
+	case linux.SensorUPSOnBattery:
+		s.IsBinary = true
+		s.IsDiagnostic = false
+		if state, ok := s.Value.(uint32); ok {
+			s.Value = battChargeState(state) == stateDischarging
+		}
+	}
 	s.generateAttributes(ctx, b)
 	return s
 }
@@ -359,10 +488,18 @@ func monitorBattery(ctx context.Context, battery *upowerBattery) <-chan tracker.
 			}
 			go func() {
 				for propName, propValue := range props {
-					if s, ok := dBusPropToSensor[propName]; ok {
+					// Only update sensors that were chosen for this
+					// battery when it was added.
+					if s, ok := dBusPropToSensor[propName]; ok && battery.hasSensor(s) {
 						sensorCh <- newBatterySensor(ctx, battery, s, propValue)
 					}
 				}
+				if (props["EnergyRate"].Value() != nil || props["State"].Value() != nil) && battery.hasSensor(linux.SensorBattChargingPower) {
+					sensorCh <- newBatterySensor(ctx, battery, linux.SensorBattChargingPower, dbus.Variant{})
+				}
+				if state, ok := props["State"]; ok && battery.hasSensor(linux.SensorUPSOnBattery) {
+					sensorCh <- newBatterySensor(ctx, battery, linux.SensorUPSOnBattery, state)
+				}
 			}()
 		}).
 		AddWatch(ctx)
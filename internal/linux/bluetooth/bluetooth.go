@@ -0,0 +1,110 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package bluetooth controls the Bluetooth adapter, through BlueZ.
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	bluezDest    = "org.bluez"
+	adapterIntr  = "org.bluez.Adapter1"
+	poweredProp  = "Powered"
+	objectMgrGet = "org.freedesktop.DBus.ObjectManager.GetManagedObjects"
+)
+
+// ErrNoAdapter is returned when there is no Bluetooth adapter.
+var ErrNoAdapter = errors.New("no Bluetooth adapter found")
+
+// Adapter is a Bluetooth adapter.
+type Adapter struct {
+	path dbus.ObjectPath
+}
+
+// FindAdapter returns the Bluetooth adapter. If there is more than one, the
+// first (usually hci0) is returned.
+func FindAdapter(ctx context.Context) (*Adapter, error) {
+	data := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path("/").
+		Destination(bluezDest).
+		GetData(objectMgrGet)
+	if data == nil {
+		return nil, errors.New("no bus connection")
+	}
+	if err := data.Err(); err != nil {
+		return nil, err
+	}
+	objects, ok := data.AsRawInterface().(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+	if !ok {
+		return nil, ErrNoAdapter
+	}
+	var adapters []dbus.ObjectPath
+	for path, intrs := range objects {
+		if _, ok := intrs[adapterIntr]; ok {
+			adapters = append(adapters, path)
+		}
+	}
+	if len(adapters) == 0 {
+		return nil, ErrNoAdapter
+	}
+	slices.Sort(adapters)
+	return &Adapter{path: adapters[0]}, nil
+}
+
+// Powered returns whether the adapter is powered on.
+func (a *Adapter) Powered(ctx context.Context) (bool, error) {
+	value, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(a.path).
+		Destination(bluezDest).
+		GetProp(adapterIntr + "." + poweredProp)
+	if err != nil {
+		return false, err
+	}
+	return dbusx.VariantToValue[bool](value), nil
+}
+
+// SetPowered powers the adapter on or off.
+func (a *Adapter) SetPowered(ctx context.Context, powered bool) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(a.path).
+		Destination(bluezDest).
+		SetProp(adapterIntr+"."+poweredProp, dbus.MakeVariant(powered))
+}
+
+// WatchPowered calls the given function whenever the adapter is powered on or
+// off, including from outside the agent (e.g., the desktop Bluetooth settings
+// or rfkill).
+func (a *Adapter) WatchPowered(ctx context.Context, changed func()) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(a.path),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != a.path || s.Name != dbusx.PropChangedSignal || len(s.Body) < 2 {
+				return
+			}
+			if intr, ok := s.Body[0].(string); !ok || intr != adapterIntr {
+				return
+			}
+			props, ok := s.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				return
+			}
+			if _, ok := props[poweredProp]; ok {
+				go changed()
+			}
+		}).
+		AddWatch(ctx)
+}
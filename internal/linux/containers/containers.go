@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package containers controls Docker or Podman containers, through the Docker
+// Engine API (which Podman also provides) on a local socket.
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	apiTimeout = 30 * time.Second
+	// stopTimeout is how long a container has to stop before it is killed.
+	stopTimeout = 10
+)
+
+var (
+	// ErrNoSocket is returned when no Docker or Podman socket is found.
+	ErrNoSocket = errors.New("no Docker or Podman socket found")
+	// ErrContainerNotFound is returned for a container that does not exist.
+	ErrContainerNotFound = errors.New("container not found")
+)
+
+// sockets returns the paths of the sockets to try, in order. DOCKER_HOST is
+// used if set to a unix socket, then the Docker socket, then the Podman
+// sockets for the user and system.
+func sockets() []string {
+	var paths []string
+	if host, ok := strings.CutPrefix(os.Getenv("DOCKER_HOST"), "unix://"); ok {
+		paths = append(paths, host)
+	}
+	paths = append(paths, "/var/run/docker.sock")
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "podman", "podman.sock"))
+	}
+	return append(paths, "/run/podman/podman.sock")
+}
+
+// Client controls containers through the Docker Engine API.
+type Client struct {
+	http   *http.Client
+	socket string
+}
+
+// NewClient returns a client for the first Docker or Podman socket found. It
+// returns ErrNoSocket if none is found.
+func NewClient() (*Client, error) {
+	for _, socket := range sockets() {
+		if _, err := os.Stat(socket); err != nil {
+			continue
+		}
+		return &Client{
+			socket: socket,
+			http: &http.Client{
+				Timeout: apiTimeout,
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						var d net.Dialer
+						return d.DialContext(ctx, "unix", socket)
+					},
+				},
+			},
+		}, nil
+	}
+	return nil, ErrNoSocket
+}
+
+// Socket returns the path of the socket used by the client.
+func (c *Client) Socket() string {
+	return c.socket
+}
+
+func (c *Client) do(ctx context.Context, method, path string) (*http.Response, error) {
+	// The host is ignored, as requests are sent to the socket.
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrContainerNotFound
+	}
+	return resp, nil
+}
+
+// Running returns whether the container with the given name or ID is running.
+func (c *Client) Running(ctx context.Context, name string) (bool, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("could not inspect container: %s", resp.Status)
+	}
+	var details struct {
+		State struct {
+			Running bool `json:"Running"`
+		} `json:"State"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return false, err
+	}
+	return details.State.Running, nil
+}
+
+// Start starts the container with the given name or ID.
+func (c *Client) Start(ctx context.Context, name string) error {
+	return c.action(ctx, name, "start")
+}
+
+// Stop stops the container with the given name or ID.
+func (c *Client) Stop(ctx context.Context, name string) error {
+	return c.action(ctx, name, fmt.Sprintf("stop?t=%d", stopTimeout))
+}
+
+func (c *Client) action(ctx context.Context, name, action string) error {
+	resp, err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/"+action)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 304 means the container was already started or stopped.
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
+		return fmt.Errorf("could not %s container: %s", strings.Split(action, "?")[0], resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package cpu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/paths"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	energyPollInterval = time.Minute
+	energyStateFile    = "energy.json"
+
+	energySourcePlatform = "Platform (psys)"
+	energySourcePackages = "CPU Packages"
+)
+
+// energyState is the estimated energy used by the machine, saved so that the
+// total keeps increasing across restarts of the agent.
+type energyState struct {
+	Source   string  `json:"source"`
+	TotalKWh float64 `json:"total_kwh"`
+}
+
+func loadEnergyState(path, source string) *energyState {
+	state := &energyState{Source: source}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Debug().Err(err).Msg("Could not read saved energy estimate.")
+		}
+		return state
+	}
+	var saved energyState
+	if err := json.Unmarshal(b, &saved); err != nil {
+		log.Debug().Err(err).Msg("Could not parse saved energy estimate.")
+		return state
+	}
+	// A total measured from different zones is not comparable, so start again.
+	if saved.Source == source {
+		state.TotalKWh = saved.TotalKWh
+	}
+	return state
+}
+
+func (s *energyState) save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+type energySensor struct {
+	source string
+	zones  []string
+	linux.Sensor
+}
+
+func (s *energySensor) Attributes() any {
+	return struct {
+		Source     string   `json:"Source"`
+		DataSource string   `json:"Data Source"`
+		Zones      []string `json:"Zones"`
+	}{
+		Source:     s.source,
+		Zones:      s.zones,
+		DataSource: linux.DataSrcSysfs,
+	}
+}
+
+func newEnergySensor(state *energyState, zones []*raplZone) *energySensor {
+	s := &energySensor{source: state.Source}
+	for _, z := range zones {
+		s.zones = append(s.zones, z.label)
+	}
+	s.SensorTypeValue = linux.SensorEstimatedEnergy
+	s.IconString = "mdi:lightning-bolt"
+	s.UnitsString = "kWh"
+	s.SensorSrc = linux.DataSrcSysfs
+	s.DeviceClassValue = sensor.Energy
+	s.StateClassValue = sensor.StateTotalIncreasing
+	s.Value = math.Round(state.TotalKWh*10000) / 10000
+	return s
+}
+
+// energyZones returns the zones to estimate the energy used by the machine
+// from. The platform (psys) zone covers the whole machine, so is used if
+// available. Otherwise, the CPU package zones are the best estimate.
+func energyZones(zones []*raplZone) ([]*raplZone, string) {
+	var packages []*raplZone
+	for _, z := range zones {
+		switch {
+		case z.subZone:
+			continue
+		case z.label == raplZoneLabel("psys"):
+			return []*raplZone{z}, energySourcePlatform
+		default:
+			packages = append(packages, z)
+		}
+	}
+	return packages, energySourcePackages
+}
+
+// EnergyUpdater estimates the energy used by the machine from the RAPL energy
+// counters, as a total that keeps increasing across restarts of the agent, so
+// that the device can be added to the Home Assistant energy dashboard.
+func EnergyUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	allZones, err := findRAPLZones()
+	if err != nil || len(allZones) == 0 {
+		log.Debug().Err(err).Msg("No RAPL energy counters found. Energy estimate sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	zones, source := energyZones(allZones)
+	if len(zones) == 0 {
+		log.Debug().Msg("No RAPL package or platform energy counters found. Energy estimate sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	statePath := filepath.Join(paths.DataDir(), energyStateFile)
+	state := loadEnergyState(statePath, source)
+
+	var mu sync.Mutex
+	sendEnergySensor := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		var totalUJ uint64
+		for _, z := range zones {
+			before := z.totalUJ
+			if _, err := z.update(); err != nil {
+				log.Debug().Err(err).Str("zone", z.label).Msg("Could not read RAPL energy counter.")
+				continue
+			}
+			totalUJ += z.totalUJ - before
+		}
+		state.TotalKWh += float64(totalUJ) / ujPerKWh
+		if err := state.save(statePath); err != nil {
+			log.Debug().Err(err).Msg("Could not save energy estimate.")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case sensorCh <- newEnergySensor(state, zones):
+		case <-ctx.Done():
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendEnergySensor, energyPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped energy estimate sensor.")
+	}()
+	return sensorCh
+}
@@ -18,6 +18,9 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
 
+// procLoadAvg is the source of load averages.
+const procLoadAvg = "/proc/loadavg"
+
 type loadavgSensor struct {
 	linux.Sensor
 }
@@ -25,11 +28,16 @@ type loadavgSensor struct {
 func LoadAvgUpdater(ctx context.Context) chan tracker.Sensor {
 	sensorCh := make(chan tracker.Sensor, 3)
 	sendLoadAvgStats := func(_ time.Duration) {
+		if helpers.IsDenied(procLoadAvg) {
+			return
+		}
 		var latest *load.AvgStat
 		var err error
 		if latest, err = load.AvgWithContext(ctx); err != nil {
-			log.Debug().Err(err).Caller().
-				Msg("Problem fetching loadavg stats.")
+			if !helpers.CheckDenied(procLoadAvg, err) {
+				log.Debug().Err(err).Caller().
+					Msg("Problem fetching loadavg stats.")
+			}
 			return
 		}
 		for _, loadType := range []linux.SensorTypeValue{linux.SensorLoad1, linux.SensorLoad5, linux.SensorLoad15} {
@@ -0,0 +1,295 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package cpu
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	raplPath         = "/sys/class/powercap"
+	hwmonPath        = "/sys/class/hwmon"
+	raplPollInterval = 15 * time.Second
+
+	// ujPerKWh is the number of microjoules in a kilowatt-hour.
+	ujPerKWh = 3.6e12
+)
+
+// raplZone is a RAPL power domain, such as a CPU package. It keeps the last
+// energy counter reading, so that the power used between readings can be
+// calculated, and the total energy used since the agent started. Sub-zones
+// (e.g., the cores of a package) are included in their parent zone, so only
+// their power is reported.
+type raplZone struct {
+	last     time.Time
+	label    string
+	files    []string
+	maxRange uint64
+	lastUJ   uint64
+	totalUJ  uint64
+	subZone  bool
+}
+
+// raplZoneLabel converts a RAPL zone name (e.g., package-0, psys or core) to a
+// friendlier label.
+func raplZoneLabel(name string) string {
+	switch {
+	case strings.HasPrefix(name, "package-"):
+		return "CPU Package " + strings.TrimPrefix(name, "package-")
+	case name == "psys":
+		return "Platform"
+	case name == "dram":
+		return "DRAM"
+	default:
+		return strcase.ToCamel(name)
+	}
+}
+
+func readUint(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// init takes the first reading of the energy counters of the zone.
+func (z *raplZone) init() error {
+	var err error
+	// The energy counters are usually only readable by root.
+	if z.lastUJ, err = z.read(); err != nil {
+		return err
+	}
+	z.last = time.Now()
+	return nil
+}
+
+// read returns the current energy counter of the zone, in microjoules.
+func (z *raplZone) read() (uint64, error) {
+	var total uint64
+	for _, f := range z.files {
+		uj, err := readUint(f)
+		if err != nil {
+			return 0, err
+		}
+		total += uj
+	}
+	return total, nil
+}
+
+// findRAPLZones returns the RAPL zones on the device, including sub-zones
+// (e.g., core, uncore and dram), from the powercap interface or, for AMD CPUs
+// without it, the amd_energy hwmon driver.
+func findRAPLZones() ([]*raplZone, error) {
+	paths, err := filepath.Glob(filepath.Join(raplPath, "intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return findAMDEnergyZones()
+	}
+	slices.Sort(paths)
+	var zones []*raplZone
+	parents := make(map[string]string)
+	for _, p := range paths {
+		base := filepath.Base(p)
+		name, err := os.ReadFile(filepath.Join(p, "name"))
+		if err != nil {
+			continue
+		}
+		zone := &raplZone{
+			files: []string{filepath.Join(p, "energy_uj")},
+			label: raplZoneLabel(strings.TrimSpace(string(name))),
+		}
+		switch strings.Count(base, ":") {
+		case 1:
+			parents[base] = zone.label
+		case 2:
+			parent, ok := parents[base[:strings.LastIndex(base, ":")]]
+			if !ok {
+				continue
+			}
+			zone.label = parent + " " + zone.label
+			zone.subZone = true
+		default:
+			continue
+		}
+		if err := zone.init(); err != nil {
+			return nil, err
+		}
+		zone.maxRange, _ = readUint(filepath.Join(p, "max_energy_range_uj"))
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
+
+// findAMDEnergyZones returns the zones reported by the amd_energy hwmon
+// driver. Each socket is a zone and the counters of all cores are combined
+// into a single sub-zone, rather than reporting the power of every core.
+func findAMDEnergyZones() ([]*raplZone, error) {
+	names, err := filepath.Glob(filepath.Join(hwmonPath, "hwmon*", "name"))
+	if err != nil {
+		return nil, err
+	}
+	var zones []*raplZone
+	for _, nameFile := range names {
+		if name, err := os.ReadFile(nameFile); err != nil || strings.TrimSpace(string(name)) != "amd_energy" {
+			continue
+		}
+		labels, _ := filepath.Glob(filepath.Join(filepath.Dir(nameFile), "energy*_label"))
+		slices.Sort(labels)
+		cores := &raplZone{label: "CPU Cores", subZone: true}
+		for _, labelFile := range labels {
+			label, err := os.ReadFile(labelFile)
+			if err != nil {
+				continue
+			}
+			input := strings.TrimSuffix(labelFile, "_label") + "_input"
+			switch l := strings.TrimSpace(string(label)); {
+			case strings.HasPrefix(l, "Esocket"):
+				zones = append(zones, &raplZone{
+					files: []string{input},
+					label: "CPU Package " + strings.TrimPrefix(l, "Esocket"),
+				})
+			case strings.HasPrefix(l, "Ecore"):
+				cores.files = append(cores.files, input)
+			}
+		}
+		if len(cores.files) > 0 {
+			zones = append(zones, cores)
+		}
+	}
+	for _, zone := range zones {
+		if err := zone.init(); err != nil {
+			return nil, err
+		}
+	}
+	return zones, nil
+}
+
+// update reads the energy counter and returns the average power in watts since
+// the last reading. The counter wraps around at maxRange, which is accounted
+// for. Counters without a known range are assumed to have been reset if they
+// go backwards.
+func (z *raplZone) update() (float64, error) {
+	uj, err := z.read()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	var delta uint64
+	switch {
+	case uj >= z.lastUJ:
+		delta = uj - z.lastUJ
+	case z.maxRange > 0:
+		delta = z.maxRange - z.lastUJ + uj
+	default:
+		delta = uj
+	}
+	elapsed := now.Sub(z.last).Seconds()
+	z.lastUJ, z.last = uj, now
+	z.totalUJ += delta
+	if elapsed <= 0 {
+		return 0, nil
+	}
+	return float64(delta) / elapsed / 1e6, nil
+}
+
+type raplSensor struct {
+	zone string
+	linux.Sensor
+}
+
+func (s *raplSensor) Name() string {
+	return s.zone + " " + s.SensorTypeValue.String()
+}
+
+func (s *raplSensor) ID() string {
+	return strcase.ToSnake(s.zone + "_" + s.SensorTypeValue.String())
+}
+
+// newRAPLSensors creates the power and energy sensors for a zone. Sub-zones only
+// have a power sensor, as their energy is included in the parent zone.
+func newRAPLSensors(z *raplZone, watts float64) []tracker.Sensor {
+	power := &raplSensor{zone: z.label}
+	power.SensorTypeValue = linux.SensorRAPLPower
+	power.IconString = "mdi:flash"
+	power.UnitsString = "W"
+	power.SensorSrc = linux.DataSrcSysfs
+	power.DeviceClassValue = sensor.SensorPower
+	power.StateClassValue = sensor.StateMeasurement
+	power.Value = math.Round(watts*100) / 100
+	if z.subZone {
+		return []tracker.Sensor{power}
+	}
+
+	energy := &raplSensor{zone: z.label}
+	energy.SensorTypeValue = linux.SensorRAPLEnergy
+	energy.IconString = "mdi:lightning-bolt"
+	energy.UnitsString = "kWh"
+	energy.SensorSrc = linux.DataSrcSysfs
+	energy.DeviceClassValue = sensor.Energy
+	energy.StateClassValue = sensor.StateTotalIncreasing
+	energy.Value = float64(z.totalUJ) / ujPerKWh
+
+	return []tracker.Sensor{power, energy}
+}
+
+// RAPLUpdater reports the power draw and energy used by the CPU package(s),
+// and the power draw of their cores and other sub-zones, from the Intel/AMD
+// RAPL energy counters. Energy is counted from when the agent
+// starts, which Home Assistant handles as a meter reset.
+func RAPLUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	zones, err := findRAPLZones()
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		log.Warn().Msg("No permission to read RAPL energy counters. CPU power sensors will not run. See the FAQ.")
+		close(sensorCh)
+		return sensorCh
+	case err != nil || len(zones) == 0:
+		log.Debug().Err(err).Msg("No RAPL energy counters found. CPU power sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	sendRAPLSensors := func(_ time.Duration) {
+		for _, z := range zones {
+			watts, err := z.update()
+			if err != nil {
+				log.Debug().Err(err).Str("zone", z.label).Msg("Could not read RAPL energy counter.")
+				continue
+			}
+			for _, s := range newRAPLSensors(z, watts) {
+				sensorCh <- s
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendRAPLSensors, raplPollInterval, time.Second)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped CPU power sensors.")
+	}()
+	return sensorCh
+}
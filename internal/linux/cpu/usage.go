@@ -18,6 +18,9 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
 
+// procStat is the source of CPU usage statistics.
+const procStat = "/proc/stat"
+
 type cpuUsageSensor struct {
 	linux.Sensor
 }
@@ -25,9 +28,15 @@ type cpuUsageSensor struct {
 func UsageUpdater(ctx context.Context) chan tracker.Sensor {
 	sensorCh := make(chan tracker.Sensor)
 	sendCPUUsage := func(d time.Duration) {
+		if helpers.IsDenied(procStat) {
+			return
+		}
 		usage, err := cpu.Percent(d, false)
-		if err != nil {
-			log.Warn().Err(err).Msg("Could not retrieve CPU usage.")
+		if err != nil || len(usage) == 0 {
+			if !helpers.CheckDenied(procStat, err) {
+				log.Warn().Err(err).Msg("Could not retrieve CPU usage.")
+			}
+			return
 		}
 		s := &cpuUsageSensor{}
 		s.IconString = "mdi:chip"
@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package desktop
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	portalDest          = "org.freedesktop.portal.Desktop"
+	portalPath          = "/org/freedesktop/portal/desktop"
+	portalSettingsIntr  = "org.freedesktop.portal.Settings"
+	settingChangedEvent = portalSettingsIntr + ".SettingChanged"
+
+	appearanceNamespace = "org.freedesktop.appearance"
+	colorSchemeKey      = "color-scheme"
+	accentColorKey      = "accent-color"
+
+	dataSrcPortal = "D-Bus (XDG Desktop Portal)"
+)
+
+// colorSchemes are the values of the color-scheme setting.
+var colorSchemes = map[uint32]string{
+	0: "Default",
+	1: "Dark",
+	2: "Light",
+}
+
+type appearanceSensor struct {
+	attributes any
+	linux.Sensor
+}
+
+func (s *appearanceSensor) Attributes() any {
+	return s.attributes
+}
+
+func newColorSchemeSensor(scheme uint32) *appearanceSensor {
+	s := &appearanceSensor{
+		attributes: struct {
+			DataSource string `json:"Data Source"`
+		}{
+			DataSource: dataSrcPortal,
+		},
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorColorScheme,
+			IconString:      "mdi:theme-light-dark",
+			Value:           "Default",
+		},
+	}
+	if value, ok := colorSchemes[scheme]; ok {
+		s.Value = value
+	}
+	switch s.Value {
+	case "Dark":
+		s.IconString = "mdi:weather-night"
+	case "Light":
+		s.IconString = "mdi:weather-sunny"
+	}
+	return s
+}
+
+// parseAccentColor converts the accent-color setting, a tuple of red, green and
+// blue values between 0 and 1, to its RGB values. Values outside this range
+// mean that no accent color is set.
+func parseAccentColor(value any) ([3]uint8, bool) {
+	var rgb [3]uint8
+	components, ok := value.([]any)
+	if !ok || len(components) != 3 {
+		return rgb, false
+	}
+	for i, c := range components {
+		f, ok := c.(float64)
+		if !ok || f < 0 || f > 1 {
+			return rgb, false
+		}
+		rgb[i] = uint8(math.Round(f * 255))
+	}
+	return rgb, true
+}
+
+func newAccentColorSensor(rgb [3]uint8) *appearanceSensor {
+	return &appearanceSensor{
+		attributes: struct {
+			DataSource string   `json:"Data Source"`
+			RGB        [3]uint8 `json:"RGB"`
+		}{
+			DataSource: dataSrcPortal,
+			RGB:        rgb,
+		},
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorAccentColor,
+			IconString:      "mdi:palette",
+			Value:           fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2]),
+		},
+	}
+}
+
+// unwrapVariant returns the value of a (possibly nested) variant.
+func unwrapVariant(v any) any {
+	for {
+		variant, ok := v.(dbus.Variant)
+		if !ok {
+			return v
+		}
+		v = variant.Value()
+	}
+}
+
+// readSetting reads a setting from the settings portal. ReadOne is used where
+// available, falling back to the deprecated Read method on older portals.
+func readSetting(ctx context.Context, namespace, key string) any {
+	req := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(portalPath).
+		Destination(portalDest)
+	if value := req.GetData(portalSettingsIntr+".ReadOne", namespace, key).AsRawInterface(); value != nil {
+		return unwrapVariant(value)
+	}
+	return unwrapVariant(req.GetData(portalSettingsIntr+".Read", namespace, key).AsRawInterface())
+}
+
+// newAppearanceSensor returns the sensor for the given appearance setting, or
+// nil if the setting is not one that is tracked or has an invalid value.
+func newAppearanceSensor(key string, value any) tracker.Sensor {
+	switch key {
+	case colorSchemeKey:
+		if scheme, ok := value.(uint32); ok {
+			return newColorSchemeSensor(scheme)
+		}
+	case accentColorKey:
+		if rgb, ok := parseAccentColor(value); ok {
+			return newAccentColorSensor(rgb)
+		}
+	}
+	return nil
+}
+
+// AppearanceUpdater reports the desktop color scheme preference (dark or light)
+// and accent color, from the XDG desktop portal. Values are updated when the
+// settings change.
+func AppearanceUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var mu sync.Mutex
+	send := func(s tracker.Sensor) {
+		mu.Lock()
+		defer mu.Unlock()
+		if s == nil || ctx.Err() != nil {
+			return
+		}
+		select {
+		case sensorCh <- s:
+		case <-ctx.Done():
+		}
+	}
+
+	scheme := readSetting(ctx, appearanceNamespace, colorSchemeKey)
+	if scheme == nil {
+		log.Debug().Msg("Desktop portal does not provide the color scheme. Appearance sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(portalPath),
+			dbus.WithMatchInterface(portalSettingsIntr),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != portalPath || s.Name != settingChangedEvent || len(s.Body) < 3 {
+				return
+			}
+			if namespace, ok := s.Body[0].(string); !ok || namespace != appearanceNamespace {
+				return
+			}
+			key, ok := s.Body[1].(string)
+			if !ok {
+				return
+			}
+			send(newAppearanceSensor(key, unwrapVariant(s.Body[2])))
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch for desktop appearance changes. Appearance sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	go func() {
+		send(newAppearanceSensor(colorSchemeKey, scheme))
+		send(newAppearanceSensor(accentColorKey, readSetting(ctx, appearanceNamespace, accentColorKey)))
+	}()
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped desktop appearance sensors.")
+	}()
+	return sensorCh
+}
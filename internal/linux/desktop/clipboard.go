@@ -0,0 +1,46 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package desktop
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoClipboard is returned when no command to set the clipboard is
+// installed.
+var ErrNoClipboard = errors.New("no wl-copy, xclip or xsel command found")
+
+// clipboardCommands returns the commands that can set the clipboard, in order
+// of preference, for the type of session (Wayland or X11).
+func clipboardCommands() [][]string {
+	x11 := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append([][]string{{"wl-copy"}}, x11...)
+	}
+	return x11
+}
+
+// SetClipboard copies the text to the clipboard of the desktop, with wl-copy on
+// Wayland or xclip/xsel on X11. These keep running in the background to serve
+// the clipboard until something else is copied.
+func SetClipboard(ctx context.Context, text string) error {
+	for _, command := range clipboardCommands() {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	return ErrNoClipboard
+}
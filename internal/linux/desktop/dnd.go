@@ -0,0 +1,284 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package desktop provides sensors for the state of the user's desktop
+// environment.
+package desktop
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	notificationsPath = "/org/freedesktop/Notifications"
+	notificationsDest = "org.freedesktop.Notifications"
+
+	// inhibitedProp is set by notification daemons (such as KDE Plasma) that
+	// implement the Inhibited property of the notification specification.
+	inhibitedProp = "Inhibited"
+	// dunstIntr and dunstPausedProp are used by dunst, which does not
+	// implement the Inhibited property.
+	dunstIntr       = "org.dunstproject.cmd0"
+	dunstPausedProp = "paused"
+
+	gnomeNotificationsSchema = "org.gnome.desktop.notifications"
+	gnomeShowBannersKey      = "show-banners"
+
+	dataSrcGSettings = "GSettings"
+)
+
+var (
+	ErrNoDNDSource = errors.New("no supported source for do not disturb state")
+	// ErrDNDInhibited is returned when trying to turn off do not disturb
+	// that was turned on by something other than the agent.
+	ErrDNDInhibited = errors.New("do not disturb was not turned on by the agent")
+)
+
+// dndCookie is the cookie of the notification inhibition taken by the agent
+// to turn on do not disturb, for notification daemons (e.g., KDE Plasma) that
+// support inhibiting notifications. The inhibition lasts until it is released
+// or the agent exits.
+var (
+	dndCookie   uint32
+	dndCookieMu sync.Mutex
+)
+
+type dndSensor struct {
+	linux.Sensor
+}
+
+func (s *dndSensor) Icon() string {
+	if dnd, ok := s.Value.(bool); ok && dnd {
+		return "mdi:bell-off"
+	}
+	return "mdi:bell"
+}
+
+func newDNDSensor(dnd bool, source string) *dndSensor {
+	return &dndSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorDoNotDisturb,
+			IsBinary:        true,
+			SensorSrc:       source,
+			Value:           dnd,
+		},
+	}
+}
+
+// dbusDNDProp returns the D-Bus property of the notification daemon that
+// reports whether notifications are inhibited (i.e., do not disturb is on) and
+// its current value. The property is empty if the daemon has no such property.
+func dbusDNDProp(ctx context.Context) (string, bool) {
+	req := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(notificationsPath).
+		Destination(notificationsDest)
+	for _, prop := range []string{notificationsDest + "." + inhibitedProp, dunstIntr + "." + dunstPausedProp} {
+		if v, err := req.GetProp(prop); err == nil {
+			return prop, dbusx.VariantToValue[bool](v)
+		}
+	}
+	return "", false
+}
+
+// watchDBusDND watches the notification daemon for changes to its do not
+// disturb state.
+func watchDBusDND(ctx context.Context, send func(bool, string)) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(notificationsPath),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != notificationsPath || s.Name != dbusx.PropChangedSignal || len(s.Body) <= 1 {
+				return
+			}
+			props, ok := s.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				return
+			}
+			for _, prop := range []string{inhibitedProp, dunstPausedProp} {
+				if v, ok := props[prop]; ok {
+					send(dbusx.VariantToValue[bool](v), linux.DataSrcDbus)
+				}
+			}
+		}).
+		AddWatch(ctx)
+}
+
+// parseShowBanners parses a line of gsettings get/monitor output for the GNOME
+// show-banners key. Do not disturb is on when banners are not shown.
+func parseShowBanners(line string) (bool, bool) {
+	value := strings.TrimSpace(strings.TrimPrefix(line, gnomeShowBannersKey+":"))
+	switch value {
+	case "true":
+		return false, true
+	case "false":
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// watchGnomeDND uses gsettings to retrieve and monitor the GNOME do not disturb
+// setting.
+func watchGnomeDND(ctx context.Context, send func(bool, string)) error {
+	gsettings, err := exec.LookPath("gsettings")
+	if err != nil {
+		return ErrNoDNDSource
+	}
+	out, err := exec.CommandContext(ctx, gsettings, "get", gnomeNotificationsSchema, gnomeShowBannersKey).Output()
+	if err != nil {
+		return ErrNoDNDSource
+	}
+	dnd, ok := parseShowBanners(string(out))
+	if !ok {
+		return ErrNoDNDSource
+	}
+	cmd := exec.CommandContext(ctx, gsettings, "monitor", gnomeNotificationsSchema, gnomeShowBannersKey)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if dnd, ok := parseShowBanners(scanner.Text()); ok {
+				send(dnd, dataSrcGSettings)
+			}
+		}
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			log.Debug().Err(err).Msg("Stopped monitoring GNOME notification settings.")
+		}
+	}()
+	send(dnd, dataSrcGSettings)
+	return nil
+}
+
+// DoNotDisturb returns whether do not disturb is on for desktop notifications.
+func DoNotDisturb(ctx context.Context) (bool, error) {
+	if prop, dnd := dbusDNDProp(ctx); prop != "" {
+		return dnd, nil
+	}
+	out, err := exec.CommandContext(ctx, "gsettings", "get", gnomeNotificationsSchema, gnomeShowBannersKey).Output()
+	if err != nil {
+		return false, ErrNoDNDSource
+	}
+	dnd, ok := parseShowBanners(string(out))
+	if !ok {
+		return false, ErrNoDNDSource
+	}
+	return dnd, nil
+}
+
+// SetDoNotDisturb turns do not disturb for desktop notifications on or off.
+// For dunst, notifications are paused. For notification daemons that support
+// inhibiting notifications (e.g., KDE Plasma), the agent holds an inhibition
+// while do not disturb is on. Otherwise, the GNOME notification banners
+// setting is changed.
+func SetDoNotDisturb(ctx context.Context, dnd bool) error {
+	prop, _ := dbusDNDProp(ctx)
+	req := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(notificationsPath).
+		Destination(notificationsDest)
+	switch prop {
+	case dunstIntr + "." + dunstPausedProp:
+		return req.SetProp(prop, dbus.MakeVariant(dnd))
+	case notificationsDest + "." + inhibitedProp:
+		dndCookieMu.Lock()
+		defer dndCookieMu.Unlock()
+		switch {
+		case dnd && dndCookie == 0:
+			data := req.GetData(notificationsDest+".Inhibit", preferences.AppName, "Do not disturb turned on from Home Assistant", map[string]dbus.Variant{})
+			if err := data.Err(); err != nil {
+				return err
+			}
+			cookie, ok := data.AsRawInterface().(uint32)
+			if !ok {
+				return errors.New("invalid inhibition cookie")
+			}
+			dndCookie = cookie
+		case !dnd && dndCookie != 0:
+			if err := req.Call(notificationsDest+".UnInhibit", dndCookie); err != nil {
+				return err
+			}
+			dndCookie = 0
+		case !dnd:
+			if _, on := dbusDNDProp(ctx); on {
+				return ErrDNDInhibited
+			}
+		}
+		return nil
+	default:
+		showBanners := "true"
+		if dnd {
+			showBanners = "false"
+		}
+		if err := exec.CommandContext(ctx, "gsettings", "set", gnomeNotificationsSchema, gnomeShowBannersKey, showBanners).Run(); err != nil {
+			return errors.Join(ErrNoDNDSource, err)
+		}
+		return nil
+	}
+}
+
+// DNDUpdater reports whether do not disturb is on for desktop notifications.
+// The state is read from the notification daemon where it supports this (e.g.,
+// KDE Plasma and dunst), otherwise from the GNOME notification settings.
+func DNDUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+
+	var mu sync.Mutex
+	var last *bool
+	send := func(dnd bool, source string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || (last != nil && *last == dnd) {
+			return
+		}
+		last = &dnd
+		select {
+		case sensorCh <- newDNDSensor(dnd, source):
+		case <-ctx.Done():
+		}
+	}
+
+	var err error
+	if prop, dnd := dbusDNDProp(ctx); prop != "" {
+		if err = watchDBusDND(ctx, send); err == nil {
+			send(dnd, linux.DataSrcDbus)
+		}
+	} else {
+		err = watchGnomeDND(ctx, send)
+	}
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not determine do not disturb state. Do not disturb sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped do not disturb sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package desktop
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	kdeKeyboardDest = "org.kde.keyboard"
+	kdeKeyboardPath = "/Layouts"
+	kdeKeyboardIntr = "org.kde.KeyboardLayouts"
+
+	localeDest = "org.freedesktop.locale1"
+	localePath = "/org/freedesktop/locale1"
+)
+
+var (
+	// ErrNoKeyboardLayouts is returned when the keyboard layouts cannot be
+	// found or changed, i.e., not running KDE Plasma or an X11 session with
+	// setxkbmap.
+	ErrNoKeyboardLayouts = errors.New("no supported source for keyboard layouts")
+
+	errUnknownLayout = errors.New("unknown keyboard layout")
+)
+
+// KeyboardLayout is a keyboard layout configured on the desktop.
+type KeyboardLayout struct {
+	// Name is the XKB layout, with any variant in parentheses, e.g.,
+	// de(nodeadkeys).
+	Name string
+	// Description is the name of the layout shown to users. On KDE Plasma,
+	// this is the long name, e.g., German (no dead keys). Otherwise, it is the
+	// same as Name.
+	Description string
+}
+
+// layoutName returns the name of an XKB layout and variant.
+func layoutName(layout, variant string) string {
+	if variant == "" {
+		return layout
+	}
+	return layout + "(" + variant + ")"
+}
+
+// splitLayoutName returns the XKB layout and variant of a layout name.
+func splitLayoutName(name string) (layout, variant string) {
+	layout, variant, _ = strings.Cut(name, "(")
+	return layout, strings.TrimSuffix(variant, ")")
+}
+
+// KeyboardLayouts returns the keyboard layouts configured on the desktop and
+// the index of the active layout, or -1 if the active layout is not one of
+// them. KDE Plasma and X11 sessions are supported.
+// On X11, the layouts are those configured with localed (e.g., with localectl
+// set-x11-keymap).
+func KeyboardLayouts(ctx context.Context) ([]KeyboardLayout, int, error) {
+	if layouts, active, err := kdeKeyboardLayouts(ctx); err == nil {
+		return layouts, active, nil
+	}
+	return x11KeyboardLayouts(ctx)
+}
+
+// SetKeyboardLayout makes the keyboard layout with the given name active.
+func SetKeyboardLayout(ctx context.Context, name string) error {
+	if layouts, _, err := kdeKeyboardLayouts(ctx); err == nil {
+		for i, l := range layouts {
+			if l.Name == name {
+				return dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+					Path(kdeKeyboardPath).
+					Destination(kdeKeyboardDest).
+					Call(kdeKeyboardIntr+".setLayout", uint32(i))
+			}
+		}
+		return errUnknownLayout
+	}
+	layouts, _, err := x11KeyboardLayouts(ctx)
+	if err != nil {
+		return err
+	}
+	// Make the chosen layout the first (active) layout, keeping the others
+	// available to switch to.
+	names := []string{name}
+	for _, l := range layouts {
+		if l.Name != name {
+			names = append(names, l.Name)
+		}
+	}
+	if len(names) > len(layouts) {
+		return errUnknownLayout
+	}
+	xkbLayouts := make([]string, 0, len(names))
+	xkbVariants := make([]string, 0, len(names))
+	for _, n := range names {
+		layout, variant := splitLayoutName(n)
+		xkbLayouts = append(xkbLayouts, layout)
+		xkbVariants = append(xkbVariants, variant)
+	}
+	return exec.CommandContext(ctx, "setxkbmap",
+		"-layout", strings.Join(xkbLayouts, ","),
+		"-variant", strings.Join(xkbVariants, ",")).Run()
+}
+
+// kdeKeyboardLayouts returns the keyboard layouts configured in KDE Plasma,
+// and the index of the active one.
+func kdeKeyboardLayouts(ctx context.Context) ([]KeyboardLayout, int, error) {
+	list := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(kdeKeyboardPath).
+		Destination(kdeKeyboardDest).
+		GetData(kdeKeyboardIntr + ".getLayoutsList")
+	if list == nil || list.Err() != nil {
+		return nil, 0, ErrNoKeyboardLayouts
+	}
+	entries, ok := list.AsRawInterface().([][]any)
+	if !ok || len(entries) == 0 {
+		return nil, 0, ErrNoKeyboardLayouts
+	}
+	layouts := make([]KeyboardLayout, 0, len(entries))
+	for _, e := range entries {
+		if len(e) != 3 {
+			continue
+		}
+		shortName, _ := e[0].(string)
+		variant, _ := e[1].(string)
+		longName, _ := e[2].(string)
+		l := KeyboardLayout{Name: layoutName(shortName, variant), Description: longName}
+		if l.Description == "" {
+			l.Description = l.Name
+		}
+		layouts = append(layouts, l)
+	}
+	current := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(kdeKeyboardPath).
+		Destination(kdeKeyboardDest).
+		GetData(kdeKeyboardIntr + ".getLayout")
+	if current == nil || current.Err() != nil {
+		return nil, 0, ErrNoKeyboardLayouts
+	}
+	active, _ := current.AsRawInterface().(uint32)
+	return layouts, int(active), nil
+}
+
+// x11KeyboardLayouts returns the keyboard layouts configured with localed,
+// and the index of the active one as reported by setxkbmap. GNOME manages the
+// layouts itself, and resets any changes made with setxkbmap, so is not
+// supported.
+func x11KeyboardLayouts(ctx context.Context) ([]KeyboardLayout, int, error) {
+	if os.Getenv("DISPLAY") == "" || os.Getenv("WAYLAND_DISPLAY") != "" ||
+		strings.Contains(os.Getenv("XDG_CURRENT_DESKTOP"), "GNOME") {
+		return nil, 0, ErrNoKeyboardLayouts
+	}
+	out, err := exec.CommandContext(ctx, "setxkbmap", "-query").Output()
+	if err != nil {
+		return nil, 0, errors.Join(ErrNoKeyboardLayouts, err)
+	}
+	var activeLayout, activeVariant string
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		first, _, _ := strings.Cut(strings.TrimSpace(value), ",")
+		switch strings.TrimSpace(key) {
+		case "layout":
+			activeLayout = first
+		case "variant":
+			activeVariant = first
+		}
+	}
+
+	req := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(localePath).
+		Destination(localeDest)
+	layoutProp, err := req.GetProp(localeDest + ".X11Layout")
+	if err != nil {
+		return nil, 0, errors.Join(ErrNoKeyboardLayouts, err)
+	}
+	variantProp, err := req.GetProp(localeDest + ".X11Variant")
+	if err != nil {
+		return nil, 0, errors.Join(ErrNoKeyboardLayouts, err)
+	}
+	xkbLayouts := strings.Split(dbusx.VariantToValue[string](layoutProp), ",")
+	xkbVariants := strings.Split(dbusx.VariantToValue[string](variantProp), ",")
+	var layouts []KeyboardLayout
+	active := -1
+	for i, layout := range xkbLayouts {
+		if layout == "" {
+			continue
+		}
+		var variant string
+		if i < len(xkbVariants) {
+			variant = xkbVariants[i]
+		}
+		name := layoutName(layout, variant)
+		if name == layoutName(activeLayout, activeVariant) {
+			active = len(layouts)
+		}
+		layouts = append(layouts, KeyboardLayout{Name: name, Description: name})
+	}
+	if len(layouts) == 0 {
+		return nil, 0, ErrNoKeyboardLayouts
+	}
+	return layouts, active, nil
+}
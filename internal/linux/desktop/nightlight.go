@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package desktop
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	gnomeColorSchema          = "org.gnome.settings-daemon.plugins.color"
+	gnomeNightLightKey        = "night-light-enabled"
+	gnomeNightLightTempKey    = "night-light-temperature"
+	gnomeNightLightTempPrefix = "uint32 "
+
+	// NightLightMinTemperature and NightLightMaxTemperature are the range of
+	// color temperatures (in Kelvin) GNOME allows for night light.
+	NightLightMinTemperature = 1700
+	NightLightMaxTemperature = 4700
+)
+
+// ErrNoNightLight is returned when night light is not available, i.e., not
+// running GNOME.
+var ErrNoNightLight = errors.New("no GNOME night light settings found")
+
+func getColorSetting(ctx context.Context, key string) (string, error) {
+	out, err := exec.CommandContext(ctx, "gsettings", "get", gnomeColorSchema, key).Output()
+	if err != nil {
+		return "", errors.Join(ErrNoNightLight, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func setColorSetting(ctx context.Context, key, value string) error {
+	return exec.CommandContext(ctx, "gsettings", "set", gnomeColorSchema, key, value).Run()
+}
+
+// NightLight returns whether night light is turned on.
+func NightLight(ctx context.Context) (bool, error) {
+	value, err := getColorSetting(ctx, gnomeNightLightKey)
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// SetNightLight turns night light on or off. When on, night light follows its
+// schedule (e.g., sunset to sunrise) set in the desktop settings.
+func SetNightLight(ctx context.Context, enabled bool) error {
+	return setColorSetting(ctx, gnomeNightLightKey, strconv.FormatBool(enabled))
+}
+
+// NightLightTemperature returns the color temperature of night light, in
+// Kelvin.
+func NightLightTemperature(ctx context.Context) (int, error) {
+	value, err := getColorSetting(ctx, gnomeNightLightTempKey)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimPrefix(value, gnomeNightLightTempPrefix))
+}
+
+// SetNightLightTemperature sets the color temperature of night light, in
+// Kelvin, limited to between NightLightMinTemperature and
+// NightLightMaxTemperature.
+func SetNightLightTemperature(ctx context.Context, kelvin int) error {
+	kelvin = min(max(kelvin, NightLightMinTemperature), NightLightMaxTemperature)
+	return setColorSetting(ctx, gnomeNightLightTempKey, strconv.Itoa(kelvin))
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package desktop
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+// Notification urgency levels, as defined by the desktop notifications
+// specification.
+const (
+	UrgencyLow      = "low"
+	UrgencyNormal   = "normal"
+	UrgencyCritical = "critical"
+)
+
+// ErrInvalidUrgency is returned for an unknown notification urgency.
+var ErrInvalidUrgency = errors.New("invalid urgency")
+
+// Notification is a desktop notification.
+type Notification struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	// Urgency is one of low, normal or critical. Defaults to normal.
+	Urgency string `json:"urgency,omitempty"`
+	// Icon is an icon name from the desktop icon theme (e.g.,
+	// dialog-information) or a path to an image file.
+	Icon string `json:"icon,omitempty"`
+}
+
+func urgencyLevel(urgency string) (byte, error) {
+	switch urgency {
+	case UrgencyLow:
+		return 0, nil
+	case UrgencyNormal, "":
+		return 1, nil
+	case UrgencyCritical:
+		return 2, nil
+	default:
+		return 0, ErrInvalidUrgency
+	}
+}
+
+// Notify shows the notification on the desktop through the notification
+// daemon.
+func Notify(ctx context.Context, n *Notification) error {
+	urgency, err := urgencyLevel(n.Urgency)
+	if err != nil {
+		return err
+	}
+	return dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(notificationsPath).
+		Destination(notificationsDest).
+		Call(notificationsDest+".Notify",
+			preferences.AppName,
+			uint32(0),
+			n.Icon,
+			n.Title,
+			n.Message,
+			[]string{},
+			map[string]dbus.Variant{"urgency": dbus.MakeVariant(urgency)},
+			int32(-1))
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package desktop
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	portalOpenURIIntr   = "org.freedesktop.portal.OpenURI"
+	portalOpenURIMethod = portalOpenURIIntr + ".OpenURI"
+)
+
+// OpenURL opens the URL in the default browser of the desktop. The XDG desktop
+// portal is used, which also works for the agent running in a sandbox (e.g.,
+// Flatpak), falling back to xdg-open.
+func OpenURL(ctx context.Context, url string) error {
+	err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(portalPath).
+		Destination(portalDest).
+		Call(portalOpenURIMethod, "", url, map[string]dbus.Variant{})
+	if err == nil {
+		return nil
+	}
+	log.Debug().Err(err).Msg("Could not open URL with desktop portal, trying xdg-open.")
+	if xdgErr := exec.CommandContext(ctx, "xdg-open", url).Run(); xdgErr != nil {
+		return errors.Join(err, xdgErr)
+	}
+	return nil
+}
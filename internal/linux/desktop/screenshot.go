@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package desktop
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	portalScreenshotMethod = "org.freedesktop.portal.Screenshot.Screenshot"
+	portalRequestIntr      = "org.freedesktop.portal.Request"
+	portalResponseEvent    = portalRequestIntr + ".Response"
+
+	screenshotTimeout = 30 * time.Second
+)
+
+var (
+	// ErrScreenshotDenied is returned when the desktop did not allow the
+	// screenshot, for example, because permission was not granted.
+	ErrScreenshotDenied = errors.New("screenshot was not allowed")
+
+	errNoScreenshot = errors.New("no screenshot returned by desktop portal")
+)
+
+// Screenshot captures the screen through the XDG desktop portal and returns
+// the image (PNG). The portal saves the screenshot to a file (usually in the
+// Pictures directory), which is removed once it has been read. Some desktops
+// ask the user for permission the first time a screenshot is taken.
+func Screenshot(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, screenshotTimeout)
+	defer cancel()
+
+	// The response is sent as a signal on a request object. Responses are
+	// watched for before asking for the screenshot, so that a quick response
+	// is not missed.
+	responseCh := make(chan *dbus.Signal, 10)
+	watch := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchInterface(portalRequestIntr),
+			dbus.WithMatchMember("Response"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Name != portalResponseEvent {
+				return
+			}
+			select {
+			case responseCh <- s:
+			default:
+			}
+		})
+	if err := watch.AddWatch(ctx); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := watch.RemoveWatch(context.WithoutCancel(ctx)); err != nil {
+			log.Debug().Err(err).Msg("Could not remove screenshot response watch.")
+		}
+	}()
+
+	data := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(portalPath).
+		Destination(portalDest).
+		GetData(portalScreenshotMethod, "", map[string]dbus.Variant{
+			"interactive": dbus.MakeVariant(false),
+		})
+	if data == nil {
+		return nil, errors.New("no bus connection")
+	}
+	if err := data.Err(); err != nil {
+		return nil, err
+	}
+	handle := data.AsObjectPath()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case s := <-responseCh:
+			if s.Path != handle || len(s.Body) < 2 {
+				continue
+			}
+			return readScreenshot(s.Body[0], s.Body[1])
+		}
+	}
+}
+
+// readScreenshot reads and removes the screenshot from a portal response.
+func readScreenshot(response, results any) ([]byte, error) {
+	if code, ok := response.(uint32); !ok || code != 0 {
+		return nil, ErrScreenshotDenied
+	}
+	values, ok := results.(map[string]dbus.Variant)
+	if !ok {
+		return nil, errNoScreenshot
+	}
+	uri, ok := values["uri"].Value().(string)
+	if !ok {
+		return nil, errNoScreenshot
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return nil, errNoScreenshot
+	}
+	defer func() {
+		if err := os.Remove(u.Path); err != nil {
+			log.Debug().Err(err).Str("file", u.Path).Msg("Could not remove screenshot.")
+		}
+	}()
+	return os.ReadFile(u.Path)
+}
@@ -14,13 +14,14 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/shirou/gopsutil/v3/host"
 
+	"github.com/joshuar/go-hass-agent/internal/device/identity"
 	"github.com/joshuar/go-hass-agent/internal/hass/api"
 )
 
 type Device struct {
 	appName    string
 	appVersion string
-	hostname   string
+	name       string
 	deviceID   string
 	hwVendor   string
 	hwModel    string
@@ -46,8 +47,7 @@ func (l *Device) AppID() string {
 }
 
 func (l *Device) DeviceName() string {
-	shortHostname, _, _ := strings.Cut(l.hostname, ".")
-	return shortHostname
+	return l.name
 }
 
 func (l *Device) DeviceID() string {
@@ -110,40 +110,25 @@ func (l *Device) MarshalJSON() ([]byte, error) {
 	})
 }
 
-func NewDevice(name, version string) *Device {
+// NewDevice creates a new device. The device ID is taken from the first of the
+// given sources that provides one, or the default sources if none are given.
+func NewDevice(name, version string, sources ...identity.Source) *Device {
+	deviceID, err := identity.DeviceID(sources...)
+	if err != nil {
+		log.Warn().Err(err).
+			Msg("Could not retrieve a machine ID")
+		deviceID = identity.UnknownID
+	}
 	return &Device{
 		appName:    name,
 		appVersion: version,
-		deviceID:   getDeviceID(),
-		hostname:   getHostname(),
+		deviceID:   deviceID,
+		name:       identity.DeviceName(),
 		hwVendor:   getHWVendor(),
 		hwModel:    getHWModel(),
 	}
 }
 
-// getDeviceID retrieves the unique host ID of the device running the agent, or
-// unknown if that doesn't work.
-func getDeviceID() string {
-	deviceID, err := host.HostID()
-	if err != nil {
-		log.Warn().Err(err).
-			Msg("Could not retrieve a machine ID")
-		return "unknown"
-	}
-	return deviceID
-}
-
-// getHostname retrieves the hostname of the device running the agent, or
-// localhost if that doesn't work.
-func getHostname() string {
-	hostname, err := os.Hostname()
-	if err != nil {
-		log.Warn().Err(err).Msg("Could not retrieve hostname. Using 'localhost'.")
-		return "localhost"
-	}
-	return hostname
-}
-
 // getHWVendor will try to retrieve the vendor from the sysfs filesystem. It
 // will return "Unknown Vendor" if unsuccessful.
 // Reference: https://github.com/ansible/ansible/blob/devel/lib/ansible/module_utils/facts/hardware/linux.py
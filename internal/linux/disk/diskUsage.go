@@ -68,12 +68,20 @@ func UsageUpdater(ctx context.Context) chan tracker.Sensor {
 			return
 		}
 		for _, partition := range p {
+			// Confinement may deny access to some mountpoints. Skip
+			// those rather than retrying them every interval.
+			if helpers.IsDenied(partition.Mountpoint) {
+				continue
+			}
 			usage, err := disk.UsageWithContext(ctx, partition.Mountpoint)
-			if err != nil {
+			switch {
+			case helpers.CheckDenied(partition.Mountpoint, err):
+				continue
+			case err != nil:
 				log.Warn().Err(err).
 					Msgf("Failed to get usage info for mountpount %s.", partition.Mountpoint)
 				return
-			} else {
+			default:
 				sensorCh <- newDiskSensor(usage)
 			}
 		}
@@ -0,0 +1,163 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package disk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	blockSysfsDir = "/sys/class/block"
+	// maxBlockDepth limits how many layers of stacked block devices (e.g., LVM
+	// on LUKS) are walked to find an encrypted device.
+	maxBlockDepth = 8
+)
+
+// cryptDevice is a dm-crypt device backing a mountpoint.
+type cryptDevice struct {
+	Name string `json:"Crypt Device,omitempty"`
+	Type string `json:"Encryption,omitempty"`
+}
+
+type encryptionSensor struct {
+	mountpoint string
+	device     string
+	crypt      *cryptDevice
+	linux.Sensor
+}
+
+func (s *encryptionSensor) Name() string {
+	return "Mountpoint " + s.mountpoint + " " + s.SensorTypeValue.String()
+}
+
+func (s *encryptionSensor) ID() string {
+	if s.mountpoint == "/" {
+		return "mountpoint_root_encrypted"
+	}
+	return "mountpoint" + strings.ReplaceAll(s.mountpoint, "/", "_") + "_encrypted"
+}
+
+func (s *encryptionSensor) Icon() string {
+	if s.crypt != nil {
+		return "mdi:harddisk-lock"
+	}
+	return "mdi:harddisk"
+}
+
+func (s *encryptionSensor) Attributes() any {
+	return struct {
+		*cryptDevice
+		Device     string `json:"Device"`
+		DataSource string `json:"Data Source"`
+	}{
+		cryptDevice: s.crypt,
+		Device:      s.device,
+		DataSource:  linux.DataSrcSysfs,
+	}
+}
+
+func newEncryptionSensor(mountpoint, device string, crypt *cryptDevice) *encryptionSensor {
+	s := &encryptionSensor{
+		mountpoint: mountpoint,
+		device:     device,
+		crypt:      crypt,
+	}
+	s.SensorTypeValue = linux.SensorDiskEncrypted
+	s.IsBinary = true
+	s.IsDiagnostic = true
+	s.Value = crypt != nil
+	return s
+}
+
+// cryptType returns the encryption type of a device-mapper device from its
+// uuid, which dm-crypt prefixes with CRYPT- (e.g., CRYPT-LUKS2-<uuid>-<name>
+// or CRYPT-PLAIN-<name>).
+func cryptType(dmUUID string) (string, bool) {
+	rest, ok := strings.CutPrefix(dmUUID, "CRYPT-")
+	if !ok {
+		return "", false
+	}
+	kind, _, _ := strings.Cut(rest, "-")
+	return kind, true
+}
+
+// findCryptDevice walks the given block device and the devices it is stacked
+// on, returning the first dm-crypt device found, if any.
+func findCryptDevice(name string, depth int) *cryptDevice {
+	if depth > maxBlockDepth {
+		return nil
+	}
+	devDir := filepath.Join(blockSysfsDir, name)
+	if uuid, err := os.ReadFile(filepath.Join(devDir, "dm", "uuid")); err == nil {
+		if kind, ok := cryptType(strings.TrimSpace(string(uuid))); ok {
+			crypt := &cryptDevice{Name: name, Type: kind}
+			if dmName, err := os.ReadFile(filepath.Join(devDir, "dm", "name")); err == nil {
+				crypt.Name = strings.TrimSpace(string(dmName))
+			}
+			return crypt
+		}
+	}
+	slaves, err := os.ReadDir(filepath.Join(devDir, "slaves"))
+	if err != nil {
+		return nil
+	}
+	for _, slave := range slaves {
+		if crypt := findCryptDevice(slave.Name(), depth+1); crypt != nil {
+			return crypt
+		}
+	}
+	return nil
+}
+
+// blockDeviceName returns the kernel name (e.g., dm-0) of a device node,
+// resolving symlinks such as /dev/mapper/root.
+func blockDeviceName(device string) string {
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved
+	}
+	return filepath.Base(device)
+}
+
+// EncryptionUpdater reports whether each mountpoint is backed by a LUKS or
+// other dm-crypt encrypted device, including through stacked devices such as
+// LVM on LUKS.
+func EncryptionUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	sendEncryptionStats := func(_ time.Duration) {
+		p, err := disk.PartitionsWithContext(ctx, false)
+		if err != nil {
+			log.Warn().Err(err).
+				Msg("Could not retrieve list of physical partitions.")
+			return
+		}
+		for _, partition := range p {
+			if !strings.HasPrefix(partition.Device, "/dev/") {
+				continue
+			}
+			crypt := findCryptDevice(blockDeviceName(partition.Device), 0)
+			sensorCh <- newEncryptionSensor(partition.Mountpoint, partition.Device, crypt)
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendEncryptionStats, 5*time.Minute, time.Second*5)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped disk encryption sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package disk
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	mdstatFile = "/proc/mdstat"
+	mdSysfsDir = "/sys/block"
+)
+
+var (
+	mdArrayRegex    = regexp.MustCompile(`^(md\S+)\s*:\s*(\S+)\s*(?:\((?:auto-)?read-only\)\s*)?(raid\d+|linear|multipath)?\s*(.*)$`)
+	mdStatusRegex   = regexp.MustCompile(`\[(\d+)/(\d+)\]\s*\[([U_]+)\]`)
+	mdProgressRegex = regexp.MustCompile(`(recovery|resync|reshape|check)\s*=\s*([\d.]+)%`)
+)
+
+// mdArray represents a software RAID array as reported by /proc/mdstat.
+type mdArray struct {
+	Name         string   `json:"-"`
+	State        string   `json:"State"`
+	Level        string   `json:"Level,omitempty"`
+	Status       string   `json:"Status,omitempty"`
+	SyncAction   string   `json:"Sync Action,omitempty"`
+	Devices      []string `json:"Devices,omitempty"`
+	DevicesTotal int      `json:"Devices Total,omitempty"`
+	DevicesUp    int      `json:"Devices Active,omitempty"`
+	SyncProgress float64  `json:"-"`
+	Degraded     bool     `json:"-"`
+}
+
+type mdSensor struct {
+	array *mdArray
+	linux.Sensor
+}
+
+func (s *mdSensor) Name() string {
+	return s.array.Name + " " + s.SensorTypeValue.String()
+}
+
+func (s *mdSensor) ID() string {
+	return strcase.ToSnake(s.array.Name + "_" + s.SensorTypeValue.String())
+}
+
+func (s *mdSensor) Icon() string {
+	switch s.SensorTypeValue {
+	case linux.SensorRAIDDegraded:
+		if s.array.Degraded {
+			return "mdi:harddisk-remove"
+		}
+		return "mdi:harddisk"
+	case linux.SensorRAIDSyncProgress:
+		return "mdi:sync"
+	default:
+		return "mdi:harddisk"
+	}
+}
+
+func (s *mdSensor) Attributes() any {
+	return struct {
+		*mdArray
+		DataSource string `json:"Data Source"`
+	}{
+		mdArray:    s.array,
+		DataSource: linux.DataSrcProcfs,
+	}
+}
+
+func newMDSensors(array *mdArray) []*mdSensor {
+	state := &mdSensor{array: array}
+	state.SensorTypeValue = linux.SensorRAIDState
+	state.Value = array.State
+	state.IsDiagnostic = true
+
+	degraded := &mdSensor{array: array}
+	degraded.SensorTypeValue = linux.SensorRAIDDegraded
+	degraded.Value = array.Degraded
+	degraded.IsBinary = true
+
+	progress := &mdSensor{array: array}
+	progress.SensorTypeValue = linux.SensorRAIDSyncProgress
+	progress.Value = array.SyncProgress
+	progress.UnitsString = "%"
+	progress.StateClassValue = sensor.StateMeasurement
+
+	return []*mdSensor{state, degraded, progress}
+}
+
+// parseMDStat parses the contents of /proc/mdstat into a list of arrays.
+func parseMDStat(r io.Reader) []*mdArray {
+	var arrays []*mdArray
+	var current *mdArray
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := mdArrayRegex.FindStringSubmatch(line); m != nil {
+			current = &mdArray{
+				Name:  m[1],
+				State: m[2],
+				Level: m[3],
+			}
+			for _, d := range strings.Fields(m[4]) {
+				name, _, _ := strings.Cut(d, "[")
+				current.Devices = append(current.Devices, name)
+				if strings.HasSuffix(d, "(F)") {
+					current.Degraded = true
+				}
+			}
+			if current.State == "inactive" {
+				current.Degraded = true
+			}
+			arrays = append(arrays, current)
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := mdStatusRegex.FindStringSubmatch(line); m != nil {
+			current.DevicesTotal, _ = strconv.Atoi(m[1])
+			current.DevicesUp, _ = strconv.Atoi(m[2])
+			current.Status = m[3]
+			if strings.Contains(m[3], "_") || current.DevicesUp < current.DevicesTotal {
+				current.Degraded = true
+			}
+		}
+		if m := mdProgressRegex.FindStringSubmatch(line); m != nil {
+			current.SyncAction = m[1]
+			current.SyncProgress, _ = strconv.ParseFloat(m[2], 64)
+		}
+	}
+	return arrays
+}
+
+// updateFromSysfs refines the array details with the values the kernel exposes
+// in sysfs, which are more reliable than parsing /proc/mdstat.
+func (a *mdArray) updateFromSysfs() {
+	mdDir := filepath.Join(mdSysfsDir, a.Name, "md")
+	if v, err := os.ReadFile(filepath.Join(mdDir, "degraded")); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(v))); err == nil && n > 0 {
+			a.Degraded = true
+		}
+	}
+	if v, err := os.ReadFile(filepath.Join(mdDir, "array_state")); err == nil {
+		a.State = strings.TrimSpace(string(v))
+	}
+	if v, err := os.ReadFile(filepath.Join(mdDir, "sync_action")); err == nil {
+		if action := strings.TrimSpace(string(v)); action != "idle" {
+			a.SyncAction = action
+		}
+	}
+}
+
+func MDStatUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	if _, err := os.Stat(mdstatFile); err != nil {
+		log.Debug().Err(err).Msg("No mdstat file. RAID sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	sendRAIDStats := func(_ time.Duration) {
+		if helpers.IsDenied(mdstatFile) {
+			return
+		}
+		f, err := os.Open(mdstatFile)
+		if err != nil {
+			if !helpers.CheckDenied(mdstatFile, err) {
+				log.Warn().Err(err).Msg("Could not read mdstat.")
+			}
+			return
+		}
+		defer f.Close()
+		for _, array := range parseMDStat(f) {
+			array.updateFromSysfs()
+			for _, s := range newMDSensors(array) {
+				sensorCh <- s
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendRAIDStats, time.Minute, time.Second*5)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped RAID sensors.")
+	}()
+	return sensorCh
+}
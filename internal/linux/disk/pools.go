@@ -0,0 +1,295 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package disk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	btrfsSysfsDir    = "/sys/fs/btrfs"
+	btrfsScrubDir    = "/var/lib/btrfs"
+	poolTypeBtrfs    = "Btrfs"
+	poolTypeZFS      = "ZFS"
+	poolHealthOK     = "OK"
+	poolHealthErrors = "Errors"
+)
+
+// pool represents a Btrfs filesystem or ZFS pool.
+type pool struct {
+	Errors      map[string]uint64 `json:"Errors,omitempty"`
+	Name        string            `json:"-"`
+	Type        string            `json:"Type"`
+	Health      string            `json:"-"`
+	ScrubStatus string            `json:"Scrub Status,omitempty"`
+	LastScrub   string            `json:"Last Scrub,omitempty"`
+}
+
+// errorCount returns the total of all error counters for the pool.
+func (p *pool) errorCount() uint64 {
+	var total uint64
+	for _, v := range p.Errors {
+		total += v
+	}
+	return total
+}
+
+type poolSensor struct {
+	pool *pool
+	linux.Sensor
+}
+
+func (s *poolSensor) Name() string {
+	return s.pool.Name + " " + s.SensorTypeValue.String()
+}
+
+func (s *poolSensor) ID() string {
+	return strcase.ToSnake(strings.ToLower(s.pool.Type) + "_" + s.pool.Name + "_" + s.SensorTypeValue.String())
+}
+
+func (s *poolSensor) Icon() string {
+	if s.pool.Health != poolHealthOK && s.pool.Health != "ONLINE" {
+		return "mdi:database-alert"
+	}
+	return "mdi:database-check"
+}
+
+func (s *poolSensor) Attributes() any {
+	dataSrc := linux.DataSrcSysfs
+	if s.pool.Type == poolTypeZFS {
+		dataSrc = "zpool"
+	}
+	return struct {
+		*pool
+		DataSource string `json:"Data Source"`
+	}{
+		pool:       s.pool,
+		DataSource: dataSrc,
+	}
+}
+
+func newPoolSensors(p *pool) []*poolSensor {
+	health := &poolSensor{pool: p}
+	health.SensorTypeValue = linux.SensorPoolHealth
+	health.Value = p.Health
+	health.IsDiagnostic = true
+
+	errors := &poolSensor{pool: p}
+	errors.SensorTypeValue = linux.SensorPoolErrors
+	errors.Value = p.errorCount()
+	errors.UnitsString = "errors"
+	errors.StateClassValue = sensor.StateTotal
+	errors.IsDiagnostic = true
+
+	return []*poolSensor{health, errors}
+}
+
+// getBtrfsPools returns the Btrfs filesystems known to the kernel, with the
+// device error counters exposed in sysfs and the status of the last scrub, if
+// available.
+func getBtrfsPools() []*pool {
+	entries, err := os.ReadDir(btrfsSysfsDir)
+	if err != nil {
+		return nil
+	}
+	var pools []*pool
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "features" {
+			continue
+		}
+		fsid := e.Name()
+		p := &pool{
+			Name:   fsid,
+			Type:   poolTypeBtrfs,
+			Errors: make(map[string]uint64),
+		}
+		if label, err := os.ReadFile(filepath.Join(btrfsSysfsDir, fsid, "label")); err == nil {
+			if l := strings.TrimSpace(string(label)); l != "" {
+				p.Name = l
+			}
+		}
+		devs, err := filepath.Glob(filepath.Join(btrfsSysfsDir, fsid, "devinfo", "*", "error_stats"))
+		if err != nil {
+			log.Debug().Err(err).Str("fsid", fsid).Msg("Could not find Btrfs device error stats.")
+		}
+		for _, d := range devs {
+			b, err := os.ReadFile(d)
+			if err != nil {
+				continue
+			}
+			for k, v := range parseKeyValues(b, " ") {
+				if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+					p.Errors[k] += n
+				}
+			}
+		}
+		parseBtrfsScrub(p, filepath.Join(btrfsScrubDir, "scrub.status."+fsid))
+		p.Health = poolHealthOK
+		if p.errorCount() > 0 {
+			p.Health = poolHealthErrors
+		}
+		pools = append(pools, p)
+	}
+	return pools
+}
+
+// parseBtrfsScrub reads the scrub status file written by btrfs-progs for the
+// filesystem and records the last scrub time, status and any errors found.
+func parseBtrfsScrub(p *pool, file string) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	var lastStart int64
+	status := "finished"
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) < 2 {
+			continue
+		}
+		for _, f := range fields[1:] {
+			k, v, found := strings.Cut(f, ":")
+			if !found {
+				continue
+			}
+			switch k {
+			case "t_start":
+				if t, err := strconv.ParseInt(v, 10, 64); err == nil && t > lastStart {
+					lastStart = t
+				}
+			case "canceled":
+				if v == "1" {
+					status = "canceled"
+				}
+			case "finished":
+				if v == "0" && status != "canceled" {
+					status = "running"
+				}
+			case "read_errors", "csum_errors", "verify_errors", "super_errors", "uncorrectable_errors":
+				if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+					p.Errors["scrub_"+k] += n
+				}
+			}
+		}
+	}
+	if lastStart > 0 {
+		p.ScrubStatus = status
+		p.LastScrub = time.Unix(lastStart, 0).Format(time.RFC3339)
+	}
+}
+
+// getZFSPools returns the ZFS pools reported by the zpool command, with their
+// health, error counts and scrub status.
+func getZFSPools(ctx context.Context) []*pool {
+	zpool, err := exec.LookPath("zpool")
+	if err != nil {
+		return nil
+	}
+	out, err := exec.CommandContext(ctx, zpool, "list", "-H", "-o", "name,health").Output()
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not list ZFS pools.")
+		return nil
+	}
+	var pools []*pool
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		p := &pool{
+			Name:   fields[0],
+			Type:   poolTypeZFS,
+			Health: fields[1],
+			Errors: make(map[string]uint64),
+		}
+		status, err := exec.CommandContext(ctx, zpool, "status", "-p", p.Name).Output()
+		if err != nil {
+			log.Debug().Err(err).Str("pool", p.Name).Msg("Could not get ZFS pool status.")
+		} else {
+			parseZpoolStatus(p, status)
+		}
+		pools = append(pools, p)
+	}
+	return pools
+}
+
+// parseZpoolStatus extracts the scrub status and pool-level error counters
+// from the output of zpool status.
+func parseZpoolStatus(p *pool, status []byte) {
+	inConfig := false
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "scan:"):
+			p.ScrubStatus = strings.TrimSpace(strings.TrimPrefix(line, "scan:"))
+		case strings.HasPrefix(line, "NAME") && strings.Contains(line, "CKSUM"):
+			inConfig = true
+		case inConfig:
+			fields := strings.Fields(line)
+			if len(fields) >= 5 && fields[0] == p.Name {
+				for i, k := range []string{"read_errors", "write_errors", "cksum_errors"} {
+					if n, err := strconv.ParseUint(fields[2+i], 10, 64); err == nil {
+						p.Errors[k] = n
+					}
+				}
+				inConfig = false
+			}
+		}
+	}
+}
+
+// parseKeyValues parses lines of "key<sep>value" into a map.
+func parseKeyValues(b []byte, sep string) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		k, v, found := strings.Cut(strings.TrimSpace(scanner.Text()), sep)
+		if found {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return values
+}
+
+func PoolHealthUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	sendPoolHealth := func(_ time.Duration) {
+		pools := getBtrfsPools()
+		pools = append(pools, getZFSPools(ctx)...)
+		for _, p := range pools {
+			for _, s := range newPoolSensors(p) {
+				sensorCh <- s
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendPoolHealth, time.Minute*30, time.Minute)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped pool health sensors.")
+	}()
+	return sensorCh
+}
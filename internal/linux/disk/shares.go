@@ -0,0 +1,277 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package disk
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	mountsFile         = "/proc/self/mounts"
+	sharesPollInterval = 30 * time.Second
+
+	// ShareMountedEvent is fired on the Home Assistant event bus when a
+	// network share is mounted.
+	ShareMountedEvent = "go_hass_agent_share_mounted"
+	// ShareUnmountedEvent is fired on the Home Assistant event bus when a
+	// network share is unmounted.
+	ShareUnmountedEvent = "go_hass_agent_share_unmounted"
+)
+
+// networkFSTypes are the filesystem types of NFS and SMB/CIFS mounts.
+var networkFSTypes = []string{"nfs", "nfs4", "cifs", "smb3", "smbfs"}
+
+// networkShare is a mounted NFS or SMB/CIFS share.
+type networkShare struct {
+	Mountpoint string `json:"Mountpoint"`
+	Source     string `json:"Source"`
+	Server     string `json:"Server"`
+	Type       string `json:"Type"`
+}
+
+// shareServer extracts the server from the source of a network mount, which
+// is server:/export for NFS and //server/share for SMB/CIFS. IPv6 NFS servers
+// are enclosed in brackets.
+func shareServer(source string) string {
+	if rest, ok := strings.CutPrefix(source, "//"); ok {
+		server, _, _ := strings.Cut(rest, "/")
+		return server
+	}
+	if rest, ok := strings.CutPrefix(source, "["); ok {
+		server, _, _ := strings.Cut(rest, "]")
+		return server
+	}
+	server, _, _ := strings.Cut(source, ":")
+	return server
+}
+
+// unescapeMountField decodes the octal escapes (e.g., \040 for a space) used
+// for special characters in the fields of the mounts file.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if c, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
+
+// parseNetworkShares returns the network shares listed in the given mounts
+// file content, sorted by mountpoint.
+func parseNetworkShares(r io.Reader) []networkShare {
+	shares := []networkShare{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !slices.Contains(networkFSTypes, fields[2]) {
+			continue
+		}
+		source := unescapeMountField(fields[0])
+		shares = append(shares, networkShare{
+			Mountpoint: unescapeMountField(fields[1]),
+			Source:     source,
+			Server:     shareServer(source),
+			Type:       fields[2],
+		})
+	}
+	slices.SortFunc(shares, func(a, b networkShare) int {
+		return strings.Compare(a.Mountpoint, b.Mountpoint)
+	})
+	return shares
+}
+
+// networkShares returns the currently mounted network shares.
+func networkShares() ([]networkShare, error) {
+	f, err := os.Open(mountsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseNetworkShares(f), nil
+}
+
+type sharesSensor struct {
+	shares []networkShare
+	linux.Sensor
+}
+
+func (s *sharesSensor) Icon() string {
+	if len(s.shares) > 0 {
+		return "mdi:folder-network"
+	}
+	return "mdi:folder-network-outline"
+}
+
+func (s *sharesSensor) Attributes() any {
+	return struct {
+		DataSource string         `json:"Data Source"`
+		Shares     []networkShare `json:"Shares"`
+	}{
+		DataSource: linux.DataSrcProcfs,
+		Shares:     s.shares,
+	}
+}
+
+func newSharesSensor(shares []networkShare) *sharesSensor {
+	return &sharesSensor{
+		shares: shares,
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorNetworkShares,
+			StateClassValue: sensor.StateMeasurement,
+			Value:           len(shares),
+		},
+	}
+}
+
+// SharesUpdater reports the number of mounted NFS and SMB/CIFS shares, with the
+// details of each share as attributes.
+func SharesUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var mu sync.Mutex
+	var last *sharesSensor
+	sendSharesSensor := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		shares, err := networkShares()
+		if err != nil {
+			log.Debug().Err(err).Msg("Could not read mounts.")
+			return
+		}
+		s := newSharesSensor(shares)
+		if last != nil && reflect.DeepEqual(last.shares, s.shares) {
+			return
+		}
+		last = s
+		select {
+		case sensorCh <- s:
+		case <-ctx.Done():
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendSharesSensor, sharesPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped network shares sensor.")
+	}()
+	return sensorCh
+}
+
+func newShareEvent(eventType, deviceName string, share networkShare) *hass.Event {
+	return &hass.Event{
+		EventType: eventType,
+		EventData: map[string]string{
+			"device_name": deviceName,
+			"mountpoint":  share.Mountpoint,
+			"source":      share.Source,
+			"server":      share.Server,
+			"type":        share.Type,
+		},
+	}
+}
+
+// diffShares returns the shares in current that are not in previous (mounted)
+// and the shares in previous that are not in current (unmounted).
+func diffShares(previous, current []networkShare) (mounted, unmounted []networkShare) {
+	for _, s := range current {
+		if !slices.Contains(previous, s) {
+			mounted = append(mounted, s)
+		}
+	}
+	for _, s := range previous {
+		if !slices.Contains(current, s) {
+			unmounted = append(unmounted, s)
+		}
+	}
+	return mounted, unmounted
+}
+
+// ShareEventsUpdater fires an event in Home Assistant when a NFS or SMB/CIFS
+// share is mounted or unmounted. Shares already mounted when the agent starts
+// do not fire events.
+func ShareEventsUpdater(ctx context.Context) chan *hass.Event {
+	eventCh := make(chan *hass.Event)
+	deviceName := preferences.FetchFromContext(ctx).DeviceName
+	previous, err := networkShares()
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not read mounts. Network share events will not be sent.")
+		close(eventCh)
+		return eventCh
+	}
+
+	var mu sync.Mutex
+	sendShareEvents := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		current, err := networkShares()
+		if err != nil {
+			log.Debug().Err(err).Msg("Could not read mounts.")
+			return
+		}
+		mounted, unmounted := diffShares(previous, current)
+		previous = current
+		var events []*hass.Event
+		for _, s := range mounted {
+			events = append(events, newShareEvent(ShareMountedEvent, deviceName, s))
+		}
+		for _, s := range unmounted {
+			events = append(events, newShareEvent(ShareUnmountedEvent, deviceName, s))
+		}
+		for _, e := range events {
+			select {
+			case eventCh <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendShareEvents, sharesPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(eventCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped network share events.")
+	}()
+	return eventCh
+}
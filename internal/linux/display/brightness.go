@@ -0,0 +1,212 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package display
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	backlightSysfsDir = "/sys/class/backlight"
+
+	loginDest        = "org.freedesktop.login1"
+	loginSessionIntr = loginDest + ".Session"
+)
+
+var errNoMaxBrightness = errors.New("backlight has no maximum brightness")
+
+type brightnessSensor struct {
+	device string
+	max    int
+	linux.Sensor
+}
+
+func (s *brightnessSensor) Name() string {
+	return s.device + " " + s.SensorTypeValue.String()
+}
+
+func (s *brightnessSensor) ID() string {
+	return strcase.ToSnake(s.device + "_" + s.SensorTypeValue.String())
+}
+
+func (s *brightnessSensor) Icon() string {
+	if v, ok := s.Value.(int); ok && v < 50 {
+		return "mdi:brightness-5"
+	}
+	return "mdi:brightness-7"
+}
+
+func (s *brightnessSensor) Attributes() any {
+	return struct {
+		MaxBrightness int    `json:"Max Brightness"`
+		DataSource    string `json:"Data Source"`
+	}{
+		MaxBrightness: s.max,
+		DataSource:    linux.DataSrcSysfs,
+	}
+}
+
+// newBrightnessSensor reads the current brightness of the given backlight
+// device and returns it as a percentage of the maximum brightness.
+func newBrightnessSensor(device string) (*brightnessSensor, error) {
+	current, max, err := readBrightness(device)
+	if err != nil {
+		return nil, err
+	}
+	s := &brightnessSensor{device: device, max: max}
+	s.SensorTypeValue = linux.SensorBrightness
+	s.UnitsString = "%"
+	s.StateClassValue = sensor.StateMeasurement
+	if max > 0 {
+		s.Value = int(math.Round(float64(current) / float64(max) * 100))
+	}
+	return s, nil
+}
+
+// readBrightness returns the current and maximum brightness of the given
+// backlight device.
+func readBrightness(device string) (current, max int, err error) {
+	dir := filepath.Join(backlightSysfsDir, device)
+	max, err = readInt(filepath.Join(dir, "max_brightness"))
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err = readInt(filepath.Join(dir, "actual_brightness"))
+	if err != nil {
+		if current, err = readInt(filepath.Join(dir, "brightness")); err != nil {
+			return 0, 0, err
+		}
+	}
+	return current, max, nil
+}
+
+// Backlights returns the names of the backlight devices.
+func Backlights() []string {
+	entries, err := os.ReadDir(backlightSysfsDir)
+	if err != nil {
+		return nil
+	}
+	devices := make([]string, 0, len(entries))
+	for _, e := range entries {
+		devices = append(devices, e.Name())
+	}
+	return devices
+}
+
+// Brightness returns the brightness of the given backlight device, as a
+// percentage of its maximum brightness.
+func Brightness(device string) (int, error) {
+	current, max, err := readBrightness(device)
+	if err != nil {
+		return 0, err
+	}
+	if max <= 0 {
+		return 0, errNoMaxBrightness
+	}
+	return int(math.Round(float64(current) / float64(max) * 100)), nil
+}
+
+// SetBrightness sets the brightness of the given backlight device, as a
+// percentage of its maximum brightness. The brightness is set through logind,
+// which allows the user of the active session to change it without needing
+// write access to sysfs.
+func SetBrightness(ctx context.Context, device string, percent int) error {
+	_, maxBrightness, err := readBrightness(device)
+	if err != nil {
+		return err
+	}
+	percent = min(max(percent, 0), 100)
+	value := uint32(math.Round(float64(percent) / 100 * float64(maxBrightness)))
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dbusx.GetSessionPath(ctx)).
+		Destination(loginDest).
+		Call(loginSessionIntr+".SetBrightness", "backlight", device, value)
+}
+
+func readInt(file string) (int, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// BrightnessUpdater reports the brightness of each backlight device. Rather
+// than polling, the brightness files are watched for changes. Note that
+// changes made directly by the kernel or firmware (e.g., some hardware
+// brightness keys) may not generate a change notification until the brightness
+// is next set from userspace (for example, by the desktop or logind).
+func BrightnessUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	devices, err := os.ReadDir(backlightSysfsDir)
+	if err != nil || len(devices) == 0 {
+		log.Debug().Msg("No backlight devices found. Brightness sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch for brightness changes. Brightness sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	sendBrightness := func(device string) {
+		s, err := newBrightnessSensor(device)
+		if err != nil {
+			log.Debug().Err(err).Str("device", device).Msg("Could not read brightness.")
+			return
+		}
+		sensorCh <- s
+	}
+
+	for _, d := range devices {
+		if err := watcher.Add(filepath.Join(backlightSysfsDir, d.Name(), "brightness")); err != nil {
+			log.Debug().Err(err).Str("device", d.Name()).Msg("Could not watch brightness.")
+			continue
+		}
+		go sendBrightness(d.Name())
+	}
+
+	go func() {
+		defer close(sensorCh)
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug().Msg("Stopped brightness sensors.")
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Write) {
+					sendBrightness(filepath.Base(filepath.Dir(event.Name)))
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Debug().Err(err).Msg("Error watching brightness.")
+			}
+		}
+	}()
+	return sensorCh
+}
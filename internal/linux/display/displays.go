@@ -0,0 +1,238 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package display
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	drmSysfsDir = "/sys/class/drm"
+	dataSrcX11  = "X11"
+
+	// hotplugSettle is how long to wait after a hotplug event before reading
+	// the display configuration, to give the desktop time to reconfigure.
+	hotplugSettle = 2 * time.Second
+	// ueventTimeout is how long to wait for a uevent before checking whether
+	// to stop listening.
+	ueventTimeout = time.Second
+)
+
+var xrandrOutput = regexp.MustCompile(`^(\S+) connected( primary)?(?: (\d+x\d+)\+\d+\+\d+)?`)
+
+// monitor represents a connected display output.
+type monitor struct {
+	Resolution string `json:"Resolution,omitempty"`
+	Primary    bool   `json:"Primary"`
+}
+
+type displaysSensor struct {
+	monitors map[string]*monitor
+	source   string
+	linux.Sensor
+}
+
+func (s *displaysSensor) Attributes() any {
+	var primary string
+	for name, m := range s.monitors {
+		if m.Primary {
+			primary = name
+		}
+	}
+	return struct {
+		Displays   map[string]*monitor `json:"Displays"`
+		Primary    string              `json:"Primary Display,omitempty"`
+		DataSource string              `json:"Data Source"`
+	}{
+		Displays:   s.monitors,
+		Primary:    primary,
+		DataSource: s.source,
+	}
+}
+
+func newDisplaysSensor(ctx context.Context) *displaysSensor {
+	s := &displaysSensor{}
+	s.SensorTypeValue = linux.SensorDisplays
+	s.IconString = "mdi:monitor-multiple"
+	s.UnitsString = "displays"
+	if monitors, err := getX11Monitors(ctx); err == nil && len(monitors) > 0 {
+		s.monitors = monitors
+		s.source = dataSrcX11
+	} else {
+		s.monitors = getDRMMonitors()
+		s.source = linux.DataSrcSysfs
+	}
+	s.Value = len(s.monitors)
+	return s
+}
+
+// getX11Monitors uses xrandr to find the connected outputs, with their current
+// resolution and which is the primary output.
+func getX11Monitors(ctx context.Context) (map[string]*monitor, error) {
+	if os.Getenv("DISPLAY") == "" {
+		return nil, os.ErrNotExist
+	}
+	xrandr, err := exec.LookPath("xrandr")
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.CommandContext(ctx, xrandr, "--query").Output()
+	if err != nil {
+		return nil, err
+	}
+	monitors := make(map[string]*monitor)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if m := xrandrOutput.FindStringSubmatch(scanner.Text()); m != nil {
+			monitors[m[1]] = &monitor{
+				Primary:    m[2] != "",
+				Resolution: m[3],
+			}
+		}
+	}
+	return monitors, nil
+}
+
+// getDRMMonitors finds the connected outputs from the DRM subsystem in sysfs.
+// The current resolution and primary display are not available from sysfs, so
+// the preferred (first listed) mode of the output is reported instead.
+func getDRMMonitors() map[string]*monitor {
+	monitors := make(map[string]*monitor)
+	connectors, err := filepath.Glob(filepath.Join(drmSysfsDir, "card*-*"))
+	if err != nil {
+		return monitors
+	}
+	for _, c := range connectors {
+		if readString(filepath.Join(c, "status")) != "connected" {
+			continue
+		}
+		// Connector directories are named card<n>-<output>.
+		_, name, _ := strings.Cut(filepath.Base(c), "-")
+		mode, _, _ := strings.Cut(readString(filepath.Join(c, "modes")), "\n")
+		monitors[name] = &monitor{Resolution: mode}
+	}
+	return monitors
+}
+
+func readString(file string) string {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// watchDRMHotplug listens for kernel uevents and signals on the returned
+// channel whenever a DRM hotplug event is received.
+func watchDRMHotplug(ctx context.Context) (<-chan struct{}, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Pid:    0,
+		Groups: 1,
+	}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	// Closing the socket from another goroutine does not interrupt a
+	// blocked receive, so receive with a timeout and check the context in
+	// between.
+	timeout := syscall.NsecToTimeval(ueventTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	hotplugCh := make(chan struct{}, 1)
+	go func() {
+		defer close(hotplugCh)
+		defer syscall.Close(fd)
+		buf := make([]byte, os.Getpagesize())
+		for ctx.Err() == nil {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if err != nil {
+				log.Debug().Err(err).Msg("Stopped receiving uevents.")
+				return
+			}
+			if isDRMHotplug(buf[:n]) {
+				select {
+				case hotplugCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return hotplugCh, nil
+}
+
+// isDRMHotplug returns whether the given uevent message is a DRM hotplug
+// event. The message is a NUL separated list of KEY=value pairs.
+func isDRMHotplug(msg []byte) bool {
+	var drm, hotplug bool
+	for _, field := range bytes.Split(msg, []byte{0}) {
+		switch string(field) {
+		case "SUBSYSTEM=drm":
+			drm = true
+		case "HOTPLUG=1":
+			hotplug = true
+		}
+	}
+	return drm && hotplug
+}
+
+// DisplaysUpdater reports the connected displays, updating whenever a display
+// is connected or disconnected.
+func DisplaysUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	hotplugCh, err := watchDRMHotplug(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch for display hotplug events. Display sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	go func() {
+		defer close(sensorCh)
+		sensorCh <- newDisplaysSensor(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug().Msg("Stopped display sensor.")
+				return
+			case _, ok := <-hotplugCh:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(hotplugSettle):
+				}
+				sensorCh <- newDisplaysSensor(ctx)
+			}
+		}
+	}()
+	return sensorCh
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package display
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	upowerDest         = "org.freedesktop.UPower"
+	kbdBacklightPath   = "/org/freedesktop/UPower/KbdBacklight"
+	kbdBacklightIntr   = upowerDest + ".KbdBacklight"
+	kbdBrightnessEvent = kbdBacklightIntr + ".BrightnessChanged"
+)
+
+// ErrNoKbdBacklight is returned when the device has no keyboard backlight, or
+// UPower is not running.
+var ErrNoKbdBacklight = errors.New("no keyboard backlight found")
+
+// kbdBacklightValue calls the given method of the UPower keyboard backlight,
+// which returns an int32.
+func kbdBacklightValue(ctx context.Context, method string) (int, error) {
+	data := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(kbdBacklightPath).
+		Destination(upowerDest).
+		GetData(kbdBacklightIntr + "." + method)
+	if data == nil {
+		return 0, ErrNoKbdBacklight
+	}
+	if err := data.Err(); err != nil {
+		return 0, errors.Join(ErrNoKbdBacklight, err)
+	}
+	value, ok := data.AsRawInterface().(int32)
+	if !ok {
+		return 0, ErrNoKbdBacklight
+	}
+	return int(value), nil
+}
+
+// KbdBrightness returns the brightness of the keyboard backlight, as a
+// percentage of its maximum brightness.
+func KbdBrightness(ctx context.Context) (int, error) {
+	maxBrightness, err := kbdBacklightValue(ctx, "GetMaxBrightness")
+	if err != nil {
+		return 0, err
+	}
+	if maxBrightness <= 0 {
+		return 0, errNoMaxBrightness
+	}
+	current, err := kbdBacklightValue(ctx, "GetBrightness")
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Round(float64(current) / float64(maxBrightness) * 100)), nil
+}
+
+// SetKbdBrightness sets the brightness of the keyboard backlight, as a
+// percentage of its maximum brightness. Most keyboard backlights only have a
+// few levels, so the brightness is rounded to the nearest level.
+func SetKbdBrightness(ctx context.Context, percent int) error {
+	maxBrightness, err := kbdBacklightValue(ctx, "GetMaxBrightness")
+	if err != nil {
+		return err
+	}
+	percent = min(max(percent, 0), 100)
+	value := int32(math.Round(float64(percent) / 100 * float64(maxBrightness)))
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(kbdBacklightPath).
+		Destination(upowerDest).
+		Call(kbdBacklightIntr+".SetBrightness", value)
+}
+
+// WatchKbdBrightness calls the given function whenever the brightness of the
+// keyboard backlight changes, including from outside the agent (e.g., the
+// keyboard backlight keys).
+func WatchKbdBrightness(ctx context.Context, changed func()) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(kbdBacklightPath),
+			dbus.WithMatchInterface(kbdBacklightIntr),
+			dbus.WithMatchMember("BrightnessChanged"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != kbdBacklightPath || s.Name != kbdBrightnessEvent {
+				return
+			}
+			go changed()
+		}).
+		AddWatch(ctx)
+}
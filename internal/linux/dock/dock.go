@@ -0,0 +1,212 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	boltDest            = "org.freedesktop.bolt"
+	boltPath            = "/org/freedesktop/bolt"
+	boltManagerIntr     = "org.freedesktop.bolt1.Manager"
+	boltDeviceIntr      = "org.freedesktop.bolt1.Device"
+	boltListDevices     = boltManagerIntr + ".ListDevices"
+	boltPeripheralType  = "peripheral"
+	thunderboltSysfsDir = "/sys/bus/thunderbolt/devices"
+
+	dataSrcBolt  = "D-Bus (bolt)"
+	pollInterval = 30 * time.Second
+)
+
+// connectedStates are the bolt device states in which a device is attached.
+var connectedStates = []string{"connected", "authorizing", "auth-error", "authorized"}
+
+type dockSensor struct {
+	source string
+	models []string
+	linux.Sensor
+}
+
+func (s *dockSensor) Icon() string {
+	if docked, ok := s.Value.(bool); ok && docked {
+		return "mdi:laptop"
+	}
+	return "mdi:laptop-off"
+}
+
+func (s *dockSensor) Attributes() any {
+	return struct {
+		Models     []string `json:"Models,omitempty"`
+		DataSource string   `json:"Data Source"`
+	}{
+		Models:     s.models,
+		DataSource: s.source,
+	}
+}
+
+func newDockSensor(source string, models []string) *dockSensor {
+	return &dockSensor{
+		source: source,
+		models: models,
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorDocked,
+			IsBinary:        true,
+			Value:           len(models) > 0,
+		},
+	}
+}
+
+// boltDocks returns the model of each connected Thunderbolt/USB4 dock, as
+// reported by the bolt daemon. Other peripherals, such as external drives, are
+// ignored.
+func boltDocks(ctx context.Context) ([]string, bool) {
+	paths := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(boltPath).
+		Destination(boltDest).
+		GetData(boltListDevices).
+		AsObjectPathList()
+	if paths == nil {
+		return nil, false
+	}
+	var models []string
+	for _, p := range paths {
+		req := dbusx.NewBusRequest(ctx, dbusx.SystemBus).Path(p).Destination(boltDest)
+		devType, err := req.GetProp(boltDeviceIntr + ".Type")
+		if err != nil || dbusx.VariantToValue[string](devType) != boltPeripheralType {
+			continue
+		}
+		status, err := req.GetProp(boltDeviceIntr + ".Status")
+		if err != nil || !slices.Contains(connectedStates, dbusx.VariantToValue[string](status)) {
+			continue
+		}
+		var model []string
+		if v, err := req.GetProp(boltDeviceIntr + ".Vendor"); err == nil {
+			model = append(model, dbusx.VariantToValue[string](v))
+		}
+		if v, err := req.GetProp(boltDeviceIntr + ".Name"); err == nil {
+			model = append(model, dbusx.VariantToValue[string](v))
+		}
+		if name := strings.TrimSpace(strings.Join(model, " ")); isDock(name) {
+			models = append(models, name)
+		}
+	}
+	return models, true
+}
+
+// sysfsDocks returns the model of each Thunderbolt/USB4 dock found in sysfs,
+// for when bolt is not running. Peripherals have a route string as their
+// name (e.g., 0-1), whereas the host controller is always 0-0, 1-0, etc.
+func sysfsDocks() []string {
+	devices, err := filepath.Glob(filepath.Join(thunderboltSysfsDir, "*-*"))
+	if err != nil {
+		return nil
+	}
+	var models []string
+	for _, dev := range devices {
+		name := filepath.Base(dev)
+		if strings.HasSuffix(name, "-0") || strings.ContainsAny(name, ".:") {
+			continue
+		}
+		vendor, _ := os.ReadFile(filepath.Join(dev, "vendor_name"))
+		device, err := os.ReadFile(filepath.Join(dev, "device_name"))
+		if err != nil {
+			continue
+		}
+		if name := strings.TrimSpace(strings.TrimSpace(string(vendor)) + " " + strings.TrimSpace(string(device))); isDock(name) {
+			models = append(models, name)
+		}
+	}
+	return models
+}
+
+// Updater reports whether a Thunderbolt/USB4 or USB-C dock is connected, with
+// the model of each connected dock as an attribute. Thunderbolt devices are
+// retrieved from the bolt daemon, falling back to sysfs if bolt is not
+// running, and USB devices from sysfs. Changes are tracked via bolt signals,
+// udev (kernel uevents) and by polling.
+func Updater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var mu sync.Mutex
+	var last *dockSensor
+	sendDockSensor := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		models, ok := boltDocks(ctx)
+		source := dataSrcBolt + ", " + linux.DataSrcSysfs
+		if !ok {
+			models, source = sysfsDocks(), linux.DataSrcSysfs
+		}
+		// A dock may show up as more than one device with the same name.
+		models = append(models, usbDocks()...)
+		slices.Sort(models)
+		s := newDockSensor(source, slices.Compact(models))
+		if last != nil && slices.Equal(last.models, s.models) {
+			return
+		}
+		last = s
+		select {
+		case sensorCh <- s:
+		case <-ctx.Done():
+		}
+	}
+
+	err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchPathNamespace(boltPath),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if !strings.HasPrefix(string(s.Path), boltPath) {
+				return
+			}
+			switch s.Name {
+			case boltManagerIntr + ".DeviceAdded", boltManagerIntr + ".DeviceRemoved", dbusx.PropChangedSignal:
+				go sendDockSensor(0)
+			}
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not watch bolt for dock changes. Will only poll for changes.")
+	}
+
+	if hotplugCh, err := watchHotplug(ctx); err != nil {
+		log.Debug().Err(err).Msg("Could not watch udev for dock changes. Will only poll for changes.")
+	} else {
+		go func() {
+			for range hotplugCh {
+				sendDockSensor(0)
+			}
+		}()
+	}
+
+	go helpers.PollSensors(ctx, sendDockSensor, pollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped dock sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	usbSysfsDir = "/sys/bus/usb/devices"
+	// usbClassBillboard is the USB device class of the Billboard device that
+	// USB-C docks present to describe their alternate modes (e.g.,
+	// DisplayPort).
+	usbClassBillboard = "11"
+	// ueventTimeout is how long to wait for a uevent before checking whether
+	// to stop listening.
+	ueventTimeout = time.Second
+)
+
+// isDock returns whether the given device name is that of a dock, rather than
+// some other peripheral such as an external drive.
+func isDock(name string) bool {
+	return strings.Contains(strings.ToLower(name), "dock")
+}
+
+// usbDocks returns the model of each USB-C dock found in sysfs. A USB device
+// is a dock if it is named as one, or it is a Billboard device, which is only
+// found on USB-C docks and adapters with display outputs.
+func usbDocks() []string {
+	devices, err := os.ReadDir(usbSysfsDir)
+	if err != nil {
+		return nil
+	}
+	var models []string
+	for _, d := range devices {
+		// Interfaces are named <device>:<config>.<interface> and root hubs
+		// usb<bus>. Only devices are of interest.
+		if strings.Contains(d.Name(), ":") || strings.HasPrefix(d.Name(), "usb") {
+			continue
+		}
+		dev := filepath.Join(usbSysfsDir, d.Name())
+		model := strings.TrimSpace(readString(filepath.Join(dev, "manufacturer")) + " " + readString(filepath.Join(dev, "product")))
+		if model == "" {
+			continue
+		}
+		if isDock(model) || readString(filepath.Join(dev, "bDeviceClass")) == usbClassBillboard {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+func readString(file string) string {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// watchHotplug listens for kernel uevents and signals on the returned channel
+// whenever a USB or Thunderbolt device is added or removed.
+func watchHotplug(ctx context.Context) (<-chan struct{}, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Pid:    0,
+		Groups: 1,
+	}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	// Closing the socket from another goroutine does not interrupt a
+	// blocked receive, so receive with a timeout and check the context in
+	// between.
+	timeout := syscall.NsecToTimeval(ueventTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	hotplugCh := make(chan struct{}, 1)
+	go func() {
+		defer close(hotplugCh)
+		defer syscall.Close(fd)
+		buf := make([]byte, os.Getpagesize())
+		for ctx.Err() == nil {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if err != nil {
+				log.Debug().Err(err).Msg("Stopped receiving uevents.")
+				return
+			}
+			if isDeviceHotplug(buf[:n]) {
+				select {
+				case hotplugCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return hotplugCh, nil
+}
+
+// isDeviceHotplug returns whether the given uevent message is for a USB or
+// Thunderbolt device being added or removed. The message is a NUL separated
+// list of KEY=value pairs.
+func isDeviceHotplug(msg []byte) bool {
+	var device, change bool
+	for _, field := range bytes.Split(msg, []byte{0}) {
+		switch string(field) {
+		case "DEVTYPE=usb_device", "SUBSYSTEM=thunderbolt":
+			device = true
+		case "ACTION=add", "ACTION=remove":
+			change = true
+		}
+	}
+	return device && change
+}
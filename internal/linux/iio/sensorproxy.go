@@ -0,0 +1,214 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package iio
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	proxyDest      = "net.hadess.SensorProxy"
+	proxyPath      = "/net/hadess/SensorProxy"
+	proxyInterface = "net.hadess.SensorProxy"
+
+	lightLevelProp  = "LightLevel"
+	lightUnitProp   = "LightLevelUnit"
+	orientationProp = "AccelerometerOrientation"
+	proximityProp   = "ProximityNear"
+
+	// releaseTimeout is how long to wait for the proxy to release the
+	// claimed sensors when stopping.
+	releaseTimeout = 5 * time.Second
+)
+
+// proxySensor describes a sensor type provided by iio-sensor-proxy. The
+// sensor must be claimed before the proxy will report its value and should be
+// released when no longer needed.
+type proxySensor struct {
+	has  string
+	name string
+	prop string
+}
+
+var proxySensors = []proxySensor{
+	{has: "HasAmbientLight", name: "Light", prop: lightLevelProp},
+	{has: "HasAccelerometer", name: "Accelerometer", prop: orientationProp},
+	{has: "HasProximity", name: "Proximity", prop: proximityProp},
+}
+
+type iioSensor struct {
+	linux.Sensor
+}
+
+func newLightSensor(level float64, unit string) *iioSensor {
+	s := &iioSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorLightLevel,
+			IconString:      "mdi:brightness-5",
+			StateClassValue: sensor.StateMeasurement,
+			SensorSrc:       linux.DataSrcDbus,
+			Value:           level,
+		},
+	}
+	// The light level is only in lux if the hardware supports it. Otherwise,
+	// it is a vendor-specific value with no units.
+	if unit == "lux" {
+		s.UnitsString = "lx"
+		s.DeviceClassValue = sensor.Illuminance
+	}
+	return s
+}
+
+func newOrientationSensor(orientation string) *iioSensor {
+	return &iioSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorOrientation,
+			IconString:      "mdi:screen-rotation",
+			SensorSrc:       linux.DataSrcDbus,
+			Value:           orientation,
+		},
+	}
+}
+
+func newProximitySensor(near bool) *iioSensor {
+	return &iioSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorProximity,
+			IconString:      "mdi:signal-distance-variant",
+			IsBinary:        true,
+			SensorSrc:       linux.DataSrcDbus,
+			Value:           near,
+		},
+	}
+}
+
+func getProp(ctx context.Context, prop string) (dbus.Variant, error) {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(proxyPath).
+		Destination(proxyDest).
+		GetProp(proxyInterface + "." + prop)
+}
+
+func callMethod(ctx context.Context, method string) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(proxyPath).
+		Destination(proxyDest).
+		Call(proxyInterface + "." + method)
+}
+
+// release releases the given claimed sensors, so that the proxy can stop
+// polling the hardware.
+func release(ctx context.Context, claimed []proxySensor) {
+	for _, s := range claimed {
+		if err := callMethod(ctx, "Release"+s.name); err != nil {
+			log.Debug().Err(err).Str("sensor", s.name).Msg("Could not release sensor from iio-sensor-proxy.")
+		}
+	}
+}
+
+// Updater reports the ambient light level, device orientation and proximity
+// from iio-sensor-proxy, for whichever of these the hardware supports. Values
+// are updated as the proxy reports changes.
+func Updater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var claimed []proxySensor
+	for _, s := range proxySensors {
+		if has, err := getProp(ctx, s.has); err != nil || !dbusx.VariantToValue[bool](has) {
+			continue
+		}
+		if err := callMethod(ctx, "Claim"+s.name); err != nil {
+			log.Debug().Err(err).Str("sensor", s.name).Msg("Could not claim sensor from iio-sensor-proxy.")
+			continue
+		}
+		claimed = append(claimed, s)
+	}
+	if len(claimed) == 0 {
+		log.Debug().Msg("No sensors available from iio-sensor-proxy. IIO sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	var mu sync.Mutex
+	var lightUnit string
+	if v, err := getProp(ctx, lightUnitProp); err == nil {
+		lightUnit = dbusx.VariantToValue[string](v)
+	}
+	newSensor := func(prop string, v dbus.Variant) tracker.Sensor {
+		switch prop {
+		case lightLevelProp:
+			mu.Lock()
+			defer mu.Unlock()
+			return newLightSensor(dbusx.VariantToValue[float64](v), lightUnit)
+		case orientationProp:
+			return newOrientationSensor(dbusx.VariantToValue[string](v))
+		case proximityProp:
+			return newProximitySensor(dbusx.VariantToValue[bool](v))
+		}
+		return nil
+	}
+
+	err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(proxyPath),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != proxyPath || s.Name != dbusx.PropChangedSignal || len(s.Body) <= 1 {
+				return
+			}
+			props, ok := s.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				return
+			}
+			if v, ok := props[lightUnitProp]; ok {
+				mu.Lock()
+				lightUnit = dbusx.VariantToValue[string](v)
+				mu.Unlock()
+			}
+			for prop, v := range props {
+				if update := newSensor(prop, v); update != nil {
+					sensorCh <- update
+				}
+			}
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch iio-sensor-proxy. IIO sensors will not run.")
+		release(ctx, claimed)
+		close(sensorCh)
+		return sensorCh
+	}
+
+	go func() {
+		for _, s := range claimed {
+			if v, err := getProp(ctx, s.prop); err == nil {
+				sensorCh <- newSensor(s.prop, v)
+			}
+		}
+	}()
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		// The context is canceled by now, so release the sensors with a
+		// new one that keeps the D-Bus connection.
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+		defer cancel()
+		release(releaseCtx, claimed)
+		log.Debug().Msg("Stopped IIO sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package maintenance runs routine maintenance actions on the device, such as
+// emptying the trash.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/joshuar/go-hass-agent/internal/linux/systemd"
+)
+
+// Maintenance actions, as used in the preferences.
+const (
+	EmptyTrash      = "emptytrash"
+	ClearThumbnails = "thumbnails"
+	Trim            = "fstrim"
+)
+
+// Actions are the available maintenance actions.
+var Actions = []string{EmptyTrash, ClearThumbnails, Trim}
+
+var errUnknownAction = errors.New("unknown maintenance action")
+
+// Run runs the given maintenance action. It returns the number of bytes freed,
+// for actions that remove files.
+func Run(ctx context.Context, action string) (int64, error) {
+	switch action {
+	case EmptyTrash:
+		dir, err := dataHome()
+		if err != nil {
+			return 0, err
+		}
+		// The trash holds the trashed files and a matching info file for
+		// each, as per the XDG trash specification.
+		trash := filepath.Join(dir, "Trash")
+		var freed int64
+		for _, sub := range []string{"files", "info", "expunged"} {
+			n, err := removeContents(ctx, filepath.Join(trash, sub))
+			freed += n
+			if err != nil {
+				return freed, err
+			}
+		}
+		// The cached directory sizes are no longer valid.
+		if err := os.Remove(filepath.Join(trash, "directorysizes")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return freed, err
+		}
+		return freed, nil
+	case ClearThumbnails:
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return 0, err
+		}
+		return removeContents(ctx, filepath.Join(dir, "thumbnails"))
+	case Trim:
+		return 0, systemd.RunSystemService(ctx, "fstrim.service")
+	default:
+		return 0, errUnknownAction
+	}
+}
+
+// dataHome returns $XDG_DATA_HOME, or its default of ~/.local/share.
+func dataHome() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// removeContents removes everything in the given directory, but not the
+// directory itself, and returns the size of the regular files removed. A
+// directory that does not exist has nothing to remove.
+func removeContents(ctx context.Context, dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var freed int64
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return freed, err
+		}
+		path := filepath.Join(dir, e.Name())
+		freed += size(path)
+		if err := os.RemoveAll(path); err != nil {
+			return freed, err
+		}
+	}
+	return freed, nil
+}
+
+// size returns the total size of the regular files at the given path. Symlinks
+// are not followed.
+func size(path string) int64 {
+	var total int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		// Skip anything that cannot be read.
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
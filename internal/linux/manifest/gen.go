@@ -0,0 +1,405 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//go:build ignore
+
+// gen walks the source of the Linux sensor workers and writes a manifest of
+// every sensor they can produce to manifest.json. Sensors are found from the
+// linux.Sensor values the workers create and the sensor types they switch on.
+// The device class, state class and units are taken from the fields set
+// alongside the sensor type, or returned for it by the Units, DeviceClass and
+// StateClass methods.
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+const (
+	linuxDir    = ".."
+	sensorDir   = "../../hass/sensor"
+	output      = "manifest.json"
+	linuxPkg    = "linux"
+	sensorPkg   = "sensor"
+	typeField   = "SensorTypeValue"
+	typePrefix  = "Sensor"
+	platform    = "linux"
+	manifestPkg = "manifest"
+)
+
+type entry struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	DeviceClass string `json:"device_class,omitempty"`
+	StateClass  string `json:"state_class,omitempty"`
+	Units       string `json:"units,omitempty"`
+	Diagnostic  bool   `json:"diagnostic,omitempty"`
+	Platform    string `json:"platform"`
+	Worker      string `json:"worker"`
+	binary      bool
+}
+
+// fields are the linux.Sensor fields that describe a sensor, as found in the
+// source.
+type fields map[string]ast.Expr
+
+type generator struct {
+	labels        map[string]string
+	deviceClasses map[string]string
+	stateClasses  map[string]string
+	entries       map[string]*entry
+	// templates are the fields set by constructors that take the sensor type
+	// as a parameter, keyed by package and function name.
+	templates map[string]fields
+}
+
+// constants returns the names of the constants declared in the given file, in
+// order, with their line comments.
+func constants(path string) ([]string, []string) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var names, comments []string
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			v := spec.(*ast.ValueSpec)
+			for _, name := range v.Names {
+				names = append(names, name.Name)
+				comments = append(comments, strings.TrimSpace(v.Comment.Text()))
+			}
+		}
+	}
+	return names, comments
+}
+
+// selector returns the name selected from the given package by the expression
+// (e.g., SensorBattTemp for linux.SensorBattTemp).
+func selector(expr ast.Expr, pkg string) (string, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || x.Name != pkg {
+		return "", false
+	}
+	return sel.Sel.Name, true
+}
+
+// sensorType returns the sensor type for the expression, if it is one.
+func (g *generator) sensorType(expr ast.Expr) (string, bool) {
+	name, ok := selector(expr, linuxPkg)
+	if !ok {
+		return "", false
+	}
+	_, ok = g.labels[name]
+	return name, ok
+}
+
+// apply records that the worker can produce a sensor of the given type, with
+// any of the given fields that are known.
+func (g *generator) apply(worker, sensorType string, f fields) {
+	// Sensor types with the same label are the same sensor.
+	label := g.labels[sensorType]
+	key := worker + "/" + label
+	e, ok := g.entries[key]
+	if !ok {
+		e = &entry{
+			ID:       strcase.ToSnake(label),
+			Name:     label,
+			Platform: platform,
+			Worker:   worker,
+		}
+		g.entries[key] = e
+	}
+	for name, expr := range f {
+		switch name {
+		case "UnitsString":
+			if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING && e.Units == "" {
+				e.Units, _ = strconv.Unquote(lit.Value)
+			}
+		case "DeviceClassValue":
+			if class, ok := selector(expr, sensorPkg); ok && e.DeviceClass == "" {
+				e.DeviceClass = g.deviceClasses[class]
+			}
+		case "StateClassValue":
+			if class, ok := selector(expr, sensorPkg); ok && e.StateClass == "" {
+				e.StateClass = g.stateClasses[class]
+			}
+		case "IsBinary":
+			if ident, ok := expr.(*ast.Ident); ok && ident.Name == "true" {
+				e.binary = true
+			}
+		case "IsDiagnostic":
+			if ident, ok := expr.(*ast.Ident); ok && ident.Name == "true" {
+				e.Diagnostic = true
+			}
+		}
+	}
+}
+
+// literalFields returns the fields set in a linux.Sensor composite literal.
+func literalFields(lit *ast.CompositeLit) (fields, bool) {
+	if name, ok := selector(lit.Type, linuxPkg); !ok || name != "Sensor" {
+		return nil, false
+	}
+	f := make(fields)
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok {
+				f[key.Name] = kv.Value
+			}
+		}
+	}
+	return f, true
+}
+
+// assignedFields returns the fields assigned in the statements, keyed by the
+// expression they are assigned on (e.g., s for s.UnitsString = "%").
+func assignedFields(stmts []ast.Stmt) map[string]fields {
+	assigned := make(map[string]fields)
+	for _, stmt := range stmts {
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != len(assign.Rhs) {
+			continue
+		}
+		for i, lhs := range assign.Lhs {
+			sel, ok := lhs.(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			recv := exprKey(sel.X)
+			if assigned[recv] == nil {
+				assigned[recv] = make(fields)
+			}
+			assigned[recv][sel.Sel.Name] = assign.Rhs[i]
+		}
+	}
+	return assigned
+}
+
+// exprKey returns a string for the expression, to compare receivers. The
+// embedded Sensor field is ignored so that s.Sensor.UnitsString and
+// s.UnitsString are the same.
+func exprKey(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		if e.Sel.Name == "Sensor" {
+			return exprKey(e.X)
+		}
+		return exprKey(e.X) + "." + e.Sel.Name
+	case *ast.IndexExpr:
+		return exprKey(e.X) + "[]"
+	case *ast.StarExpr:
+		return exprKey(e.X)
+	case *ast.ParenExpr:
+		return exprKey(e.X)
+	}
+	return ""
+}
+
+// methodFields maps the methods that can override a linux.Sensor field to
+// that field.
+var methodFields = map[string]string{
+	"Units":       "UnitsString",
+	"DeviceClass": "DeviceClassValue",
+	"StateClass":  "StateClassValue",
+}
+
+// findTemplates records the fields set by constructors that take the sensor type
+// as a parameter.
+func (g *generator) findTemplates(worker string, file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			f, ok := literalFields(lit)
+			if !ok {
+				return true
+			}
+			if _, ok := f[typeField].(*ast.Ident); ok {
+				g.templates[worker+"."+fn.Name.Name] = f
+			}
+			return true
+		})
+	}
+}
+
+func (g *generator) walk(worker string, file *ast.File) {
+	var method string
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			method = ""
+			if n.Recv != nil {
+				method = methodFields[n.Name.Name]
+			}
+		case *ast.CompositeLit:
+			if f, ok := literalFields(n); ok {
+				if t, ok := g.sensorType(f[typeField]); ok {
+					g.apply(worker, t, f)
+				}
+			}
+		case *ast.BlockStmt:
+			g.walkStmts(worker, n.List)
+		case *ast.CaseClause:
+			g.walkStmts(worker, n.Body)
+			var types []string
+			for _, expr := range n.List {
+				if t, ok := g.sensorType(expr); ok {
+					types = append(types, t)
+				}
+			}
+			f := make(fields)
+			for _, assigned := range assignedFields(n.Body) {
+				for name, expr := range assigned {
+					f[name] = expr
+				}
+			}
+			if method != "" {
+				for _, stmt := range n.Body {
+					if ret, ok := stmt.(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+						f[method] = ret.Results[0]
+					}
+				}
+			}
+			for _, t := range types {
+				g.apply(worker, t, f)
+			}
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok {
+				if template, ok := g.templates[worker+"."+ident.Name]; ok {
+					for _, arg := range n.Args {
+						if t, ok := g.sensorType(arg); ok {
+							g.apply(worker, t, template)
+						}
+					}
+				}
+			}
+		case *ast.SelectorExpr:
+			if t, ok := g.sensorType(n); ok {
+				g.apply(worker, t, nil)
+			}
+		}
+		return true
+	})
+}
+
+// walkStmts records sensor types assigned in the statements, with the other
+// fields assigned on the same sensor.
+func (g *generator) walkStmts(worker string, stmts []ast.Stmt) {
+	for recv, f := range assignedFields(stmts) {
+		if t, ok := g.sensorType(f[typeField]); ok && recv != "" {
+			g.apply(worker, t, f)
+		}
+	}
+}
+
+func main() {
+	g := &generator{
+		labels:        make(map[string]string),
+		deviceClasses: make(map[string]string),
+		stateClasses:  make(map[string]string),
+		entries:       make(map[string]*entry),
+		templates:     make(map[string]fields),
+	}
+	names, comments := constants(filepath.Join(linuxDir, "sensorType.go"))
+	for i, name := range names {
+		g.labels[name] = comments[i]
+	}
+	names, _ = constants(filepath.Join(sensorDir, "deviceClass.go"))
+	for _, name := range names {
+		g.deviceClasses[name] = strings.ToLower(strings.TrimPrefix(name, typePrefix))
+	}
+	names, comments = constants(filepath.Join(sensorDir, "stateClass.go"))
+	for i, name := range names {
+		g.stateClasses[name] = comments[i]
+	}
+
+	files := make(map[string][]*ast.File)
+	err := filepath.WalkDir(linuxDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".go" || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		if dir == linuxDir || filepath.Base(dir) == manifestPkg {
+			return nil
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(linuxDir, dir)
+		if err != nil {
+			return err
+		}
+		worker := filepath.ToSlash(filepath.Join(platform, rel))
+		files[worker] = append(files[worker], f)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for worker, fs := range files {
+		for _, f := range fs {
+			g.findTemplates(worker, f)
+		}
+	}
+	for worker, fs := range files {
+		for _, f := range fs {
+			g.walk(worker, f)
+		}
+	}
+
+	manifest := make([]*entry, 0, len(g.entries))
+	for _, e := range g.entries {
+		e.Type = "sensor"
+		if e.binary {
+			e.Type = "binary_sensor"
+		}
+		manifest = append(manifest, e)
+	}
+	slices.SortFunc(manifest, func(a, b *entry) int {
+		if c := strings.Compare(a.Worker, b.Worker); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(output, append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
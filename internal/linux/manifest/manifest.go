@@ -0,0 +1,52 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package manifest lists every sensor the Linux sensor workers can produce.
+// The manifest is generated from the source of the workers, so that UIs can
+// show sensors that are available but not currently reporting.
+package manifest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+)
+
+//go:generate go run gen.go
+
+//go:embed manifest.json
+var manifestJSON []byte
+
+// Sensor describes a sensor that a worker can produce.
+type Sensor struct {
+	// ID is the ID of the sensor. Sensors reported per device (e.g., per
+	// network interface or disk) have the device prepended to the ID.
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	DeviceClass string `json:"device_class,omitempty"`
+	StateClass  string `json:"state_class,omitempty"`
+	Units       string `json:"units,omitempty"`
+	Diagnostic  bool   `json:"diagnostic,omitempty"`
+	Platform    string `json:"platform"`
+	// Worker is the package of the worker that produces the sensor (e.g.,
+	// linux/net).
+	Worker string `json:"worker"`
+}
+
+// Matches returns whether the given sensor ID, as reported to Home Assistant,
+// is this sensor.
+func (s *Sensor) Matches(id string) bool {
+	return id == s.ID || strings.HasSuffix(id, "_"+s.ID)
+}
+
+// Sensors returns the manifest of every sensor the agent can produce.
+func Sensors() ([]Sensor, error) {
+	var sensors []Sensor
+	if err := json.Unmarshal(manifestJSON, &sensors); err != nil {
+		return nil, err
+	}
+	return sensors, nil
+}
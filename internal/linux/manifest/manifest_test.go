@@ -0,0 +1,36 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensors(t *testing.T) {
+	sensors, err := Sensors()
+	require.NoError(t, err)
+	require.NotEmpty(t, sensors)
+	ids := make(map[string]bool)
+	for _, s := range sensors {
+		assert.NotEmpty(t, s.ID)
+		assert.NotEmpty(t, s.Name)
+		assert.Contains(t, []string{"sensor", "binary_sensor"}, s.Type)
+		assert.Equal(t, "linux", s.Platform)
+		assert.False(t, ids[s.Worker+"/"+s.ID], "duplicate sensor %s", s.ID)
+		ids[s.Worker+"/"+s.ID] = true
+	}
+}
+
+func TestSensor_Matches(t *testing.T) {
+	s := &Sensor{ID: "bytes_received"}
+	assert.True(t, s.Matches("bytes_received"))
+	assert.True(t, s.Matches("wlan0_bytes_received"))
+	assert.False(t, s.Matches("bytes_received_throughput"))
+	assert.False(t, s.Matches("wlan0bytes_received"))
+}
@@ -0,0 +1,301 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package media
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	mprisPrefix     = "org.mpris.MediaPlayer2."
+	mprisPath       = "/org/mpris/MediaPlayer2"
+	mprisInterface  = "org.mpris.MediaPlayer2"
+	mprisPlayerIntr = mprisInterface + ".Player"
+
+	dbusDest = "org.freedesktop.DBus"
+	dbusPath = "/org/freedesktop/DBus"
+
+	statusPlaying = "Playing"
+	statusPaused  = "Paused"
+	statusStopped = "Stopped"
+	statusIdle    = "Idle"
+)
+
+// ErrNoTrackLength is returned when seeking a player that does not report the
+// length of the current track.
+var ErrNoTrackLength = errors.New("player does not report track length")
+
+// Player represents an MPRIS media player on the session bus.
+type Player struct {
+	Name   string
+	Status string
+	Title  string
+	Artist string
+	Album  string
+	dest   string
+	// trackID and length are of the current track, for seeking.
+	trackID dbus.ObjectPath
+	length  int64
+}
+
+// Players returns all MPRIS media players on the session bus, sorted by their
+// D-Bus name.
+func Players(ctx context.Context) []*Player {
+	names := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(dbusPath).
+		Destination(dbusDest).
+		GetData(dbusDest + ".ListNames").
+		AsStringList()
+	sort.Strings(names)
+	var players []*Player
+	for _, n := range names {
+		if strings.HasPrefix(n, mprisPrefix) {
+			players = append(players, newPlayer(ctx, n))
+		}
+	}
+	return players
+}
+
+// ActivePlayer returns the player that is currently playing or, if no player
+// is playing, a paused player. If there are no playing or paused players, the
+// first player found is returned. It returns nil if there are no players.
+func ActivePlayer(ctx context.Context) *Player {
+	players := Players(ctx)
+	for _, status := range []string{statusPlaying, statusPaused} {
+		for _, p := range players {
+			if p.Status == status {
+				return p
+			}
+		}
+	}
+	if len(players) > 0 {
+		return players[0]
+	}
+	return nil
+}
+
+func newPlayer(ctx context.Context, dest string) *Player {
+	p := &Player{
+		dest: dest,
+		Name: strings.TrimPrefix(dest, mprisPrefix),
+	}
+	req := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(mprisPath).
+		Destination(dest)
+	if v, err := req.GetProp(mprisInterface + ".Identity"); err == nil {
+		if identity := dbusx.VariantToValue[string](v); identity != "" {
+			p.Name = identity
+		}
+	}
+	if v, err := req.GetProp(mprisPlayerIntr + ".PlaybackStatus"); err == nil {
+		p.Status = dbusx.VariantToValue[string](v)
+	}
+	if v, err := req.GetProp(mprisPlayerIntr + ".Metadata"); err == nil {
+		metadata := dbusx.VariantToValue[map[string]dbus.Variant](v)
+		p.Title = dbusx.VariantToValue[string](metadata["xesam:title"])
+		p.Album = dbusx.VariantToValue[string](metadata["xesam:album"])
+		p.Artist = strings.Join(dbusx.VariantToValue[[]string](metadata["xesam:artist"]), ", ")
+		p.trackID = dbusx.VariantToValue[dbus.ObjectPath](metadata["mpris:trackid"])
+		p.length = dbusx.VariantToValue[int64](metadata["mpris:length"])
+	}
+	return p
+}
+
+// Call will call the given method of the org.mpris.MediaPlayer2.Player
+// interface on the player.
+func (p *Player) Call(ctx context.Context, method string, args ...any) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(mprisPath).
+		Destination(p.dest).
+		Call(mprisPlayerIntr+"."+method, args...)
+}
+
+// Volume returns the volume of the player, from 0 to 1.
+func (p *Player) Volume(ctx context.Context) (float64, error) {
+	v, err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(mprisPath).
+		Destination(p.dest).
+		GetProp(mprisPlayerIntr + ".Volume")
+	if err != nil {
+		return 0, err
+	}
+	return dbusx.VariantToValue[float64](v), nil
+}
+
+// SetVolume sets the volume of the player, from 0 to 1.
+func (p *Player) SetVolume(ctx context.Context, volume float64) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(mprisPath).
+		Destination(p.dest).
+		SetProp(mprisPlayerIntr+".Volume", dbus.MakeVariant(min(max(volume, 0), 1)))
+}
+
+// Progress returns how far through the current track the player is, from 0 to
+// 1. It returns an error if the player does not report the track length.
+func (p *Player) Progress(ctx context.Context) (float64, error) {
+	if p.length <= 0 {
+		return 0, ErrNoTrackLength
+	}
+	v, err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(mprisPath).
+		Destination(p.dest).
+		GetProp(mprisPlayerIntr + ".Position")
+	if err != nil {
+		return 0, err
+	}
+	return min(float64(dbusx.VariantToValue[int64](v))/float64(p.length), 1), nil
+}
+
+// SetProgress seeks to the given point in the current track, from 0 to 1.
+func (p *Player) SetProgress(ctx context.Context, progress float64) error {
+	if p.length <= 0 || p.trackID == "" {
+		return ErrNoTrackLength
+	}
+	position := int64(min(max(progress, 0), 1) * float64(p.length))
+	return p.Call(ctx, "SetPosition", p.trackID, position)
+}
+
+type nowPlayingSensor struct {
+	player *Player
+	linux.Sensor
+}
+
+func (s *nowPlayingSensor) Icon() string {
+	switch s.Value {
+	case statusPlaying:
+		return "mdi:play"
+	case statusPaused:
+		return "mdi:pause"
+	case statusStopped:
+		return "mdi:stop"
+	default:
+		return "mdi:music-off"
+	}
+}
+
+func (s *nowPlayingSensor) Attributes() any {
+	attrs := struct {
+		Title      string `json:"Title,omitempty"`
+		Artist     string `json:"Artist,omitempty"`
+		Album      string `json:"Album,omitempty"`
+		Player     string `json:"Player,omitempty"`
+		DataSource string `json:"Data Source"`
+	}{
+		DataSource: linux.DataSrcDbus,
+	}
+	if s.player != nil {
+		attrs.Title = s.player.Title
+		attrs.Artist = s.player.Artist
+		attrs.Album = s.player.Album
+		attrs.Player = s.player.Name
+	}
+	return attrs
+}
+
+func newNowPlayingSensor(p *Player) *nowPlayingSensor {
+	s := &nowPlayingSensor{player: p}
+	s.SensorTypeValue = linux.SensorNowPlaying
+	s.SensorSrc = linux.DataSrcDbus
+	s.Value = statusIdle
+	if p != nil && p.Status != "" {
+		s.Value = p.Status
+	}
+	return s
+}
+
+// NowPlayingUpdater reports the playback state and track details of the active
+// MPRIS media player. It updates when any player changes state or when players
+// start or exit.
+func NowPlayingUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	updates := make(chan struct{}, 1)
+	// Signals from all watches on the bus are passed to each handler, so only
+	// react to those from media players.
+	notify := func(s *dbus.Signal) {
+		switch {
+		case s.Name == dbusx.PropChangedSignal && s.Path == mprisPath:
+			if len(s.Body) == 0 || s.Body[0] != mprisPlayerIntr {
+				return
+			}
+		case s.Name == dbusDest+".NameOwnerChanged":
+			if len(s.Body) == 0 {
+				return
+			}
+			if name, ok := s.Body[0].(string); !ok || !strings.HasPrefix(name, mprisPrefix) {
+				return
+			}
+		default:
+			return
+		}
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+
+	err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(mprisPath),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		}).
+		Handler(notify).
+		AddWatch(ctx)
+	if err != nil {
+		log.Warn().Err(err).
+			Msg("Could not watch D-Bus for media players. Now playing sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	err = dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchInterface(dbusDest),
+			dbus.WithMatchMember("NameOwnerChanged"),
+			dbus.WithMatchArg0Namespace(mprisInterface),
+		}).
+		Handler(notify).
+		AddWatch(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not watch for media players starting or exiting.")
+	}
+
+	go func() {
+		defer close(sensorCh)
+		var last *Player
+		sent := false
+		send := func() {
+			p := ActivePlayer(ctx)
+			// Players emit many property changes (e.g., position, volume), so
+			// only send when something reported by the sensor has changed.
+			if sent && (p == nil && last == nil || p != nil && last != nil && *p == *last) {
+				return
+			}
+			last, sent = p, true
+			sensorCh <- newNowPlayingSensor(p)
+		}
+		send()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug().Msg("Stopped now playing sensor.")
+				return
+			case <-updates:
+				send()
+			}
+		}
+	}()
+	return sensorCh
+}
@@ -18,6 +18,9 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
 
+// procMemInfo is the source of memory statistics.
+const procMemInfo = "/proc/meminfo"
+
 var stats = []linux.SensorTypeValue{
 	linux.SensorMemTotal,
 	linux.SensorMemAvail,
@@ -45,11 +48,16 @@ func (s *memorySensor) Attributes() any {
 func Updater(ctx context.Context) chan tracker.Sensor {
 	sensorCh := make(chan tracker.Sensor, 5)
 	sendMemStats := func(_ time.Duration) {
+		if helpers.IsDenied(procMemInfo) {
+			return
+		}
 		var memDetails *mem.VirtualMemoryStat
 		var err error
 		if memDetails, err = mem.VirtualMemoryWithContext(ctx); err != nil {
-			log.Debug().Err(err).Caller().
-				Msg("Problem fetching memory stats.")
+			if !helpers.CheckDenied(procMemInfo, err) {
+				log.Debug().Err(err).Caller().
+					Msg("Problem fetching memory stats.")
+			}
 			return
 		}
 		for _, stat := range stats {
@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package net
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	topAppsCount = 5
+	noAppTraffic = "None"
+)
+
+var (
+	ssProcess  = regexp.MustCompile(`users:\(\("([^"]+)"`)
+	ssSent     = regexp.MustCompile(`bytes_sent:(\d+)`)
+	ssAcked    = regexp.MustCompile(`bytes_acked:(\d+)`)
+	ssReceived = regexp.MustCompile(`bytes_received:(\d+)`)
+)
+
+// socketTraffic holds the byte counters of a single TCP socket.
+type socketTraffic struct {
+	app      string
+	sent     uint64
+	received uint64
+}
+
+// appTraffic holds the traffic rates of an application over the last
+// interval.
+type appTraffic struct {
+	Name     string  `json:"-"`
+	Sent     float64 `json:"Sent (B/s)"`
+	Received float64 `json:"Received (B/s)"`
+}
+
+func (a *appTraffic) total() float64 {
+	return a.Sent + a.Received
+}
+
+type appTrafficSensor struct {
+	apps map[string]*appTraffic
+	linux.Sensor
+}
+
+func (s *appTrafficSensor) Attributes() any {
+	return struct {
+		Apps       map[string]*appTraffic `json:"Apps"`
+		DataSource string                 `json:"Data Source"`
+	}{
+		Apps:       s.apps,
+		DataSource: "ss",
+	}
+}
+
+// parseSocketStats parses the output of `ss -tinpH` into the byte counters of
+// each socket, keyed by the socket addresses. Only sockets owned by processes
+// the agent can see (normally those of the same user) will have an app name.
+func parseSocketStats(out []byte) map[string]*socketTraffic {
+	sockets := make(map[string]*socketTraffic)
+	var current *socketTraffic
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			current = nil
+			fields := strings.Fields(line)
+			m := ssProcess.FindStringSubmatch(line)
+			if len(fields) < 5 || m == nil || isLoopback(fields[4]) {
+				continue
+			}
+			current = &socketTraffic{app: m[1]}
+			sockets[fields[3]+"-"+fields[4]] = current
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		// Prefer acknowledged bytes, as sent bytes include retransmissions.
+		if m := ssAcked.FindStringSubmatch(line); m != nil {
+			current.sent, _ = strconv.ParseUint(m[1], 10, 64)
+		} else if m := ssSent.FindStringSubmatch(line); m != nil {
+			current.sent, _ = strconv.ParseUint(m[1], 10, 64)
+		}
+		if m := ssReceived.FindStringSubmatch(line); m != nil {
+			current.received, _ = strconv.ParseUint(m[1], 10, 64)
+		}
+	}
+	return sockets
+}
+
+// isLoopback returns whether the given ss address:port is a loopback address.
+func isLoopback(addr string) bool {
+	return strings.HasPrefix(addr, "127.") ||
+		strings.HasPrefix(addr, "[::1]") ||
+		strings.HasPrefix(addr, "[::ffff:127.")
+}
+
+// calcAppTraffic returns the traffic rate of each app, from the change in
+// socket counters over the given interval. Sockets that were not seen in the
+// previous sample count all their traffic towards the interval.
+func calcAppTraffic(prev, curr map[string]*socketTraffic, interval time.Duration) map[string]*appTraffic {
+	apps := make(map[string]*appTraffic)
+	secs := interval.Seconds()
+	if secs <= 0 {
+		return apps
+	}
+	for k, s := range curr {
+		sent, received := s.sent, s.received
+		if p, ok := prev[k]; ok && p.sent <= sent && p.received <= received {
+			sent -= p.sent
+			received -= p.received
+		}
+		if sent == 0 && received == 0 {
+			continue
+		}
+		a, ok := apps[s.app]
+		if !ok {
+			a = &appTraffic{Name: s.app}
+			apps[s.app] = a
+		}
+		a.Sent += float64(sent) / secs
+		a.Received += float64(received) / secs
+	}
+	return apps
+}
+
+// topApps returns the apps with the most traffic, highest first.
+func topApps(apps map[string]*appTraffic, n int) []*appTraffic {
+	top := make([]*appTraffic, 0, len(apps))
+	for _, a := range apps {
+		top = append(top, a)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].total() > top[j].total()
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+func newAppTrafficSensor(apps map[string]*appTraffic) *appTrafficSensor {
+	s := &appTrafficSensor{apps: make(map[string]*appTraffic)}
+	s.SensorTypeValue = linux.SensorAppTraffic
+	s.IconString = "mdi:transit-connection-variant"
+	s.Value = noAppTraffic
+	top := topApps(apps, topAppsCount)
+	for _, a := range top {
+		s.apps[a.Name] = a
+	}
+	if len(top) > 0 {
+		s.Value = top[0].Name
+	}
+	return s
+}
+
+// AppTrafficUpdater reports the applications using the most network bandwidth,
+// based on the TCP socket statistics reported by the ss command. As this
+// reveals which applications are in use, it only runs if the user has opted in
+// via the preferences.
+func AppTrafficUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	if !preferences.FetchFromContext(ctx).AppTraffic {
+		log.Debug().Msg("App traffic sensor not enabled in preferences.")
+		close(sensorCh)
+		return sensorCh
+	}
+	ss, err := exec.LookPath("ss")
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not find ss command. App traffic sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+	var prev map[string]*socketTraffic
+	sendAppTraffic := func(interval time.Duration) {
+		out, err := exec.CommandContext(ctx, ss, "-tinpH").Output()
+		if err != nil {
+			log.Debug().Err(err).Msg("Could not retrieve socket statistics.")
+			return
+		}
+		curr := parseSocketStats(out)
+		if prev != nil {
+			sensorCh <- newAppTrafficSensor(calcAppTraffic(prev, curr, interval))
+		}
+		prev = curr
+	}
+
+	go helpers.PollSensors(ctx, sendAppTraffic, time.Minute, time.Second*5)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped app traffic sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package net
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	netSysfsDir = "/sys/class/net"
+	arphrdEther = "1"
+)
+
+// ethernetLink holds the negotiated link settings of a wired interface.
+type ethernetLink struct {
+	iface   string
+	duplex  string
+	speed   int
+	carrier bool
+}
+
+type ethernetLinkSensor struct {
+	link *ethernetLink
+	linux.Sensor
+}
+
+func (s *ethernetLinkSensor) Name() string {
+	return s.link.iface + " " + s.SensorTypeValue.String()
+}
+
+func (s *ethernetLinkSensor) ID() string {
+	return strcase.ToSnake(s.link.iface + "_" + s.SensorTypeValue.String())
+}
+
+func (s *ethernetLinkSensor) Attributes() any {
+	return struct {
+		Carrier    bool   `json:"Carrier"`
+		DataSource string `json:"Data Source"`
+	}{
+		Carrier:    s.link.carrier,
+		DataSource: linux.DataSrcSysfs,
+	}
+}
+
+func newEthernetLinkSensors(l *ethernetLink) []tracker.Sensor {
+	speed := &ethernetLinkSensor{link: l}
+	speed.SensorTypeValue = linux.SensorLinkSpeed
+	speed.IconString = "mdi:speedometer"
+	speed.UnitsString = "Mbit/s"
+	speed.DeviceClassValue = sensor.Data_rate
+	speed.StateClassValue = sensor.StateMeasurement
+	speed.IsDiagnostic = true
+	speed.Value = l.speed
+
+	duplex := &ethernetLinkSensor{link: l}
+	duplex.SensorTypeValue = linux.SensorLinkDuplex
+	duplex.IconString = "mdi:ethernet"
+	duplex.IsDiagnostic = true
+	duplex.Value = l.duplex
+
+	return []tracker.Sensor{speed, duplex}
+}
+
+// getEthernetLinks returns the link settings of all physical wired
+// interfaces. When an interface has no carrier, the kernel does not report a
+// speed or duplex, so these are reported as 0 and unknown.
+func getEthernetLinks() []*ethernetLink {
+	ifaces, err := os.ReadDir(netSysfsDir)
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not list network interfaces.")
+		return nil
+	}
+	var links []*ethernetLink
+	for _, i := range ifaces {
+		dir := filepath.Join(netSysfsDir, i.Name())
+		// Only physical, non-wireless Ethernet devices.
+		if readSysfsValue(dir, "type") != arphrdEther {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "wireless")); err == nil {
+			continue
+		}
+		l := &ethernetLink{
+			iface:  i.Name(),
+			duplex: sensor.StateUnknown,
+		}
+		if readSysfsValue(dir, "carrier") == "1" {
+			l.carrier = true
+			if speed, err := strconv.Atoi(readSysfsValue(dir, "speed")); err == nil && speed > 0 {
+				l.speed = speed
+			}
+			if duplex := readSysfsValue(dir, "duplex"); duplex != "" {
+				l.duplex = duplex
+			}
+		}
+		links = append(links, l)
+	}
+	return links
+}
+
+// readSysfsValue returns the trimmed contents of the given attribute file in
+// dir. Some attributes return an error when read while the link is down, in
+// which case an empty string is returned.
+func readSysfsValue(dir, attr string) string {
+	b, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func EthernetLinkUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	last := make(map[string]ethernetLink)
+	sendLinks := func(_ time.Duration) {
+		for _, l := range getEthernetLinks() {
+			// Only send when the link has changed, such as on carrier changes.
+			if prev, ok := last[l.iface]; ok && prev == *l {
+				continue
+			}
+			last[l.iface] = *l
+			for _, s := range newEthernetLinkSensors(l) {
+				sensorCh <- s
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendLinks, 15*time.Second, time.Second)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped ethernet link sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package net
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	dbusNMSettingsPath = dBusNMPath + "/Settings"
+	dbusNMSettingsIntr = dBusNMObj + ".Settings"
+	dbusNMDeviceIntr   = dBusNMObj + ".Device"
+
+	arpTable        = "/proc/net/arp"
+	arpFlagComplete = "0x2"
+)
+
+var ErrNoHotspot = errors.New("no Wi-Fi hotspot connection configured")
+
+// Hotspot represents a NetworkManager Wi-Fi connection that is configured to
+// run as an access point.
+type Hotspot struct {
+	Name string
+	path dbus.ObjectPath
+}
+
+// FindHotspot returns the first NetworkManager connection configured as a
+// Wi-Fi access point.
+func FindHotspot(ctx context.Context) (*Hotspot, error) {
+	conns := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dbusNMSettingsPath).
+		Destination(dBusNMObj).
+		GetData(dbusNMSettingsIntr + ".ListConnections").
+		AsObjectPathList()
+	for _, p := range conns {
+		data := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(p).
+			Destination(dBusNMObj).
+			GetData(dbusNMSettingsIntr + ".Connection.GetSettings").
+			AsRawInterface()
+		settings, ok := data.(map[string]map[string]dbus.Variant)
+		if !ok {
+			continue
+		}
+		if mode, ok := settings["802-11-wireless"]["mode"]; !ok || dbusx.VariantToValue[string](mode) != "ap" {
+			continue
+		}
+		return &Hotspot{
+			Name: dbusx.VariantToValue[string](settings["connection"]["id"]),
+			path: p,
+		}, nil
+	}
+	return nil, ErrNoHotspot
+}
+
+// activeConnection returns the active connection path of the hotspot, or an
+// empty path if the hotspot is not active.
+func (h *Hotspot) activeConnection(ctx context.Context) dbus.ObjectPath {
+	return findActiveConnection(ctx, h.path)
+}
+
+// Active returns whether the hotspot is currently active.
+func (h *Hotspot) Active(ctx context.Context) bool {
+	return h.activeConnection(ctx) != ""
+}
+
+// Enable will ask NetworkManager to activate the hotspot.
+func (h *Hotspot) Enable(ctx context.Context) error {
+	return activateConnection(ctx, h.path)
+}
+
+// Disable will ask NetworkManager to deactivate the hotspot.
+func (h *Hotspot) Disable(ctx context.Context) error {
+	return deactivateConnection(ctx, h.path)
+}
+
+// Clients returns the number of clients connected to the hotspot. As
+// NetworkManager does not track the stations connected to an access point,
+// this is the number of reachable neighbours on the hotspot interface.
+func (h *Hotspot) Clients(ctx context.Context) int {
+	active := h.activeConnection(ctx)
+	if active == "" {
+		return 0
+	}
+	v, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(active).
+		Destination(dBusNMObj).
+		GetProp(dbusNMActiveConnIntr + ".Devices")
+	if err != nil {
+		return 0
+	}
+	var clients int
+	for _, d := range dbusx.VariantToValue[[]dbus.ObjectPath](v) {
+		iface, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(d).
+			Destination(dBusNMObj).
+			GetProp(dbusNMDeviceIntr + ".Interface")
+		if err != nil {
+			continue
+		}
+		clients += countNeighbours(dbusx.VariantToValue[string](iface))
+	}
+	return clients
+}
+
+// countNeighbours returns the number of complete ARP entries for the given
+// interface.
+func countNeighbours(iface string) int {
+	f, err := os.Open(arpTable)
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not read ARP table.")
+		return 0
+	}
+	defer f.Close()
+	var count int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 6 && fields[5] == iface && fields[2] == arpFlagComplete {
+			count++
+		}
+	}
+	return count
+}
+
+type hotspotClientsSensor struct {
+	linux.Sensor
+}
+
+func (s *hotspotClientsSensor) Attributes() any {
+	return struct {
+		DataSource string `json:"Data Source"`
+	}{
+		DataSource: linux.DataSrcProcfs,
+	}
+}
+
+func HotspotUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	sendClients := func(_ time.Duration) {
+		h, err := FindHotspot(ctx)
+		if err != nil {
+			return
+		}
+		s := &hotspotClientsSensor{}
+		s.SensorTypeValue = linux.SensorHotspotClients
+		s.Value = h.Clients(ctx)
+		s.IconString = "mdi:access-point-network"
+		s.UnitsString = "clients"
+		s.StateClassValue = sensor.StateMeasurement
+		sensorCh <- s
+	}
+
+	go helpers.PollSensors(ctx, sendClients, time.Minute, time.Second*5)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped hotspot sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package net
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	tcpStateEstablished = "01"
+	tcpStateListen      = "0A"
+
+	servicesFile = "/etc/services"
+)
+
+// procNetFiles are the files under /proc/net that contain the TCP socket
+// tables for IPv4 and IPv6.
+var procNetFiles = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+type socketCounts struct {
+	// listening holds each listening address and port, with the service
+	// name where known.
+	listening []string
+	// ports holds the distinct listening ports, as the same port is often
+	// listened on by more than one address (e.g., IPv4 and IPv6).
+	ports       []int
+	established int
+}
+
+type netConnSensor struct {
+	listening []string
+	linux.Sensor
+}
+
+func (s *netConnSensor) Attributes() any {
+	switch s.SensorTypeValue {
+	case linux.SensorConnectionsListening:
+		return struct {
+			DataSource string   `json:"Data Source"`
+			Services   []string `json:"Listening Services"`
+		}{
+			DataSource: linux.DataSrcProcfs,
+			Services:   s.listening,
+		}
+	default:
+		return struct {
+			DataSource string `json:"Data Source"`
+		}{
+			DataSource: linux.DataSrcProcfs,
+		}
+	}
+}
+
+func newNetConnSensor(t linux.SensorTypeValue) *netConnSensor {
+	s := &netConnSensor{}
+	s.SensorTypeValue = t
+	s.StateClassValue = sensor.StateMeasurement
+	switch t {
+	case linux.SensorConnectionsEstablished:
+		s.IconString = "mdi:lan-connect"
+		s.UnitsString = "connections"
+	case linux.SensorConnectionsListening:
+		s.IconString = "mdi:lan-pending"
+		s.UnitsString = "ports"
+	}
+	return s
+}
+
+// parseSocketTable reads a /proc/net/tcp style table and updates the given
+// counts with the established connections and listening sockets found.
+func parseSocketTable(file string, services map[string]string, counts *socketCounts) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// Skip the header line.
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		switch fields[3] {
+		case tcpStateEstablished:
+			counts.established++
+		case tcpStateListen:
+			addr, port, err := parseHexAddr(fields[1])
+			if err != nil {
+				log.Debug().Err(err).Str("address", fields[1]).
+					Msg("Could not parse listening address.")
+				continue
+			}
+			l := net.JoinHostPort(addr, strconv.Itoa(port)) + "/tcp"
+			if svc, ok := services[strconv.Itoa(port)+"/tcp"]; ok {
+				l = l + " (" + svc + ")"
+			}
+			if !slices.Contains(counts.listening, l) {
+				counts.listening = append(counts.listening, l)
+			}
+			if !slices.Contains(counts.ports, port) {
+				counts.ports = append(counts.ports, port)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// parseHexAddr converts an address in the hex format used by /proc/net (e.g.,
+// 0100007F:0035) into an IP string and port number.
+func parseHexAddr(s string) (string, int, error) {
+	hexIP, hexPort, found := strings.Cut(s, ":")
+	if !found {
+		return "", 0, fmt.Errorf("invalid address %s", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, err
+	}
+	b, err := hex.DecodeString(hexIP)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(b) != net.IPv4len && len(b) != net.IPv6len {
+		return "", 0, fmt.Errorf("invalid address length %d", len(b))
+	}
+	// The kernel writes each 32-bit word of the address in host (little-endian)
+	// byte order.
+	for i := 0; i < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return net.IP(b).String(), int(port), nil
+}
+
+// readServices reads the system services file and returns a map of
+// "port/protocol" to service name. If the file cannot be read, an empty map is
+// returned.
+func readServices() map[string]string {
+	services := make(map[string]string)
+	f, err := os.Open(servicesFile)
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not read services file.")
+		return services
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if _, ok := services[fields[1]]; !ok {
+			services[fields[1]] = fields[0]
+		}
+	}
+	return services
+}
+
+func ConnectionCountsUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 2)
+	services := readServices()
+	established := newNetConnSensor(linux.SensorConnectionsEstablished)
+	listening := newNetConnSensor(linux.SensorConnectionsListening)
+
+	sendCounts := func(_ time.Duration) {
+		counts := &socketCounts{}
+		for _, file := range procNetFiles {
+			if err := parseSocketTable(file, services, counts); err != nil {
+				log.Debug().Err(err).Str("file", file).
+					Msg("Could not parse socket table.")
+			}
+		}
+		slices.Sort(counts.listening)
+
+		established.Value = counts.established
+		sensorCh <- established
+		listening.Value = len(counts.ports)
+		listening.listening = counts.listening
+		sensorCh <- listening
+	}
+
+	go helpers.PollSensors(ctx, sendCounts, time.Minute, time.Second*5)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		log.Debug().Msg("Stopped network connection count sensors.")
+	}()
+	return sensorCh
+}
@@ -290,6 +290,46 @@ func getActiveConnections(ctx context.Context) []dbus.ObjectPath {
 	return dbusx.VariantToValue[[]dbus.ObjectPath](v)
 }
 
+// findActiveConnection returns the path of the active connection of the given
+// connection (settings) path, or an empty path if it is not active.
+func findActiveConnection(ctx context.Context, conn dbus.ObjectPath) dbus.ObjectPath {
+	for _, p := range getActiveConnections(ctx) {
+		v, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(p).
+			Destination(dBusNMObj).
+			GetProp(dbusNMActiveConnIntr + ".Connection")
+		if err != nil {
+			continue
+		}
+		if dbusx.VariantToValue[dbus.ObjectPath](v) == conn {
+			return p
+		}
+	}
+	return ""
+}
+
+// activateConnection asks NetworkManager to activate the given connection,
+// letting it choose the device.
+func activateConnection(ctx context.Context, conn dbus.ObjectPath) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dBusNMPath).
+		Destination(dBusNMObj).
+		Call(dBusNMObj+".ActivateConnection", conn, dbus.ObjectPath("/"), dbus.ObjectPath("/"))
+}
+
+// deactivateConnection asks NetworkManager to deactivate the given connection,
+// if it is active.
+func deactivateConnection(ctx context.Context, conn dbus.ObjectPath) error {
+	active := findActiveConnection(ctx, conn)
+	if active == "" {
+		return nil
+	}
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dBusNMPath).
+		Destination(dBusNMObj).
+		Call(dBusNMObj+".DeactivateConnection", active)
+}
+
 func monitorActiveConnections(ctx context.Context) chan tracker.Sensor {
 	sensorCh := make(chan tracker.Sensor, 1)
 	conns := getActiveConnections(ctx)
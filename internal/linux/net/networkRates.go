@@ -18,6 +18,9 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/tracker"
 )
 
+// procNetDev is the source of network interface statistics.
+const procNetDev = "/proc/net/dev"
+
 type netIOSensorAttributes struct {
 	Packets    uint64 `json:"Packets"`     // number of packets
 	Errors     uint64 `json:"Errors"`      // total number of errors
@@ -122,10 +125,15 @@ func RatesUpdater(ctx context.Context) chan tracker.Sensor {
 	bytesTxRate := newNetIORateSensor(linux.SensorBytesSentRate)
 
 	sendNetStats := func(delta time.Duration) {
+		if helpers.IsDenied(procNetDev) {
+			return
+		}
 		netIO, err := net.IOCountersWithContext(ctx, false)
 		if err != nil {
-			log.Debug().Err(err).Caller().
-				Msg("Problem fetching network stats.")
+			if !helpers.CheckDenied(procNetDev, err) {
+				log.Debug().Err(err).Caller().
+					Msg("Problem fetching network stats.")
+			}
 			return
 		}
 
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package net
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	// nmStateConnectedSite is the NetworkManager state when the device has a
+	// network connection but may not be able to reach the Internet. As Home
+	// Assistant is commonly on the local network, this is considered online.
+	nmStateConnectedSite = 60
+
+	networkdDest      = "org.freedesktop.network1"
+	networkdPath      = "/org/freedesktop/network1"
+	networkdIntr      = networkdDest + ".Manager"
+	networkdRoutable  = "routable"
+	onlineRecheckTime = 5 * time.Second
+)
+
+var ErrNoNetworkService = errors.New("neither NetworkManager nor systemd-networkd is available")
+
+// isOnline returns whether NetworkManager or systemd-networkd report that the
+// device has a network connection. An error is returned if neither service is
+// available.
+func isOnline(ctx context.Context) (bool, error) {
+	nmState, nmErr := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dBusNMPath).
+		Destination(dBusNMObj).
+		GetProp(dBusNMObj + ".State")
+	if nmErr == nil {
+		return dbusx.VariantToValue[uint32](nmState) >= nmStateConnectedSite, nil
+	}
+	networkdState, networkdErr := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(networkdPath).
+		Destination(networkdDest).
+		GetProp(networkdIntr + ".OperationalState")
+	if networkdErr == nil {
+		return dbusx.VariantToValue[string](networkdState) == networkdRoutable, nil
+	}
+	return false, ErrNoNetworkService
+}
+
+// WaitForOnline blocks until NetworkManager or systemd-networkd report that the
+// device has a network connection, or the context is canceled. If neither
+// service is available, it returns ErrNoNetworkService immediately.
+func WaitForOnline(ctx context.Context) error {
+	online, err := isOnline(ctx)
+	if err != nil || online {
+		return err
+	}
+	log.Info().Msg("Waiting for network to come online.")
+
+	changed := make(chan struct{}, 1)
+	err = dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Name != dbusx.PropChangedSignal || (s.Path != dBusNMPath && s.Path != networkdPath) {
+				return
+			}
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not watch for network changes, polling instead.")
+	}
+
+	// Changes are also checked periodically, in case a signal is missed.
+	ticker := time.NewTicker(onlineRecheckTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-ticker.C:
+		}
+		if online, err := isOnline(ctx); err != nil || online {
+			return err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package net
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+// VPN represents a NetworkManager VPN connection, either a VPN plugin
+// connection (e.g., OpenVPN) or a WireGuard connection.
+type VPN struct {
+	Name string
+	path dbus.ObjectPath
+}
+
+// FindVPNs returns the VPN connections configured in NetworkManager.
+func FindVPNs(ctx context.Context) []*VPN {
+	conns := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dbusNMSettingsPath).
+		Destination(dBusNMObj).
+		GetData(dbusNMSettingsIntr + ".ListConnections").
+		AsObjectPathList()
+	var vpns []*VPN
+	for _, p := range conns {
+		data := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(p).
+			Destination(dBusNMObj).
+			GetData(dbusNMSettingsIntr + ".Connection.GetSettings").
+			AsRawInterface()
+		settings, ok := data.(map[string]map[string]dbus.Variant)
+		if !ok {
+			continue
+		}
+		switch dbusx.VariantToValue[string](settings["connection"]["type"]) {
+		case "vpn", "wireguard":
+			vpns = append(vpns, &VPN{
+				Name: dbusx.VariantToValue[string](settings["connection"]["id"]),
+				path: p,
+			})
+		}
+	}
+	return vpns
+}
+
+// Active returns whether the VPN is connected, or connecting.
+func (v *VPN) Active(ctx context.Context) bool {
+	return findActiveConnection(ctx, v.path) != ""
+}
+
+// Connect will ask NetworkManager to activate the VPN connection. The VPN
+// connects in the background, so it may not be connected when this returns.
+func (v *VPN) Connect(ctx context.Context) error {
+	return activateConnection(ctx, v.path)
+}
+
+// Disconnect will ask NetworkManager to deactivate the VPN connection.
+func (v *VPN) Disconnect(ctx context.Context) error {
+	return deactivateConnection(ctx, v.path)
+}
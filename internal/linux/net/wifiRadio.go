@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package net
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const wirelessEnabledProp = "WirelessEnabled"
+
+// WifiEnabled returns whether the Wi-Fi radio is turned on in NetworkManager.
+func WifiEnabled(ctx context.Context) (bool, error) {
+	value, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dBusNMPath).
+		Destination(dBusNMObj).
+		GetProp(dBusNMObj + "." + wirelessEnabledProp)
+	if err != nil {
+		return false, err
+	}
+	return dbusx.VariantToValue[bool](value), nil
+}
+
+// SetWifiEnabled turns the Wi-Fi radio on or off in NetworkManager.
+func SetWifiEnabled(ctx context.Context, enabled bool) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(dBusNMPath).
+		Destination(dBusNMObj).
+		SetProp(dBusNMObj+"."+wirelessEnabledProp, dbus.MakeVariant(enabled))
+}
+
+// WatchWifiEnabled calls the given function whenever the Wi-Fi radio is turned
+// on or off, including from outside the agent (e.g., airplane mode).
+func WatchWifiEnabled(ctx context.Context, changed func()) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(dBusNMPath),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != dBusNMPath || s.Name != dbusx.PropChangedSignal || len(s.Body) < 2 {
+				return
+			}
+			props, ok := s.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				return
+			}
+			if _, ok := props[wirelessEnabledProp]; ok {
+				go changed()
+			}
+		}).
+		AddWatch(ctx)
+}
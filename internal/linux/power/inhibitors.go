@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package power
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	listInhibitorsMethod  = managerInterface + ".ListInhibitors"
+	inhibitorPollInterval = 30 * time.Second
+)
+
+// inhibitor is a logind inhibitor lock that stops the device sleeping or going
+// idle.
+type inhibitor struct {
+	What string `json:"What"`
+	Who  string `json:"Who"`
+	Why  string `json:"Why"`
+}
+
+type inhibitorSensor struct {
+	inhibitors []inhibitor
+	linux.Sensor
+}
+
+func (s *inhibitorSensor) Icon() string {
+	if len(s.inhibitors) > 0 {
+		return "mdi:sleep-off"
+	}
+	return "mdi:sleep"
+}
+
+func (s *inhibitorSensor) Attributes() any {
+	return struct {
+		DataSource string      `json:"Data Source"`
+		Inhibitors []inhibitor `json:"Inhibitors"`
+	}{
+		DataSource: linux.DataSrcDbus,
+		Inhibitors: s.inhibitors,
+	}
+}
+
+func newInhibitorSensor(inhibitors []inhibitor) *inhibitorSensor {
+	return &inhibitorSensor{
+		inhibitors: inhibitors,
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorSleepInhibited,
+			IsBinary:        true,
+			Value:           len(inhibitors) > 0,
+		},
+	}
+}
+
+// parseInhibitors extracts the inhibitors that block sleep or idle from the
+// result of the logind ListInhibitors method, which is a list of (what, who,
+// why, mode, uid, pid). Inhibitors in "delay" mode only delay sleep briefly, so
+// they are ignored.
+func parseInhibitors(list [][]any) []inhibitor {
+	inhibitors := []inhibitor{}
+	for _, i := range list {
+		if len(i) < 4 {
+			continue
+		}
+		what, _ := i[0].(string)
+		who, _ := i[1].(string)
+		why, _ := i[2].(string)
+		mode, _ := i[3].(string)
+		if mode != "block" {
+			continue
+		}
+		locks := strings.Split(what, ":")
+		if !slices.Contains(locks, "sleep") && !slices.Contains(locks, "idle") {
+			continue
+		}
+		inhibitors = append(inhibitors, inhibitor{What: what, Who: who, Why: why})
+	}
+	return inhibitors
+}
+
+// InhibitorsUpdater reports whether anything is preventing the device from
+// sleeping or going idle, with the applications holding inhibitor locks (and
+// their reasons) as attributes. logind does not signal when inhibitors change,
+// so they are polled.
+func InhibitorsUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var mu sync.Mutex
+	var last *inhibitorSensor
+	sendInhibitorSensor := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || helpers.IsDenied(listInhibitorsMethod) {
+			return
+		}
+		data := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(loginPath).
+			Destination(loginDest).
+			GetData(listInhibitorsMethod)
+		if helpers.CheckDenied(listInhibitorsMethod, data.Err()) {
+			return
+		}
+		list, ok := data.AsRawInterface().([][]any)
+		if !ok {
+			log.Debug().Msg("Could not list inhibitors.")
+			return
+		}
+		s := newInhibitorSensor(parseInhibitors(list))
+		if last != nil && reflect.DeepEqual(last.inhibitors, s.inhibitors) {
+			return
+		}
+		last = s
+		select {
+		case sensorCh <- s:
+		case <-ctx.Done():
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendInhibitorSensor, inhibitorPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped sleep inhibitor sensor.")
+	}()
+	return sensorCh
+}
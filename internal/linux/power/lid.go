@@ -0,0 +1,207 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package power
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	upowerDest      = "org.freedesktop.UPower"
+	upowerPath      = "/org/freedesktop/UPower"
+	lidPresentProp  = upowerDest + ".LidIsPresent"
+	lidIsClosedProp = upowerDest + ".LidIsClosed"
+
+	// acpidSocket is where acpid broadcasts ACPI events, such as the lid
+	// opening or closing.
+	acpidSocket = "/var/run/acpid.socket"
+	acpiLidPath = "/proc/acpi/button/lid"
+)
+
+type lidSensor struct {
+	linux.Sensor
+}
+
+func (s *lidSensor) Icon() string {
+	if closed, ok := s.Value.(bool); ok && closed {
+		return "mdi:laptop-off"
+	}
+	return "mdi:laptop"
+}
+
+func newLidSensor(closed bool) *lidSensor {
+	return &lidSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorLidClosed,
+			IsBinary:        true,
+			SensorSrc:       linux.DataSrcDbus,
+			Value:           closed,
+		},
+	}
+}
+
+// hasLid returns whether the device has a lid switch, as reported by UPower or,
+// if UPower is not running, ACPI.
+func hasLid(ctx context.Context) bool {
+	present, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(upowerPath).
+		Destination(upowerDest).
+		GetProp(lidPresentProp)
+	if err == nil {
+		return dbusx.VariantToValue[bool](present)
+	}
+	lids, err := filepath.Glob(filepath.Join(acpiLidPath, "*", "state"))
+	return err == nil && len(lids) > 0
+}
+
+// acpiLidClosed reads whether the lid is closed from the ACPI lid state file
+// (e.g., "state:      closed").
+func acpiLidClosed() (bool, error) {
+	lids, err := filepath.Glob(filepath.Join(acpiLidPath, "*", "state"))
+	if err != nil || len(lids) == 0 {
+		return false, errors.New("no lid state found")
+	}
+	data, err := os.ReadFile(lids[0])
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(data), "closed"), nil
+}
+
+// parseACPIEvent parses an acpid event line (e.g., "button/lid LID close") and
+// returns whether the lid is closed and whether the line was a lid event.
+func parseACPIEvent(line string) (closed, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "button/lid" {
+		return false, false
+	}
+	switch fields[2] {
+	case "close":
+		return true, true
+	case "open":
+		return false, true
+	}
+	return false, false
+}
+
+// watchACPI sends the lid state on every lid event from acpid, until the
+// context is canceled.
+func watchACPI(ctx context.Context, send func(bool)) error {
+	conn, err := net.Dial("unix", acpidSocket)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if closed, ok := parseACPIEvent(scanner.Text()); ok {
+				send(closed)
+			}
+		}
+	}()
+	return nil
+}
+
+// LidUpdater reports whether the laptop lid is closed. Changes are tracked via
+// the UPower LidIsClosed property and, where acpid is running, ACPI events.
+func LidUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	if !hasLid(ctx) {
+		log.Debug().Msg("No lid switch found. Lid sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	// Both UPower and acpid report the same change, so only send the state
+	// when it differs from the last one sent.
+	var mu sync.Mutex
+	var last *bool
+	send := func(closed bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || (last != nil && *last == closed) {
+			return
+		}
+		last = &closed
+		select {
+		case sensorCh <- newLidSensor(closed):
+		case <-ctx.Done():
+		}
+	}
+
+	dbusErr := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(upowerPath),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != upowerPath || s.Name != dbusx.PropChangedSignal || len(s.Body) <= 1 {
+				return
+			}
+			props, ok := s.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				return
+			}
+			if v, ok := props["LidIsClosed"]; ok {
+				send(dbusx.VariantToValue[bool](v))
+			}
+		}).
+		AddWatch(ctx)
+	if dbusErr != nil {
+		log.Debug().Err(dbusErr).Msg("Could not watch UPower for lid changes.")
+	}
+	acpiErr := watchACPI(ctx, send)
+	if acpiErr != nil {
+		log.Debug().Err(acpiErr).Msg("Could not watch acpid for lid changes.")
+	}
+	if dbusErr != nil && acpiErr != nil {
+		log.Warn().Msg("Could not watch for lid changes. Lid sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	go func() {
+		closed, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(upowerPath).
+			Destination(upowerDest).
+			GetProp(lidIsClosedProp)
+		if err == nil {
+			send(dbusx.VariantToValue[bool](closed))
+			return
+		}
+		if closed, err := acpiLidClosed(); err == nil {
+			send(closed)
+			return
+		}
+		log.Debug().Err(err).Msg("Could not retrieve current lid state.")
+	}()
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped lid sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package power
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wakeAlarmFile holds the time of the RTC wake alarm, in seconds since the
+// epoch, or is empty if no alarm is set.
+var wakeAlarmFile = "/sys/class/rtc/rtc0/wakealarm"
+
+var (
+	// ErrWakeAlarmInPast is returned when trying to set a wake alarm in the
+	// past.
+	ErrWakeAlarmInPast = errors.New("wake alarm is in the past")
+	// ErrInvalidWakeAlarm is returned when a wake alarm time cannot be
+	// parsed.
+	ErrInvalidWakeAlarm = errors.New("invalid wake alarm time")
+)
+
+// HasWakeAlarm returns whether the device has an RTC that supports wake alarms.
+func HasWakeAlarm() bool {
+	_, err := os.Stat(wakeAlarmFile)
+	return err == nil
+}
+
+// WakeAlarm returns the time of the RTC wake alarm. The time is zero if no
+// alarm is set.
+func WakeAlarm() (time.Time, error) {
+	b, err := os.ReadFile(wakeAlarmFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	value := strings.TrimSpace(string(b))
+	if value == "" {
+		return time.Time{}, nil
+	}
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// SetWakeAlarm sets the RTC wake alarm to the given time, replacing any alarm
+// already set. A zero time clears the alarm.
+func SetWakeAlarm(t time.Time) error {
+	// Check the time first, so that an existing alarm is kept if the new one
+	// is rejected.
+	if !t.IsZero() && !t.After(time.Now()) {
+		return ErrWakeAlarmInPast
+	}
+	// An existing alarm must be cleared before a new one can be set.
+	if err := os.WriteFile(wakeAlarmFile, []byte("0"), 0o644); err != nil {
+		return err
+	}
+	if t.IsZero() {
+		return nil
+	}
+	return os.WriteFile(wakeAlarmFile, []byte(strconv.FormatInt(t.Unix(), 10)), 0o644)
+}
+
+// ParseWakeAlarm parses a wake alarm time, which can be a date and time (e.g.,
+// 2024-01-20T07:30:00+10:00 or 2024-01-20 07:30), a time of day (e.g., 07:30)
+// for its next occurrence, or a duration from now (e.g., +90m). An empty
+// value, 0 or off clears the alarm, returning a zero time.
+func ParseWakeAlarm(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "", "0", "off":
+		return time.Time{}, nil
+	}
+	if d, ok := strings.CutPrefix(value, "+"); ok {
+		duration, err := time.ParseDuration(d)
+		if err != nil || duration <= 0 {
+			return time.Time{}, ErrInvalidWakeAlarm
+		}
+		return now.Add(duration), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			alarm := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+			if !alarm.After(now) {
+				alarm = alarm.AddDate(0, 0, 1)
+			}
+			return alarm, nil
+		}
+	}
+	return time.Time{}, ErrInvalidWakeAlarm
+}
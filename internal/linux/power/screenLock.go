@@ -7,7 +7,6 @@ package power
 
 import (
 	"context"
-	"strings"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/rs/zerolog/log"
@@ -43,19 +42,47 @@ func newScreenlockEvent(v bool) *screenlockSensor {
 	}
 }
 
+// screensaverInterfaces are the desktop-specific screensaver D-Bus interfaces
+// that emit an ActiveChanged signal when the screen is locked or unlocked.
+var screensaverInterfaces = []string{
+	"org.freedesktop.ScreenSaver",
+	"org.gnome.ScreenSaver",
+	"org.cinnamon.ScreenSaver",
+	"org.mate.ScreenSaver",
+	"org.xfce.ScreenSaver",
+}
+
+const (
+	loginDest         = "org.freedesktop.login1"
+	loginPath         = "/org/freedesktop/login1"
+	managerInterface  = "org.freedesktop.login1.Manager"
+	sessionInterface  = "org.freedesktop.login1.Session"
+	sessionLockedProp = sessionInterface + ".LockedHint"
+)
+
 func ScreenLockUpdater(ctx context.Context) chan tracker.Sensor {
 	sensorCh := make(chan tracker.Sensor)
+	sessionPath := dbusx.GetSessionPath(ctx)
+	if sessionPath == "" {
+		log.Warn().
+			Msg("Could not determine login session. Screen lock sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
 	err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
 		Match([]dbus.MatchOption{
-			dbus.WithMatchPathNamespace("/org/freedesktop/login1/session"),
+			dbus.WithMatchObjectPath(sessionPath),
 		}).
 		Handler(func(s *dbus.Signal) {
-			if !strings.Contains(string(s.Path), "/org/freedesktop/login1/session") || len(s.Body) <= 1 {
+			if s.Path != sessionPath || len(s.Body) == 0 {
 				log.Trace().Caller().Msg("Not my signal or empty signal body.")
 				return
 			}
 			switch s.Name {
 			case dbusx.PropChangedSignal:
+				if len(s.Body) <= 1 {
+					return
+				}
 				props, ok := s.Body[1].(map[string]dbus.Variant)
 				if !ok {
 					log.Trace().Caller().
@@ -66,12 +93,9 @@ func ScreenLockUpdater(ctx context.Context) chan tracker.Sensor {
 				if v, ok := props["LockedHint"]; ok {
 					sensorCh <- newScreenlockEvent(dbusx.VariantToValue[bool](v))
 				}
-				if v, ok := props["IdleHint"]; ok {
-					sensorCh <- newScreenlockEvent(dbusx.VariantToValue[bool](v))
-				}
-			case "org.freedesktop.login1.Session.Lock":
+			case sessionInterface + ".Lock":
 				sensorCh <- newScreenlockEvent(true)
-			case "org.freedesktop.login1.Session.Unlock":
+			case sessionInterface + ".Unlock":
 				sensorCh <- newScreenlockEvent(false)
 			}
 		}).
@@ -82,7 +106,38 @@ func ScreenLockUpdater(ctx context.Context) chan tracker.Sensor {
 		close(sensorCh)
 		return sensorCh
 	}
+	// Not all desktops update the logind session when locking the screen, so
+	// also watch for the desktop screensaver signalling a change.
+	err = dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchMember("ActiveChanged"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if !isScreensaverSignal(s.Name) || len(s.Body) == 0 {
+				log.Trace().Caller().Msg("Not my signal or empty signal body.")
+				return
+			}
+			if locked, ok := s.Body[0].(bool); ok {
+				sensorCh <- newScreenlockEvent(locked)
+			}
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Debug().Err(err).
+			Msg("Could not watch desktop screensaver. Screen lock sensor will only use logind.")
+	}
 	log.Trace().Msg("Started screen lock sensor.")
+	go func() {
+		locked, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(sessionPath).
+			Destination(loginDest).
+			GetProp(sessionLockedProp)
+		if err != nil {
+			log.Debug().Err(err).Msg("Could not retrieve current screen lock state.")
+			return
+		}
+		sensorCh <- newScreenlockEvent(dbusx.VariantToValue[bool](locked))
+	}()
 	go func() {
 		defer close(sensorCh)
 		<-ctx.Done()
@@ -90,3 +145,14 @@ func ScreenLockUpdater(ctx context.Context) chan tracker.Sensor {
 	}()
 	return sensorCh
 }
+
+// isScreensaverSignal returns whether the signal is an ActiveChanged signal
+// from one of the known desktop screensaver interfaces.
+func isScreensaverSignal(name string) bool {
+	for _, i := range screensaverInterfaces {
+		if name == i+".ActiveChanged" {
+			return true
+		}
+	}
+	return false
+}
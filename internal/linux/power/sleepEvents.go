@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package power
+
+import (
+	"context"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	prepareForSleepSignal = managerInterface + ".PrepareForSleep"
+
+	// SuspendEvent is fired on the Home Assistant event bus when the device is
+	// about to suspend.
+	SuspendEvent = "go_hass_agent_suspend"
+	// ResumeEvent is fired on the Home Assistant event bus when the device has
+	// resumed from suspend.
+	ResumeEvent = "go_hass_agent_resume"
+)
+
+func newSleepEvent(eventType, deviceName string) *hass.Event {
+	return &hass.Event{
+		EventType: eventType,
+		EventData: map[string]string{"device_name": deviceName},
+	}
+}
+
+// SleepEventsUpdater fires an event in Home Assistant when the device is about
+// to suspend and when it resumes. On resume, all polled sensors are refreshed,
+// as their values will be stale.
+func SleepEventsUpdater(ctx context.Context) chan *hass.Event {
+	eventCh := make(chan *hass.Event, 1)
+	deviceName := preferences.FetchFromContext(ctx).DeviceName
+
+	var mu sync.Mutex
+	err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(loginPath),
+			dbus.WithMatchInterface(managerInterface),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != loginPath || s.Name != prepareForSleepSignal || len(s.Body) == 0 {
+				return
+			}
+			suspending, ok := s.Body[0].(bool)
+			if !ok {
+				return
+			}
+			event := newSleepEvent(ResumeEvent, deviceName)
+			if suspending {
+				event = newSleepEvent(SuspendEvent, deviceName)
+			} else {
+				log.Debug().Msg("Device resumed. Refreshing sensors.")
+				helpers.RefreshAll()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case eventCh <- event:
+			case <-ctx.Done():
+			}
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch for suspend/resume. Sleep events will not be sent.")
+		close(eventCh)
+		return eventCh
+	}
+
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(eventCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped sleep events.")
+	}()
+	return eventCh
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package power
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	wakeupSourcesPath = "/sys/class/wakeup"
+	wakeupIRQFile     = "/sys/power/pm_wakeup_irq"
+
+	wakeReasonUnknown = "Unknown"
+)
+
+// wakeupSource is the state of a kernel wakeup source, from
+// /sys/class/wakeup.
+type wakeupSource struct {
+	path        string
+	name        string
+	wakeupCount uint64
+	eventCount  uint64
+	lastChange  uint64
+}
+
+func readWakeupSources() map[string]wakeupSource {
+	sources := make(map[string]wakeupSource)
+	paths, err := filepath.Glob(filepath.Join(wakeupSourcesPath, "wakeup*"))
+	if err != nil {
+		return sources
+	}
+	readUint := func(p, file string) uint64 {
+		b, err := os.ReadFile(filepath.Join(p, file))
+		if err != nil {
+			return 0
+		}
+		v, _ := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+		return v
+	}
+	for _, p := range paths {
+		name, err := os.ReadFile(filepath.Join(p, "name"))
+		if err != nil {
+			continue
+		}
+		sources[p] = wakeupSource{
+			path:        p,
+			name:        strings.TrimSpace(string(name)),
+			wakeupCount: readUint(p, "wakeup_count"),
+			eventCount:  readUint(p, "event_count"),
+			lastChange:  readUint(p, "last_change_ms"),
+		}
+	}
+	return sources
+}
+
+// findWakeupSource returns the source that most likely woke the device. A
+// source that aborted the suspend (its wakeup count increased) is preferred.
+// Otherwise, the source with the most recent event is used, as many devices
+// also report events while resuming.
+func findWakeupSource(before, after map[string]wakeupSource) (wakeupSource, bool) {
+	var woke, latest wakeupSource
+	var foundWoke, foundLatest bool
+	for p, a := range after {
+		b, ok := before[p]
+		if !ok {
+			continue
+		}
+		if a.wakeupCount > b.wakeupCount && (!foundWoke || a.lastChange > woke.lastChange) {
+			woke, foundWoke = a, true
+		}
+		if a.eventCount > b.eventCount && (!foundLatest || a.lastChange > latest.lastChange) {
+			latest, foundLatest = a, true
+		}
+	}
+	if foundWoke {
+		return woke, true
+	}
+	return latest, foundLatest
+}
+
+// wakeReason returns a friendly description of a wakeup source and, for USB
+// devices, the product name of the device.
+func wakeReason(source wakeupSource) (reason, device string) {
+	devPath, _ := filepath.EvalSymlinks(filepath.Join(source.path, "device"))
+	ids := strings.ToUpper(source.name + " " + devPath)
+	switch {
+	case strings.Contains(ids, "RTC") || strings.Contains(ids, "ALARMTIMER"):
+		return "RTC", ""
+	case strings.Contains(ids, "PNP0C0D") || strings.Contains(ids, "LNXLID"):
+		return "Lid", ""
+	case strings.Contains(ids, "PNP0C0C") || strings.Contains(ids, "LNXPWRBN"):
+		return "Power Button", ""
+	case strings.Contains(ids, "PNP0C0E") || strings.Contains(ids, "LNXSLPBN"):
+		return "Sleep Button", ""
+	case strings.Contains(ids, "ACPI0003"):
+		return "AC Adapter", ""
+	case strings.Contains(devPath, "/usb"):
+		if product, err := os.ReadFile(filepath.Join(devPath, "product")); err == nil {
+			device = strings.TrimSpace(string(product))
+		}
+		return "USB", device
+	default:
+		return source.name, ""
+	}
+}
+
+type wakeReasonSensor struct {
+	source string
+	device string
+	irq    string
+	linux.Sensor
+}
+
+func (s *wakeReasonSensor) Attributes() any {
+	return struct {
+		Source     string `json:"Wakeup Source,omitempty"`
+		Device     string `json:"Device,omitempty"`
+		IRQ        string `json:"IRQ,omitempty"`
+		DataSource string `json:"Data Source"`
+	}{
+		Source:     s.source,
+		Device:     s.device,
+		IRQ:        s.irq,
+		DataSource: linux.DataSrcSysfs,
+	}
+}
+
+func newWakeSensors(before, after map[string]wakeupSource, asleep, latency time.Duration) []tracker.Sensor {
+	reason := &wakeReasonSensor{}
+	reason.SensorTypeValue = linux.SensorWakeReason
+	reason.IconString = "mdi:alarm"
+	reason.SensorSrc = linux.DataSrcSysfs
+	reason.IsDiagnostic = true
+	reason.Value = wakeReasonUnknown
+	if source, ok := findWakeupSource(before, after); ok {
+		reason.source = source.name
+		reason.Value, reason.device = wakeReason(source)
+	}
+	if irq, err := os.ReadFile(wakeupIRQFile); err == nil {
+		reason.irq = strings.TrimSpace(string(irq))
+	}
+
+	sleep := &linux.Sensor{
+		SensorTypeValue:  linux.SensorSleepDuration,
+		IconString:       "mdi:power-sleep",
+		UnitsString:      "s",
+		DeviceClassValue: sensor.Duration,
+		StateClassValue:  sensor.StateMeasurement,
+		IsDiagnostic:     true,
+		Value:            math.Round(asleep.Seconds()),
+	}
+
+	resume := &linux.Sensor{
+		SensorTypeValue:  linux.SensorResumeLatency,
+		IconString:       "mdi:timer-sand",
+		UnitsString:      "s",
+		DeviceClassValue: sensor.Duration,
+		StateClassValue:  sensor.StateMeasurement,
+		IsDiagnostic:     true,
+		Value:            math.Round(latency.Seconds()*100) / 100,
+	}
+
+	return []tracker.Sensor{reason, sleep, resume}
+}
+
+// WakeUpdater reports why the device woke up after each resume, from the
+// kernel wakeup sources, along with how long it slept and how long suspending
+// and resuming took.
+func WakeUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var (
+		mu        sync.Mutex
+		suspended time.Time
+		before    map[string]wakeupSource
+	)
+	err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchObjectPath(loginPath),
+			dbus.WithMatchInterface(managerInterface),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if s.Path != loginPath || s.Name != prepareForSleepSignal || len(s.Body) == 0 {
+				return
+			}
+			suspending, ok := s.Body[0].(bool)
+			if !ok {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if suspending {
+				suspended = time.Now()
+				before = readWakeupSources()
+				return
+			}
+			if suspended.IsZero() || ctx.Err() != nil {
+				return
+			}
+			// The monotonic clock does not advance while suspended, so
+			// the difference between the wall clock and monotonic
+			// durations is the time spent asleep. The rest was spent
+			// suspending and resuming.
+			now := time.Now()
+			latency := now.Sub(suspended)
+			asleep := now.Round(0).Sub(suspended.Round(0)) - latency
+			for _, s := range newWakeSensors(before, readWakeupSources(), max(asleep, 0), latency) {
+				select {
+				case sensorCh <- s:
+				case <-ctx.Done():
+					return
+				}
+			}
+			suspended = time.Time{}
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch for suspend/resume. Wake sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped wake sensors.")
+	}()
+	return sensorCh
+}
@@ -88,3 +88,13 @@ func (l *Sensor) Attributes() any {
 	}
 	return nil
 }
+
+// SetKind sets the device class, state class and units of the sensor to those
+// Home Assistant expects for the given kind of value.
+func (l *Sensor) SetKind(kind sensor.Kind) {
+	if i, ok := sensor.Infer(kind); ok {
+		l.DeviceClassValue = i.DeviceClass
+		l.StateClassValue = i.StateClass
+		l.UnitsString = i.Units
+	}
+}
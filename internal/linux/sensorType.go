@@ -7,58 +7,111 @@ package linux
 
 //go:generate stringer -type=SensorTypeValue -output sensorTypeStrings.go -linecomment
 const (
-	SensorAppActive         SensorTypeValue = iota + 1 // Active App
-	SensorAppRunning                                   // Running Apps
-	SensorBattType                                     // Battery Type
-	SensorBattPercentage                               // Battery Level
-	SensorBattTemp                                     // Battery Temperature
-	SensorBattVoltage                                  // Battery Voltage
-	SensorBattEnergy                                   // Battery Energy
-	SensorBattEnergyRate                               // Battery Power
-	SensorBattState                                    // Battery State
-	SensorBattNativePath                               // Battery Path
-	SensorBattLevel                                    // Battery Level
-	SensorBattModel                                    // Battery Model
-	SensorMemTotal                                     // Memory Total
-	SensorMemAvail                                     // Memory Available
-	SensorMemUsed                                      // Memory Used
-	SensorMemPc                                        // Memory Usage
-	SensorSwapTotal                                    // Swap Memory Total
-	SensorSwapUsed                                     // Swap Memory Used
-	SensorSwapFree                                     // Swap Memory Free
-	SensorSwapPc                                       // Swap Usage
-	SensorConnectionState                              // Connection State
-	SensorConnectionID                                 // Connection ID
-	SensorConnectionDevices                            // Connection Device
-	SensorConnectionType                               // Connection Type
-	SensorConnectionIPv4                               // Connection IPv4
-	SensorConnectionIPv6                               // Connection IPv6
-	SensorAddressIPv4                                  // IPv4 Address
-	SensorAddressIPv6                                  // IPv6 Address
-	SensorWifiSSID                                     // Wi-Fi SSID
-	SensorWifiFrequency                                // Wi-Fi Frequency
-	SensorWifiSpeed                                    // Wi-Fi Link Speed
-	SensorWifiStrength                                 // Wi-Fi Signal Strength
-	SensorWifiHWAddress                                // Wi-Fi BSSID
-	SensorBytesSent                                    // Bytes Sent
-	SensorBytesRecv                                    // Bytes Received
-	SensorBytesSentRate                                // Bytes Sent Throughput
-	SensorBytesRecvRate                                // Bytes Received Throughput
-	SensorPowerProfile                                 // Power Profile
-	SensorBoottime                                     // Last Reboot
-	SensorUptime                                       // Uptime
-	SensorLoad1                                        // CPU load average (1 min)
-	SensorLoad5                                        // CPU load average (5 min)
-	SensorLoad15                                       // CPU load average (15 min)
-	SensorCPUPc                                        // CPU Usage
-	SensorScreenLock                                   // Screen Lock
-	SensorProblem                                      // Problems
-	SensorKernel                                       // Kernel Version
-	SensorDistribution                                 // Distribution Name
-	SensorVersion                                      // Distribution Version
-	SensorUsers                                        // Current Users
-	SensorDeviceTemp                                   // Temperature
-	SensorPowerState                                   // Power State
+	SensorAppActive              SensorTypeValue = iota + 1 // Active App
+	SensorAppRunning                                        // Running Apps
+	SensorBattType                                          // Battery Type
+	SensorBattPercentage                                    // Battery Level
+	SensorBattTemp                                          // Battery Temperature
+	SensorBattVoltage                                       // Battery Voltage
+	SensorBattEnergy                                        // Battery Energy
+	SensorBattEnergyRate                                    // Battery Power
+	SensorBattState                                         // Battery State
+	SensorBattNativePath                                    // Battery Path
+	SensorBattLevel                                         // Battery Level
+	SensorBattModel                                         // Battery Model
+	SensorMemTotal                                          // Memory Total
+	SensorMemAvail                                          // Memory Available
+	SensorMemUsed                                           // Memory Used
+	SensorMemPc                                             // Memory Usage
+	SensorSwapTotal                                         // Swap Memory Total
+	SensorSwapUsed                                          // Swap Memory Used
+	SensorSwapFree                                          // Swap Memory Free
+	SensorSwapPc                                            // Swap Usage
+	SensorConnectionState                                   // Connection State
+	SensorConnectionID                                      // Connection ID
+	SensorConnectionDevices                                 // Connection Device
+	SensorConnectionType                                    // Connection Type
+	SensorConnectionIPv4                                    // Connection IPv4
+	SensorConnectionIPv6                                    // Connection IPv6
+	SensorAddressIPv4                                       // IPv4 Address
+	SensorAddressIPv6                                       // IPv6 Address
+	SensorWifiSSID                                          // Wi-Fi SSID
+	SensorWifiFrequency                                     // Wi-Fi Frequency
+	SensorWifiSpeed                                         // Wi-Fi Link Speed
+	SensorWifiStrength                                      // Wi-Fi Signal Strength
+	SensorWifiHWAddress                                     // Wi-Fi BSSID
+	SensorBytesSent                                         // Bytes Sent
+	SensorBytesRecv                                         // Bytes Received
+	SensorBytesSentRate                                     // Bytes Sent Throughput
+	SensorBytesRecvRate                                     // Bytes Received Throughput
+	SensorPowerProfile                                      // Power Profile
+	SensorBoottime                                          // Last Reboot
+	SensorUptime                                            // Uptime
+	SensorLoad1                                             // CPU load average (1 min)
+	SensorLoad5                                             // CPU load average (5 min)
+	SensorLoad15                                            // CPU load average (15 min)
+	SensorCPUPc                                             // CPU Usage
+	SensorScreenLock                                        // Screen Lock
+	SensorProblem                                           // Problems
+	SensorKernel                                            // Kernel Version
+	SensorDistribution                                      // Distribution Name
+	SensorVersion                                           // Distribution Version
+	SensorUsers                                             // Current Users
+	SensorDeviceTemp                                        // Temperature
+	SensorPowerState                                        // Power State
+	SensorConnectionsEstablished                            // Established Connections
+	SensorConnectionsListening                              // Listening Ports
+	SensorRAIDState                                         // RAID State
+	SensorRAIDDegraded                                      // RAID Degraded
+	SensorRAIDSyncProgress                                  // RAID Sync Progress
+	SensorPoolHealth                                        // Pool Health
+	SensorPoolErrors                                        // Pool Errors
+	SensorHotspotClients                                    // Hotspot Clients
+	SensorActiveWindow                                      // Active Window
+	SensorLinkSpeed                                         // Link Speed
+	SensorLinkDuplex                                        // Link Duplex
+	SensorBrightness                                        // Brightness
+	SensorAppTraffic                                        // Top Network App
+	SensorDisplays                                          // Connected Displays
+	SensorNowPlaying                                        // Now Playing
+	SensorBattHealth                                        // Battery Health
+	SensorBattCycles                                        // Battery Charge Cycles
+	SensorBattChargingPower                                 // Battery Charging Power
+	SensorBattTimeToEmpty                                   // Battery Time To Empty
+	SensorBattTimeToFull                                    // Battery Time To Full
+	SensorUPSCharge                                         // UPS Charge
+	SensorUPSRuntime                                        // UPS Runtime
+	SensorUPSLoad                                           // UPS Load
+	SensorUPSOnBattery                                      // UPS On Battery
+	SensorLidClosed                                         // Lid Closed
+	SensorDocked                                            // Docked
+	SensorLightLevel                                        // Light Level
+	SensorOrientation                                       // Orientation
+	SensorProximity                                         // Proximity
+	SensorRAPLPower                                         // Power
+	SensorRAPLEnergy                                        // Energy
+	SensorSleepInhibited                                    // Sleep Inhibited
+	SensorNetworkShares                                     // Network Shares
+	SensorDoNotDisturb                                      // Do Not Disturb
+	SensorColorScheme                                       // Color Scheme
+	SensorAccentColor                                       // Accent Color
+	SensorTimeSync                                          // Time Synchronized
+	SensorTimezone                                          // Timezone
+	SensorLocale                                            // Locale
+	SensorSecureBoot                                        // Secure Boot Enabled
+	SensorTPM                                               // TPM Active
+	SensorDiskEncrypted                                     // Encrypted
+	SensorEstimatedEnergy                                   // Estimated Energy
+	SensorBackupLastSuccess                                 // Last Successful Backup
+	SensorBackupResult                                      // Last Backup Result
+	SensorWakeReason                                        // Wake Reason
+	SensorSleepDuration                                     // Last Sleep Duration
+	SensorResumeLatency                                     // Resume Latency
+	SensorLastUpdate                                        // Last System Update
+	SensorBootDuration                                      // Boot Duration
+	SensorVirtualization                                    // Virtualization
+	SensorUSBTemperature                                    // USB Temperature
+	SensorUSBHumidity                                       // USB Humidity
 )
 
 // SensorTypeValue represents the unique type of sensor data being reported. Every
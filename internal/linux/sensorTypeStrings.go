@@ -60,11 +60,64 @@ func _() {
 	_ = x[SensorUsers-50]
 	_ = x[SensorDeviceTemp-51]
 	_ = x[SensorPowerState-52]
+	_ = x[SensorConnectionsEstablished-53]
+	_ = x[SensorConnectionsListening-54]
+	_ = x[SensorRAIDState-55]
+	_ = x[SensorRAIDDegraded-56]
+	_ = x[SensorRAIDSyncProgress-57]
+	_ = x[SensorPoolHealth-58]
+	_ = x[SensorPoolErrors-59]
+	_ = x[SensorHotspotClients-60]
+	_ = x[SensorActiveWindow-61]
+	_ = x[SensorLinkSpeed-62]
+	_ = x[SensorLinkDuplex-63]
+	_ = x[SensorBrightness-64]
+	_ = x[SensorAppTraffic-65]
+	_ = x[SensorDisplays-66]
+	_ = x[SensorNowPlaying-67]
+	_ = x[SensorBattHealth-68]
+	_ = x[SensorBattCycles-69]
+	_ = x[SensorBattChargingPower-70]
+	_ = x[SensorBattTimeToEmpty-71]
+	_ = x[SensorBattTimeToFull-72]
+	_ = x[SensorUPSCharge-73]
+	_ = x[SensorUPSRuntime-74]
+	_ = x[SensorUPSLoad-75]
+	_ = x[SensorUPSOnBattery-76]
+	_ = x[SensorLidClosed-77]
+	_ = x[SensorDocked-78]
+	_ = x[SensorLightLevel-79]
+	_ = x[SensorOrientation-80]
+	_ = x[SensorProximity-81]
+	_ = x[SensorRAPLPower-82]
+	_ = x[SensorRAPLEnergy-83]
+	_ = x[SensorSleepInhibited-84]
+	_ = x[SensorNetworkShares-85]
+	_ = x[SensorDoNotDisturb-86]
+	_ = x[SensorColorScheme-87]
+	_ = x[SensorAccentColor-88]
+	_ = x[SensorTimeSync-89]
+	_ = x[SensorTimezone-90]
+	_ = x[SensorLocale-91]
+	_ = x[SensorSecureBoot-92]
+	_ = x[SensorTPM-93]
+	_ = x[SensorDiskEncrypted-94]
+	_ = x[SensorEstimatedEnergy-95]
+	_ = x[SensorBackupLastSuccess-96]
+	_ = x[SensorBackupResult-97]
+	_ = x[SensorWakeReason-98]
+	_ = x[SensorSleepDuration-99]
+	_ = x[SensorResumeLatency-100]
+	_ = x[SensorLastUpdate-101]
+	_ = x[SensorBootDuration-102]
+	_ = x[SensorVirtualization-103]
+	_ = x[SensorUSBTemperature-104]
+	_ = x[SensorUSBHumidity-105]
 }
 
-const _SensorTypeValue_name = "Active AppRunning AppsBattery TypeBattery LevelBattery TemperatureBattery VoltageBattery EnergyBattery PowerBattery StateBattery PathBattery LevelBattery ModelMemory TotalMemory AvailableMemory UsedMemory UsageSwap Memory TotalSwap Memory UsedSwap Memory FreeSwap UsageConnection StateConnection IDConnection DeviceConnection TypeConnection IPv4Connection IPv6IPv4 AddressIPv6 AddressWi-Fi SSIDWi-Fi FrequencyWi-Fi Link SpeedWi-Fi Signal StrengthWi-Fi BSSIDBytes SentBytes ReceivedBytes Sent ThroughputBytes Received ThroughputPower ProfileLast RebootUptimeCPU load average (1 min)CPU load average (5 min)CPU load average (15 min)CPU UsageScreen LockProblemsKernel VersionDistribution NameDistribution VersionCurrent UsersTemperaturePower State"
+const _SensorTypeValue_name = "Active AppRunning AppsBattery TypeBattery LevelBattery TemperatureBattery VoltageBattery EnergyBattery PowerBattery StateBattery PathBattery LevelBattery ModelMemory TotalMemory AvailableMemory UsedMemory UsageSwap Memory TotalSwap Memory UsedSwap Memory FreeSwap UsageConnection StateConnection IDConnection DeviceConnection TypeConnection IPv4Connection IPv6IPv4 AddressIPv6 AddressWi-Fi SSIDWi-Fi FrequencyWi-Fi Link SpeedWi-Fi Signal StrengthWi-Fi BSSIDBytes SentBytes ReceivedBytes Sent ThroughputBytes Received ThroughputPower ProfileLast RebootUptimeCPU load average (1 min)CPU load average (5 min)CPU load average (15 min)CPU UsageScreen LockProblemsKernel VersionDistribution NameDistribution VersionCurrent UsersTemperaturePower StateEstablished ConnectionsListening PortsRAID StateRAID DegradedRAID Sync ProgressPool HealthPool ErrorsHotspot ClientsActive WindowLink SpeedLink DuplexBrightnessTop Network AppConnected DisplaysNow PlayingBattery HealthBattery Charge CyclesBattery Charging PowerBattery Time To EmptyBattery Time To FullUPS ChargeUPS RuntimeUPS LoadUPS On BatteryLid ClosedDockedLight LevelOrientationProximityPowerEnergySleep InhibitedNetwork SharesDo Not DisturbColor SchemeAccent ColorTime SynchronizedTimezoneLocaleSecure Boot EnabledTPM ActiveEncryptedEstimated EnergyLast Successful BackupLast Backup ResultWake ReasonLast Sleep DurationResume LatencyLast System UpdateBoot DurationVirtualizationUSB TemperatureUSB Humidity"
 
-var _SensorTypeValue_index = [...]uint16{0, 10, 22, 34, 47, 66, 81, 95, 108, 121, 133, 146, 159, 171, 187, 198, 210, 227, 243, 259, 269, 285, 298, 315, 330, 345, 360, 372, 384, 394, 409, 425, 446, 457, 467, 481, 502, 527, 540, 551, 557, 581, 605, 630, 639, 650, 658, 672, 689, 709, 722, 733, 744}
+var _SensorTypeValue_index = [...]uint16{0, 10, 22, 34, 47, 66, 81, 95, 108, 121, 133, 146, 159, 171, 187, 198, 210, 227, 243, 259, 269, 285, 298, 315, 330, 345, 360, 372, 384, 394, 409, 425, 446, 457, 467, 481, 502, 527, 540, 551, 557, 581, 605, 630, 639, 650, 658, 672, 689, 709, 722, 733, 744, 767, 782, 792, 805, 823, 834, 845, 860, 873, 883, 894, 904, 919, 937, 948, 962, 983, 1005, 1026, 1046, 1056, 1067, 1075, 1089, 1099, 1105, 1116, 1127, 1136, 1141, 1147, 1162, 1176, 1190, 1202, 1214, 1231, 1239, 1245, 1264, 1274, 1283, 1299, 1321, 1339, 1350, 1369, 1383, 1401, 1414, 1428, 1443, 1455}
 
 func (i SensorTypeValue) String() string {
 	i -= 1
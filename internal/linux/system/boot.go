@@ -0,0 +1,196 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	systemdDest     = "org.freedesktop.systemd1"
+	systemdPath     = "/org/freedesktop/systemd1"
+	systemdManager  = "org.freedesktop.systemd1.Manager"
+	bootCheckPeriod = 30 * time.Second
+	slowestUnits    = 5
+)
+
+var errBootNotFinished = errors.New("boot has not finished")
+
+// bootTimes are the durations of each stage of the boot, as reported by
+// systemd-analyze time, in seconds.
+type bootTimes struct {
+	Firmware  float64  `json:"Firmware,omitempty"`
+	Loader    float64  `json:"Loader,omitempty"`
+	Kernel    float64  `json:"Kernel"`
+	Initrd    float64  `json:"Initrd,omitempty"`
+	Userspace float64  `json:"Userspace"`
+	Slowest   []string `json:"Slowest Units,omitempty"`
+	total     float64
+}
+
+type bootSensor struct {
+	times *bootTimes
+	linux.Sensor
+}
+
+func (s *bootSensor) Attributes() any {
+	return struct {
+		*bootTimes
+		DataSource string `json:"Data Source"`
+	}{
+		bootTimes:  s.times,
+		DataSource: linux.DataSrcDbus,
+	}
+}
+
+func roundSeconds(usec uint64) float64 {
+	return math.Round(float64(usec)/1e4) / 100
+}
+
+// getBootTimes retrieves the boot timestamps from systemd and calculates the
+// time spent in each stage the same way as systemd-analyze time. The firmware
+// and loader timestamps count back from when the kernel started, so are only
+// known on EFI systems with a supporting boot loader.
+func getBootTimes(ctx context.Context) (*bootTimes, error) {
+	req := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(systemdPath).
+		Destination(systemdDest)
+	timestamp := func(name string) (uint64, error) {
+		v, err := req.GetProp(systemdManager + "." + name + "TimestampMonotonic")
+		if err != nil {
+			return 0, err
+		}
+		return dbusx.VariantToValue[uint64](v), nil
+	}
+	finish, err := timestamp("Finish")
+	if err != nil {
+		return nil, err
+	}
+	if finish == 0 {
+		return nil, errBootNotFinished
+	}
+	userspace, err := timestamp("Userspace")
+	if err != nil {
+		return nil, err
+	}
+	firmware, _ := timestamp("Firmware")
+	loader, _ := timestamp("Loader")
+	initrd, _ := timestamp("InitRD")
+
+	times := &bootTimes{
+		Userspace: roundSeconds(finish - userspace),
+		total:     roundSeconds(firmware + finish),
+	}
+	if firmware > 0 && loader > 0 {
+		times.Firmware = roundSeconds(firmware - loader)
+		times.Loader = roundSeconds(loader)
+	}
+	if initrd > 0 {
+		times.Kernel = roundSeconds(initrd)
+		times.Initrd = roundSeconds(userspace - initrd)
+	} else {
+		times.Kernel = roundSeconds(userspace)
+	}
+	return times, nil
+}
+
+// parseBlame returns up to n of the slowest units from the output of
+// systemd-analyze blame, which lists units slowest first, such as:
+//
+//	5.123s NetworkManager-wait-online.service
+//	1min 2.350s plocate-updatedb.service
+func parseBlame(out []byte, n int) []string {
+	var units []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() && len(units) < n {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		unit := fields[len(fields)-1]
+		units = append(units, unit+" ("+strings.Join(fields[:len(fields)-1], " ")+")")
+	}
+	return units
+}
+
+// slowestBootUnits returns the units that took the longest to start during
+// boot, from systemd-analyze blame.
+func slowestBootUnits(ctx context.Context) []string {
+	analyze, err := exec.LookPath("systemd-analyze")
+	if err != nil {
+		return nil
+	}
+	out, err := exec.CommandContext(ctx, analyze, "blame", "--no-pager").Output()
+	if err != nil {
+		log.Debug().Err(err).Msg("Could not retrieve slowest boot units.")
+		return nil
+	}
+	return parseBlame(out, slowestUnits)
+}
+
+func newBootSensor(times *bootTimes) *bootSensor {
+	return &bootSensor{
+		times: times,
+		Sensor: linux.Sensor{
+			SensorTypeValue:  linux.SensorBootDuration,
+			IconString:       "mdi:timer-play-outline",
+			UnitsString:      "s",
+			SensorSrc:        linux.DataSrcDbus,
+			DeviceClassValue: sensor.Duration,
+			StateClassValue:  sensor.StateMeasurement,
+			IsDiagnostic:     true,
+			Value:            times.total,
+		},
+	}
+}
+
+// BootUpdater reports how long the device took to boot, with the time spent in
+// each stage of the boot and the slowest units to start as attributes. As the
+// boot time only changes on reboot, it is reported once, as soon as systemd has
+// finished booting.
+func BootUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	go func() {
+		defer close(sensorCh)
+		ticker := time.NewTicker(bootCheckPeriod)
+		defer ticker.Stop()
+		for {
+			times, err := getBootTimes(ctx)
+			switch {
+			case err == nil:
+				times.Slowest = slowestBootUnits(ctx)
+				select {
+				case sensorCh <- newBootSensor(times):
+				case <-ctx.Done():
+				}
+				return
+			case !errors.Is(err, errBootNotFinished):
+				log.Debug().Err(err).Msg("Could not retrieve boot times. Boot duration sensor will not run.")
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return sensorCh
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package system
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	efiDir = "/sys/firmware/efi"
+	// efiGlobalGUID is the GUID of the EFI global variables, such as
+	// SecureBoot and SetupMode.
+	efiGlobalGUID  = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+	secureBootVar  = "SecureBoot-" + efiGlobalGUID
+	setupModeVar   = "SetupMode-" + efiGlobalGUID
+	efivarsDir     = efiDir + "/efivars"
+	tpmDir         = "/sys/class/tpm"
+	tpmVersionFile = "tpm_version_major"
+)
+
+type secureBootSensor struct {
+	firmware  string
+	setupMode bool
+	linux.Sensor
+}
+
+func (s *secureBootSensor) Icon() string {
+	if enabled, ok := s.Value.(bool); ok && enabled {
+		return "mdi:shield-lock"
+	}
+	return "mdi:shield-off-outline"
+}
+
+func (s *secureBootSensor) Attributes() any {
+	return struct {
+		Firmware   string `json:"Firmware"`
+		DataSource string `json:"Data Source"`
+		SetupMode  bool   `json:"Setup Mode"`
+	}{
+		Firmware:   s.firmware,
+		SetupMode:  s.setupMode,
+		DataSource: linux.DataSrcSysfs,
+	}
+}
+
+type tpmSensor struct {
+	version string
+	linux.Sensor
+}
+
+func (s *tpmSensor) Icon() string {
+	if active, ok := s.Value.(bool); ok && active {
+		return "mdi:chip"
+	}
+	return "mdi:close-octagon-outline"
+}
+
+func (s *tpmSensor) Attributes() any {
+	return struct {
+		Version    string `json:"Version,omitempty"`
+		DataSource string `json:"Data Source"`
+	}{
+		Version:    s.version,
+		DataSource: linux.DataSrcSysfs,
+	}
+}
+
+// readEFIVar returns the value of an EFI variable. The first four bytes of an
+// efivarfs file are the variable attributes, which are skipped.
+func readEFIVar(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(efivarsDir, name))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 5 {
+		return nil, errors.New("invalid EFI variable")
+	}
+	return data[4:], nil
+}
+
+// newSecureBootSensor reports whether Secure Boot is enabled. Systems booted
+// with legacy BIOS firmware do not support Secure Boot.
+func newSecureBootSensor() *secureBootSensor {
+	s := &secureBootSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorSecureBoot,
+			IsBinary:        true,
+			IsDiagnostic:    true,
+			SensorSrc:       linux.DataSrcSysfs,
+			Value:           false,
+		},
+	}
+	if _, err := os.Stat(efiDir); err != nil {
+		s.firmware = "BIOS"
+		return s
+	}
+	s.firmware = "UEFI"
+	if value, err := readEFIVar(secureBootVar); err == nil {
+		s.Value = value[0] == 1
+	} else {
+		log.Debug().Err(err).Msg("Could not read Secure Boot state.")
+	}
+	if value, err := readEFIVar(setupModeVar); err == nil {
+		s.setupMode = value[0] == 1
+	}
+	return s
+}
+
+// newTPMSensor reports whether a TPM is present and active. A TPM 1.2 device
+// can be present but disabled or deactivated in the firmware, while the kernel
+// only exposes a TPM 2.0 device when it is usable.
+func newTPMSensor() *tpmSensor {
+	s := &tpmSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorTPM,
+			IsBinary:        true,
+			IsDiagnostic:    true,
+			SensorSrc:       linux.DataSrcSysfs,
+			Value:           false,
+		},
+	}
+	devices, err := filepath.Glob(filepath.Join(tpmDir, "tpm[0-9]*"))
+	if err != nil || len(devices) == 0 {
+		return s
+	}
+	dev := devices[0]
+	if v, err := os.ReadFile(filepath.Join(dev, tpmVersionFile)); err == nil {
+		switch strings.TrimSpace(string(v)) {
+		case "1":
+			s.version = "1.2"
+		case "2":
+			s.version = "2.0"
+		}
+	}
+	active := true
+	for _, file := range []string{"enabled", "active"} {
+		if v, err := os.ReadFile(filepath.Join(dev, "device", file)); err == nil {
+			active = active && strings.TrimSpace(string(v)) == "1"
+		}
+	}
+	s.Value = active
+	return s
+}
+
+// SecurityUpdater reports whether Secure Boot is enabled and whether a TPM is
+// present and active. These only change across reboots, so are only reported
+// when the agent starts.
+func SecurityUpdater(_ context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 2)
+	defer close(sensorCh)
+	sensorCh <- newSecureBootSensor()
+	sensorCh <- newTPMSensor()
+	return sensorCh
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package system
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	lastUpdateInterval = time.Hour
+
+	dpkgLog   = "/var/log/dpkg.log"
+	pacmanLog = "/var/log/pacman.log"
+)
+
+// packageManager is a source of the time packages were last updated.
+type packageManager struct {
+	lastUpdate func() (time.Time, bool)
+	name       string
+	source     string
+}
+
+// packageManagers are checked in order, and the first that knows when the
+// packages were last updated is used. Where the package manager keeps a log,
+// it is used to find the last upgrade. Otherwise (or if the log has been
+// rotated since the last upgrade), the last time the package database changed
+// is used, which also includes installs and removals.
+var packageManagers = []packageManager{
+	{name: "dpkg", source: dpkgLog, lastUpdate: func() (time.Time, bool) {
+		return lastLogEntry(dpkgLog, parseDpkgLine)
+	}},
+	{name: "pacman", source: pacmanLog, lastUpdate: func() (time.Time, bool) {
+		return lastLogEntry(pacmanLog, parsePacmanLine)
+	}},
+	{name: "dpkg", source: "/var/lib/dpkg/status", lastUpdate: func() (time.Time, bool) {
+		return modTime("/var/lib/dpkg/status")
+	}},
+	{name: "pacman", source: "/var/lib/pacman/local", lastUpdate: func() (time.Time, bool) {
+		return modTime("/var/lib/pacman/local")
+	}},
+	{name: "rpm", source: "rpm database", lastUpdate: func() (time.Time, bool) {
+		return modTime("/var/lib/rpm/rpmdb.sqlite", "/usr/lib/sysimage/rpm/rpmdb.sqlite", "/var/lib/rpm/Packages")
+	}},
+	{name: "apk", source: "/lib/apk/db/installed", lastUpdate: func() (time.Time, bool) {
+		return modTime("/lib/apk/db/installed")
+	}},
+	{name: "xbps", source: "/var/db/xbps", lastUpdate: func() (time.Time, bool) {
+		return modTime("/var/db/xbps")
+	}},
+}
+
+// modTime returns the modification time of the first of the given files that
+// exists.
+func modTime(files ...string) (time.Time, bool) {
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			return info.ModTime(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// lastLogEntry returns the time of the last line in the log file that the
+// parse func recognises as a package upgrade.
+func lastLogEntry(file string, parse func(string) (time.Time, bool)) (time.Time, bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	var last time.Time
+	var found bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if t, ok := parse(scanner.Text()); ok {
+			last, found = t, true
+		}
+	}
+	return last, found
+}
+
+// parseDpkgLine parses an upgrade line of the dpkg log, such as:
+//
+//	2024-01-20 10:15:02 upgrade bash:amd64 5.2.15-2 5.2.21-2
+func parseDpkgLine(line string) (time.Time, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[2] != "upgrade" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(time.DateTime, fields[0]+" "+fields[1], time.Local)
+	return t, err == nil
+}
+
+// parsePacmanLine parses an upgrade line of the pacman log, such as:
+//
+//	[2024-01-20T10:15:02+1000] [ALPM] upgraded bash (5.2.021-1 -> 5.2.026-1)
+func parsePacmanLine(line string) (time.Time, bool) {
+	stamp, rest, ok := strings.Cut(strings.TrimPrefix(line, "["), "] [ALPM] upgraded ")
+	if !ok || rest == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02T15:04:05-0700", stamp)
+	return t, err == nil
+}
+
+type lastUpdateSensor struct {
+	manager *packageManager
+	linux.Sensor
+}
+
+func (s *lastUpdateSensor) Attributes() any {
+	return struct {
+		PackageManager string `json:"Package Manager"`
+		DataSource     string `json:"Data Source"`
+	}{
+		PackageManager: s.manager.name,
+		DataSource:     s.manager.source,
+	}
+}
+
+func newLastUpdateSensor(manager *packageManager, last time.Time) *lastUpdateSensor {
+	return &lastUpdateSensor{
+		manager: manager,
+		Sensor: linux.Sensor{
+			SensorTypeValue:  linux.SensorLastUpdate,
+			IconString:       "mdi:package-variant-closed-check",
+			DeviceClassValue: sensor.Timestamp,
+			IsDiagnostic:     true,
+			Value:            last.Format(time.RFC3339),
+		},
+	}
+}
+
+// LastUpdateUpdater reports when the system packages were last updated, from
+// the log or database of the package manager.
+func LastUpdateUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+
+	var mu sync.Mutex
+	sendLastUpdate := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range packageManagers {
+			last, ok := packageManagers[i].lastUpdate()
+			if !ok {
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case sensorCh <- newLastUpdateSensor(&packageManagers[i], last):
+			case <-ctx.Done():
+			}
+			return
+		}
+		log.Debug().Msg("Could not find when packages were last updated.")
+	}
+
+	go helpers.PollSensors(ctx, sendLastUpdate, lastUpdateInterval, time.Minute)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped last system update sensor.")
+	}()
+	return sensorCh
+}
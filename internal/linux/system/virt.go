@@ -0,0 +1,184 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	virtBareMetal = "bare_metal"
+	virtVM        = "vm"
+	virtContainer = "container"
+
+	dmiDir = "/sys/class/dmi/id"
+)
+
+// dmiVendors maps the DMI vendor and product strings of hypervisors to their
+// names, as used by systemd-detect-virt. The order matters, as the more
+// specific strings are checked first.
+var dmiVendors = []struct {
+	match string
+	name  string
+}{
+	{"KVM", "kvm"},
+	{"OpenStack", "kvm"},
+	{"KubeVirt", "kvm"},
+	{"Amazon EC2", "amazon"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VMW", "vmware"},
+	{"innotek GmbH", "oracle"},
+	{"VirtualBox", "oracle"},
+	{"Oracle Corporation", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+	{"Hyper-V", "microsoft"},
+	{"Apple Virtualization", "apple"},
+	{"Google Compute Engine", "google"},
+}
+
+type virtSensor struct {
+	technology string
+	source     string
+	linux.Sensor
+}
+
+func (s *virtSensor) Icon() string {
+	switch s.Value {
+	case virtVM:
+		return "mdi:monitor-multiple"
+	case virtContainer:
+		return "mdi:package-variant-closed"
+	default:
+		return "mdi:server"
+	}
+}
+
+func (s *virtSensor) Attributes() any {
+	return struct {
+		Technology string `json:"Technology,omitempty"`
+		DataSource string `json:"Data Source"`
+	}{
+		Technology: s.technology,
+		DataSource: s.source,
+	}
+}
+
+// detectContainer returns the container technology the agent is running in,
+// if any.
+func detectContainer() (tech, source string) {
+	// Set by systemd (and most container managers) in containers.
+	if b, err := os.ReadFile("/run/systemd/container"); err == nil {
+		return strings.TrimSpace(string(b)), "systemd"
+	}
+	if b, err := os.ReadFile("/proc/1/environ"); err == nil {
+		for _, env := range bytes.Split(b, []byte{0}) {
+			if v, ok := bytes.CutPrefix(env, []byte("container=")); ok && len(v) > 0 {
+				return string(v), linux.DataSrcProcfs
+			}
+		}
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman", "Container runtime"
+	}
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker", "Container runtime"
+	}
+	if b, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		if release := strings.ToLower(string(b)); strings.Contains(release, "microsoft") || strings.Contains(release, "wsl") {
+			return "wsl", linux.DataSrcProcfs
+		}
+	}
+	return "", ""
+}
+
+// detectVM returns the hypervisor the agent is running under, if any.
+func detectVM() (tech, source string) {
+	if b, err := os.ReadFile("/sys/hypervisor/type"); err == nil && strings.TrimSpace(string(b)) == "xen" {
+		return "xen", linux.DataSrcSysfs
+	}
+	dmi := make(map[string]string)
+	for _, file := range []string{"product_name", "sys_vendor", "board_vendor", "bios_vendor", "product_version"} {
+		if b, err := os.ReadFile(filepath.Join(dmiDir, file)); err == nil {
+			dmi[file] = strings.TrimSpace(string(b))
+		}
+	}
+	for _, v := range dmiVendors {
+		for _, value := range dmi {
+			if strings.HasPrefix(value, v.match) {
+				return v.name, linux.DataSrcSysfs
+			}
+		}
+	}
+	// Hyper-V identifies as Microsoft with a "Virtual Machine" product,
+	// unlike Microsoft hardware.
+	if dmi["product_name"] == "Virtual Machine" && strings.HasPrefix(dmi["sys_vendor"], "Microsoft") {
+		return "microsoft", linux.DataSrcSysfs
+	}
+	// Any other hypervisor sets the hypervisor CPU flag.
+	if f, err := os.Open("/proc/cpuinfo"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "flags") {
+				continue
+			}
+			if strings.Contains(line+" ", " hypervisor ") {
+				return "vm-other", linux.DataSrcProcfs
+			}
+			break
+		}
+	}
+	return "", ""
+}
+
+// newVirtSensor reports whether the agent is running on bare metal, in a
+// virtual machine or in a container, in the same way as systemd-detect-virt.
+// As with systemd-detect-virt, a container is reported in preference to the
+// virtual machine it is running in.
+func newVirtSensor() *virtSensor {
+	s := &virtSensor{
+		Sensor: linux.Sensor{
+			SensorTypeValue:  linux.SensorVirtualization,
+			DeviceClassValue: sensor.Enum,
+			IsDiagnostic:     true,
+			Value:            virtBareMetal,
+		},
+	}
+	if tech, source := detectContainer(); tech != "" {
+		s.Value, s.technology, s.source = virtContainer, tech, source
+	} else if tech, source := detectVM(); tech != "" {
+		s.Value, s.technology, s.source = virtVM, tech, source
+	} else {
+		s.source = linux.DataSrcSysfs
+	}
+	s.SensorSrc = s.source
+	return s
+}
+
+// VirtualizationUpdater reports whether the agent is running on bare metal, in
+// a virtual machine (and which hypervisor) or in a container. This cannot
+// change while the agent is running, so it is only reported when the agent
+// starts.
+func VirtualizationUpdater(_ context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	defer close(sensorCh)
+	sensorCh <- newVirtSensor()
+	return sensorCh
+}
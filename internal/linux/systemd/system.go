@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+// runInterval is how often the state of a system service being run is checked.
+const runInterval = time.Second
+
+// ErrServiceFailed is returned when a system service run with
+// RunSystemService failed.
+var ErrServiceFailed = errors.New("service failed")
+
+// RunSystemService starts the given one-shot system service (e.g.,
+// fstrim.service) and waits for it to finish. systemd queues the start job and
+// may not run it straight away, so the service is only considered finished
+// once its job has been removed. Starting a system service needs the
+// org.freedesktop.systemd1.manage-units polkit permission, which usually needs
+// administrator authentication unless a polkit rule allows it.
+func RunSystemService(ctx context.Context, name string) error {
+	data := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(systemdPath).
+		Destination(systemdDest).
+		GetData(managerIntr+".StartUnit", name, "replace")
+	if data == nil {
+		return errors.New("no bus connection")
+	}
+	if err := data.Err(); err != nil {
+		return err
+	}
+	job := data.AsObjectPath()
+	data = dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(systemdPath).
+		Destination(systemdDest).
+		GetData(managerIntr+".GetUnit", name)
+	if data == nil {
+		return errors.New("no bus connection")
+	}
+	if err := data.Err(); err != nil {
+		return err
+	}
+	path := data.AsObjectPath()
+	prop := func(name string) (dbus.Variant, error) {
+		return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+			Path(path).
+			Destination(systemdDest).
+			GetProp(unitIntr + "." + name)
+	}
+	ticker := time.NewTicker(runInterval)
+	defer ticker.Stop()
+	for {
+		// The Job property holds the id and path of the job pending for
+		// the unit, if any.
+		value, err := prop("Job")
+		if err != nil {
+			return err
+		}
+		if pending, ok := value.Value().([]any); !ok || len(pending) != 2 || pending[1] != job {
+			value, err := prop("ActiveState")
+			if err != nil {
+				return err
+			}
+			if dbusx.VariantToValue[string](value) == "failed" {
+				return fmt.Errorf("%w: %s", ErrServiceFailed, name)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
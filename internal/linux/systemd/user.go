@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package systemd controls the systemd services of the user running the agent,
+// through the systemd user manager on the session bus, and runs one-shot
+// system services through the system manager.
+package systemd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	systemdDest = "org.freedesktop.systemd1"
+	systemdPath = "/org/freedesktop/systemd1"
+	managerIntr = "org.freedesktop.systemd1.Manager"
+	unitIntr    = "org.freedesktop.systemd1.Unit"
+
+	// settleTimeout is how long to wait for a service to finish starting or
+	// stopping.
+	settleTimeout  = 10 * time.Second
+	settleInterval = 250 * time.Millisecond
+)
+
+// ErrUnitNotFound is returned for a service that systemd does not know about.
+var ErrUnitNotFound = errors.New("unit not found")
+
+// UserService is a systemd service of the user running the agent.
+type UserService struct {
+	Name string
+	path dbus.ObjectPath
+}
+
+// NewUserService returns the user service with the given name. The .service
+// suffix can be left out. It returns ErrUnitNotFound if there is no such
+// service.
+func NewUserService(ctx context.Context, name string) (*UserService, error) {
+	if !strings.Contains(name, ".") {
+		name += ".service"
+	}
+	data := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(systemdPath).
+		Destination(systemdDest).
+		GetData(managerIntr+".LoadUnit", name)
+	if data == nil {
+		return nil, errors.New("no bus connection")
+	}
+	if err := data.Err(); err != nil {
+		return nil, err
+	}
+	s := &UserService{Name: name, path: data.AsObjectPath()}
+	loadState, err := s.prop(ctx, "LoadState")
+	if err != nil {
+		return nil, err
+	}
+	if loadState == "not-found" {
+		return nil, ErrUnitNotFound
+	}
+	return s, nil
+}
+
+// callManager calls a method of the systemd user manager.
+func callManager(ctx context.Context, method string, args ...any) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(systemdPath).
+		Destination(systemdDest).
+		Call(managerIntr+"."+method, args...)
+}
+
+func (s *UserService) prop(ctx context.Context, name string) (string, error) {
+	value, err := dbusx.NewBusRequest(ctx, dbusx.SessionBus).
+		Path(s.path).
+		Destination(systemdDest).
+		GetProp(unitIntr + "." + name)
+	if err != nil {
+		return "", err
+	}
+	return dbusx.VariantToValue[string](value), nil
+}
+
+// Active returns whether the service is running (or starting).
+func (s *UserService) Active(ctx context.Context) (bool, error) {
+	state, err := s.prop(ctx, "ActiveState")
+	if err != nil {
+		return false, err
+	}
+	return state == "active" || state == "activating" || state == "reloading", nil
+}
+
+// Start starts the service and waits for it to finish starting.
+func (s *UserService) Start(ctx context.Context) error {
+	if err := callManager(ctx, "StartUnit", s.Name, "replace"); err != nil {
+		return err
+	}
+	return s.settle(ctx)
+}
+
+// Stop stops the service and waits for it to finish stopping.
+func (s *UserService) Stop(ctx context.Context) error {
+	if err := callManager(ctx, "StopUnit", s.Name, "replace"); err != nil {
+		return err
+	}
+	return s.settle(ctx)
+}
+
+// settle waits until the service is no longer starting or stopping, so that
+// its state can be reported, up to settleTimeout.
+func (s *UserService) settle(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, settleTimeout)
+	defer cancel()
+	ticker := time.NewTicker(settleInterval)
+	defer ticker.Stop()
+	for {
+		state, err := s.prop(ctx, "ActiveState")
+		if err != nil {
+			return err
+		}
+		if state != "activating" && state != "deactivating" && state != "reloading" {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
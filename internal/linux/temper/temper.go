@@ -0,0 +1,353 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package temper reads the temperature (and, where supported, humidity) from
+// TEMPer-family USB sensors, via their HID raw device.
+package temper
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	hidrawPath = "/sys/class/hidraw"
+	devPath    = "/dev"
+
+	pollInterval = time.Minute
+	readTimeout  = 2 * time.Second
+	reportSize   = 8
+)
+
+var (
+	// readCmd requests the current readings.
+	readCmd = []byte{0x01, 0x80, 0x33, 0x01, 0x00, 0x00, 0x00, 0x00}
+	// firmwareCmd requests the firmware name, which identifies the model of
+	// newer devices that share the same USB ID.
+	firmwareCmd = []byte{0x01, 0x86, 0xff, 0x01, 0x00, 0x00, 0x00, 0x00}
+
+	errUnsupported = errors.New("unsupported device")
+	errShortRead   = errors.New("short read from device")
+)
+
+// model describes how to decode the readings of a TEMPer device.
+type model int
+
+const (
+	// modelTEMPerGold covers the TEMPerGold, TEMPer2, TEMPer1F and TEMPerHUM
+	// devices with USB ID 413d:2107. Which one it is, and whether it has a
+	// humidity sensor, is found from the firmware name.
+	modelTEMPerGold model = iota + 1
+	// modelTEMPer1 covers the original TEMPer1 (USB ID 0c45:7401).
+	modelTEMPer1
+	// modelTEMPerHUM covers the original TEMPerHUM (USB ID 0c45:7402), with a
+	// Sensirion SHT1x sensor.
+	modelTEMPerHUM
+)
+
+var models = map[string]model{
+	"413D:2107": modelTEMPerGold,
+	"0C45:7401": modelTEMPer1,
+	"0C45:7402": modelTEMPerHUM,
+}
+
+// device is a TEMPer device found via its HID raw device.
+type device struct {
+	path     string
+	phys     string
+	firmware string
+	model    model
+}
+
+// name returns the model name of the device, e.g., TEMPerGold.
+func (d *device) name() string {
+	switch {
+	case d.firmware != "":
+		name, _, _ := strings.Cut(d.firmware, "_")
+		return name
+	case d.model == modelTEMPerHUM:
+		return "TEMPerHUM"
+	default:
+		return "TEMPer"
+	}
+}
+
+// port returns the USB port the device is connected to, from its physical
+// path, e.g., usb-0000:00:14.0-2. It identifies the device for as long as it
+// stays plugged into the same port.
+func (d *device) port() string {
+	return strings.TrimSuffix(d.phys, "/input1")
+}
+
+// hasHumidity returns whether the device has a humidity sensor.
+func (d *device) hasHumidity() bool {
+	return d.model == modelTEMPerHUM ||
+		(d.model == modelTEMPerGold && strings.Contains(strings.ToLower(d.firmware), "hum"))
+}
+
+// query sends a command to the device and reads the given number of reports
+// in response. The first byte written is the report number, which is zero as
+// TEMPer devices do not use numbered reports.
+func (d *device) query(cmd []byte, reports int) ([]byte, error) {
+	f, err := os.OpenFile(d.path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Write(append([]byte{0}, cmd...)); err != nil {
+		return nil, err
+	}
+	// Not all devices respond, so don't wait forever.
+	if err := f.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+		log.Trace().Err(err).Str("device", d.path).Msg("Could not set read timeout.")
+	}
+	data := make([]byte, 0, reports*reportSize)
+	buf := make([]byte, reportSize)
+	for range reports {
+		n, err := f.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, buf[:n]...)
+	}
+	return data, nil
+}
+
+// read returns the temperature (°C) and humidity (%) from the device. The
+// humidity is NaN if the device does not have a humidity sensor.
+func (d *device) read() (temp, humidity float64, err error) {
+	data, err := d.query(readCmd, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(data) < 6 {
+		return 0, 0, errShortRead
+	}
+	humidity = math.NaN()
+	switch d.model {
+	case modelTEMPerGold:
+		temp = float64(int16(binary.BigEndian.Uint16(data[2:4]))) / 100
+		if d.hasHumidity() {
+			humidity = float64(binary.BigEndian.Uint16(data[4:6])) / 100
+		}
+	case modelTEMPer1:
+		temp = float64(int16(binary.BigEndian.Uint16(data[2:4]))) / 256
+	case modelTEMPerHUM:
+		// Conversion from the SHT1x datasheet, for 14-bit temperature and
+		// 12-bit humidity readings at 5V.
+		temp = -39.7 + 0.01*float64(binary.BigEndian.Uint16(data[2:4]))
+		rh := float64(binary.BigEndian.Uint16(data[4:6]))
+		linear := -2.0468 + 0.0367*rh - 1.5955e-6*rh*rh
+		humidity = math.Max(0, math.Min(100, (temp-25)*(0.01+0.00008*rh)+linear))
+	default:
+		return 0, 0, errUnsupported
+	}
+	return math.Round(temp*100) / 100, math.Round(humidity*100) / 100, nil
+}
+
+// readUevent returns the USB ID (vendor:product) and physical path of a HID
+// raw device.
+func readUevent(hidraw string) (id, phys string) {
+	f, err := os.Open(filepath.Join(hidrawPath, hidraw, "device", "uevent"))
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "HID_ID":
+			// The HID ID is bus:vendor:product, e.g.,
+			// 0003:0000413D:00002107.
+			parts := strings.Split(value, ":")
+			if len(parts) != 3 {
+				continue
+			}
+			vendor, vendorErr := strconv.ParseUint(parts[1], 16, 16)
+			product, productErr := strconv.ParseUint(parts[2], 16, 16)
+			if vendorErr == nil && productErr == nil {
+				id = fmt.Sprintf("%04X:%04X", vendor, product)
+			}
+		case "HID_PHYS":
+			phys = value
+		}
+	}
+	return id, phys
+}
+
+// findDevices returns the TEMPer devices that are connected. Each device has
+// two HID interfaces; the readings are on the second (input1).
+func findDevices() []*device {
+	entries, err := os.ReadDir(hidrawPath)
+	if err != nil {
+		return nil
+	}
+	var devices []*device
+	for _, e := range entries {
+		id, phys := readUevent(e.Name())
+		m, ok := models[id]
+		if !ok || !strings.HasSuffix(phys, "/input1") {
+			continue
+		}
+		devices = append(devices, &device{
+			path:  filepath.Join(devPath, e.Name()),
+			phys:  phys,
+			model: m,
+		})
+	}
+	// Sort by physical path, so that devices are reported in a consistent
+	// order.
+	slices.SortFunc(devices, func(a, b *device) int {
+		return strings.Compare(a.phys, b.phys)
+	})
+	return devices
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+type temperSensor struct {
+	dev *device
+	linux.Sensor
+}
+
+// Name includes the USB port number, to tell apart devices of the same model.
+func (s *temperSensor) Name() string {
+	port := s.dev.port()
+	if i := strings.LastIndex(port, "-"); i >= 0 {
+		port = port[i+1:]
+	}
+	name := "TEMPer Port " + port
+	if s.SensorTypeValue == linux.SensorUSBHumidity {
+		return name + " Humidity"
+	}
+	return name + " Temperature"
+}
+
+// ID is derived from the USB port of the device, so that it does not change
+// when other devices are plugged in or removed.
+func (s *temperSensor) ID() string {
+	port := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(s.dev.port()), "_"), "_")
+	return "temper_" + port + "_" + strings.ToLower(strings.ReplaceAll(s.SensorTypeValue.String(), " ", "_"))
+}
+
+func (s *temperSensor) Attributes() any {
+	return struct {
+		Model      string `json:"Model"`
+		Firmware   string `json:"Firmware,omitempty"`
+		Device     string `json:"Device"`
+		Port       string `json:"Port"`
+		DataSource string `json:"Data Source"`
+	}{
+		Model:      s.dev.name(),
+		Firmware:   s.dev.firmware,
+		Device:     s.dev.path,
+		Port:       s.dev.port(),
+		DataSource: "HID",
+	}
+}
+
+func newTemperSensors(dev *device, temp, humidity float64) []tracker.Sensor {
+	t := &temperSensor{dev: dev}
+	t.SensorTypeValue = linux.SensorUSBTemperature
+	t.IconString = "mdi:thermometer"
+	t.DeviceClassValue = sensor.SensorTemperature
+	t.StateClassValue = sensor.StateMeasurement
+	t.UnitsString = "°C"
+	t.SensorSrc = "HID"
+	t.Value = temp
+	sensors := []tracker.Sensor{t}
+	if !math.IsNaN(humidity) {
+		h := &temperSensor{dev: dev}
+		h.SensorTypeValue = linux.SensorUSBHumidity
+		h.IconString = "mdi:water-percent"
+		h.DeviceClassValue = sensor.Humidity
+		h.StateClassValue = sensor.StateMeasurement
+		h.UnitsString = "%"
+		h.SensorSrc = "HID"
+		h.Value = humidity
+		sensors = append(sensors, h)
+	}
+	return sensors
+}
+
+// Updater reports the temperature, and humidity where supported, from any
+// connected TEMPer USB sensors. Devices are found on each update, so sensors
+// plugged in while the agent is running are picked up. Reading a device needs
+// write access to its /dev/hidraw device.
+func Updater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	if len(findDevices()) == 0 {
+		log.Debug().Msg("No TEMPer devices found. TEMPer sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	var mu sync.Mutex
+	// Firmware names are cached, as they don't change while the device is
+	// connected.
+	firmware := make(map[string]string)
+	sendTemperSensors := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dev := range findDevices() {
+			if dev.model == modelTEMPerGold {
+				if _, ok := firmware[dev.phys]; !ok {
+					data, err := dev.query(firmwareCmd, 2)
+					if err != nil {
+						log.Debug().Err(err).Str("device", dev.path).Msg("Could not read TEMPer firmware.")
+						continue
+					}
+					firmware[dev.phys] = strings.TrimSpace(strings.Trim(string(data), "\x00"))
+				}
+				dev.firmware = firmware[dev.phys]
+			}
+			temp, humidity, err := dev.read()
+			if err != nil {
+				log.Debug().Err(err).Str("device", dev.path).Msg("Could not read TEMPer device.")
+				continue
+			}
+			for _, s := range newTemperSensors(dev, temp, humidity) {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case sensorCh <- s:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendTemperSensors, pollInterval, time.Second*5)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped TEMPer sensors.")
+	}()
+	return sensorCh
+}
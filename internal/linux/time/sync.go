@@ -0,0 +1,202 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package time
+
+import (
+	"context"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	timedateDest = "org.freedesktop.timedate1"
+	timedatePath = "/org/freedesktop/timedate1"
+	timedateIntr = "org.freedesktop.timedate1"
+
+	dataSrcChrony  = "chronyc"
+	dataSrcAdjtime = "Kernel (adjtimex)"
+
+	// Kernel clock status flags (see adjtimex(2)).
+	staUnsync = 0x0040
+	staNano   = 0x2000
+
+	syncPollInterval = 5 * time.Minute
+)
+
+// timeSync holds the clock synchronisation status.
+type timeSync struct {
+	NTPEnabled *bool    `json:"NTP Enabled,omitempty"`
+	Offset     *float64 `json:"Last Offset (ms),omitempty"`
+	Server     string   `json:"Server,omitempty"`
+	Stratum    int      `json:"Stratum,omitempty"`
+	DataSource string   `json:"Data Source"`
+	synced     bool
+}
+
+type timeSyncSensor struct {
+	status *timeSync
+	linux.Sensor
+}
+
+func (s *timeSyncSensor) Icon() string {
+	if s.status.synced {
+		return "mdi:clock-check-outline"
+	}
+	return "mdi:clock-alert-outline"
+}
+
+func (s *timeSyncSensor) Attributes() any {
+	return s.status
+}
+
+func newTimeSyncSensor(status *timeSync) *timeSyncSensor {
+	return &timeSyncSensor{
+		status: status,
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorTimeSync,
+			IsBinary:        true,
+			IsDiagnostic:    true,
+			Value:           status.synced,
+		},
+	}
+}
+
+// timedatedSync retrieves whether NTP is enabled and the clock is synchronised
+// from systemd-timedated.
+func timedatedSync(ctx context.Context) (*timeSync, bool) {
+	req := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(timedatePath).
+		Destination(timedateDest)
+	synced, err := req.GetProp(timedateIntr + ".NTPSynchronized")
+	if err != nil {
+		return nil, false
+	}
+	status := &timeSync{
+		synced:     dbusx.VariantToValue[bool](synced),
+		DataSource: linux.DataSrcDbus,
+	}
+	if ntp, err := req.GetProp(timedateIntr + ".NTP"); err == nil {
+		enabled := dbusx.VariantToValue[bool](ntp)
+		status.NTPEnabled = &enabled
+	}
+	return status, true
+}
+
+// kernelSync retrieves the synchronisation status and offset of the clock from
+// the kernel. This works with any NTP daemon, but the offset is only that of
+// the kernel PLL, which is not used by all daemons.
+func kernelSync() (*timeSync, bool) {
+	var tx syscall.Timex
+	if _, err := syscall.Adjtimex(&tx); err != nil {
+		return nil, false
+	}
+	offset := float64(tx.Offset) / 1e3
+	if tx.Status&staNano != 0 {
+		offset /= 1e3
+	}
+	return &timeSync{
+		synced:     tx.Status&staUnsync == 0,
+		Offset:     &offset,
+		DataSource: dataSrcAdjtime,
+	}, true
+}
+
+// chronySync adds the last offset, server and stratum reported by chronyd to
+// the given status. The fields of chronyc -c tracking are: reference ID,
+// reference name, stratum, reference time, system time, last offset, etc.
+func chronySync(ctx context.Context, status *timeSync) {
+	chronyc, err := exec.LookPath("chronyc")
+	if err != nil {
+		return
+	}
+	out, err := exec.CommandContext(ctx, chronyc, "-c", "tracking").Output()
+	if err != nil {
+		log.Trace().Err(err).Msg("Could not retrieve chrony tracking.")
+		return
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), ",")
+	if len(fields) < 6 {
+		return
+	}
+	if offset, err := strconv.ParseFloat(fields[5], 64); err == nil {
+		offset *= 1e3
+		status.Offset = &offset
+	}
+	status.Server = fields[1]
+	status.Stratum, _ = strconv.Atoi(fields[2])
+	status.DataSource = dataSrcChrony
+}
+
+// getTimeSync retrieves the clock synchronisation status from timedated,
+// falling back to the kernel. The offset is added from chronyd, if it is
+// running, otherwise the offset from the kernel is used.
+func getTimeSync(ctx context.Context) (*timeSync, bool) {
+	status, ok := timedatedSync(ctx)
+	kernel, kernelOK := kernelSync()
+	switch {
+	case !ok && !kernelOK:
+		return nil, false
+	case !ok:
+		status = kernel
+	case kernelOK:
+		status.Offset = kernel.Offset
+	}
+	chronySync(ctx, status)
+	if status.Offset != nil {
+		offset := math.Round(*status.Offset*1000) / 1000
+		status.Offset = &offset
+	}
+	return status, true
+}
+
+// SyncUpdater reports whether the clock is synchronised with NTP, with the
+// last offset as an attribute. An unsynchronised clock can cause time-based
+// automations to misfire.
+func SyncUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	if _, ok := getTimeSync(ctx); !ok {
+		log.Debug().Msg("Could not retrieve time synchronisation status. Time sync sensor will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	var mu sync.Mutex
+	sendTimeSync := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		if status, ok := getTimeSync(ctx); ok {
+			select {
+			case sensorCh <- newTimeSyncSensor(status):
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendTimeSync, syncPollInterval, time.Minute)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped time sync sensor.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package time
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	localeDest = "org.freedesktop.locale1"
+	localePath = "/org/freedesktop/locale1"
+	localeIntr = "org.freedesktop.locale1"
+
+	localtimeFile = "/etc/localtime"
+	zoneinfoDir   = "zoneinfo/"
+
+	zonePollInterval = 15 * time.Minute
+
+	// TimezoneChangedEvent is fired on the Home Assistant event bus when the
+	// system timezone changes.
+	TimezoneChangedEvent = "go_hass_agent_timezone_changed"
+)
+
+type timezoneSensor struct {
+	source string
+	linux.Sensor
+}
+
+func (s *timezoneSensor) Attributes() any {
+	zone, _ := s.Value.(string)
+	var offset string
+	if loc, err := time.LoadLocation(zone); err == nil {
+		offset = time.Now().In(loc).Format("-07:00")
+	}
+	return struct {
+		UTCOffset  string `json:"UTC Offset,omitempty"`
+		DataSource string `json:"Data Source"`
+	}{
+		UTCOffset:  offset,
+		DataSource: s.source,
+	}
+}
+
+func newTimezoneSensor(zone, source string) *timezoneSensor {
+	return &timezoneSensor{
+		source: source,
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorTimezone,
+			IconString:      "mdi:map-clock-outline",
+			Value:           zone,
+		},
+	}
+}
+
+type localeSensor struct {
+	settings map[string]string
+	source   string
+	linux.Sensor
+}
+
+func (s *localeSensor) Attributes() any {
+	return struct {
+		Settings   map[string]string `json:"Settings,omitempty"`
+		DataSource string            `json:"Data Source"`
+	}{
+		Settings:   s.settings,
+		DataSource: s.source,
+	}
+}
+
+func newLocaleSensor(settings []string, source string) *localeSensor {
+	s := &localeSensor{
+		settings: make(map[string]string),
+		source:   source,
+		Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorLocale,
+			IconString:      "mdi:translate",
+		},
+	}
+	for _, setting := range settings {
+		if k, v, ok := strings.Cut(setting, "="); ok {
+			s.settings[k] = v
+		}
+	}
+	s.Value = s.settings["LANG"]
+	return s
+}
+
+// getTimezone returns the system timezone from timedated, falling back to the
+// zone that /etc/localtime links to.
+func getTimezone(ctx context.Context) (string, string) {
+	zone, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(timedatePath).
+		Destination(timedateDest).
+		GetProp(timedateIntr + ".Timezone")
+	if err == nil {
+		if z := dbusx.VariantToValue[string](zone); z != "" {
+			return z, linux.DataSrcDbus
+		}
+	}
+	target, err := filepath.EvalSymlinks(localtimeFile)
+	if err != nil {
+		return "", ""
+	}
+	if _, z, ok := strings.Cut(target, zoneinfoDir); ok {
+		return z, localtimeFile
+	}
+	return "", ""
+}
+
+// getLocale returns the system locale settings (e.g., LANG=en_AU.UTF-8) from
+// localed, falling back to the locale of the agent.
+func getLocale(ctx context.Context) ([]string, string) {
+	settings, err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(localePath).
+		Destination(localeDest).
+		GetProp(localeIntr + ".Locale")
+	if err == nil {
+		if s := dbusx.VariantToValue[[]string](settings); len(s) > 0 {
+			return s, linux.DataSrcDbus
+		}
+	}
+	if lang, ok := os.LookupEnv("LANG"); ok {
+		return []string{"LANG=" + lang}, "Environment"
+	}
+	return nil, ""
+}
+
+// watchZoneChanges calls the given function whenever timedated or localed
+// report their properties have changed.
+func watchZoneChanges(ctx context.Context, changed func()) error {
+	return dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		}).
+		Handler(func(s *dbus.Signal) {
+			if (s.Path != timedatePath && s.Path != localePath) || s.Name != dbusx.PropChangedSignal {
+				return
+			}
+			go changed()
+		}).
+		AddWatch(ctx)
+}
+
+// ZoneUpdater reports the system timezone and locale. They are updated when
+// timedated or localed report a change, and are also polled, as the timezone
+// can be changed without using timedated.
+func ZoneUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 2)
+
+	var mu sync.Mutex
+	var lastZone string
+	var lastLocale []string
+	sendZoneSensors := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		var sensors []tracker.Sensor
+		if zone, source := getTimezone(ctx); zone != "" && zone != lastZone {
+			lastZone = zone
+			sensors = append(sensors, newTimezoneSensor(zone, source))
+		}
+		if locale, source := getLocale(ctx); len(locale) > 0 && !slices.Equal(locale, lastLocale) {
+			lastLocale = locale
+			sensors = append(sensors, newLocaleSensor(locale, source))
+		}
+		for _, s := range sensors {
+			select {
+			case sensorCh <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	if err := watchZoneChanges(ctx, func() { sendZoneSensors(0) }); err != nil {
+		log.Debug().Err(err).Msg("Could not watch for timezone and locale changes. Will only poll for changes.")
+	}
+
+	go helpers.PollSensors(ctx, sendZoneSensors, zonePollInterval, time.Minute)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped timezone and locale sensors.")
+	}()
+	return sensorCh
+}
+
+// ZoneEventsUpdater fires an event in Home Assistant when the system timezone
+// changes, for example, when travelling with a laptop that sets its timezone
+// automatically.
+func ZoneEventsUpdater(ctx context.Context) chan *hass.Event {
+	eventCh := make(chan *hass.Event, 1)
+	deviceName := preferences.FetchFromContext(ctx).DeviceName
+
+	var mu sync.Mutex
+	lastZone, _ := getTimezone(ctx)
+	checkZone := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		zone, _ := getTimezone(ctx)
+		switch {
+		case zone == "" || zone == lastZone:
+			return
+		case lastZone == "":
+			lastZone = zone
+			return
+		}
+		event := &hass.Event{
+			EventType: TimezoneChangedEvent,
+			EventData: map[string]string{
+				"device_name":       deviceName,
+				"timezone":          zone,
+				"previous_timezone": lastZone,
+			},
+		}
+		lastZone = zone
+		select {
+		case eventCh <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	if err := watchZoneChanges(ctx, func() { checkZone(0) }); err != nil {
+		log.Debug().Err(err).Msg("Could not watch for timezone changes. Will only poll for changes.")
+	}
+
+	go helpers.PollSensors(ctx, checkZone, zonePollInterval, time.Minute)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(eventCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped timezone events.")
+	}()
+	return eventCh
+}
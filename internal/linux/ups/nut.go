@@ -0,0 +1,319 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package ups
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	defaultNUTServer = "localhost:3493"
+	nutTimeout       = 5 * time.Second
+	dataSrcNUT       = "NUT"
+
+	nutPollInterval = 30 * time.Second
+	// maxNUTRetryInterval is the longest time between attempts to connect to
+	// a NUT server that could not be reached. The interval doubles from the
+	// poll interval after each failed attempt, so that devices without a NUT
+	// server are not probed constantly.
+	maxNUTRetryInterval = 30 * time.Minute
+)
+
+var ErrNUTResponse = errors.New("unexpected response from NUT server")
+
+// nutClient is a minimal client for the Network UPS Tools (NUT) network
+// protocol. Only the commands needed to read UPS variables are implemented.
+type nutClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newNUTClient(ctx context.Context, server string) (*nutClient, error) {
+	d := net.Dialer{Timeout: nutTimeout}
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	return &nutClient{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (c *nutClient) Close() error {
+	fmt.Fprintln(c.conn, "LOGOUT")
+	return c.conn.Close()
+}
+
+// list sends a LIST command and returns the fields of each line of the
+// response, between the BEGIN and END lines.
+func (c *nutClient) list(query string) ([][]string, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(nutTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(c.conn, "LIST %s\n", query); err != nil {
+		return nil, err
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "ERR ") {
+		return nil, fmt.Errorf("%w: %s", ErrNUTResponse, strings.TrimPrefix(line, "ERR "))
+	}
+	if line != "BEGIN LIST "+query {
+		return nil, ErrNUTResponse
+	}
+	var lines [][]string
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "END LIST "+query {
+			return lines, nil
+		}
+		lines = append(lines, splitNUTLine(line))
+	}
+}
+
+// splitNUTLine splits a line of a NUT response into its fields. Fields may be
+// quoted, with backslash escapes.
+func splitNUTLine(line string) []string {
+	var fields []string
+	var b strings.Builder
+	var quoted, escaped, inField bool
+	for _, r := range line {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\' && quoted:
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case r == ' ' && !quoted:
+			if inField {
+				fields = append(fields, b.String())
+				b.Reset()
+				inField = false
+			}
+		default:
+			b.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// upsNames returns the names of the UPS devices known to the NUT server.
+func (c *nutClient) upsNames() ([]string, error) {
+	lines, err := c.list("UPS")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range lines {
+		if len(l) >= 2 && l[0] == "UPS" {
+			names = append(names, l[1])
+		}
+	}
+	return names, nil
+}
+
+// vars returns the variables of the given UPS.
+func (c *nutClient) vars(ups string) (map[string]string, error) {
+	lines, err := c.list("VAR " + ups)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	for _, l := range lines {
+		if len(l) >= 4 && l[0] == "VAR" {
+			vars[l[2]] = l[3]
+		}
+	}
+	return vars, nil
+}
+
+type upsSensor struct {
+	ups    string
+	model  string
+	status string
+	linux.Sensor
+}
+
+func (s *upsSensor) Name() string {
+	return s.ups + " " + s.SensorTypeValue.String()
+}
+
+func (s *upsSensor) ID() string {
+	return strcase.ToSnake(s.ups + "_" + s.SensorTypeValue.String())
+}
+
+func (s *upsSensor) Icon() string {
+	if s.SensorTypeValue == linux.SensorUPSOnBattery {
+		if v, ok := s.Value.(bool); ok && v {
+			return "mdi:power-plug-off"
+		}
+		return "mdi:power-plug"
+	}
+	return s.IconString
+}
+
+func (s *upsSensor) Attributes() any {
+	return struct {
+		Model      string `json:"Model,omitempty"`
+		Status     string `json:"Status,omitempty"`
+		DataSource string `json:"Data Source"`
+	}{
+		Model:      s.model,
+		Status:     s.status,
+		DataSource: dataSrcNUT,
+	}
+}
+
+// newUPSSensors creates sensors from the variables of a UPS. Sensors are only
+// created for the variables the UPS reports.
+func newUPSSensors(ups string, vars map[string]string) []tracker.Sensor {
+	base := upsSensor{
+		ups:    ups,
+		model:  strings.TrimSpace(vars["ups.mfr"] + " " + vars["ups.model"]),
+		status: vars["ups.status"],
+	}
+	var sensors []tracker.Sensor
+	if v, err := strconv.ParseFloat(vars["battery.charge"], 64); err == nil {
+		s := base
+		s.SensorTypeValue = linux.SensorUPSCharge
+		s.IconString = "mdi:battery"
+		s.UnitsString = "%"
+		s.DeviceClassValue = sensor.SensorBattery
+		s.StateClassValue = sensor.StateMeasurement
+		s.Value = v
+		sensors = append(sensors, &s)
+	}
+	if v, err := strconv.ParseFloat(vars["battery.runtime"], 64); err == nil {
+		s := base
+		s.SensorTypeValue = linux.SensorUPSRuntime
+		s.IconString = "mdi:battery-clock"
+		s.UnitsString = "s"
+		s.DeviceClassValue = sensor.Duration
+		s.StateClassValue = sensor.StateMeasurement
+		s.Value = v
+		sensors = append(sensors, &s)
+	}
+	if v, err := strconv.ParseFloat(vars["ups.load"], 64); err == nil {
+		s := base
+		s.SensorTypeValue = linux.SensorUPSLoad
+		s.IconString = "mdi:gauge"
+		s.UnitsString = "%"
+		s.StateClassValue = sensor.StateMeasurement
+		s.Value = v
+		sensors = append(sensors, &s)
+	}
+	if status, ok := vars["ups.status"]; ok {
+		s := base
+		s.SensorTypeValue = linux.SensorUPSOnBattery
+		s.IsBinary = true
+		// The status is a list of flags, OB meaning on battery.
+		s.Value = false
+		for _, flag := range strings.Fields(status) {
+			if flag == "OB" {
+				s.Value = true
+			}
+		}
+		sensors = append(sensors, &s)
+	}
+	return sensors
+}
+
+// Updater reports the charge, runtime remaining, load and on battery state of
+// any UPS devices managed by a Network UPS Tools (NUT) server. By default, a
+// NUT server on the local device is used. If no NUT server is available, no
+// sensors are reported until it becomes available, with connection attempts
+// backing off to every maxNUTRetryInterval.
+func Updater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor, 1)
+	server := preferences.FetchFromContext(ctx).UPSServer
+	if server == "" {
+		server = defaultNUTServer
+	}
+
+	var client *nutClient
+	var mu sync.Mutex
+	var nextAttempt time.Time
+	retryInterval := nutPollInterval
+	sendUPSSensors := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Retry connecting until connected, so that a NUT server that starts
+		// after the agent or is restarted is picked up.
+		if client == nil {
+			if time.Now().Before(nextAttempt) {
+				return
+			}
+			var err error
+			if client, err = newNUTClient(ctx, server); err != nil {
+				log.Debug().Err(err).Str("server", server).Dur("retry", retryInterval).
+					Msg("Could not connect to NUT server.")
+				nextAttempt = time.Now().Add(retryInterval)
+				retryInterval = min(2*retryInterval, maxNUTRetryInterval)
+				return
+			}
+			retryInterval = nutPollInterval
+		}
+		names, err := client.upsNames()
+		if err != nil {
+			log.Debug().Err(err).Msg("Could not list UPS devices.")
+			client.Close()
+			client = nil
+			return
+		}
+		for _, ups := range names {
+			vars, err := client.vars(ups)
+			if err != nil {
+				log.Debug().Err(err).Str("ups", ups).Msg("Could not retrieve UPS variables.")
+				continue
+			}
+			for _, s := range newUPSSensors(ups, vars) {
+				sensorCh <- s
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendUPSSensors, nutPollInterval, time.Second)
+	go func() {
+		defer close(sensorCh)
+		<-ctx.Done()
+		mu.Lock()
+		if client != nil {
+			client.Close()
+		}
+		mu.Unlock()
+		log.Debug().Msg("Stopped UPS sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package user
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	login1Dest        = "org.freedesktop.login1"
+	managerInterface  = "org.freedesktop.login1.Manager"
+	sessionInterface  = "org.freedesktop.login1.Session"
+	sessionPathPrefix = login1DBusPath + "/session/"
+
+	// SessionNewEvent is fired on the Home Assistant event bus when a user
+	// logs in.
+	SessionNewEvent = "go_hass_agent_session_new"
+	// SessionRemovedEvent is fired on the Home Assistant event bus when a
+	// user logs out.
+	SessionRemovedEvent = "go_hass_agent_session_removed"
+	// SessionActiveEvent is fired on the Home Assistant event bus when a
+	// session becomes the active (foreground) session, such as when switching
+	// users or unlocking.
+	SessionActiveEvent = "go_hass_agent_session_active"
+	// SessionInactiveEvent is fired on the Home Assistant event bus when a
+	// session is no longer the active session.
+	SessionInactiveEvent = "go_hass_agent_session_inactive"
+)
+
+// session contains the details of a logind user session that are sent with
+// session events.
+type session struct {
+	ID     string `json:"session_id"`
+	User   string `json:"user"`
+	Type   string `json:"session_type"`
+	Remote bool   `json:"remote"`
+	active bool
+}
+
+// getSession retrieves the details of the logind session at the given path.
+// Only user sessions (i.e., not greeter or other system sessions) are
+// returned.
+func getSession(ctx context.Context, path dbus.ObjectPath) (*session, bool) {
+	req := dbusx.NewBusRequest(ctx, dbusx.SystemBus).Path(path).Destination(login1Dest)
+	class, err := req.GetProp(sessionInterface + ".Class")
+	if err != nil || dbusx.VariantToValue[string](class) != "user" {
+		return nil, false
+	}
+	s := &session{}
+	if v, err := req.GetProp(sessionInterface + ".Id"); err == nil {
+		s.ID = dbusx.VariantToValue[string](v)
+	}
+	if v, err := req.GetProp(sessionInterface + ".Name"); err == nil {
+		s.User = dbusx.VariantToValue[string](v)
+	}
+	if v, err := req.GetProp(sessionInterface + ".Type"); err == nil {
+		s.Type = dbusx.VariantToValue[string](v)
+	}
+	if v, err := req.GetProp(sessionInterface + ".Remote"); err == nil {
+		s.Remote = dbusx.VariantToValue[bool](v)
+	}
+	if v, err := req.GetProp(sessionInterface + ".Active"); err == nil {
+		s.active = dbusx.VariantToValue[bool](v)
+	}
+	return s, true
+}
+
+func newSessionEvent(eventType, deviceName string, s *session) *hass.Event {
+	return &hass.Event{
+		EventType: eventType,
+		EventData: struct {
+			*session
+			DeviceName string `json:"device_name"`
+		}{
+			session:    s,
+			DeviceName: deviceName,
+		},
+	}
+}
+
+// SessionEventsUpdater fires events in Home Assistant when users log in or out
+// of the device, and when a user session becomes active or inactive (e.g., when
+// switching users).
+func SessionEventsUpdater(ctx context.Context) chan *hass.Event {
+	eventCh := make(chan *hass.Event, 1)
+	deviceName := preferences.FetchFromContext(ctx).DeviceName
+
+	// Track existing sessions, so that the details of a session are known when
+	// it is removed and active changes can be detected.
+	sessions := make(map[dbus.ObjectPath]*session)
+	if list, ok := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Path(login1DBusPath).
+		Destination(login1Dest).
+		GetData(managerInterface + ".ListSessions").
+		AsRawInterface().([][]any); ok {
+		for _, s := range list {
+			if len(s) < 5 {
+				continue
+			}
+			if path, ok := s[4].(dbus.ObjectPath); ok {
+				if info, ok := getSession(ctx, path); ok {
+					sessions[path] = info
+				}
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	send := func(e *hass.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case eventCh <- e:
+		case <-ctx.Done():
+		}
+	}
+
+	err := dbusx.NewBusRequest(ctx, dbusx.SystemBus).
+		Match([]dbus.MatchOption{
+			dbus.WithMatchPathNamespace(login1DBusPath),
+		}).
+		Handler(func(s *dbus.Signal) {
+			switch {
+			case s.Path == login1DBusPath && s.Name == managerInterface+".SessionNew" && len(s.Body) > 1:
+				path, ok := s.Body[1].(dbus.ObjectPath)
+				if !ok {
+					return
+				}
+				info, ok := getSession(ctx, path)
+				if !ok {
+					return
+				}
+				sessions[path] = info
+				send(newSessionEvent(SessionNewEvent, deviceName, info))
+			case s.Path == login1DBusPath && s.Name == managerInterface+".SessionRemoved" && len(s.Body) > 1:
+				path, ok := s.Body[1].(dbus.ObjectPath)
+				if !ok {
+					return
+				}
+				info, ok := sessions[path]
+				if !ok {
+					return
+				}
+				delete(sessions, path)
+				send(newSessionEvent(SessionRemovedEvent, deviceName, info))
+			case strings.HasPrefix(string(s.Path), sessionPathPrefix) && s.Name == dbusx.PropChangedSignal && len(s.Body) > 1:
+				info, ok := sessions[s.Path]
+				if !ok {
+					return
+				}
+				props, ok := s.Body[1].(map[string]dbus.Variant)
+				if !ok {
+					return
+				}
+				v, ok := props["Active"]
+				if !ok {
+					return
+				}
+				active := dbusx.VariantToValue[bool](v)
+				if active == info.active {
+					return
+				}
+				info.active = active
+				if active {
+					send(newSessionEvent(SessionActiveEvent, deviceName, info))
+				} else {
+					send(newSessionEvent(SessionInactiveEvent, deviceName, info))
+				}
+			}
+		}).
+		AddWatch(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch for user sessions. Session events will not be sent.")
+		close(eventCh)
+		return eventCh
+	}
+
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(eventCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped session events.")
+	}()
+	return eventCh
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package webcam captures still images from a V4L2 webcam, using ffmpeg or
+// fswebcam. The webcam is only opened while an image is being captured, so its
+// indicator LED (if any) is only lit while capturing.
+package webcam
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// captureTimeout is how long to wait for an image. Some webcams take a few
+// seconds to start.
+const captureTimeout = 15 * time.Second
+
+var (
+	// ErrNoCaptureTool is returned when neither ffmpeg nor fswebcam is
+	// available.
+	ErrNoCaptureTool = errors.New("no ffmpeg or fswebcam command found")
+
+	errNoImage = errors.New("no image captured")
+)
+
+// Webcam captures still images from a webcam.
+type Webcam struct {
+	device   string
+	ffmpeg   string
+	fswebcam string
+}
+
+// New returns a webcam for the given V4L2 device (e.g., /dev/video0),
+// preferring ffmpeg over fswebcam for capturing. It returns ErrNoCaptureTool
+// if neither is available.
+func New(device string) (*Webcam, error) {
+	if _, err := os.Stat(device); err != nil {
+		return nil, err
+	}
+	w := &Webcam{device: device}
+	if path, err := exec.LookPath("ffmpeg"); err == nil {
+		w.ffmpeg = path
+	} else if path, err := exec.LookPath("fswebcam"); err == nil {
+		w.fswebcam = path
+	} else {
+		return nil, ErrNoCaptureTool
+	}
+	return w, nil
+}
+
+// Capture captures a still image (JPEG) from the webcam.
+func (w *Webcam) Capture(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if w.ffmpeg != "" {
+		cmd = exec.CommandContext(ctx, w.ffmpeg,
+			"-hide_banner", "-loglevel", "error",
+			"-f", "v4l2", "-i", w.device,
+			"-frames:v", "1", "-f", "image2", "-c:v", "mjpeg", "-")
+	} else {
+		cmd = exec.CommandContext(ctx, w.fswebcam,
+			"--quiet", "--no-banner", "--device", w.device, "--jpeg", "90", "-")
+	}
+	img, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	if len(img) == 0 {
+		return nil, errNoImage
+	}
+	return img, nil
+}
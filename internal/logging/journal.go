@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// journalSocket is the socket for the native systemd journal protocol.
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalWriter is a zerolog.LevelWriter that sends log entries to the systemd
+// journal using its native protocol. Each field of the entry is sent as a
+// journal field, with the level mapped to the journal priority.
+type journalWriter struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+func newJournalWriter(identifier string) (*journalWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalWriter{conn: conn, identifier: identifier}, nil
+}
+
+func (w *journalWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *journalWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var entry map[string]any
+	if err := json.Unmarshal(p, &entry); err != nil {
+		entry = map[string]any{zerolog.MessageFieldName: string(p)}
+	}
+	var msg bytes.Buffer
+	writeJournalField(&msg, "PRIORITY", fmt.Sprint(journalPriority(level)))
+	writeJournalField(&msg, "SYSLOG_IDENTIFIER", w.identifier)
+	for k, v := range entry {
+		var value string
+		switch k {
+		case zerolog.MessageFieldName:
+			k = "MESSAGE"
+		case zerolog.LevelFieldName, zerolog.TimestampFieldName:
+			continue
+		}
+		if s, ok := v.(string); ok {
+			value = s
+		} else {
+			b, _ := json.Marshal(v)
+			value = string(b)
+		}
+		writeJournalField(&msg, journalFieldName(k), value)
+	}
+	if _, err := w.conn.Write(msg.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// journalPriority maps a log level to a syslog/journal priority.
+func journalPriority(level zerolog.Level) int {
+	switch level {
+	case zerolog.PanicLevel:
+		return 0
+	case zerolog.FatalLevel:
+		return 2
+	case zerolog.ErrorLevel:
+		return 3
+	case zerolog.WarnLevel:
+		return 4
+	case zerolog.InfoLevel, zerolog.NoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// journalFieldName converts a log field name to a valid journal field name,
+// which must be uppercase letters, digits and underscores and not start with
+// an underscore or digit.
+func journalFieldName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	name = strings.TrimLeft(name, "_0123456789")
+	if name == "" {
+		return "FIELD"
+	}
+	return name
+}
+
+// writeJournalField writes a field in the journal native format. Values
+// containing newlines must be written with their length instead.
+func writeJournalField(b *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(b, "%s=%s\n", name, value)
+		return
+	}
+	b.WriteString(name + "\n")
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(value)))
+	b.Write(size)
+	b.WriteString(value + "\n")
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalFieldName(t *testing.T) {
+	assert.Equal(t, "MESSAGE", journalFieldName("message"))
+	assert.Equal(t, "SENSOR_ID", journalFieldName("sensor-id"))
+	assert.Equal(t, "CALLER", journalFieldName("_caller"))
+	assert.Equal(t, "FIELD", journalFieldName("123"))
+}
@@ -12,15 +12,16 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/adrg/xdg"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/joshuar/go-hass-agent/internal/paths"
 )
 
 func init() {
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	log.Logger = log.Output(zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stderr}, sinks))
 }
 
 func setProfiling() {
@@ -54,17 +55,26 @@ func SetLoggingLevel(trace, debug, profile bool) {
 	}
 }
 
+// LogFile returns the path to the agent log file, in the data directory.
+func LogFile() string {
+	return filepath.Join(paths.DataDir(), "go-hass-app.log")
+}
+
 // SetLogFile will attempt to create and then write logging to a file. If it
-// cannot do this, logging will only be available on stdout.
+// cannot do this, logging will only be available on stdout. The log file is
+// rotated when it grows too large. Entries in the file include the source file
+// of the log call so that they can be filtered by module, but this is not shown
+// on the console.
 func SetLogFile() {
-	logFile := filepath.Join(xdg.StateHome, "go-hass-app.log")
-	logWriter, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	logWriter, err := newRotatingFile(LogFile(), maxLogSize, maxLogBackups)
 	if err != nil {
 		log.Error().Err(err).
 			Msg("Unable to open log file for writing.")
 	} else {
-		consoleWriter := zerolog.ConsoleWriter{Out: os.Stdout}
-		multiWriter := zerolog.MultiLevelWriter(consoleWriter, logWriter)
-		log.Logger = log.Output(multiWriter)
+		consoleWriter := zerolog.ConsoleWriter{
+			Out:          os.Stdout,
+			PartsExclude: []string{zerolog.CallerFieldName},
+		}
+		log.Logger = log.Output(zerolog.MultiLevelWriter(consoleWriter, logWriter, sinks)).With().Caller().Logger()
 	}
 }
@@ -0,0 +1,210 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// followInterval is how often the log file is checked for new entries when
+// following it.
+const followInterval = 500 * time.Millisecond
+
+// Filter selects entries from the agent log file. Entries logged below Level
+// are excluded. If Level is nil, entries of any level are included, so that the
+// zero value of Filter matches everything.
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	Level  *zerolog.Level
+	Module string
+}
+
+type logEntry struct {
+	Time   time.Time `json:"time"`
+	Level  string    `json:"level"`
+	Caller string    `json:"caller"`
+}
+
+func (f *Filter) isZero() bool {
+	return f.Since.IsZero() && f.Until.IsZero() && f.Module == "" && (f.Level == nil || *f.Level <= zerolog.TraceLevel)
+}
+
+// Match returns whether the given log file line matches the filter. Lines that
+// are not valid log entries only match an empty filter.
+func (f *Filter) Match(line []byte) bool {
+	var e logEntry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return f.isZero()
+	}
+	if e.Level != "" && f.Level != nil {
+		if level, err := zerolog.ParseLevel(e.Level); err == nil && level < *f.Level {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Module != "" && !strings.Contains(e.Caller, f.Module) {
+		return false
+	}
+	return true
+}
+
+// ParseTime parses a time for a log filter. It can either be an RFC3339
+// timestamp or a duration, which is treated as that long before now.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is not a duration or RFC3339 timestamp", s)
+	}
+	return t, nil
+}
+
+// PrintLogs writes the entries in the agent log file that match the filter to
+// the given writer. Entries in the rotated log files are written first, oldest
+// first. If follow is true, it will continue to write any new entries until the
+// context is canceled, re-opening the log file if it is rotated.
+func PrintLogs(ctx context.Context, w io.Writer, filter *Filter, follow bool) error {
+	return printLogs(ctx, w, LogFile(), filter, follow)
+}
+
+func printLogs(ctx context.Context, w io.Writer, path string, filter *Filter, follow bool) error {
+	console := zerolog.ConsoleWriter{Out: w}
+	for n := maxLogBackups; n > 0; n-- {
+		if err := printRotated(console, w, backupName(path, n), filter); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+	}()
+
+	reader := bufio.NewReader(file)
+	var partial []byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		switch {
+		case err == nil:
+			line = append(partial, line...)
+			partial = nil
+			printEntry(console, w, filter, line)
+			continue
+		case !errors.Is(err, io.EOF):
+			return err
+		}
+		// Reached the end of the file.
+		if !follow {
+			if len(line) > 0 {
+				printEntry(console, w, filter, append(partial, line...))
+			}
+			return nil
+		}
+		partial = append(partial, line...)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(followInterval):
+		}
+		if rotated(file, path) {
+			newFile, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			// Print whatever was written to the old file before it was
+			// rotated. A partial line left at the end will not be
+			// completed in the new file, so it is dropped.
+			rest, _ := io.ReadAll(reader)
+			lines := bytes.Split(append(partial, rest...), []byte{'\n'})
+			for _, l := range lines[:len(lines)-1] {
+				printEntry(console, w, filter, l)
+			}
+			partial = nil
+			file.Close()
+			file = newFile
+			reader.Reset(file)
+		}
+	}
+}
+
+// printRotated writes the entries in a rotated log file that match the filter.
+// Missing files, and files last written before the start of the filter, are
+// skipped.
+func printRotated(console zerolog.ConsoleWriter, w io.Writer, path string, filter *Filter) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.ModTime().Before(filter.Since)) {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		printEntry(console, w, filter, line)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// printEntry writes a log file line if it matches the filter, formatted as it
+// would be on the console. Lines that are not log entries are written as-is.
+func printEntry(console zerolog.ConsoleWriter, w io.Writer, filter *Filter, line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || !filter.Match(line) {
+		return
+	}
+	if _, err := console.Write(line); err != nil {
+		fmt.Fprintf(w, "%s\n", line)
+	}
+}
+
+// rotated returns whether the file at path is no longer the given open file,
+// or has been truncated.
+func rotated(file *os.File, path string) bool {
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	open, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	if !os.SameFile(open, current) {
+		return true
+	}
+	offset, err := file.Seek(0, io.SeekCurrent)
+	return err == nil && current.Size() < offset
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	line := []byte(`{"level":"warn","caller":"/src/internal/linux/battery/battery.go:120","time":"2024-03-01T10:00:00Z","message":"test"}`)
+	entryTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		line   []byte
+		want   bool
+	}{
+		{name: "all", filter: Filter{Level: levelPtr(zerolog.TraceLevel)}, line: line, want: true},
+		{name: "zero value includes trace", filter: Filter{}, line: []byte(`{"level":"trace","time":"2024-03-01T10:00:00Z","message":"test"}`), want: true},
+		{name: "level below", filter: Filter{Level: levelPtr(zerolog.InfoLevel)}, line: line, want: true},
+		{name: "level above", filter: Filter{Level: levelPtr(zerolog.ErrorLevel)}, line: line, want: false},
+		{name: "module match", filter: Filter{Module: "linux/battery"}, line: line, want: true},
+		{name: "module mismatch", filter: Filter{Module: "linux/net"}, line: line, want: false},
+		{name: "since before", filter: Filter{Since: entryTime.Add(-time.Minute)}, line: line, want: true},
+		{name: "since after", filter: Filter{Since: entryTime.Add(time.Minute)}, line: line, want: false},
+		{name: "until before", filter: Filter{Until: entryTime.Add(-time.Minute)}, line: line, want: false},
+		{name: "not an entry", filter: Filter{Level: levelPtr(zerolog.TraceLevel)}, line: []byte("panic: oops"), want: true},
+		{name: "not an entry filtered", filter: Filter{Level: levelPtr(zerolog.WarnLevel)}, line: []byte("panic: oops"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(tt.line))
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	got, err := ParseTime("1h", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), got)
+	got, err = ParseTime("2024-02-01T00:00:00Z", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), got)
+	_, err = ParseTime("yesterday", now)
+	assert.Error(t, err)
+}
+
+func TestPrintLogs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	files := map[string]string{
+		backupName(path, 2): `{"level":"info","time":"2024-03-01T10:00:00Z","message":"oldest"}` + "\n",
+		backupName(path, 1): `{"level":"info","time":"2024-03-01T11:00:00Z","message":"older"}` + "\n",
+		path:                `{"level":"info","time":"2024-03-01T12:00:00Z","message":"newest"}` + "\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, printLogs(context.TODO(), &out, path, &Filter{}, false))
+	oldest := bytes.Index(out.Bytes(), []byte("oldest"))
+	older := bytes.Index(out.Bytes(), []byte("older"))
+	newest := bytes.Index(out.Bytes(), []byte("newest"))
+	assert.True(t, oldest >= 0 && oldest < older && older < newest, out.String())
+
+	out.Reset()
+	since := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	assert.NoError(t, printLogs(context.TODO(), &out, path, &Filter{Since: since}, false))
+	assert.NotContains(t, out.String(), "oldest")
+	assert.Contains(t, out.String(), "older")
+}
+
+func levelPtr(l zerolog.Level) *zerolog.Level {
+	return &l
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	// maxLogSize is the size in bytes the log file can reach before it is
+	// rotated.
+	maxLogSize = 10 * 1024 * 1024
+	// maxLogBackups is the number of rotated log files that are kept.
+	maxLogBackups = 3
+)
+
+// rotatingFile is an io.Writer that writes to a file, rotating it once it
+// grows larger than maxSize. Rotated files have a numeric suffix, with .1
+// being the most recent, and only the given number of backups are kept.
+type rotatingFile struct {
+	file    *os.File
+	path    string
+	mu      sync.Mutex
+	size    int64
+	maxSize int64
+	backups int
+}
+
+func newRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:    path,
+		maxSize: maxSize,
+		backups: backups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate closes the current file, shifts any existing backups along by one,
+// removing the oldest, then opens a new file. A new file is always opened, even
+// if moving the backups failed, so that logging can continue.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	var err error
+	for i := r.backups; i > 0 && err == nil; i-- {
+		src := r.path
+		if i > 1 {
+			src = backupName(r.path, i-1)
+		}
+		if _, statErr := os.Stat(src); statErr != nil {
+			continue
+		}
+		err = os.Rename(src, backupName(r.path, i))
+	}
+	if r.backups == 0 {
+		if rmErr := os.Remove(r.path); rmErr != nil && !os.IsNotExist(rmErr) {
+			err = rmErr
+		}
+	}
+	if openErr := r.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		// The logger itself cannot be used to report a failure to rotate, so
+		// report on stderr and keep writing.
+		if err := r.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "could not rotate log file: %v\n", err)
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	r, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	read := func(p string) string {
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+		return strings.TrimSpace(string(b))
+	}
+	assert.Equal(t, "dddddddd", read(path))
+	assert.Equal(t, "cccccccc", read(backupName(path, 1)))
+	assert.Equal(t, "bbbbbbbb", read(backupName(path, 2)))
+	assert.NoFileExists(t, backupName(path, 3))
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/url"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrUnsupportedSyslog is returned when the syslog server address uses a
+// protocol other than udp, tcp or unix.
+var ErrUnsupportedSyslog = errors.New("unsupported syslog protocol")
+
+// newSyslogWriter connects to the syslog server at the given address, which is
+// given as a URL with the protocol as the scheme (e.g., udp://host:514,
+// tcp://host:514 or unix:///dev/log).
+func newSyslogWriter(server, identifier string) (*syslog.Writer, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	var addr string
+	switch u.Scheme {
+	case "udp", "tcp":
+		addr = u.Host
+	case "unix", "unixgram":
+		addr = u.Path
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSyslog, u.Scheme)
+	}
+	return syslog.Dial(u.Scheme, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, identifier)
+}
+
+// sinks are the additional writers set by SetSinks. The logger always writes
+// to sinks, so that they can be replaced without swapping the global logger
+// while other goroutines are logging.
+var sinks = &sinkWriter{}
+
+// sinkWriter sends logging to the sinks it currently holds. The sinks can be
+// swapped at any time.
+type sinkWriter struct {
+	writer  zerolog.LevelWriter
+	closers []io.Closer
+	mu      sync.RWMutex
+}
+
+func (s *sinkWriter) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (s *sinkWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.writer == nil {
+		return len(p), nil
+	}
+	return s.writer.WriteLevel(level, p)
+}
+
+// set replaces the sinks and closes the connections of the previous ones.
+func (s *sinkWriter) set(writers []io.Writer, closers []io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(writers) > 0 {
+		s.writer = zerolog.MultiLevelWriter(writers...)
+	} else {
+		s.writer = nil
+	}
+	for _, c := range s.closers {
+		c.Close()
+	}
+	s.closers = closers
+}
+
+// SetSinks will additionally send logging to the systemd journal and/or a
+// (remote) syslog server, as requested. Any sinks previously set are replaced,
+// so that it can be called again when the preferences change. Any sinks that
+// could not be set up are skipped and the errors are returned.
+func SetSinks(identifier string, journal bool, syslogServer string) error {
+	var writers []io.Writer
+	var closers []io.Closer
+	var errs error
+	if journal {
+		if w, err := newJournalWriter(identifier); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("could not connect to journal: %w", err))
+		} else {
+			writers = append(writers, w)
+			closers = append(closers, w.conn)
+		}
+	}
+	if syslogServer != "" {
+		if w, err := newSyslogWriter(syslogServer, identifier); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("could not connect to syslog: %w", err))
+		} else {
+			writers = append(writers, zerolog.SyslogLevelWriter(w))
+			closers = append(closers, w)
+		}
+	}
+	sinks.set(writers, closers)
+	return errs
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package paths provides the directories used by the agent. Configuration
+// (preferences and scripts) is kept separate from mutable state (the sensor
+// registry and logs) and cached data, following the XDG base directory
+// specification. Each directory can be overridden, for example, on immutable
+// distributions where only some locations are persisted.
+package paths
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/adrg/xdg"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ConfigDirEnv is the environment variable to override the configuration
+	// directory.
+	ConfigDirEnv = "GOHASSAGENT_CONFIG_DIR"
+	// DataDirEnv is the environment variable to override the data (state)
+	// directory.
+	DataDirEnv = "GOHASSAGENT_DATA_DIR"
+	// CacheDirEnv is the environment variable to override the cache
+	// directory.
+	CacheDirEnv = "GOHASSAGENT_CACHE_DIR"
+
+	defaultAppID = "go-hass-agent"
+	registryDir  = "sensorRegistry"
+	simulatorDir = "simulator"
+	lockFile     = "agent.lock"
+)
+
+// Dirs holds the directories used by the agent.
+type Dirs struct {
+	// Config holds the preferences and scripts.
+	Config string
+	// Data holds mutable state, such as the sensor registry and log file.
+	Data string
+	// Cache holds data that can be safely deleted.
+	Cache string
+}
+
+var (
+	dirs   = resolve(defaultAppID, Dirs{})
+	dirsMu sync.Mutex
+)
+
+// resolve returns the directories to use for the given app ID. Directories set
+// in d take precedence, followed by those set in the environment, with the XDG
+// base directories as a default.
+func resolve(appID string, d Dirs) Dirs {
+	firstOf := func(dirs ...string) string {
+		for _, dir := range dirs {
+			if dir != "" {
+				return dir
+			}
+		}
+		return ""
+	}
+	return Dirs{
+		Config: firstOf(d.Config, os.Getenv(ConfigDirEnv), filepath.Join(xdg.ConfigHome, appID)),
+		Data:   firstOf(d.Data, os.Getenv(DataDirEnv), filepath.Join(xdg.StateHome, appID)),
+		Cache:  firstOf(d.Cache, os.Getenv(CacheDirEnv), filepath.Join(xdg.CacheHome, appID)),
+	}
+}
+
+// Setup sets the directories used by the agent with the given app ID. Any
+// directory not set in d is taken from its environment variable, if set, or
+// the XDG base directory otherwise. Sensor registries in the location used by
+// older versions of the agent, including those of simulated devices, are moved
+// to the data directory.
+func Setup(appID string, d Dirs) {
+	dirsMu.Lock()
+	dirs = resolve(appID, d)
+	dirsMu.Unlock()
+	legacyDir := filepath.Join(os.Getenv("HOME"), ".config", appID)
+	registries := map[string]string{
+		filepath.Join(legacyDir, registryDir): RegistryDir(),
+	}
+	devices, _ := os.ReadDir(filepath.Join(legacyDir, simulatorDir))
+	for _, device := range devices {
+		if device.IsDir() {
+			registries[filepath.Join(legacyDir, simulatorDir, device.Name(), registryDir)] = SimulatorRegistryDir(device.Name())
+		}
+	}
+	for legacy, registry := range registries {
+		if err := migrate(legacy, registry); err != nil {
+			log.Warn().Err(err).Str("path", legacy).Msg("Could not move sensor registry to the data directory.")
+		}
+	}
+}
+
+// migrate moves the directory at from to to, if from exists and to does not.
+func migrate(from, to string) error {
+	if from == to {
+		return nil
+	}
+	if _, err := os.Stat(to); !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if _, err := os.Stat(from); err != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	log.Info().Str("from", from).Str("to", to).Msg("Moved sensor registry to the data directory.")
+	return nil
+}
+
+// ConfigDir returns the directory for the preferences and scripts.
+func ConfigDir() string {
+	dirsMu.Lock()
+	defer dirsMu.Unlock()
+	return dirs.Config
+}
+
+// DataDir returns the directory for mutable state, such as the sensor registry
+// and log file.
+func DataDir() string {
+	dirsMu.Lock()
+	defer dirsMu.Unlock()
+	return dirs.Data
+}
+
+// CacheDir returns the directory for data that can be safely deleted.
+func CacheDir() string {
+	dirsMu.Lock()
+	defer dirsMu.Unlock()
+	return dirs.Cache
+}
+
+// RegistryDir returns the directory of the sensor registry.
+func RegistryDir() string {
+	return filepath.Join(DataDir(), registryDir)
+}
+
+// SimulatorRegistryDir returns the directory of the sensor registry of the
+// given simulated device.
+func SimulatorRegistryDir(device string) string {
+	return filepath.Join(DataDir(), simulatorDir, device, registryDir)
+}
+
+// LockFile returns the file used to ensure only one instance of the agent runs
+// with the same data directory.
+func LockFile() string {
+	return filepath.Join(DataDir(), lockFile)
+}
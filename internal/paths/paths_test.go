@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_resolve(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		dirs Dirs
+		want Dirs
+		name string
+	}{
+		{
+			name: "defaults",
+			want: Dirs{
+				Config: filepath.Join(xdg.ConfigHome, "test"),
+				Data:   filepath.Join(xdg.StateHome, "test"),
+				Cache:  filepath.Join(xdg.CacheHome, "test"),
+			},
+		},
+		{
+			name: "environment",
+			env:  map[string]string{ConfigDirEnv: "/etc/agent", DataDirEnv: "/var/lib/agent"},
+			want: Dirs{
+				Config: "/etc/agent",
+				Data:   "/var/lib/agent",
+				Cache:  filepath.Join(xdg.CacheHome, "test"),
+			},
+		},
+		{
+			name: "options take precedence",
+			env:  map[string]string{ConfigDirEnv: "/etc/agent", DataDirEnv: "/var/lib/agent"},
+			dirs: Dirs{Config: "/persist/config", Cache: "/tmp/cache"},
+			want: Dirs{
+				Config: "/persist/config",
+				Data:   "/var/lib/agent",
+				Cache:  "/tmp/cache",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{ConfigDirEnv, DataDirEnv, CacheDirEnv} {
+				t.Setenv(env, tt.env[env])
+			}
+			assert.Equal(t, tt.want, resolve("test", tt.dirs))
+		})
+	}
+}
+
+func Test_migrate(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "config", "sensorRegistry")
+	to := filepath.Join(dir, "state", "sensorRegistry")
+	assert.Nil(t, os.MkdirAll(from, 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(from, "sensor.json"), []byte("{}"), 0o600))
+
+	// Moved when the new location does not exist.
+	assert.Nil(t, migrate(from, to))
+	assert.NoDirExists(t, from)
+	assert.FileExists(t, filepath.Join(to, "sensor.json"))
+
+	// Not moved when the new location already exists.
+	assert.Nil(t, os.MkdirAll(from, 0o755))
+	assert.Nil(t, migrate(from, to))
+	assert.DirExists(t, from)
+
+	// Nothing to move.
+	assert.Nil(t, migrate(filepath.Join(dir, "missing"), filepath.Join(dir, "other")))
+	assert.NoDirExists(t, filepath.Join(dir, "other"))
+}
+
+func TestSetup_migrateSimulators(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	legacy := filepath.Join(home, ".config", "test", "simulator", "device_1", "sensorRegistry")
+	assert.Nil(t, os.MkdirAll(legacy, 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(legacy, "sensor.json"), []byte("{}"), 0o600))
+
+	data := t.TempDir()
+	Setup("test", Dirs{Config: t.TempDir(), Data: data, Cache: t.TempDir()})
+	t.Cleanup(func() { Setup(defaultAppID, Dirs{}) })
+
+	assert.NoDirExists(t, legacy)
+	assert.Equal(t, filepath.Join(data, "simulator", "device_1", "sensorRegistry"), SimulatorRegistryDir("device_1"))
+	assert.FileExists(t, filepath.Join(SimulatorRegistryDir("device_1"), "sensor.json"))
+}
@@ -6,7 +6,9 @@
 package preferences
 
 import (
+	"crypto/rand"
 	_ "embed"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -14,6 +16,8 @@ import (
 	"github.com/adrg/xdg"
 	"github.com/pelletier/go-toml/v2"
 	"golang.org/x/sync/errgroup"
+
+	"github.com/joshuar/go-hass-agent/internal/filelock"
 )
 
 var (
@@ -28,27 +32,94 @@ var AppVersion string
 var (
 	preferencesPath = filepath.Join(xdg.ConfigHome, "go-hass-agent")
 	preferencesFile = "preferences.toml"
+	// saveMu serialises saves within the agent (e.g., from the settings form
+	// and a worker). A lock file serialises saves between processes.
+	saveMu sync.Mutex
 )
 
+// Preferences holds the agent configuration. Fields tagged with diag:"redact"
+// contain secrets or identifying details and are masked in diagnostics reports.
 type Preferences struct {
 	mu             *sync.Mutex
-	Version        string `toml:"agent.version" validate:"required"`
-	Host           string `toml:"registration.host" validate:"required,http_url"`
-	Token          string `toml:"registration.token" validate:"required,ascii"`
-	DeviceID       string `toml:"device.id" validate:"required,ascii"`
-	DeviceName     string `toml:"device.name" validate:"required,hostname"`
-	RestAPIURL     string `toml:"hass.apiurl,omitempty" validate:"http_url,required_without=CloudhookURL RemoteUIURL"`
-	CloudhookURL   string `toml:"hass.cloudhookurl,omitempty" validate:"omitempty,http_url"`
-	WebsocketURL   string `toml:"hass.websocketurl" validate:"required,url"`
-	WebhookID      string `toml:"hass.webhookid" validate:"required,ascii"`
-	RemoteUIURL    string `toml:"hass.remoteuiurl,omitempty" validate:"omitempty,http_url"`
-	Secret         string `toml:"hass.secret,omitempty" validate:"omitempty"`
-	MQTTPassword   string `toml:"mqtt.password,omitempty" validate:"omitempty"`
-	MQTTUser       string `toml:"mqtt.user,omitempty" validate:"omitempty"`
-	MQTTServer     string `toml:"mqtt.server,omitempty" validate:"omitempty,uri"`
-	Registered     bool   `toml:"hass.registered" validate:"boolean"`
-	MQTTEnabled    bool   `toml:"mqtt.enabled" validate:"boolean"`
-	MQTTRegistered bool   `toml:"mqtt.registered" validate:"boolean"`
+	Version        string            `toml:"agent.version" validate:"required"`
+	Host           string            `toml:"registration.host" validate:"required,http_url" diag:"redact"`
+	Token          string            `toml:"registration.token" validate:"required,ascii" diag:"redact"`
+	DeviceID       string            `toml:"device.id" validate:"required,ascii"`
+	DeviceName     string            `toml:"device.name" validate:"required,hostname"`
+	RestAPIURL     string            `toml:"hass.apiurl,omitempty" validate:"http_url,required_without=CloudhookURL RemoteUIURL" diag:"redact"`
+	CloudhookURL   string            `toml:"hass.cloudhookurl,omitempty" validate:"omitempty,http_url" diag:"redact"`
+	WebsocketURL   string            `toml:"hass.websocketurl" validate:"required,url" diag:"redact"`
+	WebhookID      string            `toml:"hass.webhookid" validate:"required,ascii" diag:"redact"`
+	RemoteUIURL    string            `toml:"hass.remoteuiurl,omitempty" validate:"omitempty,http_url" diag:"redact"`
+	Secret         string            `toml:"hass.secret,omitempty" validate:"omitempty" diag:"redact"`
+	MQTTPassword   string            `toml:"mqtt.password,omitempty" validate:"omitempty" diag:"redact"`
+	MQTTUser       string            `toml:"mqtt.user,omitempty" validate:"omitempty" diag:"redact"`
+	MQTTServer     string            `toml:"mqtt.server,omitempty" validate:"omitempty,uri" diag:"redact"`
+	TTSModel       string            `toml:"mqtt.ttsmodel,omitempty" validate:"omitempty,filepath"`
+	Webcam         string            `toml:"mqtt.webcam,omitempty" validate:"omitempty,filepath"`
+	InstallID      string            `toml:"agent.installid,omitempty" validate:"omitempty,uuid4" diag:"redact"`
+	TelemetryURL   string            `toml:"agent.telemetryurl,omitempty" validate:"omitempty,http_url" diag:"redact"`
+	SummaryCron    string            `toml:"summary.schedule,omitempty" validate:"omitempty,cron"`
+	Language       string            `toml:"agent.language,omitempty" validate:"omitempty,bcp47_language_tag"`
+	SummaryTarget  string            `toml:"summary.target,omitempty" validate:"omitempty,oneof=notification mqtt"`
+	UPSServer      string            `toml:"sensors.upsserver,omitempty" validate:"omitempty,host_port"`
+	LogSyslog      string            `toml:"logging.syslog,omitempty" validate:"omitempty,uri" diag:"redact"`
+	UserAgent      string            `toml:"agent.useragent,omitempty" validate:"omitempty,printascii"`
+	PreferIP       string            `toml:"agent.preferip,omitempty" validate:"omitempty,oneof=ipv4 ipv6"`
+	UpdateChannel  string            `toml:"agent.updatechannel,omitempty" validate:"omitempty,oneof=stable beta"`
+	HTTPHeaders    map[string]string `toml:"hass.headers,omitempty" validate:"omitempty,dive,keys,required,printascii,endkeys,printascii" diag:"redact"`
+	Features       map[string]bool   `toml:"agent.features,omitempty" validate:"omitempty,dive,keys,required,printascii,endkeys"`
+	BackupUnits    []string          `toml:"sensors.backupunits,omitempty" validate:"omitempty,dive,required,printascii"`
+	UserServices   []string          `toml:"mqtt.userservices,omitempty" validate:"omitempty,dive,required,printascii"`
+	Containers     []string          `toml:"mqtt.containers,omitempty" validate:"omitempty,dive,required,printascii"`
+	PowerActions   map[string]bool   `toml:"mqtt.poweractions,omitempty" validate:"omitempty,dive,keys,oneof=poweroff reboot suspend hibernate,endkeys"`
+	Maintenance    map[string]bool   `toml:"mqtt.maintenance,omitempty" validate:"omitempty,dive,keys,oneof=emptytrash thumbnails fstrim,endkeys"`
+	Dashboards     []Dashboard       `toml:"ui.dashboards,omitempty" validate:"omitempty,dive"`
+	Commands       []Command         `toml:"mqtt.commands,omitempty" validate:"omitempty,unique=Name,dive" diag:"redact"`
+	WakeHosts      []WakeHost        `toml:"mqtt.wakehosts,omitempty" validate:"omitempty,unique=Name,dive" diag:"redact"`
+	Registered     bool              `toml:"hass.registered" validate:"boolean"`
+	MQTTEnabled    bool              `toml:"mqtt.enabled" validate:"boolean"`
+	MQTTRegistered bool              `toml:"mqtt.registered" validate:"boolean"`
+	Screenshots    bool              `toml:"mqtt.screenshots" validate:"boolean"`
+	FontScale      float64           `toml:"ui.fontscale,omitempty" validate:"omitempty,min=0.5,max=3"`
+	WindowScale    float64           `toml:"ui.windowscale,omitempty" validate:"omitempty,min=0.5,max=3"`
+	StartupDelay   int               `toml:"agent.startupdelay,omitempty" validate:"omitempty,min=0,max=600"`
+	CPULimit       int               `toml:"agent.cpulimit,omitempty" validate:"omitempty,min=1,max=800"`
+	MemoryLimit    int               `toml:"agent.memorylimit,omitempty" validate:"omitempty,min=16"`
+	GCPercent      int               `toml:"agent.gcpercent,omitempty" validate:"omitempty,min=-1,max=1000"`
+	ScreenshotSecs int               `toml:"mqtt.screenshotinterval,omitempty" validate:"omitempty,min=10"`
+	WebcamSecs     int               `toml:"mqtt.webcaminterval,omitempty" validate:"omitempty,min=10"`
+	WaitForNetwork bool              `toml:"agent.waitfornetwork" validate:"boolean"`
+	Telemetry      bool              `toml:"agent.telemetry" validate:"boolean"`
+	ActiveWindow   bool              `toml:"sensors.activewindow" validate:"boolean"`
+	AppTraffic     bool              `toml:"sensors.apptraffic" validate:"boolean"`
+	LogJournal     bool              `toml:"logging.journal" validate:"boolean"`
+}
+
+// Dashboard is a Home Assistant dashboard shown in the tray menu for quick
+// access. The URL can be a full URL or a path (e.g., /lovelace/0) on the Home
+// Assistant server.
+type Dashboard struct {
+	Name string `toml:"name" validate:"required"`
+	URL  string `toml:"url" validate:"required"`
+}
+
+// Command is a user-defined command exposed as a button over MQTT. Only the
+// commands listed in the preferences can be run from Home Assistant. The
+// command is run with sh -c, with a timeout in seconds (default 60).
+type Command struct {
+	Name    string `toml:"name" validate:"required,printascii"`
+	Command string `toml:"command" validate:"required"`
+	Timeout int    `toml:"timeout,omitempty" validate:"omitempty,min=1,max=3600"`
+}
+
+// WakeHost is a device that can be woken with Wake-on-LAN from Home Assistant
+// by its name, rather than its MAC address. The magic packet is sent to the
+// broadcast address, if set, or the broadcast address of the local network.
+type WakeHost struct {
+	Name      string `toml:"name" validate:"required,printascii"`
+	MAC       string `toml:"mac" validate:"required,mac"`
+	Broadcast string `toml:"broadcast,omitempty" validate:"omitempty,ip4_addr"`
 }
 
 type Preference func(*Preferences) error
@@ -196,6 +267,177 @@ func MQTTRegistered(status bool) Preference {
 	}
 }
 
+// EnsureInstallID will generate a new random installation ID, if one has not
+// already been set. The installation ID is anonymous and only used to identify
+// this installation of the agent in telemetry reports.
+func EnsureInstallID() Preference {
+	return func(p *Preferences) error {
+		if p.InstallID != "" {
+			return nil
+		}
+		id, err := newInstallID()
+		if err != nil {
+			return err
+		}
+		p.InstallID = id
+		return nil
+	}
+}
+
+func Telemetry(status bool) Preference {
+	return func(p *Preferences) error {
+		p.Telemetry = status
+		return nil
+	}
+}
+
+func ActiveWindow(status bool) Preference {
+	return func(p *Preferences) error {
+		p.ActiveWindow = status
+		return nil
+	}
+}
+
+func AppTraffic(status bool) Preference {
+	return func(p *Preferences) error {
+		p.AppTraffic = status
+		return nil
+	}
+}
+
+func SummarySchedule(schedule string) Preference {
+	return func(p *Preferences) error {
+		p.SummaryCron = schedule
+		return nil
+	}
+}
+
+func SummaryTarget(target string) Preference {
+	return func(p *Preferences) error {
+		p.SummaryTarget = target
+		return nil
+	}
+}
+
+func Language(lang string) Preference {
+	return func(p *Preferences) error {
+		p.Language = lang
+		return nil
+	}
+}
+
+func FontScale(scale float64) Preference {
+	return func(p *Preferences) error {
+		p.FontScale = scale
+		return nil
+	}
+}
+
+func WindowScale(scale float64) Preference {
+	return func(p *Preferences) error {
+		p.WindowScale = scale
+		return nil
+	}
+}
+
+func StartupDelay(seconds int) Preference {
+	return func(p *Preferences) error {
+		p.StartupDelay = seconds
+		return nil
+	}
+}
+
+func WaitForNetwork(status bool) Preference {
+	return func(p *Preferences) error {
+		p.WaitForNetwork = status
+		return nil
+	}
+}
+
+func UPSServer(server string) Preference {
+	return func(p *Preferences) error {
+		p.UPSServer = server
+		return nil
+	}
+}
+
+func CPULimit(limit int) Preference {
+	return func(p *Preferences) error {
+		p.CPULimit = limit
+		return nil
+	}
+}
+
+func MemoryLimit(limit int) Preference {
+	return func(p *Preferences) error {
+		p.MemoryLimit = limit
+		return nil
+	}
+}
+
+func GCPercent(percent int) Preference {
+	return func(p *Preferences) error {
+		p.GCPercent = percent
+		return nil
+	}
+}
+
+func PreferIP(family string) Preference {
+	return func(p *Preferences) error {
+		p.PreferIP = family
+		return nil
+	}
+}
+
+func UserAgent(ua string) Preference {
+	return func(p *Preferences) error {
+		p.UserAgent = ua
+		return nil
+	}
+}
+
+func HTTPHeaders(headers map[string]string) Preference {
+	return func(p *Preferences) error {
+		p.HTTPHeaders = headers
+		return nil
+	}
+}
+
+func LogJournal(enabled bool) Preference {
+	return func(p *Preferences) error {
+		p.LogJournal = enabled
+		return nil
+	}
+}
+
+func LogSyslog(server string) Preference {
+	return func(p *Preferences) error {
+		p.LogSyslog = server
+		return nil
+	}
+}
+
+// UpdateChannel sets the release channel to check for updates on, either
+// stable or beta.
+func UpdateChannel(channel string) Preference {
+	return func(p *Preferences) error {
+		p.UpdateChannel = channel
+		return nil
+	}
+}
+
+// Feature enables or disables the named experimental feature, overriding its
+// default for the release channel.
+func Feature(name string, enabled bool) Preference {
+	return func(p *Preferences) error {
+		if p.Features == nil {
+			p.Features = make(map[string]bool)
+		}
+		p.Features[name] = enabled
+		return nil
+	}
+}
+
 func defaultPreferences() *Preferences {
 	return &Preferences{
 		Version: AppVersion,
@@ -224,11 +466,23 @@ func Load() (*Preferences, error) {
 // Save will save the new values of the specified preferences to the existing
 // preferences file. NOTE: if the preferences file does not exist, Save will
 // return an error. Use New if saving preferences for the first time.
+//
+// Saves are serialised, both within the agent and between processes, so that
+// concurrent saves cannot lose each other's changes or corrupt the file.
 func Save(setters ...Preference) error {
 	if err := checkPath(preferencesPath); err != nil {
 		return err
 	}
 
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	file := filepath.Join(preferencesPath, preferencesFile)
+	lock, err := filelock.Acquire(file + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	prefs, err := Load()
 	if err != nil && !os.IsNotExist(err) {
 		return err
@@ -241,7 +495,6 @@ func Save(setters ...Preference) error {
 		return showValidationErrors(err)
 	}
 
-	file := filepath.Join(preferencesPath, preferencesFile)
 	return write(prefs, file)
 }
 
@@ -263,11 +516,32 @@ func write(prefs *Preferences, file string) error {
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(file, b, 0o600)
+	// Write to a temporary file and rename it over the preferences file, so
+	// that the file is never seen partially written.
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	return nil
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// newInstallID generates a random (version 4) UUID.
+func newInstallID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
 }
 
 func checkPath(path string) error {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -548,6 +549,51 @@ func TestSave(t *testing.T) {
 	}
 }
 
+func TestSave_concurrent(t *testing.T) {
+	SetPath(t.TempDir())
+
+	testServer := "http://test.host:9999"
+	err := Save(
+		Host(testServer),
+		Token("testToken"),
+		WebhookID("testID"),
+		RestAPIURL(testServer),
+		WebsocketURL(testServer),
+		DeviceName("testDevice"),
+		DeviceID("testID"),
+		Version("6.4.0"),
+		Registered(true),
+	)
+	assert.NoError(t, err)
+
+	// Each save changes a different preference. None of the changes should be
+	// lost.
+	setters := []Preference{
+		MQTTServer("tcp://localhost:1883"),
+		MQTTUser("testUser"),
+		UPSServer("localhost:3493"),
+		SummaryTarget("mqtt"),
+		UserAgent("testAgent"),
+	}
+	var wg sync.WaitGroup
+	for _, setter := range setters {
+		wg.Add(1)
+		go func(setter Preference) {
+			defer wg.Done()
+			assert.NoError(t, Save(setter))
+		}(setter)
+	}
+	wg.Wait()
+
+	prefs, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://localhost:1883", prefs.MQTTServer)
+	assert.Equal(t, "testUser", prefs.MQTTUser)
+	assert.Equal(t, "localhost:3493", prefs.UPSServer)
+	assert.Equal(t, "mqtt", prefs.SummaryTarget)
+	assert.Equal(t, "testAgent", prefs.UserAgent)
+}
+
 func Test_set(t *testing.T) {
 	testPrefs := defaultPreferences()
 	testSetter := func(p *Preferences) error {
@@ -635,3 +681,40 @@ func Test_checkPath(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureInstallID(t *testing.T) {
+	tests := []struct {
+		prefs *Preferences
+		name  string
+		want  string
+	}{
+		{
+			name:  "generate new id",
+			prefs: &Preferences{},
+		},
+		{
+			name:  "keep existing id",
+			prefs: &Preferences{InstallID: "0f8fad5b-d9cb-469f-a165-70867728950e"},
+			want:  "0f8fad5b-d9cb-469f-a165-70867728950e",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Nil(t, EnsureInstallID()(tt.prefs))
+			if tt.want != "" {
+				assert.Equal(t, tt.want, tt.prefs.InstallID)
+			}
+			assert.Nil(t, validatePreferences(&Preferences{
+				InstallID:    tt.prefs.InstallID,
+				Version:      "1",
+				Host:         "http://localhost:8123",
+				Token:        "token",
+				DeviceID:     "id",
+				DeviceName:   "host",
+				RestAPIURL:   "http://localhost:8123",
+				WebsocketURL: "ws://localhost:8123",
+				WebhookID:    "webhook",
+			}))
+		})
+	}
+}
@@ -8,6 +8,8 @@ package preferences
 import (
 	"errors"
 	"fmt"
+	"net"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog/log"
@@ -15,9 +17,32 @@ import (
 
 func validatePreferences(prefs *Preferences) error {
 	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.RegisterValidation("host_port", validateHostPort); err != nil {
+		return err
+	}
 	return validate.Struct(prefs)
 }
 
+// validateHostPort validates a field is a host:port combination. Unlike the
+// built-in hostname_port validation, the host can be an IPv6 address (e.g.,
+// [::1]:3493).
+func validateHostPort(fl validator.FieldLevel) bool {
+	return ValidHostPort(fl.Field().String())
+}
+
+// ValidHostPort returns whether the given string is a valid host:port
+// combination, where the host is a hostname or IPv4/IPv6 address.
+func ValidHostPort(s string) bool {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || host == "" {
+		return false
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return false
+	}
+	return true
+}
+
 func showValidationErrors(e error) error {
 	validationErrors, ok := e.(validator.ValidationErrors)
 	if !ok {
@@ -0,0 +1,34 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package preferences
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidHostPort(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{s: "localhost:3493", want: true},
+		{s: "192.168.1.1:3493", want: true},
+		{s: "[::1]:3493", want: true},
+		{s: "[2001:db8::1]:1883", want: true},
+		{s: "localhost", want: false},
+		{s: "::1:3493", want: false},
+		{s: ":3493", want: false},
+		{s: "localhost:0", want: false},
+		{s: "localhost:http", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			assert.Equal(t, tt.want, ValidHostPort(tt.s))
+		})
+	}
+}
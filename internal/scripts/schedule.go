@@ -0,0 +1,113 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package scripts
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+// scheduleSensor reports when a script last ran or will next run, so that it
+// can be seen in Home Assistant whether scripts are actually running.
+type scheduleSensor struct {
+	when   time.Time
+	err    error
+	script string
+	name   string
+	icon   string
+	last   bool
+}
+
+func (s *scheduleSensor) Name() string {
+	return "Script " + s.script + " " + s.name
+}
+
+func (s *scheduleSensor) ID() string {
+	return strcase.ToSnake("script_" + s.script + "_" + s.name)
+}
+
+func (s *scheduleSensor) Icon() string {
+	return s.icon
+}
+
+func (s *scheduleSensor) SensorType() sensor.SensorType {
+	return sensor.TypeSensor
+}
+
+func (s *scheduleSensor) DeviceClass() sensor.SensorDeviceClass {
+	return sensor.Timestamp
+}
+
+func (s *scheduleSensor) StateClass() sensor.SensorStateClass {
+	return 0
+}
+
+func (s *scheduleSensor) State() any {
+	if s.when.IsZero() {
+		return sensor.StateUnknown
+	}
+	return s.when.Format(time.RFC3339)
+}
+
+func (s *scheduleSensor) Units() string {
+	return ""
+}
+
+func (s *scheduleSensor) Category() string {
+	return "diagnostic"
+}
+
+func (s *scheduleSensor) Attributes() any {
+	if !s.last {
+		return nil
+	}
+	var lastErr string
+	if s.err != nil {
+		lastErr = s.err.Error()
+	}
+	return struct {
+		Error      string `json:"Error,omitempty"`
+		Successful bool   `json:"Successful"`
+	}{
+		Error:      lastErr,
+		Successful: s.err == nil,
+	}
+}
+
+// ScheduleSensors returns sensors for when the script will next run and,
+// if it has run, when it last ran and whether that run succeeded.
+func (s *script) ScheduleSensors() []tracker.Sensor {
+	if s.sched == nil {
+		return nil
+	}
+	name := filepath.Base(s.path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sensors := []tracker.Sensor{
+		&scheduleSensor{
+			script: name,
+			name:   "Next Run",
+			icon:   "mdi:calendar-clock",
+			when:   s.sched.Next(time.Now()),
+		},
+	}
+	if !s.lastRun.IsZero() {
+		sensors = append(sensors, &scheduleSensor{
+			script: name,
+			name:   "Last Run",
+			icon:   "mdi:calendar-check",
+			when:   s.lastRun,
+			err:    s.lastErr,
+			last:   true,
+		})
+	}
+	return sensors
+}
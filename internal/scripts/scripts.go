@@ -11,9 +11,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/iancoleman/strcase"
 	"github.com/pelletier/go-toml/v2"
+	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 
@@ -23,8 +26,12 @@ import (
 
 type script struct {
 	Output   chan tracker.Sensor
+	sched    cron.Schedule
+	lastRun  time.Time
+	lastErr  error
 	path     string
 	schedule string
+	mu       sync.Mutex
 }
 
 func (s *script) execute() (*scriptOutput, error) {
@@ -45,8 +52,20 @@ func (s *script) execute() (*scriptOutput, error) {
 // its specified schedule. It is implemented to satisfy the cron package
 // interface, so the script can be treated as a cron job. Run will execute the
 // script, collect the output and send it through a channel as a sensor object.
+//
+// After each run, the sensors for when the script last ran and will next run
+// are also sent.
 func (s *script) Run() {
 	output, err := s.execute()
+	s.mu.Lock()
+	s.lastRun = time.Now()
+	s.lastErr = err
+	s.mu.Unlock()
+	defer func() {
+		for _, o := range s.ScheduleSensors() {
+			s.Output <- o
+		}
+	}()
 	if err != nil {
 		log.Warn().Err(err).Str("script", s.path).
 			Msg("Could not run script.")
@@ -82,6 +101,12 @@ func NewScript(p string) *script {
 		return nil
 	}
 	s.schedule = o.Schedule
+	if s.schedule != "" {
+		if s.sched, err = cron.ParseStandard(s.schedule); err != nil {
+			log.Warn().Err(err).Str("script", p).
+				Msg("Invalid script schedule.")
+		}
+	}
 	return s
 }
 
@@ -122,6 +147,20 @@ type scriptSensor struct {
 	SensorStateClass  string `json:"sensor_state_class,omitempty" yaml:"sensor_state_class,omitempty" toml:"sensor_state_class,omitempty"`
 	SensorStateType   string `json:"sensor_type,omitempty" yaml:"sensor_type,omitempty" toml:"sensor_type,omitempty"`
 	SensorUnits       string `json:"sensor_units,omitempty" yaml:"sensor_units,omitempty" toml:"sensor_units,omitempty"`
+	SensorKind        string `json:"sensor_kind,omitempty" yaml:"sensor_kind,omitempty" toml:"sensor_kind,omitempty"`
+}
+
+// inferred returns the device class, state class and units inferred from the
+// kind of the sensor, or failing that, its units. Any of these set explicitly
+// in the script output take precedence.
+func (s *scriptSensor) inferred() sensor.Inferred {
+	if s.SensorKind != "" {
+		if i, ok := sensor.Infer(sensor.Kind(s.SensorKind)); ok {
+			return i
+		}
+	}
+	i, _ := sensor.InferFromUnits(s.SensorUnits)
+	return i
 }
 
 func (s *scriptSensor) Name() string {
@@ -146,6 +185,9 @@ func (s *scriptSensor) SensorType() sensor.SensorType {
 }
 
 func (s *scriptSensor) DeviceClass() sensor.SensorDeviceClass {
+	if s.SensorDeviceClass == "" {
+		return s.inferred().DeviceClass
+	}
 	for d := sensor.Apparent_power; d <= sensor.Wind_speed; d++ {
 		if s.SensorDeviceClass == d.String() {
 			return d
@@ -162,6 +204,8 @@ func (s *scriptSensor) StateClass() sensor.SensorStateClass {
 		return sensor.StateTotal
 	case "total_increasing":
 		return sensor.StateTotalIncreasing
+	case "":
+		return s.inferred().StateClass
 	default:
 		return 0
 	}
@@ -172,6 +216,9 @@ func (s *scriptSensor) State() any {
 }
 
 func (s *scriptSensor) Units() string {
+	if s.SensorUnits == "" {
+		return s.inferred().Units
+	}
 	return s.SensorUnits
 }
 
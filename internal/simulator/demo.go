@@ -0,0 +1,125 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package simulator
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+// DemoHAVersion is the Home Assistant version shown in demo mode.
+const DemoHAVersion = "2024.1.0"
+
+// demoSensors are plausible sensors of a desktop device, shown in demo mode.
+var demoSensors = []struct {
+	value   any
+	name    string
+	units   string
+	icon    string
+	min     float64
+	max     float64
+	pattern Pattern
+}{
+	{name: "CPU Usage", units: "%", icon: "mdi:chip", pattern: RandomWalk, min: 2, max: 60},
+	{name: "Load Average (1 min)", icon: "mdi:chip", pattern: RandomWalk, min: 0.1, max: 4},
+	{name: "Memory Usage", units: "%", icon: "mdi:memory", pattern: RandomWalk, min: 30, max: 70},
+	{name: "Swap Usage", units: "%", icon: "mdi:memory", pattern: RandomWalk, min: 0, max: 10},
+	{name: "CPU Temperature", units: "°C", icon: "mdi:thermometer", pattern: Sine, min: 40, max: 75},
+	{name: "Battery Level", units: "%", icon: "mdi:battery-charging", pattern: Sawtooth, min: 20, max: 100},
+	{name: "Download Rate", units: "kB/s", icon: "mdi:transfer-down", pattern: RandomWalk, min: 0, max: 2000},
+	{name: "Upload Rate", units: "kB/s", icon: "mdi:transfer-up", pattern: RandomWalk, min: 0, max: 500},
+	{name: "Screen Lock State", icon: "mdi:eye-lock", pattern: Toggle},
+	{name: "Active Connections", icon: "mdi:network", pattern: Static, value: 2},
+	{name: "Wi-Fi SSID", icon: "mdi:wifi", pattern: Static, value: "HomeNetwork"},
+	{name: "Kernel Version", icon: "mdi:chip", pattern: Static, value: "6.7.4"},
+	{name: "Distribution Name", icon: "mdi:linux", pattern: Static, value: "Fedora Linux"},
+	{name: "Power Profile", icon: "mdi:flash", pattern: Static, value: "balanced"},
+}
+
+// DemoTracker provides simulated sensors of a desktop device, for running the
+// UI in demo mode without a Home Assistant instance. It satisfies the
+// ui.SensorTracker interface.
+type DemoTracker struct {
+	sensors map[string]*Sensor
+	mu      sync.Mutex
+}
+
+func (t *DemoTracker) SensorList() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]string, 0, len(t.sensors))
+	for id := range t.sensors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (t *DemoTracker) Get(id string) (tracker.Sensor, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.sensors[id]; ok {
+		// Return a copy, as the sensor may be updated while it is being read.
+		c := *s
+		return &c, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// Run updates the values of the sensors every interval until the context is
+// canceled.
+func (t *DemoTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.mu.Lock()
+			for _, s := range t.sensors {
+				s.Update(now)
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+func NewDemoTracker() *DemoTracker {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	start := time.Now().Add(-time.Duration(r.Int63n(int64(period))))
+	t := &DemoTracker{sensors: make(map[string]*Sensor)}
+	for _, d := range demoSensors {
+		s := newSensor(r, start, d.name, d.pattern, d.min, d.max)
+		s.units = d.units
+		s.icon = d.icon
+		if d.value != nil {
+			s.value = d.value
+		}
+		t.sensors[s.ID()] = s
+	}
+	return t
+}
+
+// DemoHAConfig returns a plausible Home Assistant config for demo mode.
+func DemoHAConfig() *hass.Config {
+	entities := make(map[string]map[string]any)
+	for _, d := range demoSensors {
+		entities["sensor.demo_"+d.name] = map[string]any{"disabled": false}
+	}
+	return &hass.Config{
+		Version:      DemoHAVersion,
+		LocationName: "Home",
+		Entities:     entities,
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package simulator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
+)
+
+const (
+	simManufacturer = "Go Hass Agent"
+	simModel        = "Simulated Device"
+	simOS           = "Simulator"
+)
+
+// Device is a virtual device that can be registered with Home Assistant in
+// place of the device running the agent. It satisfies the api.DeviceInfo
+// interface.
+type Device struct {
+	appName    string
+	appVersion string
+	name       string
+}
+
+func (d *Device) AppName() string {
+	return d.appName
+}
+
+func (d *Device) AppVersion() string {
+	return d.appVersion
+}
+
+func (d *Device) AppID() string {
+	return d.appName + "-simulator"
+}
+
+func (d *Device) DeviceName() string {
+	return d.name
+}
+
+// DeviceID is derived from the device name, so that the same device will be
+// used each time the simulator is run.
+func (d *Device) DeviceID() string {
+	return d.appName + "-" + d.name
+}
+
+func (d *Device) Manufacturer() string {
+	return simManufacturer
+}
+
+func (d *Device) Model() string {
+	return simModel
+}
+
+func (d *Device) OsName() string {
+	return simOS
+}
+
+func (d *Device) OsVersion() string {
+	return d.appVersion
+}
+
+func (d *Device) SupportsEncryption() bool {
+	return false
+}
+
+func (d *Device) AppData() any {
+	return nil
+}
+
+func (d *Device) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&api.RegistrationRequest{
+		DeviceID:           d.DeviceID(),
+		AppID:              d.AppID(),
+		AppName:            d.AppName(),
+		AppVersion:         d.AppVersion(),
+		DeviceName:         d.DeviceName(),
+		Manufacturer:       d.Manufacturer(),
+		Model:              d.Model(),
+		OsName:             d.OsName(),
+		OsVersion:          d.OsVersion(),
+		SupportsEncryption: d.SupportsEncryption(),
+		AppData:            d.AppData(),
+	})
+}
+
+// NewDevice creates the nth virtual device.
+func NewDevice(n int, appName, appVersion string) *Device {
+	return &Device{
+		appName:    appName,
+		appVersion: appVersion,
+		name:       fmt.Sprintf("sim-device-%02d", n),
+	}
+}
+
+// NewTemporaryDevice creates a virtual device with a random name, for
+// registering a throwaway device (e.g., for a self-test).
+func NewTemporaryDevice(prefix, appName, appVersion string) (*Device, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &Device{
+		appName:    appName,
+		appVersion: appVersion,
+		name:       prefix + "-" + hex.EncodeToString(b),
+	}, nil
+}
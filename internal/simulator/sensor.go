@@ -0,0 +1,175 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+//go:generate stringer -type=Pattern -output patternStrings.go -linecomment
+const (
+	RandomWalk Pattern = iota + 1 // Random Walk
+	Sine                          // Sine Wave
+	Sawtooth                      // Sawtooth
+	Toggle                        // Toggle
+	Static                        // Static
+)
+
+const (
+	minValue = 0
+	maxValue = 100
+
+	// walkStep is the largest change in value of a random walk at each
+	// update, as a fraction of the range of values.
+	walkStep = 0.05
+	// period is the time taken for a repeating pattern to complete a cycle.
+	period = 10 * time.Minute
+)
+
+// Pattern is the pattern of values a simulated sensor will generate.
+type Pattern int
+
+// Sensor is a simulated sensor that generates values following a pattern. It
+// satisfies the tracker.Sensor interface.
+type Sensor struct {
+	rand    *rand.Rand
+	start   time.Time
+	value   any
+	name    string
+	units   string
+	icon    string
+	min     float64
+	max     float64
+	pattern Pattern
+}
+
+func (s *Sensor) Name() string {
+	return s.name
+}
+
+func (s *Sensor) ID() string {
+	return strcase.ToSnake(s.name)
+}
+
+func (s *Sensor) Icon() string {
+	if s.icon != "" {
+		return s.icon
+	}
+	switch s.pattern {
+	case Sine:
+		return "mdi:sine-wave"
+	case Sawtooth:
+		return "mdi:sawtooth-wave"
+	case Toggle:
+		return "mdi:toggle-switch"
+	default:
+		return "mdi:chart-bell-curve"
+	}
+}
+
+func (s *Sensor) SensorType() sensor.SensorType {
+	if s.pattern == Toggle {
+		return sensor.TypeBinary
+	}
+	return sensor.TypeSensor
+}
+
+func (s *Sensor) DeviceClass() sensor.SensorDeviceClass {
+	return 0
+}
+
+func (s *Sensor) StateClass() sensor.SensorStateClass {
+	if s.pattern == Toggle || s.pattern == Static {
+		return 0
+	}
+	return sensor.StateMeasurement
+}
+
+func (s *Sensor) State() any {
+	return s.value
+}
+
+func (s *Sensor) Units() string {
+	return s.units
+}
+
+func (s *Sensor) Category() string {
+	return ""
+}
+
+func (s *Sensor) Attributes() any {
+	return struct {
+		Pattern    string `json:"Pattern"`
+		DataSource string `json:"Data Source"`
+	}{
+		Pattern:    s.pattern.String(),
+		DataSource: simModel,
+	}
+}
+
+// Update generates the next value of the sensor for the given time.
+func (s *Sensor) Update(t time.Time) {
+	// Position in the current cycle of a repeating pattern, from 0 to 1.
+	phase := math.Mod(t.Sub(s.start).Seconds(), period.Seconds()) / period.Seconds()
+	switch s.pattern {
+	case RandomWalk:
+		v, _ := s.value.(float64)
+		v += (s.rand.Float64()*2 - 1) * walkStep * (s.max - s.min)
+		s.value = round(math.Max(s.min, math.Min(s.max, v)))
+	case Sine:
+		s.value = round(s.min + (s.max-s.min)*(1+math.Sin(2*math.Pi*phase))/2)
+	case Sawtooth:
+		s.value = round(s.min + (s.max-s.min)*phase)
+	case Toggle:
+		s.value = phase >= 0.5
+	}
+}
+
+// round rounds the value to two decimal places.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// newSensor creates a simulated sensor with the given pattern, generating
+// values between min and max.
+func newSensor(r *rand.Rand, start time.Time, name string, p Pattern, min, max float64) *Sensor {
+	s := &Sensor{
+		rand:    r,
+		start:   start,
+		pattern: p,
+		name:    name,
+		min:     min,
+		max:     max,
+		value:   round(min + r.Float64()*(max-min)),
+	}
+	s.Update(time.Now())
+	return s
+}
+
+// NewSensors creates the given number of simulated sensors, cycling through the
+// available patterns. The seed is used for the randomness of the sensors, so
+// that each device can generate different values.
+func NewSensors(count int, seed int64) []*Sensor {
+	r := rand.New(rand.NewSource(seed))
+	start := time.Now().Add(-time.Duration(r.Int63n(int64(period))))
+	sensors := make([]*Sensor, 0, count)
+	for i := 0; i < count; i++ {
+		p := Pattern(i%int(Toggle) + 1)
+		s := newSensor(r, start, fmt.Sprintf("Simulated %s %d", p, i/int(Toggle)+1), p, minValue, maxValue)
+		if p != Toggle {
+			s.units = "%"
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+func TestNewSensors(t *testing.T) {
+	sensors := NewSensors(6, 1)
+	assert.Len(t, sensors, 6)
+	assert.Equal(t, "simulated_random_walk_1", sensors[0].ID())
+	assert.Equal(t, "Simulated Toggle 1", sensors[3].Name())
+	assert.Equal(t, sensor.TypeBinary, sensors[3].SensorType())
+	assert.Equal(t, "Simulated Random Walk 2", sensors[4].Name())
+
+	// The same seed generates the same sensors.
+	assert.Equal(t, sensors[0].State(), NewSensors(1, 1)[0].State())
+}
+
+func TestSensor_Update(t *testing.T) {
+	start := time.Now()
+	sensors := NewSensors(4, 1)
+	for _, s := range sensors {
+		s.start = start
+	}
+	for i := 0; i < 1000; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		for _, s := range sensors {
+			s.Update(now)
+			if s.pattern == Toggle {
+				assert.IsType(t, true, s.State())
+				continue
+			}
+			v, ok := s.State().(float64)
+			assert.True(t, ok)
+			assert.GreaterOrEqual(t, v, float64(minValue))
+			assert.LessOrEqual(t, v, float64(maxValue))
+		}
+	}
+
+	sine := sensors[1]
+	sine.Update(start.Add(period / 4))
+	assert.Equal(t, float64(maxValue), sine.State())
+	sawtooth := sensors[2]
+	sawtooth.Update(start.Add(period / 2))
+	assert.Equal(t, float64(maxValue/2), sawtooth.State())
+	toggle := sensors[3]
+	toggle.Update(start.Add(period / 4))
+	assert.Equal(t, false, toggle.State())
+	toggle.Update(start.Add(3 * period / 4))
+	assert.Equal(t, true, toggle.State())
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package summary compiles a periodic summary of the state of the device from
+// the sensors being tracked, suitable for sending as a notification.
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	// NotificationID is the ID used for the summary persistent notification
+	// in Home Assistant, so that each summary replaces the last.
+	NotificationID = "go_hass_agent_summary"
+
+	TargetNotification = "notification"
+	TargetMQTT         = "mqtt"
+
+	uptimeID     = "uptime"
+	problemsID   = "problems"
+	lastUpdateID = "last_system_update"
+	diskPrefix   = "mountpoint_"
+)
+
+// Sensors is the source of the sensor states used in the summary.
+type Sensors interface {
+	SensorList() []string
+	Get(key string) (tracker.Sensor, error)
+}
+
+// Summary is a compiled summary of the state of the device.
+type Summary struct {
+	Title   string   `json:"title"`
+	Entries []string `json:"entries"`
+}
+
+// Message returns the summary entries as a markdown list.
+func (s *Summary) Message() string {
+	var b strings.Builder
+	for _, e := range s.Entries {
+		b.WriteString("- " + e + "\n")
+	}
+	return b.String()
+}
+
+// MarshalJSON includes the formatted message alongside the summary entries.
+func (s *Summary) MarshalJSON() ([]byte, error) {
+	type summary Summary
+	return json.Marshal(struct {
+		*summary
+		Message string `json:"message"`
+	}{
+		summary: (*summary)(s),
+		Message: s.Message(),
+	})
+}
+
+// Compiler compiles summaries. It remembers the disk usage reported in the last
+// summary, so that the change in usage between summaries can be shown.
+type Compiler struct {
+	lastDiskUsage map[string]float64
+}
+
+// Compile creates a summary for the given device from the current sensor
+// states. Sensors that are not being tracked are left out of the summary.
+func (c *Compiler) Compile(deviceName string, sensors Sensors) *Summary {
+	s := &Summary{Title: "Summary for " + deviceName}
+	for _, id := range []string{uptimeID, lastUpdateID, problemsID} {
+		if sensor, err := sensors.Get(id); err == nil {
+			s.Entries = append(s.Entries, formatSensor(sensor))
+		}
+	}
+
+	diskUsage := make(map[string]float64)
+	var disks []string
+	for _, id := range sensors.SensorList() {
+		if strings.HasPrefix(id, diskPrefix) {
+			disks = append(disks, id)
+		}
+	}
+	sort.Strings(disks)
+	for _, id := range disks {
+		sensor, err := sensors.Get(id)
+		// Other sensors of the mountpoint, such as whether it is encrypted,
+		// share the prefix but are not usage.
+		if err != nil || sensor.Units() != "%" {
+			continue
+		}
+		entry := formatSensor(sensor)
+		if usage, ok := toFloat(sensor.State()); ok {
+			diskUsage[id] = usage
+			if last, ok := c.lastDiskUsage[id]; ok {
+				entry += fmt.Sprintf(" (%+.1f%s since last summary)", usage-last, sensor.Units())
+			}
+		}
+		s.Entries = append(s.Entries, entry)
+	}
+	c.lastDiskUsage = diskUsage
+	return s
+}
+
+func formatSensor(s tracker.Sensor) string {
+	state := fmt.Sprintf("%v", s.State())
+	if v, ok := toFloat(s.State()); ok {
+		state = fmt.Sprintf("%.4g", v)
+	}
+	if s.Units() == "%" {
+		return s.Name() + ": " + state + "%"
+	}
+	if s.Units() != "" {
+		return s.Name() + ": " + state + " " + s.Units()
+	}
+	return s.Name() + ": " + state
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
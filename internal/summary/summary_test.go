@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package summary
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+type testSensor struct {
+	state any
+	name  string
+	id    string
+	units string
+}
+
+func (s *testSensor) Name() string                          { return s.name }
+func (s *testSensor) ID() string                            { return s.id }
+func (s *testSensor) Icon() string                          { return "" }
+func (s *testSensor) SensorType() sensor.SensorType         { return sensor.TypeSensor }
+func (s *testSensor) DeviceClass() sensor.SensorDeviceClass { return 0 }
+func (s *testSensor) StateClass() sensor.SensorStateClass   { return 0 }
+func (s *testSensor) State() any                            { return s.state }
+func (s *testSensor) Units() string                         { return s.units }
+func (s *testSensor) Category() string                      { return "" }
+func (s *testSensor) Attributes() any                       { return nil }
+
+type testSensors map[string]tracker.Sensor
+
+func (t testSensors) SensorList() []string {
+	var ids []string
+	for id := range t {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (t testSensors) Get(id string) (tracker.Sensor, error) {
+	if s, ok := t[id]; ok {
+		return s, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestCompiler_Compile(t *testing.T) {
+	sensors := testSensors{
+		"uptime":                    &testSensor{id: "uptime", name: "Uptime", state: 12.5, units: "h"},
+		"problems":                  &testSensor{id: "problems", name: "Problems", state: 0},
+		"last_system_update":        &testSensor{id: "last_system_update", name: "Last System Update", state: "2024-01-20T10:15:02+10:00"},
+		"mountpoint_root":           &testSensor{id: "mountpoint_root", name: "Mountpoint / Usage", state: 40.0, units: "%"},
+		"mountpoint_root_encrypted": &testSensor{id: "mountpoint_root_encrypted", name: "Mountpoint / Encrypted", state: true},
+		"cpu_usage":                 &testSensor{id: "cpu_usage", name: "CPU Usage", state: 5.0, units: "%"},
+	}
+	c := &Compiler{}
+
+	s := c.Compile("test", sensors)
+	assert.Equal(t, "Summary for test", s.Title)
+	assert.Equal(t, []string{
+		"Uptime: 12.5 h",
+		"Last System Update: 2024-01-20T10:15:02+10:00",
+		"Problems: 0",
+		"Mountpoint / Usage: 40%",
+	}, s.Entries)
+
+	sensors["mountpoint_root"] = &testSensor{id: "mountpoint_root", name: "Mountpoint / Usage", state: 42.5, units: "%"}
+	s = c.Compile("test", sensors)
+	assert.Equal(t, "Mountpoint / Usage: 42.5% (+2.5% since last summary)", s.Entries[3])
+	assert.Equal(t, "- Uptime: 12.5 h\n- Last System Update: 2024-01-20T10:15:02+10:00\n- Problems: 0\n- Mountpoint / Usage: 42.5% (+2.5% since last summary)\n", s.Message())
+}
+
+// TestSensorIDs checks that the summary looks up the IDs actually used by the
+// sensors it reports on.
+func TestSensorIDs(t *testing.T) {
+	tests := map[string]linux.SensorTypeValue{
+		uptimeID:     linux.SensorUptime,
+		problemsID:   linux.SensorProblem,
+		lastUpdateID: linux.SensorLastUpdate,
+	}
+	for id, sensorType := range tests {
+		s := &linux.Sensor{SensorTypeValue: sensorType}
+		assert.Equal(t, id, s.ID())
+	}
+}
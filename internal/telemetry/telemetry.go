@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package telemetry contains the strictly opt-in reporting of anonymous usage
+// data. Nothing is sent unless the user has enabled telemetry in the
+// preferences or on the command-line and a telemetry URL is configured.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// ReportInterval is how often a telemetry report is sent while the agent is
+// running.
+const ReportInterval = 24 * time.Hour
+
+var ErrNoTelemetryURL = errors.New("no telemetry URL configured")
+
+// Report is the data sent in a telemetry report. It contains no personal
+// information, only an anonymous installation ID and details about the agent
+// and the platform it is running on.
+type Report struct {
+	InstallID string   `json:"install_id"`
+	Version   string   `json:"version"`
+	OS        string   `json:"os"`
+	OSVersion string   `json:"os_version"`
+	Arch      string   `json:"arch"`
+	Features  []string `json:"features"`
+}
+
+// NewReport creates a telemetry report from the agent preferences, the
+// operating system details and the list of enabled features.
+func NewReport(prefs *preferences.Preferences, osName, osVersion string, features ...string) *Report {
+	return &Report{
+		InstallID: prefs.InstallID,
+		Version:   preferences.AppVersion,
+		OS:        osName,
+		OSVersion: osVersion,
+		Arch:      runtime.GOARCH,
+		Features:  features,
+	}
+}
+
+// Send will send the given report to the given URL. The full report is logged
+// before sending so that users can see exactly what is shared.
+func Send(ctx context.Context, url string, r *Report) error {
+	if url == "" {
+		return ErrNoTelemetryURL
+	}
+	log.Info().
+		Str("install_id", r.InstallID).
+		Str("version", r.Version).
+		Str("os", r.OS).
+		Str("os_version", r.OSVersion).
+		Str("arch", r.Arch).
+		Strs("features", r.Features).
+		Str("url", url).
+		Msg("Sending telemetry report.")
+	requestCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	return requests.
+		URL(url).
+		BodyJSON(r).
+		Fetch(requestCtx)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func TestNewReport(t *testing.T) {
+	prefs := &preferences.Preferences{
+		InstallID:  "a3a7e0d5-6f0c-4d6e-9d1c-2a4b8e3f1c7d",
+		DeviceName: "testDevice",
+		Token:      "secret-token",
+	}
+	r := NewReport(prefs, "testOS", "1.0", "mqtt", "headless")
+
+	b, err := json.Marshal(r)
+	require.NoError(t, err)
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(b, &payload))
+	// Only the anonymous details listed in the docs are sent.
+	assert.Equal(t, map[string]any{
+		"install_id": prefs.InstallID,
+		"version":    preferences.AppVersion,
+		"os":         "testOS",
+		"os_version": "1.0",
+		"arch":       runtime.GOARCH,
+		"features":   []any{"mqtt", "headless"},
+	}, payload)
+}
+
+func TestSend(t *testing.T) {
+	r := &Report{InstallID: "id", Version: "v1", OS: "testOS", Arch: "amd64"}
+
+	var received *Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = &Report{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, Send(context.TODO(), server.URL, r))
+	assert.Equal(t, r, received)
+}
+
+func TestSendNoURL(t *testing.T) {
+	err := Send(context.TODO(), "", &Report{})
+	assert.ErrorIs(t, err, ErrNoTelemetryURL)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package termux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	batteryStatusCmd    = "termux-battery-status"
+	batteryPollInterval = time.Minute
+)
+
+// batteryStatus is the output of termux-battery-status.
+type batteryStatus struct {
+	Health      string  `json:"health"`
+	Plugged     string  `json:"plugged"`
+	Status      string  `json:"status"`
+	Percentage  int     `json:"percentage"`
+	Temperature float64 `json:"temperature"`
+}
+
+type batterySensor struct {
+	plugged string
+	linux.Sensor
+}
+
+func (s *batterySensor) Attributes() any {
+	return struct {
+		DataSource string `json:"Data Source"`
+		Plugged    string `json:"Plugged,omitempty"`
+	}{
+		DataSource: DataSrcTermuxAPI,
+		Plugged:    s.plugged,
+	}
+}
+
+func (s *batterySensor) Icon() string {
+	if s.SensorTypeValue != linux.SensorBattPercentage {
+		return s.IconString
+	}
+	level, ok := s.Value.(int)
+	switch {
+	case !ok:
+		return "mdi:battery-unknown"
+	case level >= 95:
+		return "mdi:battery"
+	case level < 10:
+		return "mdi:battery-outline"
+	default:
+		return fmt.Sprintf("mdi:battery-%d0", level/10)
+	}
+}
+
+func newBatterySensors(b *batteryStatus) []tracker.Sensor {
+	plugged := strcase.ToCamel(strings.TrimPrefix(strings.ToLower(b.Plugged), "plugged_"))
+	return []tracker.Sensor{
+		&batterySensor{
+			plugged: plugged,
+			Sensor: linux.Sensor{
+				SensorTypeValue:  linux.SensorBattPercentage,
+				UnitsString:      "%",
+				DeviceClassValue: sensor.SensorBattery,
+				StateClassValue:  sensor.StateMeasurement,
+				Value:            b.Percentage,
+			},
+		},
+		&batterySensor{
+			Sensor: linux.Sensor{
+				SensorTypeValue:  linux.SensorBattTemp,
+				IconString:       "mdi:thermometer",
+				UnitsString:      "°C",
+				DeviceClassValue: sensor.SensorTemperature,
+				StateClassValue:  sensor.StateMeasurement,
+				Value:            b.Temperature,
+			},
+		},
+		&batterySensor{
+			plugged: plugged,
+			Sensor: linux.Sensor{
+				SensorTypeValue: linux.SensorBattState,
+				IconString:      "mdi:battery-charging",
+				Value:           strcase.ToCamel(strings.ToLower(b.Status)),
+			},
+		},
+		&batterySensor{
+			Sensor: linux.Sensor{
+				SensorTypeValue: linux.SensorBattHealth,
+				IconString:      "mdi:battery-heart-variant",
+				IsDiagnostic:    true,
+				Value:           strcase.ToCamel(strings.ToLower(b.Health)),
+			},
+		},
+	}
+}
+
+// BatteryUpdater reports the level, temperature, charging state and health of
+// the device battery.
+func BatteryUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	if _, err := exec.LookPath(batteryStatusCmd); err != nil {
+		log.Warn().Msg("Termux:API is not installed. Battery sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	var mu sync.Mutex
+	sendBatterySensors := func(_ time.Duration) {
+		var status batteryStatus
+		if err := runAPI(ctx, batteryStatusCmd, &status); err != nil {
+			if !errors.Is(err, ErrNoTermuxAPI) {
+				log.Debug().Err(err).Msg("Could not retrieve battery status.")
+			}
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, s := range newBatterySensors(&status) {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case sensorCh <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendBatterySensors, batteryPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped battery sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package termux
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const setClipboardCmd = "termux-clipboard-set"
+
+// SetClipboard copies the text to the clipboard of the Android device, with
+// the termux-clipboard-set command from Termux:API.
+func SetClipboard(ctx context.Context, text string) error {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, setClipboardCmd)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", setClipboardCmd, err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package termux
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	wifiInfoCmd      = "termux-wifi-connectioninfo"
+	wifiPollInterval = time.Minute
+
+	// wifiCompleted is the supplicant state when connected to a network.
+	wifiCompleted = "COMPLETED"
+)
+
+// wifiInfo is the output of termux-wifi-connectioninfo.
+type wifiInfo struct {
+	SSID            string `json:"ssid"`
+	BSSID           string `json:"bssid"`
+	IP              string `json:"ip"`
+	SupplicantState string `json:"supplicant_state"`
+	Frequency       int    `json:"frequency_mhz"`
+	LinkSpeed       int    `json:"link_speed_mbps"`
+	RSSI            int    `json:"rssi"`
+}
+
+type wifiSensor struct {
+	linux.Sensor
+}
+
+func (s *wifiSensor) Icon() string {
+	if s.SensorTypeValue != linux.SensorWifiStrength {
+		return "mdi:wifi"
+	}
+	rssi, ok := s.Value.(int)
+	switch {
+	case !ok:
+		return "mdi:wifi-strength-alert-outline"
+	case rssi >= -55:
+		return "mdi:wifi-strength-4"
+	case rssi >= -67:
+		return "mdi:wifi-strength-3"
+	case rssi >= -80:
+		return "mdi:wifi-strength-2"
+	default:
+		return "mdi:wifi-strength-1"
+	}
+}
+
+func newWifiSensors(w *wifiInfo) []tracker.Sensor {
+	// When not connected, report the SSID as unknown and skip the
+	// connection details.
+	if w.SupplicantState != wifiCompleted {
+		return []tracker.Sensor{
+			&wifiSensor{Sensor: linux.Sensor{
+				SensorTypeValue: linux.SensorWifiSSID,
+				SensorSrc:       DataSrcTermuxAPI,
+				IsDiagnostic:    true,
+				Value:           sensor.StateUnknown,
+			}},
+		}
+	}
+	return []tracker.Sensor{
+		&wifiSensor{Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorWifiSSID,
+			SensorSrc:       DataSrcTermuxAPI,
+			IsDiagnostic:    true,
+			Value:           w.SSID,
+		}},
+		&wifiSensor{Sensor: linux.Sensor{
+			SensorTypeValue: linux.SensorWifiHWAddress,
+			SensorSrc:       DataSrcTermuxAPI,
+			IsDiagnostic:    true,
+			Value:           w.BSSID,
+		}},
+		&wifiSensor{Sensor: linux.Sensor{
+			SensorTypeValue:  linux.SensorWifiFrequency,
+			UnitsString:      "MHz",
+			SensorSrc:        DataSrcTermuxAPI,
+			DeviceClassValue: sensor.Frequency,
+			StateClassValue:  sensor.StateMeasurement,
+			IsDiagnostic:     true,
+			Value:            w.Frequency,
+		}},
+		&wifiSensor{Sensor: linux.Sensor{
+			SensorTypeValue:  linux.SensorWifiSpeed,
+			UnitsString:      "Mbit/s",
+			SensorSrc:        DataSrcTermuxAPI,
+			DeviceClassValue: sensor.Data_rate,
+			StateClassValue:  sensor.StateMeasurement,
+			IsDiagnostic:     true,
+			Value:            w.LinkSpeed,
+		}},
+		&wifiSensor{Sensor: linux.Sensor{
+			SensorTypeValue:  linux.SensorWifiStrength,
+			UnitsString:      "dBm",
+			SensorSrc:        DataSrcTermuxAPI,
+			DeviceClassValue: sensor.Signal_strength,
+			StateClassValue:  sensor.StateMeasurement,
+			IsDiagnostic:     true,
+			Value:            w.RSSI,
+		}},
+	}
+}
+
+// NetworkUpdater reports details of the Wi-Fi network the device is connected
+// to.
+func NetworkUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	if _, err := exec.LookPath(wifiInfoCmd); err != nil {
+		log.Warn().Msg("Termux:API is not installed. Network sensors will not run.")
+		close(sensorCh)
+		return sensorCh
+	}
+
+	var mu sync.Mutex
+	sendWifiSensors := func(_ time.Duration) {
+		var info wifiInfo
+		if err := runAPI(ctx, wifiInfoCmd, &info); err != nil {
+			if !errors.Is(err, ErrNoTermuxAPI) {
+				log.Debug().Err(err).Msg("Could not retrieve Wi-Fi connection info.")
+			}
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, s := range newWifiSensors(&info) {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case sensorCh <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendWifiSensors, wifiPollInterval, time.Second)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped network sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package termux
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+const openURLCmd = "termux-open-url"
+
+// OpenURL opens the URL in the default browser of the Android device, with the
+// termux-open-url command from Termux itself (not Termux:API).
+func OpenURL(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	if err := exec.CommandContext(ctx, openURLCmd, url).Run(); err != nil {
+		return fmt.Errorf("%s: %w", openURLCmd, err)
+	}
+	return nil
+}
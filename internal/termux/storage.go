@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package termux
+
+import (
+	"context"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/joshuar/go-hass-agent/internal/device/helpers"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/tracker"
+)
+
+const (
+	// sharedStoragePath is the shared (user-visible) storage on Android. It
+	// is only accessible once termux-setup-storage has been run.
+	sharedStoragePath = "/storage/emulated/0"
+
+	storagePollInterval = time.Minute
+)
+
+// storageVolume is a storage location to report the usage of.
+type storageVolume struct {
+	label string
+	path  string
+}
+
+// storageVolumes returns the storage locations on the device. Internal storage
+// is where Termux (and other apps) keep their data.
+func storageVolumes() []storageVolume {
+	internal := os.Getenv("HOME")
+	if internal == "" {
+		internal = "/data"
+	}
+	return []storageVolume{
+		{label: "Internal Storage", path: internal},
+		{label: "Shared Storage", path: sharedStoragePath},
+	}
+}
+
+type storageSensor struct {
+	label string
+	stats *disk.UsageStat
+	linux.Sensor
+}
+
+func (s *storageSensor) Name() string {
+	return s.label + " Usage"
+}
+
+func (s *storageSensor) ID() string {
+	return strings.ToLower(strings.ReplaceAll(s.label, " ", "_")) + "_usage"
+}
+
+func (s *storageSensor) Attributes() any {
+	return struct {
+		DataSource string `json:"Data Source"`
+		Path       string `json:"Path"`
+		Total      uint64 `json:"Total"`
+		Free       uint64 `json:"Free"`
+	}{
+		DataSource: "StatFS",
+		Path:       s.stats.Path,
+		Total:      s.stats.Total,
+		Free:       s.stats.Free,
+	}
+}
+
+func newStorageSensor(label string, stats *disk.UsageStat) *storageSensor {
+	return &storageSensor{
+		label: label,
+		stats: stats,
+		Sensor: linux.Sensor{
+			IconString:      "mdi:harddisk",
+			UnitsString:     "%",
+			StateClassValue: sensor.StateTotal,
+			Value:           math.Round(stats.UsedPercent/0.05) * 0.05,
+		},
+	}
+}
+
+// StorageUpdater reports the usage of the internal and shared storage of the
+// device. Shared storage is only reported if Termux has been granted access
+// to it.
+func StorageUpdater(ctx context.Context) chan tracker.Sensor {
+	sensorCh := make(chan tracker.Sensor)
+	volumes := storageVolumes()
+
+	var mu sync.Mutex
+	sendStorageSensors := func(_ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, v := range volumes {
+			if ctx.Err() != nil {
+				return
+			}
+			usage, err := disk.UsageWithContext(ctx, v.path)
+			if err != nil {
+				log.Debug().Err(err).Str("path", v.path).Msg("Could not retrieve storage usage.")
+				continue
+			}
+			select {
+			case sensorCh <- newStorageSensor(v.label, usage):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go helpers.PollSensors(ctx, sendStorageSensors, storagePollInterval, time.Second*5)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		close(sensorCh)
+		mu.Unlock()
+		log.Debug().Msg("Stopped storage sensors.")
+	}()
+	return sensorCh
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package termux provides sensors for Android devices running the agent under
+// Termux. Battery and network details are retrieved with the commands of the
+// Termux:API add-on, which must be installed (both the Termux:API app and the
+// termux-api package).
+package termux
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const (
+	DataSrcTermuxAPI = "Termux:API"
+
+	// apiTimeout is how long to wait for a Termux:API command. Commands can
+	// hang if the Termux:API app is not installed.
+	apiTimeout = 15 * time.Second
+)
+
+// ErrNoTermuxAPI is returned when a Termux:API command is not available.
+var ErrNoTermuxAPI = errors.New("termux-api package not installed")
+
+// runAPI runs the given Termux:API command and parses its JSON output into v.
+func runAPI(ctx context.Context, command string, v any) error {
+	if _, err := exec.LookPath(command); err != nil {
+		return ErrNoTermuxAPI
+	}
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, command).Output()
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("%s: could not parse output: %w", command, err)
+	}
+	return nil
+}
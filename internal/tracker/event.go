@@ -0,0 +1,27 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package tracker
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/api"
+)
+
+func fireEvent(ctx context.Context, e *hass.Event) {
+	response := <-api.ExecuteRequest(ctx, e)
+	switch r := response.(type) {
+	case []byte:
+		log.Debug().Str("event", e.EventType).Msg("Event fired.")
+	case error:
+		log.Warn().Err(r).Str("event", e.EventType).Msg("Failed to fire event.")
+	default:
+		log.Warn().Msgf("Unknown response type %T", r)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package tracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+const (
+	// minPause and maxPause are the bounds of the period a sensor rejected by
+	// Home Assistant is paused for. Each subsequent rejection doubles the
+	// period.
+	minPause = time.Minute
+	maxPause = time.Hour
+	// defaultRateLimitPause is how long all updates are paused when Home
+	// Assistant is rate limiting but did not say for how long.
+	defaultRateLimitPause = 5 * time.Minute
+)
+
+type pauseEntry struct {
+	until   time.Time
+	reason  string
+	strikes int
+}
+
+// pauseList tracks sensors that have been rejected by Home Assistant and
+// should not be sent again until their pause has expired. It also tracks a
+// global pause for when Home Assistant is rate limiting all requests.
+type pauseList struct {
+	all     time.Time
+	sensors map[string]*pauseEntry
+	mu      sync.Mutex
+}
+
+// isPaused returns whether updates for the given sensor should be held back.
+func (p *pauseList) isPaused(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Before(p.all) {
+		return true
+	}
+	if e, ok := p.sensors[id]; ok {
+		return now.Before(e.until)
+	}
+	return false
+}
+
+// isPausedAll returns whether updates for all sensors are being held back,
+// because Home Assistant is rate limiting.
+func (p *pauseList) isPausedAll() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Now().Before(p.all)
+}
+
+// pause will pause the given sensor, with an increasing period for repeated
+// rejections. It returns the period of the pause and whether the sensor was
+// newly paused.
+func (p *pauseList) pause(id, reason string) (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sensors == nil {
+		p.sensors = make(map[string]*pauseEntry)
+	}
+	e, ok := p.sensors[id]
+	if !ok {
+		e = &pauseEntry{}
+		p.sensors[id] = e
+	}
+	e.strikes++
+	d := minPause << (e.strikes - 1)
+	if d > maxPause || d <= 0 {
+		d = maxPause
+	}
+	e.until = time.Now().Add(d)
+	e.reason = reason
+	return d, !ok
+}
+
+// pauseAll will pause updates for all sensors for the given period.
+func (p *pauseList) pauseAll(d time.Duration) time.Duration {
+	if d <= 0 {
+		d = defaultRateLimitPause
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.all = time.Now().Add(d)
+	return d
+}
+
+// clear removes any pause on the given sensor. It returns true if the sensor
+// was paused.
+func (p *pauseList) clear(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.sensors[id]; !ok {
+		return false
+	}
+	delete(p.sensors, id)
+	return true
+}
+
+// reasons returns the paused sensors and the reason they were paused.
+func (p *pauseList) reasons() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := make(map[string]string, len(p.sensors))
+	for id, e := range p.sensors {
+		r[id] = e.reason
+	}
+	return r
+}
+
+// pausedSensors is a diagnostic sensor that reports the sensors currently
+// paused after being rejected by Home Assistant.
+type pausedSensors struct {
+	paused map[string]string
+}
+
+func (s *pausedSensors) Name() string { return "Paused Sensors" }
+
+func (s *pausedSensors) ID() string { return "paused_sensors" }
+
+func (s *pausedSensors) Icon() string { return "mdi:pause-octagon" }
+
+func (s *pausedSensors) SensorType() sensor.SensorType { return sensor.TypeSensor }
+
+func (s *pausedSensors) DeviceClass() sensor.SensorDeviceClass { return 0 }
+
+func (s *pausedSensors) StateClass() sensor.SensorStateClass { return sensor.StateMeasurement }
+
+func (s *pausedSensors) State() any { return len(s.paused) }
+
+func (s *pausedSensors) Units() string { return "sensors" }
+
+func (s *pausedSensors) Category() string { return "diagnostic" }
+
+func (s *pausedSensors) Attributes() any {
+	ids := make([]string, 0, len(s.paused))
+	for id := range s.paused {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return struct {
+		Reasons map[string]string `json:"Reasons,omitempty"`
+		Sensors []string          `json:"Sensors"`
+	}{
+		Sensors: ids,
+		Reasons: s.paused,
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pauseList(t *testing.T) {
+	var p pauseList
+
+	d, newlyPaused := p.pause("sensor", "rejected")
+	assert.Equal(t, minPause, d)
+	assert.True(t, newlyPaused)
+	assert.True(t, p.isPaused("sensor"))
+	assert.False(t, p.isPaused("other"))
+
+	d, newlyPaused = p.pause("sensor", "rejected")
+	assert.Equal(t, 2*minPause, d)
+	assert.False(t, newlyPaused)
+
+	for i := 0; i < 100; i++ {
+		d, _ = p.pause("sensor", "rejected")
+	}
+	assert.Equal(t, maxPause, d)
+	assert.Equal(t, map[string]string{"sensor": "rejected"}, p.reasons())
+
+	assert.True(t, p.clear("sensor"))
+	assert.False(t, p.clear("sensor"))
+	assert.False(t, p.isPaused("sensor"))
+
+	assert.False(t, p.isPausedAll())
+	assert.Equal(t, defaultRateLimitPause, p.pauseAll(0))
+	assert.True(t, p.isPaused("other"))
+	assert.True(t, p.isPausedAll())
+	p.all = time.Time{}
+	assert.False(t, p.isPaused("other"))
+}
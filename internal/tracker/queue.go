@@ -0,0 +1,178 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package tracker
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxQueuedUpdates is the maximum number of sensor updates that will be held
+// while Home Assistant is unreachable. Once reached, the oldest updates are
+// discarded.
+const maxQueuedUpdates = 1000
+
+// timestampedSensor wraps a Sensor with the time the update was generated. If
+// the update was replayed from the offline queue, the original time is included
+// in the attributes sent to Home Assistant, as the API does not allow setting
+// the time of a state change directly.
+type timestampedSensor struct {
+	Sensor
+	updated  time.Time
+	replayed bool
+}
+
+func (s *timestampedSensor) Attributes() any {
+	if !s.replayed {
+		return s.Sensor.Attributes()
+	}
+	return withTimestamp(s.Sensor.Attributes(), s.updated)
+}
+
+// stamp wraps the given sensor with the current time, unless it has already
+// been timestamped.
+func stamp(s Sensor) *timestampedSensor {
+	if ts, ok := s.(*timestampedSensor); ok {
+		return ts
+	}
+	return &timestampedSensor{
+		Sensor:  s,
+		updated: time.Now(),
+	}
+}
+
+// withTimestamp adds a "Last Updated" field to the given attributes. Attributes
+// that cannot be represented as a JSON object are returned unchanged.
+func withTimestamp(attrs any, t time.Time) any {
+	withTime := make(map[string]any)
+	if attrs != nil {
+		b, err := json.Marshal(attrs)
+		if err != nil {
+			return attrs
+		}
+		if err := json.Unmarshal(b, &withTime); err != nil || withTime == nil {
+			return attrs
+		}
+	}
+	withTime["Last Updated"] = t.Format(time.RFC3339)
+	return withTime
+}
+
+// updateQueue holds sensor updates that could not be sent to Home Assistant
+// and tracks the time of the last update sent for each sensor, so that stale
+// updates arriving out of order are not sent.
+type updateQueue struct {
+	lastSent map[string]time.Time
+	updates  []*timestampedSensor
+	mu       sync.Mutex
+	flushing bool
+	retrying bool
+}
+
+// isStale returns whether a newer update for the same sensor has already been
+// sent.
+func (q *updateQueue) isStale(s *timestampedSensor) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	last, ok := q.lastSent[s.ID()]
+	return ok && s.updated.Before(last)
+}
+
+// markSent records the given update as the latest sent for its sensor.
+func (q *updateQueue) markSent(s *timestampedSensor) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.lastSent == nil {
+		q.lastSent = make(map[string]time.Time)
+	}
+	if last, ok := q.lastSent[s.ID()]; !ok || s.updated.After(last) {
+		q.lastSent[s.ID()] = s.updated
+	}
+}
+
+// push adds an update to the queue, in the order the updates were generated,
+// discarding the oldest update if the queue is full.
+func (q *updateQueue) push(s *timestampedSensor) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.updates) >= maxQueuedUpdates {
+		q.updates = q.updates[1:]
+	}
+	i := sort.Search(len(q.updates), func(i int) bool {
+		return q.updates[i].updated.After(s.updated)
+	})
+	q.updates = slices.Insert(q.updates, i, s)
+}
+
+// pending returns whether there are updates waiting to be replayed. While
+// there are, new updates must be queued behind them so that Home Assistant
+// receives them in order.
+func (q *updateQueue) pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.flushing || len(q.updates) > 0
+}
+
+// startFlush returns true if the caller should replay the queue. Only one
+// replay runs at a time and only when there is something in the queue.
+func (q *updateQueue) startFlush() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.flushing || len(q.updates) == 0 {
+		return false
+	}
+	q.flushing = true
+	return true
+}
+
+// next removes and returns the oldest update in the queue, marked as replayed.
+// When the queue is empty, the replay is finished and false is returned.
+func (q *updateQueue) next() (*timestampedSensor, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.updates) == 0 {
+		q.flushing = false
+		return nil, false
+	}
+	u := q.updates[0]
+	q.updates = q.updates[1:]
+	u.replayed = true
+	return u, true
+}
+
+// endFlush stops a replay early, leaving any remaining updates queued.
+func (q *updateQueue) endFlush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.flushing = false
+}
+
+// startRetry returns true if the caller should start retrying the replay of the
+// queue. Only one retry runs at a time.
+func (q *updateQueue) startRetry() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.retrying {
+		return false
+	}
+	q.retrying = true
+	return true
+}
+
+// endRetry stops retrying the replay once there is nothing left to replay, and
+// returns whether it was stopped. If force is true, it is stopped regardless.
+func (q *updateQueue) endRetry(force bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !force && (q.flushing || len(q.updates) > 0) {
+		return false
+	}
+	q.retrying = false
+	return true
+}
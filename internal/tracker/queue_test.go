@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package tracker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_withTimestamp(t *testing.T) {
+	ts := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	type args struct {
+		attrs any
+		t     time.Time
+	}
+	tests := []struct {
+		want any
+		name string
+		args args
+	}{
+		{
+			name: "nil attributes",
+			args: args{attrs: nil, t: ts},
+			want: map[string]any{"Last Updated": "2024-02-01T10:00:00Z"},
+		},
+		{
+			name: "struct attributes",
+			args: args{attrs: struct {
+				DataSource string `json:"Data Source"`
+			}{DataSource: "ProcFS"}, t: ts},
+			want: map[string]any{"Data Source": "ProcFS", "Last Updated": "2024-02-01T10:00:00Z"},
+		},
+		{
+			name: "non-object attributes",
+			args: args{attrs: []string{"a", "b"}, t: ts},
+			want: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withTimestamp(tt.args.attrs, tt.args.t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_updateQueue(t *testing.T) {
+	now := time.Now()
+	mockSensor := &SensorMock{
+		IDFunc: func() string { return "sensorID" },
+	}
+	older := &timestampedSensor{Sensor: mockSensor, updated: now.Add(-time.Minute)}
+	newer := &timestampedSensor{Sensor: mockSensor, updated: now}
+
+	q := &updateQueue{}
+	assert.False(t, q.isStale(older))
+	q.markSent(newer)
+	assert.True(t, q.isStale(older))
+	assert.False(t, q.isStale(newer))
+
+	assert.False(t, q.startFlush())
+	assert.False(t, q.pending())
+	q.push(newer)
+	q.push(older)
+	assert.True(t, q.pending())
+	assert.True(t, q.startFlush())
+	assert.False(t, q.startFlush())
+	u, ok := q.next()
+	assert.True(t, ok)
+	assert.Equal(t, older, u)
+	assert.True(t, u.replayed)
+	// Still replaying, so new updates must be queued.
+	assert.True(t, q.pending())
+	u, ok = q.next()
+	assert.True(t, ok)
+	assert.Equal(t, newer, u)
+	_, ok = q.next()
+	assert.False(t, ok)
+	assert.False(t, q.pending())
+	assert.Empty(t, q.updates)
+
+	// A replay stopped early leaves the remaining updates queued.
+	q.push(older)
+	assert.True(t, q.startFlush())
+	q.endFlush()
+	assert.True(t, q.pending())
+	assert.True(t, q.startFlush())
+
+	// Retrying continues until there is nothing left to replay.
+	assert.True(t, q.startRetry())
+	assert.False(t, q.startRetry())
+	assert.False(t, q.endRetry(false))
+	_, ok = q.next()
+	assert.True(t, ok)
+	_, ok = q.next()
+	assert.False(t, ok)
+	assert.True(t, q.endRetry(false))
+	assert.True(t, q.startRetry())
+	assert.True(t, q.endRetry(true))
+}
@@ -164,3 +164,24 @@ func parseFile(path string) (string, metadata) {
 	}
 	return sensorID, *m
 }
+
+// State is whether a sensor is registered with and/or disabled in Home
+// Assistant.
+type State metadata
+
+// States reads the registry at the given path and returns the state of each
+// sensor in it, keyed by sensor ID. Unlike NewJSONFilesRegistry, the registry
+// is read before returning.
+func States(path string) (map[string]State, error) {
+	files, err := filepath.Glob(path + "/*.json")
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]State, len(files))
+	for _, filename := range files {
+		if id, meta := parseFile(filename); id != "" {
+			states[id] = State(meta)
+		}
+	}
+	return states, nil
+}
@@ -372,3 +372,20 @@ func Test_parseFile(t *testing.T) {
 		})
 	}
 }
+
+func TestStates(t *testing.T) {
+	path := t.TempDir()
+	err := os.WriteFile(path+"/registered.json", []byte(`{"Registered":true,"Disabled":false}`), 0o644)
+	assert.Nil(t, err)
+	err = os.WriteFile(path+"/disabled.json", []byte(`{"Registered":true,"Disabled":true}`), 0o644)
+	assert.Nil(t, err)
+	err = os.WriteFile(path+"/invalid.json", []byte(`not json`), 0o644)
+	assert.Nil(t, err)
+
+	states, err := States(path)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]State{
+		"registered": {Registered: true},
+		"disabled":   {Registered: true, Disabled: true},
+	}, states)
+}
@@ -8,19 +8,23 @@ package tracker
 import (
 	"context"
 	"errors"
+	"net"
 	"os"
-	"path/filepath"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/joshuar/go-hass-agent/internal/diagnostics"
 	"github.com/joshuar/go-hass-agent/internal/hass"
 	"github.com/joshuar/go-hass-agent/internal/hass/api"
 	registry "github.com/joshuar/go-hass-agent/internal/tracker/registry/jsonFiles"
 )
 
-var basePath = filepath.Join(os.Getenv("HOME"), ".config")
+// replayRetryInterval is how often the replay of queued updates is retried
+// while Home Assistant cannot be reached.
+const replayRetryInterval = 30 * time.Second
 
 //go:generate moq -out mock_Registry_test.go . Registry
 type Registry interface {
@@ -41,6 +45,8 @@ type apiResponse interface {
 type SensorTracker struct {
 	registry Registry
 	sensor   map[string]Sensor
+	queue    updateQueue
+	paused   pauseList
 	mu       sync.Mutex
 }
 
@@ -86,25 +92,169 @@ func (t *SensorTracker) SensorList() []string {
 
 // send will send a sensor update to HA, checking to ensure the sensor is not
 // disabled. It will also update the local registry state based on the response.
-func (t *SensorTracker) send(ctx context.Context, sensorUpdate Sensor) {
-	var req api.Request
+// Updates older than the last update sent for the same sensor are discarded.
+// Updates that could not be sent due to a network problem are queued. While
+// there are queued updates, new updates are queued behind them and the queue is
+// replayed in the background, so that updates reach Home Assistant in the order
+// they were generated. Sensors rejected by Home Assistant are paused for an
+// increasing period rather than retried on every update.
+func (t *SensorTracker) send(ctx context.Context, s Sensor) {
+	sensorUpdate := stamp(s)
+	if t.paused.isPaused(sensorUpdate.ID()) {
+		log.Trace().Str("id", sensorUpdate.ID()).
+			Msg("Sensor is paused. Ignoring update.")
+		return
+	}
+	if !sensorUpdate.replayed && t.queue.pending() {
+		t.enqueue(ctx, sensorUpdate)
+		go t.replay(ctx)
+		return
+	}
+	t.deliver(ctx, sensorUpdate)
+}
+
+// deliver sends a single sensor update to HA. It returns false if the update
+// could not be sent and was queued to be retried. Queued updates are put back
+// in the queue while all updates are paused due to rate limiting, but are
+// discarded if their sensor has since been rejected by Home Assistant and
+// paused.
+func (t *SensorTracker) deliver(ctx context.Context, sensorUpdate *timestampedSensor) bool {
+	if sensorUpdate.replayed && t.paused.isPausedAll() {
+		t.enqueue(ctx, sensorUpdate)
+		return false
+	}
+	if t.paused.isPaused(sensorUpdate.ID()) {
+		log.Trace().Str("id", sensorUpdate.ID()).
+			Msg("Sensor is paused. Ignoring update.")
+		return true
+	}
+	if t.queue.isStale(sensorUpdate) {
+		log.Debug().Str("id", sensorUpdate.ID()).
+			Msg("Newer update already sent. Ignoring stale update.")
+		return true
+	}
 	if disabled := <-t.registry.IsDisabled(sensorUpdate.ID()); disabled {
 		log.Debug().Str("id", sensorUpdate.ID()).
 			Msg("Sensor is disabled. Ignoring update.")
-		return
+		return true
 	}
 	registered := <-t.registry.IsRegistered(sensorUpdate.ID())
-	req = marshallSensorState(sensorUpdate, registered)
+	req := marshallSensorState(sensorUpdate, registered)
 	response := <-api.ExecuteRequest(ctx, req)
 	switch r := response.(type) {
 	case apiResponse:
+		t.queue.markSent(sensorUpdate)
 		t.handle(r, sensorUpdate)
+		if t.paused.clear(sensorUpdate.ID()) {
+			t.send(ctx, &pausedSensors{paused: t.paused.reasons()})
+		}
 	case error:
-		log.Warn().Err(r).Str("id", sensorUpdate.ID()).
-			Msg("Failed to send sensor data to Home Assistant.")
+		diagnostics.RecordUpdateFailure()
+		var netErr net.Error
+		var rateLimitErr *api.RateLimitError
+		switch {
+		case errors.As(r, &netErr):
+			log.Warn().Err(r).Str("id", sensorUpdate.ID()).
+				Msg("Could not reach Home Assistant. Queueing sensor update.")
+			t.enqueue(ctx, sensorUpdate)
+			return false
+		case errors.As(r, &rateLimitErr):
+			d := t.paused.pauseAll(rateLimitErr.RetryAfter)
+			log.Warn().Err(r).Dur("pause", d).
+				Msg("Home Assistant is rate limiting. Pausing all sensor updates.")
+			t.enqueue(ctx, sensorUpdate)
+			return false
+		case errors.Is(r, api.ErrSensorRejected):
+			t.pauseSensor(ctx, sensorUpdate, r)
+		default:
+			log.Warn().Err(r).Str("id", sensorUpdate.ID()).
+				Msg("Failed to send sensor data to Home Assistant.")
+		}
 	default:
 		log.Warn().Msgf("Unknown response type %T", r)
 	}
+	return true
+}
+
+// pauseSensor will pause sending updates for a sensor that was rejected by
+// Home Assistant and report the paused sensors as a diagnostic sensor.
+func (t *SensorTracker) pauseSensor(ctx context.Context, s Sensor, reason error) {
+	if errors.Is(reason, api.ErrNotRegistered) {
+		if err := t.registry.SetRegistered(s.ID(), false); err != nil {
+			log.Warn().Err(err).Str("id", s.ID()).
+				Msg("Unable to set as not registered in registry.")
+		}
+	}
+	d, newlyPaused := t.paused.pause(s.ID(), reason.Error())
+	if newlyPaused {
+		log.Warn().Err(reason).Str("id", s.ID()).Dur("pause", d).
+			Msg("Sensor rejected by Home Assistant. Pausing updates.")
+		if s.ID() != (&pausedSensors{}).ID() {
+			t.send(ctx, &pausedSensors{paused: t.paused.reasons()})
+		}
+	} else {
+		log.Debug().Err(reason).Str("id", s.ID()).Dur("pause", d).
+			Msg("Sensor rejected by Home Assistant again. Extending pause.")
+	}
+}
+
+// enqueue adds an update to the queue and makes sure the queue will be
+// replayed, even if no further updates are sent.
+func (t *SensorTracker) enqueue(ctx context.Context, sensorUpdate *timestampedSensor) {
+	t.queue.push(sensorUpdate)
+	if t.queue.startRetry() {
+		go t.retryReplay(ctx)
+	}
+}
+
+// retryReplay replays the queue whenever requests to Home Assistant start
+// succeeding again, and otherwise periodically, until the queue is empty.
+func (t *SensorTracker) retryReplay(ctx context.Context) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+	connectivity := diagnostics.WatchConnectivity(ctx)
+	ticker := time.NewTicker(replayRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.queue.endRetry(true)
+			return
+		case c, ok := <-connectivity:
+			if !ok {
+				t.queue.endRetry(true)
+				return
+			}
+			if c.Webhook {
+				t.replay(ctx)
+			}
+		case <-ticker.C:
+			t.replay(ctx)
+		}
+		if t.queue.endRetry(false) {
+			return
+		}
+	}
+}
+
+// replay will send any queued sensor updates to HA, in the order they were
+// generated, until the queue is empty. If an update cannot be sent, it is left
+// in the queue and the replay stops, to be retried later (see retryReplay).
+func (t *SensorTracker) replay(ctx context.Context) {
+	if !t.queue.startFlush() {
+		return
+	}
+	log.Debug().Msg("Replaying queued sensor updates.")
+	for {
+		u, ok := t.queue.next()
+		if !ok {
+			return
+		}
+		if !t.deliver(ctx, u) {
+			t.queue.endFlush()
+			return
+		}
+	}
 }
 
 // handle will take the response sent back by the Home Assistant API and run
@@ -164,6 +314,8 @@ func (t *SensorTracker) UpdateSensors(ctx context.Context, s any) {
 		t.send(ctx, sensor)
 	case *hass.LocationData:
 		updateLocation(ctx, sensor)
+	case *hass.Event:
+		fireEvent(ctx, sensor)
 	default:
 		log.Warn().Msgf("Unknown sensor received %v", sensor)
 	}
@@ -180,8 +332,9 @@ func (t *SensorTracker) Reset() {
 	t.sensor = nil
 }
 
-func NewSensorTracker(id string) (*SensorTracker, error) {
-	registryPath := filepath.Join(basePath, id, "sensorRegistry")
+// NewSensorTracker creates a new sensor tracker, with its registry stored in the
+// given directory.
+func NewSensorTracker(registryPath string) (*SensorTracker, error) {
 	db, err := registry.NewJSONFilesRegistry(registryPath)
 	if err != nil {
 		return nil, err
@@ -10,11 +10,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -326,6 +326,25 @@ func TestSensorTracker_send(t *testing.T) {
 	}
 }
 
+func TestSensorTracker_deliverRateLimited(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	mockSensor := &SensorMock{
+		IDFunc: func() string { return "sensorID" },
+	}
+	tr := &SensorTracker{}
+	tr.paused.pauseAll(time.Minute)
+
+	// Queued updates are put back in the queue while rate limited, rather than
+	// being discarded.
+	u := &timestampedSensor{Sensor: mockSensor, updated: time.Now(), replayed: true}
+	assert.False(t, tr.deliver(ctx, u))
+	assert.True(t, tr.queue.pending())
+	assert.Equal(t, []*timestampedSensor{u}, tr.queue.updates)
+	// And will be replayed, even if no further updates are sent.
+	assert.False(t, tr.queue.startRetry())
+}
+
 func TestSensorTracker_handle(t *testing.T) {
 	mockUpdate := &SensorMock{
 		IDFunc:         func() string { return "updateID" },
@@ -442,11 +461,8 @@ func TestSensorTracker_handle(t *testing.T) {
 }
 
 func TestNewSensorTracker(t *testing.T) {
-	testID := "go-hass-agent-test"
-	basePath = t.TempDir()
-	assert.Nil(t, os.Mkdir(filepath.Join(basePath, testID), 0o755))
 	type args struct {
-		id string
+		path string
 	}
 	tests := []struct {
 		name    string
@@ -456,12 +472,12 @@ func TestNewSensorTracker(t *testing.T) {
 	}{
 		{
 			name: "default test",
-			args: args{id: testID},
+			args: args{path: filepath.Join(t.TempDir(), "sensorRegistry")},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewSensorTracker(tt.args.id)
+			_, err := NewSensorTracker(tt.args.path)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewSensorTracker() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -40,66 +40,152 @@ func init() {
 }
 
 var messageKeyToIndex = map[string]int{
-	"About":                   0,
-	"App":                     3,
-	"App Preferences":         9,
-	"App Registration":        6,
-	"Auto-discovered Servers": 13,
-	"Fyne":                    4,
-	"Fyne Preferences":        8,
-	"MQTT Password":           18,
-	"MQTT Server":             16,
-	"MQTT User":               17,
-	"Manual Server Entry":     15,
-	"Please restart the agent to use changed preferences.": 11,
-	"Preferences": 2,
-	"Quit":        5,
-	"Save":        10,
-	"Sensors":     1,
-	"To register the agent, please enter the relevant details for your Home Assistant\nserver (if not auto-detected) and long-lived access token.": 7,
-	"Token":              12,
-	"Use Custom Server?": 14,
-	"Use MQTT?":          19,
+	"A long-lived access token created in your Home Assistant profile.": 23,
+	"About":                   2,
+	"Accessibility":           48,
+	"App":                     5,
+	"App Preferences":         14,
+	"App Registration":        9,
+	"Applied":                 17,
+	"Apply":                   19,
+	"Auto-discovered Servers": 24,
+	"Cancel":                  11,
+	"Checking server...":      31,
+	"Choose beta to also be told about prereleases.": 41,
+	"Connected to Home Assistant":                    0,
+	"Could not reach server.":                        34,
+	"Dashboards":                                     8,
+	"Default Window Size":                            46,
+	"Demo Mode":                                      15,
+	"Enter the server address manually.":             27,
+	"For example, http://homeassistant.local:8123.":  29,
+	"Fyne":             6,
+	"Fyne Preferences": 13,
+	"Home Assistant servers found on your network.":                           25,
+	"Increase the size of text and controls in the agent windows.":            45,
+	"Increase the size windows open at. Resized windows remember their size.": 47,
+	"Language":                        43,
+	"Log to Journal?":                 52,
+	"MQTT Password":                   37,
+	"MQTT Server":                     35,
+	"MQTT User":                       36,
+	"Manual Server Entry":             28,
+	"Not connected to Home Assistant": 1,
+	"Preferences":                     4,
+	"Preferences are not saved in demo mode.":  16,
+	"Preferences have been saved and applied.": 18,
+	"Quit":                       7,
+	"Register":                   10,
+	"Report Active Window?":      55,
+	"Report App Network Usage?":  56,
+	"Send Anonymous Usage Data?": 39,
+	"Send logs to the systemd journal as well as the log file.": 53,
+	"Sensor":                           20,
+	"Sensors":                          3,
+	"Server Status":                    30,
+	"Server found and token accepted.": 32,
+	"Server found but the token was not accepted.": 33,
+	"Startup Delay (seconds)":                      49,
+	"Syslog Server":                                54,
+	"System Default":                               42,
+	"Text and Control Size":                        44,
+	"To register the agent, please enter the relevant details for your Home Assistant\nserver (if not auto-detected) and long-lived access token.": 12,
+	"Token":              22,
+	"Update Channel":     40,
+	"Use Custom Server?": 26,
+	"Use MQTT?":          38,
+	"Value":              21,
+	"Wait before connecting to Home Assistant when the agent starts.": 50,
+	"Wait for Network?": 51,
 }
 
-var deIndex = []uint32{ // 21 elements
+var deIndex = []uint32{ // 58 elements
+	// Entry 0 - 1F
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
-	0x00000000,
-} // Size: 108 bytes
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	// Entry 20 - 3F
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000,
+} // Size: 256 bytes
 
 const deData string = ""
 
-var enIndex = []uint32{ // 21 elements
-	0x00000000, 0x00000006, 0x0000000e, 0x0000001a,
-	0x0000001e, 0x00000023, 0x00000028, 0x00000039,
-	0x000000c5, 0x000000d6, 0x000000e6, 0x000000eb,
-	0x00000120, 0x00000126, 0x0000013e, 0x00000151,
-	0x00000165, 0x00000171, 0x0000017b, 0x00000189,
-	0x00000193,
-} // Size: 108 bytes
+var enIndex = []uint32{ // 58 elements
+	// Entry 0 - 1F
+	0x00000000, 0x0000001c, 0x0000003c, 0x00000042,
+	0x0000004a, 0x00000056, 0x0000005a, 0x0000005f,
+	0x00000064, 0x0000006f, 0x00000080, 0x00000089,
+	0x00000090, 0x0000011c, 0x0000012d, 0x0000013d,
+	0x00000147, 0x0000016f, 0x00000177, 0x000001a0,
+	0x000001a6, 0x000001ad, 0x000001b3, 0x000001b9,
+	0x000001fb, 0x00000213, 0x00000241, 0x00000254,
+	0x00000277, 0x0000028b, 0x000002b9, 0x000002c7,
+	// Entry 20 - 3F
+	0x000002da, 0x000002fb, 0x00000328, 0x00000340,
+	0x0000034c, 0x00000356, 0x00000364, 0x0000036e,
+	0x00000389, 0x00000398, 0x000003c7, 0x000003d6,
+	0x000003df, 0x000003f5, 0x00000432, 0x00000446,
+	0x0000048e, 0x0000049c, 0x000004b4, 0x000004f4,
+	0x00000506, 0x00000516, 0x00000550, 0x0000055e,
+	0x00000574, 0x0000058e,
+} // Size: 256 bytes
 
-const enData string = "" + // Size: 403 bytes
-	"\x02About\x02Sensors\x02Preferences\x02App\x02Fyne\x02Quit\x02App Regist" +
-	"ration\x02To register the agent, please enter the relevant details for y" +
-	"our Home Assistant\x0aserver (if not auto-detected) and long-lived acces" +
-	"s token.\x02Fyne Preferences\x02App Preferences\x02Save\x02Please restar" +
-	"t the agent to use changed preferences.\x02Token\x02Auto-discovered Serv" +
-	"ers\x02Use Custom Server?\x02Manual Server Entry\x02MQTT Server\x02MQTT " +
-	"User\x02MQTT Password\x02Use MQTT?"
+const enData string = "" + // Size: 1422 bytes
+	"\x02Connected to Home Assistant\x02Not connected to Home Assistant\x02Ab" +
+	"out\x02Sensors\x02Preferences\x02App\x02Fyne\x02Quit\x02Dashboards\x02Ap" +
+	"p Registration\x02Register\x02Cancel\x02To register the agent, please en" +
+	"ter the relevant details for your Home Assistant\x0aserver (if not auto-" +
+	"detected) and long-lived access token.\x02Fyne Preferences\x02App Prefer" +
+	"ences\x02Demo Mode\x02Preferences are not saved in demo mode.\x02Applied" +
+	"\x02Preferences have been saved and applied.\x02Apply\x02Sensor\x02Value" +
+	"\x02Token\x02A long-lived access token created in your Home Assistant pr" +
+	"ofile.\x02Auto-discovered Servers\x02Home Assistant servers found on you" +
+	"r network.\x02Use Custom Server?\x02Enter the server address manually." +
+	"\x02Manual Server Entry\x02For example, http://homeassistant.local:8123." +
+	"\x02Server Status\x02Checking server...\x02Server found and token accept" +
+	"ed.\x02Server found but the token was not accepted.\x02Could not reach s" +
+	"erver.\x02MQTT Server\x02MQTT User\x02MQTT Password\x02Use MQTT?\x02Send" +
+	" Anonymous Usage Data?\x02Update Channel\x02Choose beta to also be told " +
+	"about prereleases.\x02System Default\x02Language\x02Text and Control Siz" +
+	"e\x02Increase the size of text and controls in the agent windows.\x02Def" +
+	"ault Window Size\x02Increase the size windows open at. Resized windows r" +
+	"emember their size.\x02Accessibility\x02Startup Delay (seconds)\x02Wait " +
+	"before connecting to Home Assistant when the agent starts.\x02Wait for N" +
+	"etwork?\x02Log to Journal?\x02Send logs to the systemd journal as well a" +
+	"s the log file.\x02Syslog Server\x02Report Active Window?\x02Report App " +
+	"Network Usage?"
 
-var frIndex = []uint32{ // 21 elements
+var frIndex = []uint32{ // 58 elements
+	// Entry 0 - 1F
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
+	// Entry 20 - 3F
+	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
 	0x00000000, 0x00000000, 0x00000000, 0x00000000,
-	0x00000000,
-} // Size: 108 bytes
+	0x00000000, 0x00000000,
+} // Size: 256 bytes
 
 const frData string = ""
 
-// Total table size 727 bytes (0KiB); checksum: 4C189FE6
+// Total table size 2190 bytes (2KiB); checksum: B3EDC96
@@ -6,6 +6,8 @@
 package translations
 
 import (
+	"sync"
+
 	"github.com/jeandeaual/go-locale"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/text/language"
@@ -18,25 +20,47 @@ import (
 // translation of the UI
 type Translator struct {
 	msgPrinter *message.Printer
+	mu         sync.RWMutex
 }
 
+// Languages are the languages that have translations available, in addition
+// to English. Catalogs for fr and de are generated for translators, but are
+// not listed until they have been translated.
+var Languages []string
+
 // NewTranslator creates a new Translator in the locale of the system. Strings
 // translater by this Translator instance will be localised if a translation is
 // available.
 func NewTranslator() *Translator {
 	t := &Translator{}
-	userLocales, err := locale.GetLocales()
-	if err != nil {
+	t.SetLanguage("")
+	return t
+}
+
+// SetLanguage changes the language used by the Translator. If lang is empty,
+// the locale of the system is used. Strings translated after calling
+// SetLanguage will use the new language.
+func (t *Translator) SetLanguage(lang string) {
+	var printer *message.Printer
+	if lang != "" {
+		log.Debug().Msgf("Setting language to %s.", lang)
+		printer = message.NewPrinter(message.MatchLanguage(lang))
+	} else if userLocales, err := locale.GetLocales(); err != nil {
 		log.Warn().Msg("Could not find a suitable locale. Using English.")
-		t.msgPrinter = message.NewPrinter(message.MatchLanguage(language.English.String()))
+		printer = message.NewPrinter(message.MatchLanguage(language.English.String()))
+	} else {
+		log.Debug().Msgf("Setting language to %v.", userLocales)
+		printer = message.NewPrinter(message.MatchLanguage(userLocales...))
 	}
-	log.Debug().Msgf("Setting language to %v.", userLocales)
-	t.msgPrinter = message.NewPrinter(message.MatchLanguage(userLocales...))
-	return t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.msgPrinter = printer
 }
 
 // Translate will take a string defined in English and apply the appropriate
 // translation (if available) of the defined Translator.
 func (t *Translator) Translate(key string, args ...interface{}) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return t.msgPrinter.Sprintf(key, args...)
 }
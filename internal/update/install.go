@@ -0,0 +1,186 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+var (
+	// ErrNoAsset is returned when a release has no binary for this platform,
+	// or no checksums to verify it.
+	ErrNoAsset = errors.New("release has no binary for this platform")
+
+	// ErrChecksumMismatch is returned when the downloaded binary does not
+	// match the checksum published with the release.
+	ErrChecksumMismatch = errors.New("checksum of downloaded binary does not match")
+
+	// ErrBadSignature is returned when the downloaded binary is not signed
+	// with the release signing key.
+	ErrBadSignature = errors.New("signature of downloaded binary is not valid")
+)
+
+// publicKey is the cosign public key that release artifacts are signed with.
+// It is a copy of cosign.pub in the root of the repository.
+//
+//go:generate cp ../../cosign.pub cosign.pub
+//go:embed cosign.pub
+var publicKey []byte
+
+// verifySignature verifies a cosign signature, as written by cosign sign-blob,
+// of a blob with the given SHA-256 digest against the release public key.
+func verifySignature(digest, signature []byte) error {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return errors.New("could not decode public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("could not parse public key: %w", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBadSignature, err)
+	}
+	if !ecdsa.VerifyASN1(ecdsaKey, digest, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// binaryName returns the name of the binary asset of the release for this
+// platform, e.g., go-hass-agent_7.1.0_linux_amd64.
+func (r *Release) binaryName() string {
+	return fmt.Sprintf("go-hass-agent_%s_%s_%s", strings.TrimPrefix(r.Version, "v"), runtime.GOOS, runtime.GOARCH)
+}
+
+// asset returns the URL of the asset of the release with a name matching the
+// given function.
+func (r *Release) asset(match func(name string) bool) (string, bool) {
+	for _, a := range r.Assets {
+		if match(a.Name) {
+			return a.URL, true
+		}
+	}
+	return "", false
+}
+
+// parseChecksums parses a checksums file, with a SHA-256 checksum and file name
+// on each line, as written by sha256sum.
+func parseChecksums(b []byte) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return checksums
+}
+
+// Install downloads the binary of the release for this platform, verifies it
+// against the checksums published with the release and its signature against
+// the release signing key, and replaces the executable
+// at the given path with it. The executable is replaced by renaming the new
+// binary over it, so the user running the agent needs write access to its
+// directory. Agents installed with a package manager should be updated with
+// the package manager instead.
+func (r *Release) Install(ctx context.Context, exe string) error {
+	name := r.binaryName()
+	binaryURL, ok := r.asset(func(n string) bool { return n == name })
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoAsset, name)
+	}
+	checksumsURL, ok := r.asset(func(n string) bool { return strings.HasSuffix(n, "checksums.txt") })
+	if !ok {
+		return fmt.Errorf("%w: no checksums", ErrNoAsset)
+	}
+	signatureURL, ok := r.asset(func(n string) bool { return n == name+".sig" })
+	if !ok {
+		return fmt.Errorf("%w: no signature for %s", ErrNoAsset, name)
+	}
+
+	var checksums bytes.Buffer
+	if err := requests.URL(checksumsURL).ToBytesBuffer(&checksums).Fetch(ctx); err != nil {
+		return fmt.Errorf("could not download checksums: %w", err)
+	}
+	want, ok := parseChecksums(checksums.Bytes())[name]
+	if !ok {
+		return fmt.Errorf("%w: no checksum for %s", ErrNoAsset, name)
+	}
+	var signature bytes.Buffer
+	if err := requests.URL(signatureURL).ToBytesBuffer(&signature).Fetch(ctx); err != nil {
+		return fmt.Errorf("could not download signature: %w", err)
+	}
+
+	// Download to the same directory as the executable, so that it can be
+	// renamed over it.
+	tmp, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+"-update-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	hash := sha256.New()
+	err = requests.URL(binaryURL).
+		Handle(func(res *http.Response) error {
+			_, err := io.Copy(io.MultiWriter(tmp, hash), res.Body)
+			return err
+		}).
+		Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("could not download binary: %w", err)
+	}
+	digest := hash.Sum(nil)
+	if got := hex.EncodeToString(digest); got != want {
+		return ErrChecksumMismatch
+	}
+	if err := verifySignature(digest, signature.Bytes()); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0o755); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// Executable returns the path of the running executable, with any symlinks
+// resolved. It must be called before the executable is replaced, after which
+// the path can no longer be found.
+func Executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package update checks for new releases of the agent on the release channel
+// chosen in the preferences. The stable channel only considers releases, while
+// the beta channel also considers prereleases.
+package update
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// Channel is the release channel used for updates.
+type Channel string
+
+const (
+	// Stable only offers releases.
+	Stable Channel = "stable"
+	// Beta offers releases and prereleases.
+	Beta Channel = "beta"
+)
+
+// releasesURL is the GitHub API endpoint listing the releases of the agent,
+// newest first.
+var releasesURL = "https://api.github.com/repos/joshuar/go-hass-agent/releases"
+
+// ErrNoRelease is returned when no release could be found for the channel.
+var ErrNoRelease = errors.New("no release found")
+
+// ChannelFromString returns the channel with the given name. Anything other
+// than beta is the stable channel.
+func ChannelFromString(name string) Channel {
+	if Channel(name) == Beta {
+		return Beta
+	}
+	return Stable
+}
+
+// Release is a published release of the agent.
+type Release struct {
+	Published  time.Time `json:"published_at"`
+	Version    string    `json:"tag_name"`
+	URL        string    `json:"html_url"`
+	Assets     []Asset   `json:"assets"`
+	Prerelease bool      `json:"prerelease"`
+	Draft      bool      `json:"draft"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Latest returns the latest release on the given channel.
+func Latest(ctx context.Context, channel Channel) (*Release, error) {
+	var releases []Release
+	err := requests.URL(releasesURL).
+		Param("per_page", "20").
+		Accept("application/vnd.github+json").
+		ToJSON(&releases).
+		Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var latest *Release
+	for i, r := range releases {
+		if r.Draft || (r.Prerelease && channel != Beta) {
+			continue
+		}
+		if _, ok := parseVersion(r.Version); !ok {
+			continue
+		}
+		if latest == nil || IsNewer(latest.Version, r.Version) {
+			latest = &releases[i]
+		}
+	}
+	if latest == nil {
+		return nil, ErrNoRelease
+	}
+	return latest, nil
+}
+
+var versionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+type version struct {
+	prerelease []string
+	core       [3]int
+}
+
+func parseVersion(v string) (version, bool) {
+	m := versionRegex.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return version{}, false
+	}
+	var parsed version
+	for i := range parsed.core {
+		parsed.core[i], _ = strconv.Atoi(m[i+1])
+	}
+	if m[4] != "" {
+		parsed.prerelease = strings.Split(m[4], ".")
+	}
+	return parsed, true
+}
+
+// compareIdentifiers compares prerelease identifiers as per semantic
+// versioning: numeric identifiers are compared numerically and sort before
+// alphanumeric ones.
+func compareIdentifiers(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return an - bn
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// compare returns a negative number if a is older than b, a positive number if
+// a is newer and zero if they are the same version.
+func (a version) compare(b version) int {
+	for i := range a.core {
+		if d := a.core[i] - b.core[i]; d != 0 {
+			return d
+		}
+	}
+	// A prerelease is older than the release of the same version.
+	switch {
+	case len(a.prerelease) == 0 && len(b.prerelease) == 0:
+		return 0
+	case len(a.prerelease) == 0:
+		return 1
+	case len(b.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.prerelease) && i < len(b.prerelease); i++ {
+		if d := compareIdentifiers(a.prerelease[i], b.prerelease[i]); d != 0 {
+			return d
+		}
+	}
+	return len(a.prerelease) - len(b.prerelease)
+}
+
+// IsNewer returns whether the candidate version is newer than the current
+// version. If either version cannot be parsed (e.g., for a development build),
+// it returns false.
+func IsNewer(current, candidate string) bool {
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	n, ok := parseVersion(candidate)
+	if !ok {
+		return false
+	}
+	return n.compare(c) > 0
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		current, candidate string
+		want               bool
+	}{
+		{current: "v7.0.0", candidate: "v7.0.1", want: true},
+		{current: "v7.0.0", candidate: "v7.1.0", want: true},
+		{current: "v7.9.0", candidate: "v7.10.0", want: true},
+		{current: "v7.0.0", candidate: "v7.0.0", want: false},
+		{current: "v7.1.0", candidate: "v7.0.9", want: false},
+		{current: "v7.1.0-beta.1", candidate: "v7.1.0", want: true},
+		{current: "v7.1.0", candidate: "v7.1.0-rc.1", want: false},
+		{current: "v7.1.0-beta.2", candidate: "v7.1.0-beta.10", want: true},
+		{current: "v7.1.0-beta.1", candidate: "v7.1.0-rc.1", want: true},
+		{current: "v7.1.0-beta", candidate: "v7.1.0-beta.1", want: true},
+		{current: "Unknown", candidate: "v7.1.0", want: false},
+		{current: "v7.0.0", candidate: "nightly", want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsNewer(tt.current, tt.candidate), "%s -> %s", tt.current, tt.candidate)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"tag_name":"v7.2.0","draft":true},
+			{"tag_name":"v7.1.0-beta.1","prerelease":true,"html_url":"https://example.com/beta"},
+			{"tag_name":"v7.0.1","html_url":"https://example.com/stable"},
+			{"tag_name":"v7.0.0"}
+		]`))
+	}))
+	defer server.Close()
+	origURL := releasesURL
+	releasesURL = server.URL
+	defer func() { releasesURL = origURL }()
+
+	r, err := Latest(context.TODO(), Stable)
+	require.NoError(t, err)
+	assert.Equal(t, "v7.0.1", r.Version)
+	assert.Equal(t, "https://example.com/stable", r.URL)
+
+	r, err = Latest(context.TODO(), Beta)
+	require.NoError(t, err)
+	assert.Equal(t, "v7.1.0-beta.1", r.Version)
+}
+
+func TestChannelFromString(t *testing.T) {
+	assert.Equal(t, Beta, ChannelFromString("beta"))
+	assert.Equal(t, Stable, ChannelFromString("stable"))
+	assert.Equal(t, Stable, ChannelFromString(""))
+}
+
+func TestInstall(t *testing.T) {
+	binary := []byte("new agent binary")
+	sum := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(sum[:])
+
+	// Sign the binary with a test key in place of the release key.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	origKey := publicKey
+	publicKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	defer func() { publicKey = origKey }()
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	badSig, err := ecdsa.SignASN1(rand.Reader, otherKey, sum[:])
+	require.NoError(t, err)
+
+	release := &Release{Version: "v7.1.0"}
+	name := release.binaryName()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(binary)
+	})
+	mux.HandleFunc("/checksums.txt", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(checksum + "  " + name + "\n" + strings.Repeat("0", 64) + "  go-hass-agent_7.1.0_other\n"))
+	})
+	mux.HandleFunc("/bad_checksums.txt", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(strings.Repeat("0", 64) + "  " + name + "\n"))
+	})
+	mux.HandleFunc("/binary.sig", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(sig)))
+	})
+	mux.HandleFunc("/bad_binary.sig", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(badSig)))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		wantErr error
+		name    string
+		assets  []Asset
+	}{
+		{
+			name: "valid",
+			assets: []Asset{
+				{Name: name, URL: server.URL + "/binary"},
+				{Name: name + ".sig", URL: server.URL + "/binary.sig"},
+				{Name: "go-hass-agent_7.1.0_checksums.txt", URL: server.URL + "/checksums.txt"},
+			},
+		},
+		{
+			name: "checksum mismatch",
+			assets: []Asset{
+				{Name: name, URL: server.URL + "/binary"},
+				{Name: name + ".sig", URL: server.URL + "/binary.sig"},
+				{Name: "go-hass-agent_7.1.0_checksums.txt", URL: server.URL + "/bad_checksums.txt"},
+			},
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name: "bad signature",
+			assets: []Asset{
+				{Name: name, URL: server.URL + "/binary"},
+				{Name: name + ".sig", URL: server.URL + "/bad_binary.sig"},
+				{Name: "go-hass-agent_7.1.0_checksums.txt", URL: server.URL + "/checksums.txt"},
+			},
+			wantErr: ErrBadSignature,
+		},
+		{
+			name: "no signature",
+			assets: []Asset{
+				{Name: name, URL: server.URL + "/binary"},
+				{Name: "go-hass-agent_7.1.0_checksums.txt", URL: server.URL + "/checksums.txt"},
+			},
+			wantErr: ErrNoAsset,
+		},
+		{
+			name: "no binary",
+			assets: []Asset{
+				{Name: "go-hass-agent_7.1.0_checksums.txt", URL: server.URL + "/checksums.txt"},
+			},
+			wantErr: ErrNoAsset,
+		},
+		{
+			name:    "no checksums",
+			assets:  []Asset{{Name: name, URL: server.URL + "/binary"}},
+			wantErr: ErrNoAsset,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exe := filepath.Join(t.TempDir(), "go-hass-agent")
+			require.NoError(t, os.WriteFile(exe, []byte("old agent binary"), 0o755))
+			release.Assets = tt.assets
+			err := release.Install(context.TODO(), exe)
+			got, readErr := os.ReadFile(exe)
+			require.NoError(t, readErr)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, []byte("old agent binary"), got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, binary, got)
+			info, err := os.Stat(exe)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+			// The temporary download is removed.
+			entries, err := os.ReadDir(filepath.Dir(exe))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	// The embedded key must match the key release artifacts are signed with.
+	want, err := os.ReadFile(filepath.Join("..", "..", "cosign.pub"))
+	require.NoError(t, err)
+	assert.Equal(t, want, publicKey)
+}
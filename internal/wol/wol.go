@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package wol sends Wake-on-LAN magic packets, to wake devices on the local
+// network.
+package wol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	// port is the UDP port magic packets are sent to. Port 9 (discard) is the
+	// most widely supported.
+	port = 9
+	// DefaultBroadcast is the broadcast address of the local network, used
+	// when no broadcast address is given.
+	DefaultBroadcast = "255.255.255.255"
+)
+
+// ErrInvalidMAC is returned when the MAC address is not a 48-bit (EUI-48)
+// address.
+var ErrInvalidMAC = errors.New("invalid MAC address")
+
+// MagicPacket returns the magic packet to wake the device with the given MAC
+// address: six bytes of 0xFF followed by the MAC address 16 times.
+func MagicPacket(mac string) ([]byte, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMAC, mac)
+	}
+	packet := bytes.Repeat([]byte{0xFF}, 6)
+	packet = append(packet, bytes.Repeat(hw, 16)...)
+	return packet, nil
+}
+
+// Send sends a magic packet to wake the device with the given MAC address to
+// the given broadcast address, or DefaultBroadcast if empty.
+func Send(ctx context.Context, mac, broadcast string) error {
+	packet, err := MagicPacket(mac)
+	if err != nil {
+		return err
+	}
+	if broadcast == "" {
+		broadcast = DefaultBroadcast
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp4", net.JoinHostPort(broadcast, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(packet)
+	return err
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicPacket(t *testing.T) {
+	mac := []byte{0x00, 0x11, 0x22, 0xaa, 0xbb, 0xcc}
+	want := append(bytes.Repeat([]byte{0xFF}, 6), bytes.Repeat(mac, 16)...)
+	for _, addr := range []string{"00:11:22:aa:bb:cc", "00-11-22-AA-BB-CC", "0011.22aa.bbcc"} {
+		got, err := MagicPacket(addr)
+		require.NoError(t, err, addr)
+		assert.Len(t, got, 102)
+		assert.Equal(t, want, got, addr)
+	}
+	for _, addr := range []string{"", "not a mac", "00:11:22:aa:bb", "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01"} {
+		_, err := MagicPacket(addr)
+		assert.ErrorIs(t, err, ErrInvalidMAC, addr)
+	}
+}
@@ -164,6 +164,7 @@ func (r *busRequest) GetData(method string, args ...any) *dbusData {
 	if err != nil {
 		log.Debug().Err(err).
 			Msgf("Unable to execute %s on %s (args: %s)", method, r.dest, args)
+		d.err = err
 	}
 	return d
 }
@@ -180,6 +181,28 @@ func (r *busRequest) Call(method string, args ...any) error {
 	return obj.Call(method, 0).Err
 }
 
+// Export makes the exported methods of v available to other clients of the
+// bus, under the given interface at the path in the builder, until the context
+// is canceled. It returns the unique name of the connection, which clients use
+// as the destination of their method calls.
+func (r *busRequest) Export(ctx context.Context, v any, iface string) (string, error) {
+	if r.bus == nil {
+		return "", errors.New("no bus connection")
+	}
+	if err := r.bus.conn.Export(v, r.path, iface); err != nil {
+		return "", err
+	}
+	r.bus.wg.Add(1)
+	go func() {
+		defer r.bus.wg.Done()
+		<-ctx.Done()
+		if err := r.bus.conn.Export(nil, r.path, iface); err != nil {
+			log.Debug().Err(err).Str("path", string(r.path)).Msg("Could not remove exported D-Bus object.")
+		}
+	}()
+	return r.bus.conn.Names()[0], nil
+}
+
 func (r *busRequest) AddWatch(ctx context.Context) error {
 	if r.bus == nil {
 		return errors.New("no bus connection")
@@ -229,6 +252,15 @@ func (r *busRequest) RemoveWatch(ctx context.Context) error {
 
 type dbusData struct {
 	data any
+	err  error
+}
+
+// Err returns the error, if any, from fetching the D-Bus data.
+func (d *dbusData) Err() error {
+	if d == nil {
+		return errors.New("no bus connection")
+	}
+	return d.err
 }
 
 // AsVariantMap formats DBus data as a map[string]dbus.Variant.